@@ -2,17 +2,95 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"time"
 
+	"github.com/tim-beatham/smegmesh/pkg/conf"
 	smegdns "github.com/tim-beatham/smegmesh/pkg/dns"
+	"github.com/tim-beatham/smegmesh/pkg/ipc"
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
 )
 
+// WATCH_RETRY: time to wait before watching the daemon again after it
+// could not be reached
+const WATCH_RETRY = 5 * time.Second
+
+// watch: keep the index up to date with the nodes in the daemon. The
+// client is closed and the daemon dialled again if the watch fails as
+// the connection does not survive the daemon restarting
+func watch(client *ipc.SmegmeshIpc, index *smegdns.Index) {
+	for {
+		if client == nil {
+			var err error
+			client, err = ipc.NewClientIpc()
+
+			if err != nil {
+				logging.Log.WriteErrorf("failed to connect to the daemon: %s", err.Error())
+				time.Sleep(WATCH_RETRY)
+				continue
+			}
+		}
+
+		var reply ipc.WatchReply
+
+		err := client.Watch(ipc.WatchArgs{Version: index.Version()}, &reply)
+
+		if err != nil {
+			logging.Log.WriteErrorf("failed to watch the daemon: %s", err.Error())
+			client.Close()
+			client = nil
+			time.Sleep(WATCH_RETRY)
+			continue
+		}
+
+		if index.Update(reply.Version, reply.Meshes, reply.Names) {
+			logging.Log.WriteInfof("updated DNS index to version %s", reply.Version)
+		}
+	}
+}
+
 func main() {
-	server, err := smegdns.NewDns(53)
+	configuration := &conf.DnsConfiguration{WordsFile: "./cmd/api/words.txt"}
+	var err error
+
+	if len(os.Args) == 2 {
+		configuration, err = conf.ParseDnsConfiguration(os.Args[1])
+	} else {
+		err = conf.ValidateDnsConfiguration(configuration)
+	}
 
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
+	client, err := ipc.NewClientIpc()
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	index := smegdns.NewIndex()
+
+	server, err := smegdns.NewServer(&smegdns.NewServerParams{
+		Conf:       configuration,
+		Lookup:     index.Lookup,
+		ListMeshes: index.ListMeshes,
+	})
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	go watch(client, index)
+
+	if err := server.Bind(""); err != nil {
+		log.Fatal(err.Error())
+	}
+
 	defer server.Close()
-	server.Listen()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	<-c
 }
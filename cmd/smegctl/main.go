@@ -1,15 +1,24 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	ipcRpc "net/rpc"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/akamensky/argparse"
+	"github.com/tim-beatham/smegmesh/pkg/acl"
 	"github.com/tim-beatham/smegmesh/pkg/ctrlserver"
 	graph "github.com/tim-beatham/smegmesh/pkg/dot"
+	"github.com/tim-beatham/smegmesh/pkg/history"
 	"github.com/tim-beatham/smegmesh/pkg/ipc"
+	"github.com/tim-beatham/smegmesh/pkg/lib"
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
 )
 
 const SockAddr = "/tmp/wgmesh_ipc.sock"
@@ -45,7 +54,25 @@ func listMeshes(client *ipc.SmegmeshIpc) {
 	}
 
 	for _, meshId := range reply.Meshes {
-		fmt.Println(meshId)
+		info, ok := reply.Infos[meshId]
+
+		if !ok {
+			fmt.Println(meshId)
+			continue
+		}
+
+		name := meshId
+
+		if info.Name != "" {
+			name = fmt.Sprintf("%s (%s)", meshId, info.Name)
+		}
+
+		fmt.Printf("%s created by %s at %s\n", name, info.Creator,
+			time.Unix(info.Created, 0).Format(time.RFC3339))
+
+		if info.Description != "" {
+			fmt.Printf("  %s\n", info.Description)
+		}
 	}
 }
 
@@ -96,7 +123,12 @@ func getGraph(client *ipc.SmegmeshIpc) {
 			return
 		}
 
-		meshes[meshId] = meshReply.Nodes
+		// Mesh names are unique among the meshes we have joined
+		if info, ok := listMeshesReply.Infos[meshId]; ok && info.Name != "" {
+			meshes[info.Name] = meshReply.Nodes
+		} else {
+			meshes[meshId] = meshReply.Nodes
+		}
 	}
 
 	dotGenerator := graph.NewMeshGraphConverter(meshes)
@@ -110,12 +142,13 @@ func getGraph(client *ipc.SmegmeshIpc) {
 	fmt.Println(dot)
 }
 
-func queryMesh(client *ipc.SmegmeshIpc, meshId, query string) {
+func queryMesh(client *ipc.SmegmeshIpc, meshId, selector, query string) {
 	var reply string
 
 	args := ipc.QueryMesh{
-		MeshId: meshId,
-		Query:  query,
+		MeshId:   meshId,
+		Query:    query,
+		Selector: selector,
 	}
 
 	err := client.Query(args, &reply)
@@ -178,6 +211,35 @@ func setService(client *ipc.SmegmeshIpc, meshId, service, value string) {
 	fmt.Println(reply)
 }
 
+// label: places a label given as key=value on the node or removes the
+// label given as key-
+func label(client *ipc.SmegmeshIpc, meshId, label string) {
+	var reply string
+	var err error
+
+	if key, ok := strings.CutSuffix(label, "-"); ok && !strings.Contains(label, "=") {
+		err = client.DeleteLabel(ipc.DeleteLabelArgs{
+			MeshId: meshId,
+			Key:    key,
+		}, &reply)
+	} else if key, value, ok := strings.Cut(label, "="); ok {
+		err = client.PutLabel(ipc.PutLabelArgs{
+			MeshId: meshId,
+			Key:    key,
+			Value:  value,
+		}, &reply)
+	} else {
+		err = fmt.Errorf("label %q must be given as key=value or key- to remove it", label)
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(reply)
+}
+
 func deleteService(client *ipc.SmegmeshIpc, meshId, service string) {
 	var reply string
 
@@ -194,6 +256,476 @@ func deleteService(client *ipc.SmegmeshIpc, meshId, service string) {
 	fmt.Println(reply)
 }
 
+// diffState: compares our store with the peer's and prints each
+// divergence
+func diffState(client *ipc.SmegmeshIpc, meshId, peer string) {
+	var reply ipc.DiffStateReply
+
+	err := client.DiffState(ipc.DiffStateArgs{
+		MeshId: meshId,
+		Peer:   peer,
+	}, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("highest stale: local %d remote %d\n", reply.LocalHighestStale, reply.RemoteHighestStale)
+
+	if len(reply.Differences) == 0 {
+		fmt.Println("stores are consistent")
+		return
+	}
+
+	for _, difference := range reply.Differences {
+		if difference.Type == mesh.HIGHEST_STALE {
+			fmt.Printf("%-18s %s\n", difference.Type, difference.Reason)
+			continue
+		}
+
+		node := ""
+		localClock := "-"
+		remoteClock := "-"
+
+		if difference.Local != nil {
+			node = difference.Local.PublicKey
+			localClock = fmt.Sprintf("add=%d remove=%d", difference.Local.AddClock, difference.Local.RemoveClock)
+		}
+
+		if difference.Remote != nil {
+			if node == "" {
+				node = difference.Remote.PublicKey
+			}
+
+			remoteClock = fmt.Sprintf("add=%d remove=%d", difference.Remote.AddClock, difference.Remote.RemoveClock)
+		}
+
+		if node == "" {
+			node = fmt.Sprintf("%d", difference.Key)
+		}
+
+		fmt.Printf("%-18s %s local(%s) remote(%s): %s\n", difference.Type, node, localClock, remoteClock, difference.Reason)
+	}
+}
+
+// grantJoin: issues a credential granting the node access to the mesh
+func grantJoin(client *ipc.SmegmeshIpc, meshId, publicKey string, ttl int) {
+	var reply string
+
+	err := client.GrantJoin(ipc.GrantJoinArgs{
+		MeshId:    meshId,
+		PublicKey: publicKey,
+		Ttl:       ttl,
+	}, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(reply)
+}
+
+// createInvite: issues an invite to the mesh
+func createInvite(client *ipc.SmegmeshIpc, meshId, ttl string, uses int, role string) {
+	duration, err := time.ParseDuration(ttl)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	var reply string
+
+	err = client.CreateInvite(ipc.CreateInviteArgs{
+		MeshId: meshId,
+		Ttl:    int(duration.Seconds()),
+		Uses:   uses,
+		Role:   role,
+	}, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(reply)
+}
+
+// pendingJoins: lists requests to join the mesh awaiting a decision
+func pendingJoins(client *ipc.SmegmeshIpc, meshId string) {
+	var reply ipc.PendingJoinsReply
+
+	err := client.PendingJoins(meshId, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if len(reply.Requests) == 0 {
+		fmt.Println("no pending requests")
+		return
+	}
+
+	for _, request := range reply.Requests {
+		fmt.Printf("%s %s role=%s alias=%q description=%q\n",
+			time.Unix(request.Timestamp, 0).Format(time.RFC3339),
+			request.PublicKey, request.Role, request.Alias, request.Description)
+	}
+}
+
+// decideJoin: approves or rejects a node's request to join the mesh
+func decideJoin(client *ipc.SmegmeshIpc, meshId, publicKey string, approve bool) {
+	var reply string
+
+	err := client.DecideJoin(ipc.DecideJoinArgs{
+		MeshId:    meshId,
+		PublicKey: publicKey,
+		Approve:   approve,
+	}, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(reply)
+}
+
+// evict: evicts a node from the mesh
+func evict(client *ipc.SmegmeshIpc, meshId, publicKey string) {
+	var reply string
+
+	err := client.Evict(ipc.EvictArgs{
+		MeshId:    meshId,
+		PublicKey: publicKey,
+	}, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(reply)
+}
+
+// grantAdmin: grants or revokes a node's admin rights
+func grantAdmin(client *ipc.SmegmeshIpc, meshId, publicKey string, admin bool) {
+	var reply string
+
+	err := client.GrantAdmin(ipc.GrantAdminArgs{
+		MeshId:    meshId,
+		PublicKey: publicKey,
+		Admin:     admin,
+	}, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(reply)
+}
+
+// rotateKey: replaces the node's WireGuard key in every mesh
+func rotateKey(client *ipc.SmegmeshIpc) {
+	var reply ipc.RotateKeyReply
+
+	err := client.RotateKey(&reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("Rotating %s to %s at %s\n", reply.PublicKey, reply.Successor,
+		time.Unix(reply.Activates, 0).Format(time.RFC3339))
+}
+
+// listAdmins: lists the owner and admins of the mesh
+func listAdmins(client *ipc.SmegmeshIpc, meshId string) {
+	var reply ipc.ListAdminsReply
+
+	err := client.ListAdmins(meshId, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	for _, admin := range reply.Admins {
+		if admin == reply.Owner {
+			fmt.Printf("%s owner\n", admin)
+		} else {
+			fmt.Printf("%s admin\n", admin)
+		}
+	}
+}
+
+// setAcl: sets the access control policy of the mesh to the policy in
+// the JSON file. An empty file name clears the policy
+func setAcl(client *ipc.SmegmeshIpc, meshId, fileName string) {
+	var policy *acl.Policy
+
+	if fileName != "" {
+		contents, err := os.ReadFile(fileName)
+
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+
+		policy = &acl.Policy{}
+
+		if err := json.Unmarshal(contents, policy); err != nil {
+			fmt.Printf("could not parse %s: %s\n", fileName, err.Error())
+			return
+		}
+	}
+
+	var reply string
+
+	err := client.SetAcl(ipc.SetAclArgs{
+		MeshId: meshId,
+		Policy: policy,
+	}, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(reply)
+}
+
+// getAcl: prints the access control policy of the mesh as JSON
+func getAcl(client *ipc.SmegmeshIpc, meshId string) {
+	var reply ipc.GetAclReply
+
+	err := client.GetAcl(meshId, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if reply.Policy == nil {
+		fmt.Println("No access control policy is set")
+		return
+	}
+
+	policy, err := json.MarshalIndent(reply.Policy, "", "  ")
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("Set by %s at %s\n", reply.Author,
+		time.Unix(reply.Timestamp, 0).Format(time.RFC3339))
+	fmt.Println(string(policy))
+}
+
+// status: prints the state of the node in each mesh, the aliases
+// claimed by more than one node and the recent events
+func status(client *ipc.SmegmeshIpc, meshId string) {
+	var reply ipc.StatusReply
+
+	err := client.Status(meshId, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("public key %s\n", reply.PublicKey)
+
+	for _, meshStatus := range reply.Meshes {
+		fmt.Printf("\nmesh %s: %d nodes\n", meshStatus.MeshId, meshStatus.Nodes)
+
+		if meshStatus.Alias != meshStatus.ResolvedAlias {
+			fmt.Printf("  alias %s is taken, known as %s\n", meshStatus.Alias, meshStatus.ResolvedAlias)
+		} else if meshStatus.Alias != "" {
+			fmt.Printf("  alias %s\n", meshStatus.Alias)
+		}
+
+		for _, conflict := range meshStatus.AliasConflicts {
+			fmt.Printf("  ! alias %s claimed by %d nodes, kept by %s\n", conflict.Alias,
+				len(conflict.Renamed)+1, conflict.Owner)
+
+			renamed := lib.MapKeys(conflict.Renamed)
+			slices.Sort(renamed)
+
+			for _, publicKey := range renamed {
+				fmt.Printf("    %s is known as %s\n", publicKey, conflict.Renamed[publicKey])
+			}
+		}
+	}
+
+	if len(reply.Events) != 0 {
+		fmt.Println("\nevents:")
+	}
+
+	for _, event := range reply.Events {
+		fmt.Printf("%s %s %s %s\n", time.Unix(event.Timestamp, 0).Format(time.RFC3339),
+			event.MeshId, event.Type, event.Message)
+	}
+}
+
+// parseTime: parses a point in time either as RFC3339, a date and time,
+// a time today or a UNIX timestamp
+func parseTime(value string) (time.Time, error) {
+	if value == "" || value == "now" {
+		return time.Now(), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			now := time.Now()
+			return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local), nil
+		}
+	}
+
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+
+	return time.Time{}, fmt.Errorf("could not parse time %s", value)
+}
+
+// getHistory: lists the snapshots recorded for the mesh
+func getHistory(client *ipc.SmegmeshIpc, meshId string) {
+	var reply ipc.HistoryReply
+
+	err := client.History(meshId, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	for _, entry := range reply.Entries {
+		fmt.Printf("%s %d nodes\n", time.Unix(entry.Timestamp, 0).Format(time.RFC3339), entry.NodeCount)
+	}
+}
+
+// showAt: prints the state of the mesh at the given time
+func showAt(client *ipc.SmegmeshIpc, meshId, at string) {
+	atTime, err := parseTime(at)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	var reply history.Snapshot
+
+	err = client.ShowAt(ipc.ShowAtArgs{MeshId: meshId, At: atTime.Unix()}, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	output, err := json.MarshalIndent(reply, "", "  ")
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println(string(output))
+}
+
+// diffHistory: prints the changes to the mesh between two points in time
+func diffHistory(client *ipc.SmegmeshIpc, meshId, from, to string) {
+	fromTime, err := parseTime(from)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	toTime, err := parseTime(to)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	var reply history.Diff
+
+	err = client.DiffHistory(ipc.DiffHistoryArgs{
+		MeshId: meshId,
+		From:   fromTime.Unix(),
+		To:     toTime.Unix(),
+	}, &reply)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("changes from %s to %s\n", time.Unix(reply.From, 0).Format(time.RFC3339),
+		time.Unix(reply.To, 0).Format(time.RFC3339))
+
+	for _, node := range reply.Joined {
+		fmt.Printf("+ joined %s %s %s\n", node.PublicKey, node.Alias, node.WgHost)
+	}
+
+	for _, node := range reply.Left {
+		fmt.Printf("- left %s %s %s\n", node.PublicKey, node.Alias, node.WgHost)
+	}
+
+	for _, change := range reply.RouteChanges {
+		for _, route := range change.Added {
+			fmt.Printf("+ route %s via %s\n", route, change.PublicKey)
+		}
+
+		for _, route := range change.Removed {
+			fmt.Printf("- route %s via %s\n", route, change.PublicKey)
+		}
+	}
+
+	for _, change := range reply.ServiceChanges {
+		for service, value := range change.Added {
+			fmt.Printf("+ service %s=%s on %s\n", service, value, change.PublicKey)
+		}
+
+		for service, value := range change.Changed {
+			fmt.Printf("~ service %s=%s on %s\n", service, value, change.PublicKey)
+		}
+
+		for _, service := range change.Removed {
+			fmt.Printf("- service %s on %s\n", service, change.PublicKey)
+		}
+	}
+
+	for _, change := range reply.RecordChanges {
+		for _, service := range change.Added {
+			fmt.Printf("+ service record %s %s/%d %s on %s\n", service.Name, service.Protocol,
+				service.Port, service.Status, change.PublicKey)
+		}
+
+		for _, service := range change.Changed {
+			fmt.Printf("~ service record %s %s/%d %s on %s\n", service.Name, service.Protocol,
+				service.Port, service.Status, change.PublicKey)
+		}
+
+		for _, service := range change.Removed {
+			fmt.Printf("- service record %s on %s\n", service, change.PublicKey)
+		}
+	}
+}
+
 func main() {
 	parser := argparse.NewParser("smgctl",
 		"smegctl Manipulate WireGuard mesh networks")
@@ -208,6 +740,25 @@ func main() {
 	putAliasCmd := parser.NewCommand("put-alias", "Place an alias for the node")
 	setServiceCmd := parser.NewCommand("set-service", "Place a service into your advertisements")
 	deleteServiceCmd := parser.NewCommand("delete-service", "Remove a service from your advertisements")
+	labelCmd := parser.NewCommand("label", "Place a label key=value on the node or remove it with key-")
+	diffStateCmd := parser.NewCommand("diff-state", "Compare the mesh state with that of a peer")
+	historyCmd := parser.NewCommand("history", "List the recorded history of a mesh")
+	showCmd := parser.NewCommand("show", "Show the mesh at a point in time")
+	diffHistoryCmd := parser.NewCommand("diff-history", "Show the changes to a mesh between two points in time")
+	grantJoinCmd := parser.NewCommand("grant-join", "Issue a credential allowing a node to join a mesh")
+	createInviteCmd := parser.NewCommand("create-invite", "Issue an invite allowing a number of nodes to join a mesh")
+	pendingJoinsCmd := parser.NewCommand("pending-joins", "List requests to join a mesh awaiting approval")
+	approveCmd := parser.NewCommand("approve", "Approve a node's request to join a mesh")
+	rejectCmd := parser.NewCommand("reject", "Reject a node's request to join a mesh")
+	evictCmd := parser.NewCommand("evict", "Evict a node from a mesh")
+	grantAdminCmd := parser.NewCommand("grant-admin", "Grant a node admin rights in a mesh")
+	revokeAdminCmd := parser.NewCommand("revoke-admin", "Revoke a node's admin rights in a mesh")
+	listAdminsCmd := parser.NewCommand("list-admins", "List the owner and admins of a mesh")
+	rotateKeyCmd := parser.NewCommand("rotate-key", "Replace the node's WireGuard key in every mesh")
+	setAclCmd := parser.NewCommand("set-acl", "Set the access control policy of a mesh")
+	clearAclCmd := parser.NewCommand("clear-acl", "Clear the access control policy of a mesh")
+	getAclCmd := parser.NewCommand("get-acl", "Show the access control policy of a mesh")
+	statusCmd := parser.NewCommand("status", "Show the node's state in each mesh, alias conflicts and recent events")
 
 	var newMeshPort *int = newMeshCmd.Int("p", "wgport", &argparse.Options{
 		Default: 0,
@@ -234,14 +785,28 @@ func main() {
 		Help: "Advertise ::/0 into the mesh network",
 	})
 
+	var newMeshRequireApproval *bool = newMeshCmd.Flag("", "require-approval", &argparse.Options{
+		Help: "Require an admin to approve every node that joins the mesh",
+	})
+
+	var newMeshPresharedKeys *bool = newMeshCmd.Flag("", "preshared-keys", &argparse.Options{
+		Help: "Agree a WireGuard pre-shared key between every pair of nodes for post-quantum resistance",
+	})
+
+	var newMeshName *string = newMeshCmd.String("n", "name", &argparse.Options{
+		Help: "Human readable name of the mesh that can be given in place of its id. Must be unique among the meshes joined",
+	})
+
+	var newMeshDescription *string = newMeshCmd.String("", "description", &argparse.Options{
+		Help: "Description of the mesh",
+	})
+
 	var joinMeshId *string = joinMeshCmd.String("m", "meshid", &argparse.Options{
-		Required: true,
-		Help:     "MeshID of the mesh network to join",
+		Help: "MeshID of the mesh network to join. Required unless an invite is given",
 	})
 
 	var joinMeshIpAddress *string = joinMeshCmd.String("i", "ip", &argparse.Options{
-		Required: true,
-		Help:     "IP address of the bootstrapping node to join through",
+		Help: "IP address of the bootstrapping node to join through. Required unless an invite is given",
 	})
 
 	var joinMeshEndpoint *string = joinMeshCmd.String("e", "endpoint", &argparse.Options{
@@ -272,6 +837,22 @@ func main() {
 		Help: "Advertise ::/0 into the mesh network",
 	})
 
+	var joinMeshCredential *string = joinMeshCmd.String("c", "credential", &argparse.Options{
+		Help: "Credential issued by a member of the mesh with grant-join",
+	})
+
+	var joinMeshInvite *string = joinMeshCmd.String("", "invite", &argparse.Options{
+		Help: "Invite issued by a member of the mesh with create-invite",
+	})
+
+	var joinMeshAlias *string = joinMeshCmd.String("", "alias", &argparse.Options{
+		Help: "Alias to request if the mesh requires approval",
+	})
+
+	var joinMeshDescription *string = joinMeshCmd.String("", "description", &argparse.Options{
+		Help: "Description to give the mesh's admins if the mesh requires approval",
+	})
+
 	var leaveMeshMeshId *string = leaveMeshCmd.String("m", "mesh", &argparse.Options{
 		Required: true,
 		Help:     "MeshID of the mesh to leave",
@@ -282,8 +863,10 @@ func main() {
 		Help:     "MeshID of the mesh to query",
 	})
 	var queryMeshQuery *string = queryMeshCmd.String("q", "query", &argparse.Options{
-		Required: true,
-		Help:     "JMESPath Query Of The Mesh Network To Query",
+		Help: "JMESPath Query Of The Mesh Network To Query. Returns the nodes if not given",
+	})
+	var queryMeshSelector *string = queryMeshCmd.String("l", "selector", &argparse.Options{
+		Help: "Label selector such as env=prod,role!=db restricting the nodes to query",
 	})
 
 	var description *string = putDescriptionCmd.String("d", "description", &argparse.Options{
@@ -330,6 +913,173 @@ func main() {
 		Help:     "MeshID of the mesh network to join",
 	})
 
+	var labelMeshId *string = labelCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to label the node in",
+	})
+
+	var labelLabel *string = labelCmd.StringPositional(&argparse.Options{
+		Help: "Label to place given as key=value or key- to remove the label",
+	})
+
+	var diffStateMeshId *string = diffStateCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to compare",
+	})
+
+	var diffStatePeer *string = diffStateCmd.String("p", "peer", &argparse.Options{
+		Required: true,
+		Help:     "gRPC endpoint of the peer to compare against",
+	})
+
+	var historyMeshId *string = historyCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to list the history of",
+	})
+
+	var showMeshId *string = showCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to show",
+	})
+
+	var showAtTime *string = showCmd.String("t", "at", &argparse.Options{
+		Required: true,
+		Help:     "Time to show the mesh at. Either RFC3339, YYYY-MM-DD HH:MM, HH:MM today or a UNIX timestamp",
+	})
+
+	var diffHistoryMeshId *string = diffHistoryCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to compare",
+	})
+
+	var diffHistoryFrom *string = diffHistoryCmd.String("f", "from", &argparse.Options{
+		Required: true,
+		Help:     "Earlier point in time to compare from",
+	})
+
+	var diffHistoryTo *string = diffHistoryCmd.String("t", "to", &argparse.Options{
+		Default: "now",
+		Help:    "Later point in time to compare to, defaults to now",
+	})
+
+	var grantJoinMeshId *string = grantJoinCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to grant access to",
+	})
+
+	var grantJoinPublicKey *string = grantJoinCmd.String("k", "key", &argparse.Options{
+		Required: true,
+		Help:     "WireGuard public key of the node joining the mesh",
+	})
+
+	var grantJoinTtl *int = grantJoinCmd.Int("t", "ttl", &argparse.Options{
+		Default: 3600,
+		Help:    "Number of seconds the credential is valid for",
+	})
+
+	var createInviteMeshId *string = createInviteCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to invite nodes to",
+	})
+
+	var createInviteTtl *string = createInviteCmd.String("t", "ttl", &argparse.Options{
+		Default: "24h",
+		Help:    "How long the invite is valid for e.g. 24h",
+	})
+
+	var createInviteUses *int = createInviteCmd.Int("u", "uses", &argparse.Options{
+		Default: 1,
+		Help:    "Number of nodes that may redeem the invite",
+	})
+
+	var createInviteRole *string = createInviteCmd.Selector("r", "role", []string{"peer", "client"}, &argparse.Options{
+		Help: "Role nodes must join the mesh with. Any role if not given",
+	})
+
+	var pendingJoinsMeshId *string = pendingJoinsCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to list requests for",
+	})
+
+	var approveMeshId *string = approveCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network the node requested to join",
+	})
+
+	var approvePublicKey *string = approveCmd.String("k", "key", &argparse.Options{
+		Required: true,
+		Help:     "WireGuard public key of the node to approve",
+	})
+
+	var rejectMeshId *string = rejectCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network the node requested to join",
+	})
+
+	var rejectPublicKey *string = rejectCmd.String("k", "key", &argparse.Options{
+		Required: true,
+		Help:     "WireGuard public key of the node to reject",
+	})
+
+	var evictMeshId *string = evictCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to evict the node from",
+	})
+
+	var evictPublicKey *string = evictCmd.String("n", "node", &argparse.Options{
+		Required: true,
+		Help:     "WireGuard public key of the node to evict",
+	})
+
+	var grantAdminMeshId *string = grantAdminCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to grant admin rights in",
+	})
+
+	var grantAdminPublicKey *string = grantAdminCmd.String("k", "key", &argparse.Options{
+		Required: true,
+		Help:     "WireGuard public key of the node to grant admin rights to",
+	})
+
+	var revokeAdminMeshId *string = revokeAdminCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to revoke admin rights in",
+	})
+
+	var revokeAdminPublicKey *string = revokeAdminCmd.String("k", "key", &argparse.Options{
+		Required: true,
+		Help:     "WireGuard public key of the node to revoke admin rights from",
+	})
+
+	var listAdminsMeshId *string = listAdminsCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to list the admins of",
+	})
+
+	var setAclMeshId *string = setAclCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to set the access control policy of",
+	})
+
+	var setAclFile *string = setAclCmd.String("f", "file", &argparse.Options{
+		Required: true,
+		Help:     "JSON file containing the access control policy",
+	})
+
+	var clearAclMeshId *string = clearAclCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to clear the access control policy of",
+	})
+
+	var getAclMeshId *string = getAclCmd.String("m", "mesh", &argparse.Options{
+		Required: true,
+		Help:     "MeshID of the mesh network to show the access control policy of",
+	})
+
+	var statusMeshId *string = statusCmd.String("m", "mesh", &argparse.Options{
+		Help: "MeshID of the mesh network to show the state of. Shows every mesh if not given",
+	})
+
 	err := parser.Parse(os.Args)
 
 	if err != nil {
@@ -352,6 +1102,10 @@ func main() {
 				AdvertiseDefaultRoute: *newMeshAdvertiseDefaults,
 				AdvertiseRoutes:       *newMeshAdvertiseRoutes,
 			},
+			RequireApproval: *newMeshRequireApproval,
+			PresharedKeys:   *newMeshPresharedKeys,
+			Name:            *newMeshName,
+			Description:     *newMeshDescription,
 		}
 
 		createMesh(client, args)
@@ -362,9 +1116,18 @@ func main() {
 	}
 
 	if joinMeshCmd.Happened() {
+		if *joinMeshInvite == "" && (*joinMeshId == "" || *joinMeshIpAddress == "") {
+			fmt.Print(parser.Usage("join-mesh requires --meshid and --ip or --invite"))
+			return
+		}
+
 		args := ipc.JoinMeshArgs{
-			IpAddress: *joinMeshIpAddress,
-			MeshId:    *joinMeshId,
+			IpAddress:   *joinMeshIpAddress,
+			MeshId:      *joinMeshId,
+			Credential:  *joinMeshCredential,
+			Invite:      *joinMeshInvite,
+			Alias:       *joinMeshAlias,
+			Description: *joinMeshDescription,
 			WgArgs: ipc.WireGuardArgs{
 				Endpoint:              *joinMeshEndpoint,
 				Role:                  *joinMeshRole,
@@ -386,7 +1149,7 @@ func main() {
 	}
 
 	if queryMeshCmd.Happened() {
-		queryMesh(client, *queryMeshMeshId, *queryMeshQuery)
+		queryMesh(client, *queryMeshMeshId, *queryMeshSelector, *queryMeshQuery)
 	}
 
 	if putDescriptionCmd.Happened() {
@@ -404,4 +1167,80 @@ func main() {
 	if deleteServiceCmd.Happened() {
 		deleteService(client, *deleteServiceMeshid, *deleteServiceKey)
 	}
+
+	if labelCmd.Happened() {
+		label(client, *labelMeshId, *labelLabel)
+	}
+
+	if diffStateCmd.Happened() {
+		diffState(client, *diffStateMeshId, *diffStatePeer)
+	}
+
+	if historyCmd.Happened() {
+		getHistory(client, *historyMeshId)
+	}
+
+	if showCmd.Happened() {
+		showAt(client, *showMeshId, *showAtTime)
+	}
+
+	if diffHistoryCmd.Happened() {
+		diffHistory(client, *diffHistoryMeshId, *diffHistoryFrom, *diffHistoryTo)
+	}
+
+	if grantJoinCmd.Happened() {
+		grantJoin(client, *grantJoinMeshId, *grantJoinPublicKey, *grantJoinTtl)
+	}
+
+	if createInviteCmd.Happened() {
+		createInvite(client, *createInviteMeshId, *createInviteTtl, *createInviteUses, *createInviteRole)
+	}
+
+	if pendingJoinsCmd.Happened() {
+		pendingJoins(client, *pendingJoinsMeshId)
+	}
+
+	if approveCmd.Happened() {
+		decideJoin(client, *approveMeshId, *approvePublicKey, true)
+	}
+
+	if rejectCmd.Happened() {
+		decideJoin(client, *rejectMeshId, *rejectPublicKey, false)
+	}
+
+	if evictCmd.Happened() {
+		evict(client, *evictMeshId, *evictPublicKey)
+	}
+
+	if grantAdminCmd.Happened() {
+		grantAdmin(client, *grantAdminMeshId, *grantAdminPublicKey, true)
+	}
+
+	if revokeAdminCmd.Happened() {
+		grantAdmin(client, *revokeAdminMeshId, *revokeAdminPublicKey, false)
+	}
+
+	if listAdminsCmd.Happened() {
+		listAdmins(client, *listAdminsMeshId)
+	}
+
+	if rotateKeyCmd.Happened() {
+		rotateKey(client)
+	}
+
+	if setAclCmd.Happened() {
+		setAcl(client, *setAclMeshId, *setAclFile)
+	}
+
+	if clearAclCmd.Happened() {
+		setAcl(client, *clearAclMeshId, "")
+	}
+
+	if getAclCmd.Happened() {
+		getAcl(client, *getAclMeshId)
+	}
+
+	if statusCmd.Happened() {
+		status(client, *statusMeshId)
+	}
 }
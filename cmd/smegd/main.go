@@ -4,7 +4,10 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"time"
 
+	"github.com/tim-beatham/smegmesh/pkg/auth"
+	"github.com/tim-beatham/smegmesh/pkg/ca"
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	robin "github.com/tim-beatham/smegmesh/pkg/cplane"
 	ctrlserver "github.com/tim-beatham/smegmesh/pkg/ctrlserver"
@@ -54,6 +57,25 @@ func main() {
 	}
 
 	syncProvider.MeshManager = ctrlServer.MeshManager
+	syncProvider.Conf = configuration
+
+	if configuration.MeshAuthorisation {
+		authoriser := auth.NewMeshAuthoriser()
+		syncProvider.Authoriser = authoriser
+		robinRpc.Authoriser = authoriser
+	}
+
+	if configuration.CaPrivateKeyPath != "" {
+		ttl := time.Duration(configuration.CertificateTtl) * time.Second
+		authority, err := ca.LoadAuthority(configuration.CaCertificatePath, configuration.CaPrivateKeyPath, ttl)
+
+		if err != nil {
+			logging.Log.WriteErrorf("Could not load certificate authority: %s", err.Error())
+			return
+		}
+
+		robinRpc.Authority = authority
+	}
 
 	robinIpcParams := robin.RobinIpcParams{
 		CtrlServer: ctrlServer,
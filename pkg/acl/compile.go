@@ -0,0 +1,128 @@
+package acl
+
+import (
+	"bytes"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/tim-beatham/smegmesh/pkg/labels"
+)
+
+// Node: a node in the mesh the policy is compiled against
+type Node struct {
+	// PublicKey: the first key of the node
+	PublicKey string
+	// Address: the node's address in the mesh
+	Address net.IPNet
+}
+
+// Accept: accept traffic from the sources to the protocol and ports
+type Accept struct {
+	// Sources: addresses the traffic may come from
+	Sources []net.IPNet
+	// Protocol: tcp, udp or icmp. Any protocol if empty
+	Protocol string
+	// Ports: destination ports. Any port if empty
+	Ports []PortRange
+}
+
+// Ruleset: the rules a node enforces on traffic arriving on its mesh
+// interface. Traffic no rule accepts is dropped
+type Ruleset struct {
+	// Interface: the mesh interface the rules apply to
+	Interface string
+	Accepts   []Accept
+}
+
+// compiler: compiles the policy against the nodes in the mesh now
+type compiler struct {
+	// tags: the parsed label selector of each tag
+	tags map[string]labels.Selector
+	// labels: the labels the policy grants each node by its first key
+	labels map[string]map[string]string
+	// identity: maps the keys named in the policy to the first key of
+	// their node so that rotating keys does not change the nodes selected
+	identity func(string) string
+}
+
+// selects: returns true if the selector selects the node
+func (c *compiler) selects(selector string, node Node) bool {
+	if selector == ANY {
+		return true
+	}
+
+	if tag, ok := strings.CutPrefix(selector, TAG_PREFIX); ok {
+		tagSelector, ok := c.tags[tag]
+		return ok && tagSelector.Matches(c.labels[node.PublicKey])
+	}
+
+	return c.identity(selector) == node.PublicKey
+}
+
+// selectsAny: returns true if any of the selectors selects the node
+func (c *compiler) selectsAny(selectors []string, node Node) bool {
+	return slices.ContainsFunc(selectors, func(selector string) bool {
+		return c.selects(selector, node)
+	})
+}
+
+// Compile: compile the rules self enforces on traffic arriving on the
+// interface from the other nodes in the mesh. Tags select nodes by the
+// labels the policy grants them. The policy must be valid
+func Compile(policy *Policy, ifName string, self Node, nodes []Node, identity func(string) string) *Ruleset {
+	ruleset := &Ruleset{Interface: ifName, Accepts: make([]Accept, 0)}
+	compiler := &compiler{
+		tags:     make(map[string]labels.Selector),
+		labels:   make(map[string]map[string]string),
+		identity: identity,
+	}
+
+	for tag, selector := range policy.Tags {
+		tagSelector, _ := labels.Parse(selector)
+		compiler.tags[tag] = tagSelector
+	}
+
+	for node, granted := range policy.Labels {
+		compiler.labels[identity(node)] = granted
+	}
+
+	// Order the nodes so the same policy always compiles to the same rules
+	nodes = slices.Clone(nodes)
+	slices.SortFunc(nodes, func(a, b Node) int {
+		return bytes.Compare(a.Address.IP, b.Address.IP)
+	})
+
+	for _, rule := range policy.Rules {
+		if !compiler.selectsAny(rule.To, self) {
+			continue
+		}
+
+		sources := make([]net.IPNet, 0)
+
+		for _, node := range nodes {
+			if node.PublicKey != self.PublicKey && compiler.selectsAny(rule.From, node) {
+				sources = append(sources, node.Address)
+			}
+		}
+
+		if len(sources) == 0 {
+			continue
+		}
+
+		ports := make([]PortRange, 0, len(rule.Ports))
+
+		for _, port := range rule.Ports {
+			portRange, _ := parsePortRange(port)
+			ports = append(ports, portRange)
+		}
+
+		ruleset.Accepts = append(ruleset.Accepts, Accept{
+			Sources:  sources,
+			Protocol: rule.Protocol,
+			Ports:    ports,
+		})
+	}
+
+	return ruleset
+}
@@ -0,0 +1,152 @@
+package acl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+func newNode(address string) Node {
+	_, ipNet, _ := net.ParseCIDR(address)
+	return Node{PublicKey: newPublicKey(), Address: *ipNet}
+}
+
+func sameKey(key string) string {
+	return key
+}
+
+func TestCompileAcceptsTaggedSources(t *testing.T) {
+	db := newNode("fd00::1/128")
+	app := newNode("fd00::2/128")
+	other := newNode("fd00::3/128")
+
+	policy := &Policy{
+		Tags: map[string]string{"db": "role=db", "app": "role=app,env=prod"},
+		Labels: map[string]map[string]string{
+			db.PublicKey:    {"role": "db"},
+			app.PublicKey:   {"role": "app", "env": "prod"},
+			other.PublicKey: {"role": "app", "env": "dev"},
+		},
+		Rules: []Rule{
+			{From: []string{"tag:app"}, To: []string{"tag:db"}, Protocol: TCP, Ports: []string{"5432"}},
+		},
+	}
+
+	nodes := []Node{db, app, other}
+	ruleset := Compile(policy, "wg0", db, nodes, sameKey)
+
+	if len(ruleset.Accepts) != 1 {
+		t.Fatalf(`expected 1 accept got %d`, len(ruleset.Accepts))
+	}
+
+	accept := ruleset.Accepts[0]
+
+	if len(accept.Sources) != 1 || accept.Sources[0].String() != app.Address.String() {
+		t.Fatalf(`expected only the app node to be accepted got %v`, accept.Sources)
+	}
+
+	if len(Compile(policy, "wg0", app, nodes, sameKey).Accepts) != 0 {
+		t.Fatalf(`expected nodes the rule is not to to accept nothing`)
+	}
+}
+
+func TestCompileMapsRotatedKeys(t *testing.T) {
+	self := newNode("fd00::1/128")
+	rotated := newNode("fd00::2/128")
+	previous := newPublicKey()
+
+	policy := &Policy{Rules: []Rule{{From: []string{previous}, To: []string{ANY}}}}
+
+	identity := func(key string) string {
+		if key == previous {
+			return rotated.PublicKey
+		}
+
+		return key
+	}
+
+	ruleset := Compile(policy, "wg0", self, []Node{self, rotated}, identity)
+
+	if len(ruleset.Accepts) != 1 {
+		t.Fatalf(`expected the node to be selected by the key it rotated from`)
+	}
+}
+
+func TestRulesDropUnacceptedTraffic(t *testing.T) {
+	self := newNode("fd00::1/128")
+	app := newNode("fd00::2/128")
+
+	policy := &Policy{
+		Rules: []Rule{
+			{From: []string{ANY}, To: []string{ANY}, Protocol: TCP, Ports: []string{"5432", "8000-8080"}},
+			{From: []string{ANY}, To: []string{ANY}, Protocol: ICMP},
+		},
+	}
+
+	rules := Compile(policy, "wg0", self, []Node{self, app}, sameKey).Rules()
+
+	// Other interfaces, replies, each port range, ICMP then the drop
+	if len(rules) != 6 {
+		t.Fatalf(`expected 6 rules got %d`, len(rules))
+	}
+
+	if !slices.ContainsFunc(rules[0], func(e expr.Any) bool {
+		cmp, ok := e.(*expr.Cmp)
+		return ok && cmp.Op == expr.CmpOpNeq && bytes.HasPrefix(cmp.Data, []byte("wg0\x00"))
+	}) {
+		t.Fatalf(`expected traffic on other interfaces to be accepted`)
+	}
+
+	if !slices.ContainsFunc(rules[3], func(e expr.Any) bool {
+		portRange, ok := e.(*expr.Range)
+		return ok && binary.BigEndian.Uint16(portRange.FromData) == 8000 && binary.BigEndian.Uint16(portRange.ToData) == 8080
+	}) {
+		t.Fatalf(`expected the port range to be accepted`)
+	}
+
+	if !slices.ContainsFunc(rules[4], func(e expr.Any) bool {
+		cmp, ok := e.(*expr.Cmp)
+		return ok && bytes.Equal(cmp.Data, []byte{unix.IPPROTO_ICMPV6})
+	}) {
+		t.Fatalf(`expected ICMPv6 to be accepted from an IPv6 source`)
+	}
+
+	verdict, ok := rules[5][0].(*expr.Verdict)
+
+	if !ok || verdict.Kind != expr.VerdictDrop {
+		t.Fatalf(`expected the last rule to drop traffic`)
+	}
+}
+
+func TestNftFirewallOnlyAppliesChanges(t *testing.T) {
+	applied := 0
+	removed := 0
+	firewall := NewNftFirewall()
+	firewall.apply = func(*Ruleset) error {
+		applied++
+		return nil
+	}
+	firewall.remove = func(string) error {
+		removed++
+		return nil
+	}
+
+	firewall.Apply(&Ruleset{Interface: "wg0"})
+	firewall.Apply(&Ruleset{Interface: "wg0"})
+
+	if applied != 1 {
+		t.Fatalf(`expected an unchanged ruleset to be applied once got %d`, applied)
+	}
+
+	firewall.Remove("wg0")
+	firewall.Remove("wg0")
+
+	if removed != 1 {
+		t.Fatalf(`expected the table to be deleted once`)
+	}
+}
@@ -0,0 +1,282 @@
+package acl
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+// Firewall: enforces rulesets on mesh interfaces
+type Firewall interface {
+	// Apply: replace the rules enforced on the ruleset's interface
+	Apply(ruleset *Ruleset) error
+	// Remove: stop enforcing rules on the interface
+	Remove(ifName string) error
+}
+
+// TableName: the nftables table holding the rules for the interface
+func TableName(ifName string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+
+		return '_'
+	}, ifName)
+
+	return "smegmesh_" + name
+}
+
+// paddedIfName: the interface name as nftables compares it, NUL padded to
+// IFNAMSIZ
+func paddedIfName(name string) []byte {
+	padded := make([]byte, unix.IFNAMSIZ)
+	copy(padded[:unix.IFNAMSIZ-1], name)
+	return padded
+}
+
+// sourceMatch: matches traffic from the network. IPv4 and IPv6
+// addresses are at different offsets so the family is matched first
+func sourceMatch(source net.IPNet) []expr.Any {
+	family := byte(unix.NFPROTO_IPV6)
+	address := source.IP.To16()
+	mask := source.Mask
+	offset := uint32(8)
+
+	if ip4 := source.IP.To4(); ip4 != nil {
+		family = unix.NFPROTO_IPV4
+		address = ip4
+		offset = 12
+
+		if len(mask) == net.IPv6len {
+			mask = mask[12:]
+		}
+	}
+
+	return []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{family}},
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: offset, Len: uint32(len(address))},
+		&expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            uint32(len(address)),
+			Mask:           mask,
+			Xor:            make([]byte, len(address)),
+		},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: address.Mask(mask)},
+	}
+}
+
+// protocolMatches: the matches for the protocol and ports of the
+// accept. Each port range is matched by a rule of its own
+func protocolMatches(accept Accept, ip6 bool) [][]expr.Any {
+	var protocol byte
+
+	switch {
+	case accept.Protocol == "":
+		return [][]expr.Any{{}}
+	case accept.Protocol == ICMP && ip6:
+		protocol = unix.IPPROTO_ICMPV6
+	case accept.Protocol == ICMP:
+		protocol = unix.IPPROTO_ICMP
+	case accept.Protocol == TCP:
+		protocol = unix.IPPROTO_TCP
+	case accept.Protocol == UDP:
+		protocol = unix.IPPROTO_UDP
+	}
+
+	match := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{protocol}},
+	}
+
+	if len(accept.Ports) == 0 {
+		return [][]expr.Any{match}
+	}
+
+	matches := make([][]expr.Any, 0, len(accept.Ports))
+
+	for _, port := range accept.Ports {
+		// The destination port follows the source port in both TCP and UDP
+		portMatch := append(slices.Clone(match),
+			&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2})
+
+		if port.First == port.Last {
+			portMatch = append(portMatch, &expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     binaryutil.BigEndian.PutUint16(port.First),
+			})
+		} else {
+			portMatch = append(portMatch, &expr.Range{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				FromData: binaryutil.BigEndian.PutUint16(port.First),
+				ToData:   binaryutil.BigEndian.PutUint16(port.Last),
+			})
+		}
+
+		matches = append(matches, portMatch)
+	}
+
+	return matches
+}
+
+// Rules: the rules of the input chain that enforces the ruleset on the
+// interface. Replies to traffic the node sent are always accepted and
+// traffic no rule accepts is dropped
+func (r *Ruleset) Rules() [][]expr.Any {
+	accept := &expr.Verdict{Kind: expr.VerdictAccept}
+
+	rules := [][]expr.Any{
+		{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: paddedIfName(r.Interface)},
+			accept,
+		},
+		{
+			&expr.Ct{Register: 1, Key: expr.CtKeySTATE},
+			&expr.Bitwise{
+				SourceRegister: 1,
+				DestRegister:   1,
+				Len:            4,
+				Mask:           binaryutil.NativeEndian.PutUint32(expr.CtStateBitESTABLISHED | expr.CtStateBitRELATED),
+				Xor:            binaryutil.NativeEndian.PutUint32(0),
+			},
+			&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: binaryutil.NativeEndian.PutUint32(0)},
+			accept,
+		},
+	}
+
+	for _, acceptRule := range r.Accepts {
+		for _, source := range acceptRule.Sources {
+			for _, match := range protocolMatches(acceptRule, source.IP.To4() == nil) {
+				rule := append(sourceMatch(source), match...)
+				rules = append(rules, append(rule, accept))
+			}
+		}
+	}
+
+	return append(rules, []expr.Any{&expr.Verdict{Kind: expr.VerdictDrop}})
+}
+
+// table: the nftables table holding the rules for the interface
+func table(ifName string) *nftables.Table {
+	return &nftables.Table{Family: nftables.TableFamilyINet, Name: TableName(ifName)}
+}
+
+// applyNft: replace the table enforcing the ruleset over netlink. The
+// batch is applied in a single transaction so a ruleset is never
+// partially applied. Declaring the table first means deleting it never
+// fails
+func applyNft(ruleset *Ruleset) error {
+	conn, err := nftables.New()
+
+	if err != nil {
+		return fmt.Errorf("nftables: %w", err)
+	}
+
+	rulesetTable := table(ruleset.Interface)
+	conn.AddTable(rulesetTable)
+	conn.DelTable(rulesetTable)
+	conn.AddTable(rulesetTable)
+
+	policy := nftables.ChainPolicyAccept
+
+	chain := conn.AddChain(&nftables.Chain{
+		Name:     "input",
+		Table:    rulesetTable,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookInput,
+		Priority: nftables.ChainPriorityFilter,
+		Policy:   &policy,
+	})
+
+	for _, rule := range ruleset.Rules() {
+		conn.AddRule(&nftables.Rule{Table: rulesetTable, Chain: chain, Exprs: rule})
+	}
+
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("nftables: %w", err)
+	}
+
+	return nil
+}
+
+// removeNft: delete the table holding the rules for the interface if
+// it exists
+func removeNft(ifName string) error {
+	conn, err := nftables.New()
+
+	if err != nil {
+		return fmt.Errorf("nftables: %w", err)
+	}
+
+	interfaceTable := table(ifName)
+	conn.AddTable(interfaceTable)
+	conn.DelTable(interfaceTable)
+
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("nftables: %w", err)
+	}
+
+	return nil
+}
+
+// NftFirewall: enforces rulesets with nftables. Tables are programmed
+// over netlink in a single transaction so a ruleset is never partially
+// applied. Rulesets are only applied when the rules change
+type NftFirewall struct {
+	lock    sync.Mutex
+	applied map[string]*Ruleset
+	apply   func(ruleset *Ruleset) error
+	remove  func(ifName string) error
+}
+
+// NewNftFirewall: create a firewall enforcing rulesets with nftables
+func NewNftFirewall() *NftFirewall {
+	return &NftFirewall{applied: make(map[string]*Ruleset), apply: applyNft, remove: removeNft}
+}
+
+// Apply: replace the rules enforced on the ruleset's interface
+func (f *NftFirewall) Apply(ruleset *Ruleset) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if applied, ok := f.applied[ruleset.Interface]; ok && reflect.DeepEqual(applied, ruleset) {
+		return nil
+	}
+
+	if err := f.apply(ruleset); err != nil {
+		return err
+	}
+
+	f.applied[ruleset.Interface] = ruleset
+	return nil
+}
+
+// Remove: stop enforcing rules on the interface
+func (f *NftFirewall) Remove(ifName string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if _, ok := f.applied[ifName]; !ok {
+		return nil
+	}
+
+	if err := f.remove(ifName); err != nil {
+		return err
+	}
+
+	delete(f.applied, ifName)
+	return nil
+}
@@ -0,0 +1,173 @@
+// acl compiles a mesh's access control policy into the rules each node
+// enforces on traffic arriving on its mesh interface
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tim-beatham/smegmesh/pkg/labels"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const (
+	// ANY: selects every node in the mesh
+	ANY = "*"
+	// TAG_PREFIX: selects the nodes in the tag that follows
+	TAG_PREFIX = "tag:"
+)
+
+const (
+	TCP  = "tcp"
+	UDP  = "udp"
+	ICMP = "icmp"
+)
+
+// Policy: which nodes may reach which ports on which nodes. Once a
+// policy is set traffic no rule accepts is dropped
+type Policy struct {
+	// Tags: the label selector of each tag such as env=prod,role=db.
+	// A node is in the tag if the labels the policy grants it match
+	// when the policy is compiled
+	Tags map[string]string `json:"tags,omitempty"`
+	// Labels: the labels granted to each node by its public key. Nodes
+	// label themselves so only labels granted by the author of the
+	// policy select nodes
+	Labels map[string]map[string]string `json:"labels,omitempty"`
+	// Rules: the traffic to accept
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// Rule: accept traffic from one set of nodes to another. Nodes are
+// selected by *, tag:<name> or their public key
+type Rule struct {
+	// From: the nodes the traffic may come from
+	From []string `json:"from"`
+	// To: the nodes that accept the traffic
+	To []string `json:"to"`
+	// Protocol: tcp, udp or icmp. Any protocol if empty
+	Protocol string `json:"protocol,omitempty"`
+	// Ports: destination ports or port ranges such as 8000-8080. Any
+	// port if empty
+	Ports []string `json:"ports,omitempty"`
+}
+
+// PortRange: an inclusive range of ports
+type PortRange struct {
+	First uint16
+	Last  uint16
+}
+
+// parsePortRange: parse a port such as 5432 or a range such as 8000-8080
+func parsePortRange(port string) (PortRange, error) {
+	first, last, isRange := strings.Cut(port, "-")
+
+	if !isRange {
+		last = first
+	}
+
+	firstPort, err := strconv.ParseUint(first, 10, 16)
+
+	if err != nil || firstPort == 0 {
+		return PortRange{}, fmt.Errorf("invalid port %s", port)
+	}
+
+	lastPort, err := strconv.ParseUint(last, 10, 16)
+
+	if err != nil || lastPort < firstPort {
+		return PortRange{}, fmt.Errorf("invalid port %s", port)
+	}
+
+	return PortRange{First: uint16(firstPort), Last: uint16(lastPort)}, nil
+}
+
+// validateSelector: check the selector names every node, a tag defined
+// in the policy or a public key
+func (p *Policy) validateSelector(selector string) error {
+	if selector == ANY {
+		return nil
+	}
+
+	if tag, ok := strings.CutPrefix(selector, TAG_PREFIX); ok {
+		if _, ok := p.Tags[tag]; !ok {
+			return fmt.Errorf("tag %s is not defined", tag)
+		}
+
+		return nil
+	}
+
+	if _, err := wgtypes.ParseKey(selector); err != nil {
+		return fmt.Errorf("%s is not *, a tag or a public key", selector)
+	}
+
+	return nil
+}
+
+// Validate: check the policy can be compiled
+func (p *Policy) Validate() error {
+	for tag, selector := range p.Tags {
+		if strings.TrimSpace(selector) == "" {
+			return fmt.Errorf("tag %s has no selector", tag)
+		}
+
+		if _, err := labels.Parse(selector); err != nil {
+			return fmt.Errorf("tag %s: %w", tag, err)
+		}
+	}
+
+	for node, granted := range p.Labels {
+		if _, err := wgtypes.ParseKey(node); err != nil {
+			return fmt.Errorf("labels granted to %s: not a public key", node)
+		}
+
+		if len(granted) == 0 {
+			return fmt.Errorf("no labels granted to %s", node)
+		}
+
+		for key, value := range granted {
+			if err := labels.Validate(key, value); err != nil {
+				return fmt.Errorf("labels granted to %s: %w", node, err)
+			}
+		}
+	}
+
+	for i, rule := range p.Rules {
+		if err := p.validateRule(rule); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Policy) validateRule(rule Rule) error {
+	if len(rule.From) == 0 || len(rule.To) == 0 {
+		return errors.New("from and to must select at least one node")
+	}
+
+	for _, selector := range append(rule.From, rule.To...) {
+		if err := p.validateSelector(selector); err != nil {
+			return err
+		}
+	}
+
+	switch rule.Protocol {
+	case "", TCP, UDP, ICMP:
+	default:
+		return fmt.Errorf("unknown protocol %s", rule.Protocol)
+	}
+
+	if len(rule.Ports) != 0 && rule.Protocol != TCP && rule.Protocol != UDP {
+		return errors.New("ports require tcp or udp")
+	}
+
+	for _, port := range rule.Ports {
+		if _, err := parsePortRange(port); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
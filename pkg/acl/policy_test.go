@@ -0,0 +1,50 @@
+package acl
+
+import (
+	"testing"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func newPublicKey() string {
+	key, _ := wgtypes.GeneratePrivateKey()
+	return key.PublicKey().String()
+}
+
+func TestValidateAcceptsPolicy(t *testing.T) {
+	policy := &Policy{
+		Tags:   map[string]string{"db": "role in (db, replica)"},
+		Labels: map[string]map[string]string{newPublicKey(): {"role": "db"}},
+		Rules: []Rule{
+			{From: []string{ANY}, To: []string{"tag:db"}, Protocol: TCP, Ports: []string{"5432", "8000-8080"}},
+			{From: []string{newPublicKey()}, To: []string{ANY}, Protocol: ICMP},
+		},
+	}
+
+	if err := policy.Validate(); err != nil {
+		t.Fatalf(`expected policy to be valid got %s`, err.Error())
+	}
+}
+
+func TestValidateRejectsInvalidPolicies(t *testing.T) {
+	policies := map[string]*Policy{
+		"undefined tag": {Rules: []Rule{{From: []string{"tag:app"}, To: []string{ANY}}}},
+		"empty tag":     {Tags: map[string]string{"app": ""}},
+		"tag selector":  {Tags: map[string]string{"app": "role in (app"}},
+		"granted to":    {Labels: map[string]map[string]string{"app": {"role": "app"}}},
+		"granted label": {Labels: map[string]map[string]string{newPublicKey(): {"role": "app?"}}},
+		"no labels":     {Labels: map[string]map[string]string{newPublicKey(): {}}},
+		"not a key":     {Rules: []Rule{{From: []string{"app"}, To: []string{ANY}}}},
+		"no from":       {Rules: []Rule{{To: []string{ANY}}}},
+		"protocol":      {Rules: []Rule{{From: []string{ANY}, To: []string{ANY}, Protocol: "sctp"}}},
+		"icmp ports":    {Rules: []Rule{{From: []string{ANY}, To: []string{ANY}, Protocol: ICMP, Ports: []string{"1"}}}},
+		"port range":    {Rules: []Rule{{From: []string{ANY}, To: []string{ANY}, Protocol: TCP, Ports: []string{"90-80"}}}},
+		"port zero":     {Rules: []Rule{{From: []string{ANY}, To: []string{ANY}, Protocol: UDP, Ports: []string{"0"}}}},
+	}
+
+	for name, policy := range policies {
+		if err := policy.Validate(); err == nil {
+			t.Fatalf(`expected policy with invalid %s to be rejected`, name)
+		}
+	}
+}
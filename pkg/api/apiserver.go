@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/tim-beatham/smegmesh/pkg/ctrlserver"
 	"github.com/tim-beatham/smegmesh/pkg/ipc"
+	"github.com/tim-beatham/smegmesh/pkg/labels"
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
 	"github.com/tim-beatham/smegmesh/pkg/what8words"
 )
@@ -30,6 +31,29 @@ func (s *SmegServer) routeToApiRoute(meshNode ctrlserver.MeshNode) []Route {
 	return routes
 }
 
+// serviceRecordsToApiServiceRecords: convert the node's services to
+// JSON objects
+func (s *SmegServer) serviceRecordsToApiServiceRecords(meshNode ctrlserver.MeshNode) []ServiceRecord {
+	records := make([]ServiceRecord, len(meshNode.ServiceRecords))
+
+	for index, service := range meshNode.ServiceRecords {
+		if service.Tags == nil {
+			service.Tags = make([]string, 0)
+		}
+
+		records[index] = ServiceRecord{
+			Name:     service.Name,
+			Port:     service.Port,
+			Protocol: service.Protocol,
+			Tags:     service.Tags,
+			Metadata: service.Metadata,
+			Status:   string(service.Status),
+		}
+	}
+
+	return records
+}
+
 // meshNodeToAPImeshNode: convert daemon node to a JSON node
 func (s *SmegServer) meshNodeToAPIMeshNode(meshNode ctrlserver.MeshNode) *SmegNode {
 	if meshNode.Routes == nil {
@@ -43,15 +67,18 @@ func (s *SmegServer) meshNodeToAPIMeshNode(meshNode ctrlserver.MeshNode) *SmegNo
 	}
 
 	return &SmegNode{
-		WgHost:      meshNode.WgHost,
-		WgEndpoint:  meshNode.WgEndpoint,
-		Endpoint:    meshNode.HostEndpoint,
-		Timestamp:   int(meshNode.Timestamp),
-		Description: meshNode.Description,
-		Routes:      s.routeToApiRoute(meshNode),
-		PublicKey:   meshNode.PublicKey,
-		Alias:       alias,
-		Services:    meshNode.Services,
+		WgHost:         meshNode.WgHost,
+		WgEndpoint:     meshNode.WgEndpoint,
+		Endpoint:       meshNode.HostEndpoint,
+		Timestamp:      int(meshNode.Timestamp),
+		Description:    meshNode.Description,
+		Routes:         s.routeToApiRoute(meshNode),
+		PublicKey:      meshNode.PublicKey,
+		Alias:          alias,
+		Services:       meshNode.Services,
+		Labels:         meshNode.Labels,
+		ServiceRecords: s.serviceRecordsToApiServiceRecords(meshNode),
+		Version:        meshNode.Version,
 		Stats: SmegStats{
 			TotalTransmit:     meshNode.Stats.TransmitBytes,
 			TotalReceived:     meshNode.Stats.ReceivedBytes,
@@ -62,18 +89,45 @@ func (s *SmegServer) meshNodeToAPIMeshNode(meshNode ctrlserver.MeshNode) *SmegNo
 }
 
 // meshToAPIMesh: Convert daemon mesh network to a JSON mesh network
-func (s *SmegServer) meshToAPIMesh(meshId string, nodes []ctrlserver.MeshNode) SmegMesh {
+// including only the nodes matching the label selector
+func (s *SmegServer) meshToAPIMesh(reply *ipc.GetMeshReply, selector labels.Selector) SmegMesh {
 	var smegMesh SmegMesh
-	smegMesh.MeshId = meshId
+	smegMesh.MeshId = reply.MeshId
 	smegMesh.Nodes = make(map[string]SmegNode)
 
-	for _, node := range nodes {
+	if reply.Info != nil {
+		smegMesh.Name = reply.Info.Name
+		smegMesh.Creator = reply.Info.Creator
+		smegMesh.Created = reply.Info.Created
+		smegMesh.Description = reply.Info.Description
+	}
+
+	for _, node := range reply.Nodes {
+		if !selector.Matches(node.Labels) {
+			continue
+		}
+
 		smegMesh.Nodes[node.WgHost] = *s.meshNodeToAPIMeshNode(node)
 	}
 
 	return smegMesh
 }
 
+// parseSelector: parse the label selector given in the selector query
+// parameter. Responds with bad request if the selector is invalid
+func parseSelector(c *gin.Context) (labels.Selector, bool) {
+	selector, err := labels.Parse(c.Query("selector"))
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+
+	return selector, true
+}
+
 // putAlias: place an alias in the mesh
 func (s *SmegServer) putAlias(meshId, alias string) error {
 	var reply string
@@ -115,6 +169,8 @@ func (s *SmegServer) CreateMesh(c *gin.Context) {
 			AdvertiseRoutes:       createMesh.AdvertiseRoutes,
 			AdvertiseDefaultRoute: createMesh.AdvertiseDefaults,
 		},
+		Name:        createMesh.Name,
+		Description: createMesh.MeshDescription,
 	}
 
 	var reply string
@@ -188,10 +244,16 @@ func (s *SmegServer) JoinMesh(c *gin.Context) {
 	})
 }
 
-// GetMesh: given a meshId returns the corresponding mesh
-// network.
+// GetMesh: given a meshId or mesh name returns the corresponding mesh
+// network. The selector query parameter restricts the nodes
+// returned to those matching the label selector
 func (s *SmegServer) GetMesh(c *gin.Context) {
 	meshidParam := c.Param("meshid")
+	selector, ok := parseSelector(c)
+
+	if !ok {
+		return
+	}
 
 	var meshid string = meshidParam
 
@@ -207,7 +269,7 @@ func (s *SmegServer) GetMesh(c *gin.Context) {
 		return
 	}
 
-	mesh := s.meshToAPIMesh(meshidParam, getMeshReply.Nodes)
+	mesh := s.meshToAPIMesh(getMeshReply, selector)
 
 	c.JSON(http.StatusOK, mesh)
 }
@@ -215,6 +277,12 @@ func (s *SmegServer) GetMesh(c *gin.Context) {
 // GetMeshes: return all the mesh networks that the
 // user is a part of
 func (s *SmegServer) GetMeshes(c *gin.Context) {
+	selector, ok := parseSelector(c)
+
+	if !ok {
+		return
+	}
+
 	listMeshesReply := new(ipc.ListMeshReply)
 
 	err := s.client.ListMeshes(listMeshesReply)
@@ -238,7 +306,7 @@ func (s *SmegServer) GetMeshes(c *gin.Context) {
 			return
 		}
 
-		meshes = append(meshes, s.meshToAPIMesh(mesh, getMeshReply.Nodes))
+		meshes = append(meshes, s.meshToAPIMesh(getMeshReply, selector))
 	}
 
 	c.JSON(http.StatusOK, meshes)
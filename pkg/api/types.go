@@ -29,6 +29,22 @@ type SmegStats struct {
 	AllowedIps []string `json:"allowedIps"`
 }
 
+// ServiceRecord is a healthy service offered by a node
+type ServiceRecord struct {
+	// Name is the name of the service unique to the node
+	Name string `json:"name"`
+	// Port is the port the service listens on
+	Port int `json:"port"`
+	// Protocol is the protocol of the service either tcp or udp
+	Protocol string `json:"protocol"`
+	// Tags is the tags consumers filter the service by
+	Tags []string `json:"tags"`
+	// Metadata is free-form key value pairs describing the service
+	Metadata map[string]string `json:"metadata"`
+	// Status is the health of the service either passing or warning
+	Status string `json:"status"`
+}
+
 // SmegNode is a node in the mesh network
 type SmegNode struct {
 	// Alias is the human readable name that the node is assocaited with
@@ -54,14 +70,29 @@ type SmegNode struct {
 	Routes []Route `json:"routes"`
 	// Services is information about services that the node offers
 	Services map[string]string `json:"services"`
+	// Labels is the key value pairs the node has placed on itself
+	Labels map[string]string `json:"labels"`
+	// ServiceRecords is the services the node offers that are not
+	// critical and have not expired
+	ServiceRecords []ServiceRecord `json:"serviceRecords"`
 	// Stats is the WireGuard stats of the node (if any)
 	Stats SmegStats `json:"stats"`
+	// Version is the software version the node is running
+	Version string `json:"version"`
 }
 
 // SmegMesh encapsulates a single mesh in the API
 type SmegMesh struct {
 	// MeshId is the mesh id of the network
 	MeshId string `json:"meshid"`
+	// Name is the human readable name of the network if it has one
+	Name string `json:"name"`
+	// Creator is the public key of the node that created the network
+	Creator string `json:"creator"`
+	// Created is the UNIX time the network was created
+	Created int64 `json:"created"`
+	// Description is the description of the network
+	Description string `json:"description"`
 	// Nodes is the nodes in the network keyed by their public
 	// key
 	Nodes map[string]SmegNode `json:"nodes"`
@@ -83,6 +114,11 @@ type CreateMeshRequest struct {
 	Description string `json:"description"`
 	// PublicEndpoint: an alternative public endpoint to advertise
 	PublicEndpoint string `json:"publicEndpoint"`
+	// Name: human readable name of the mesh that can be used in place
+	// of its id
+	Name string `json:"name"`
+	// MeshDescription: description of the mesh
+	MeshDescription string `json:"meshDescription"`
 }
 
 // JoinMeshRequests encapsulates a request to create a mesh network
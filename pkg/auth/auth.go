@@ -0,0 +1,182 @@
+// auth authorises peers to access a mesh
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conn"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Authoriser: authorises a peer to access a mesh
+type Authoriser interface {
+	// Authorise: returns a PermissionDenied error if the peer is not a
+	// current member of the mesh and does not hold a valid credential
+	// or invite
+	Authorise(ctx context.Context, theMesh mesh.MeshProvider, credential string) error
+}
+
+// MeshAuthoriser: authorises peers identified by the WireGuard key
+// their certificate names
+type MeshAuthoriser struct {
+	now func() time.Time
+}
+
+// Authorise: authorise the peer in the context of a gRPC call or
+// stream. theMesh may be nil if the mesh does not exist
+func (a *MeshAuthoriser) Authorise(ctx context.Context, theMesh mesh.MeshProvider, credential string) error {
+	if theMesh == nil {
+		return status.Error(codes.PermissionDenied, "not authorised to access the mesh")
+	}
+
+	key, err := conn.PeerWgKey(ctx)
+
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return a.AuthoriseKey(theMesh, key.String(), credential)
+}
+
+// AuthoriseKey: authorise the node with the given WireGuard public key.
+// The caller must have established the node holds the key
+func (a *MeshAuthoriser) AuthoriseKey(theMesh mesh.MeshProvider, key string, credential string) error {
+	if theMesh == nil {
+		return status.Error(codes.PermissionDenied, "not authorised to access the mesh")
+	}
+
+	if err := refuseEvictedKey(theMesh, key); err != nil {
+		return err
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	// The key may be one the member has since rotated from
+	if theMesh.NodeExists(admission.CurrentKey(key)) {
+		return nil
+	}
+
+	if credential == "" {
+		return status.Errorf(codes.PermissionDenied, "%s is not a member of the mesh", key)
+	}
+
+	if err := a.verify(theMesh, key, credential); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return nil
+}
+
+// RefuseEvicted: returns a PermissionDenied error if the peer has been
+// evicted from the mesh or its certificate does not name a WireGuard key.
+// The peer can only be identified by the key its certificate is bound to
+func RefuseEvicted(ctx context.Context, theMesh mesh.MeshProvider) error {
+	key, err := conn.PeerWgKey(ctx)
+
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return refuseEvictedKey(theMesh, key.String())
+}
+
+// refuseEvictedKey: returns a PermissionDenied error if the node with
+// the given WireGuard key has been evicted from the mesh. The caller
+// must have established the node holds the key
+func refuseEvictedKey(theMesh mesh.MeshProvider, key string) error {
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	if admission.IsRevoked(key) {
+		return status.Errorf(codes.PermissionDenied, "%s has been evicted from the mesh", key)
+	}
+
+	return nil
+}
+
+// RefuseEvictedCertificates: returns a function that refuses the
+// WireGuard key of a certificate at the TLS layer if the key has been
+// evicted from a mesh and is not a member of any other mesh
+func RefuseEvictedCertificates(manager mesh.MeshManager) func(key wgtypes.Key) error {
+	return func(key wgtypes.Key) error {
+		evicted := false
+
+		for _, theMesh := range manager.GetMeshes() {
+			admission, err := theMesh.GetAdmission()
+
+			if err != nil {
+				return err
+			}
+
+			if admission.IsRevoked(key.String()) {
+				evicted = true
+				continue
+			}
+
+			if theMesh.NodeExists(admission.CurrentKey(key.String())) {
+				return nil
+			}
+		}
+
+		if evicted {
+			return fmt.Errorf("%s has been evicted from the mesh", key.String())
+		}
+
+		return nil
+	}
+}
+
+// verifyIssuer: meshes with a genesis only accept credentials and
+// invites issued by their admins
+func verifyIssuer(theMesh mesh.MeshProvider, issuer string) error {
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	if admission.GetGenesis() != nil && !admission.IsAdmin(issuer) {
+		return fmt.Errorf("issuer %s is not an admin of the mesh", issuer)
+	}
+
+	return nil
+}
+
+// verify: verify the credential or invite is valid for the caller
+func (a *MeshAuthoriser) verify(theMesh mesh.MeshProvider, caller string, credential string) error {
+	if IsInvite(credential) {
+		invite, err := DecodeInvite(credential)
+
+		if err != nil {
+			return err
+		}
+
+		// The caller's role is checked on redemption
+		return invite.Verify(theMesh, caller, "", a.now())
+	}
+
+	joinCredential, err := DecodeJoinCredential(credential)
+
+	if err != nil {
+		return err
+	}
+
+	return joinCredential.Verify(theMesh, caller, a.now())
+}
+
+// NewMeshAuthoriser: create a new mesh authoriser
+func NewMeshAuthoriser() *MeshAuthoriser {
+	return &MeshAuthoriser{now: time.Now}
+}
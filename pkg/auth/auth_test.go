@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conn"
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// peerContext: context of a call from a peer whose certificate names
+// the WireGuard key
+func peerContext(t *testing.T, key wgtypes.Key) context.Context {
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "node"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{conn.WgKeyURI(key)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+		},
+	})
+}
+
+func expectPermissionDenied(t *testing.T, err error) {
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf(`expected PermissionDenied got %v`, err)
+	}
+}
+
+func TestAuthoriseMember(t *testing.T) {
+	member, _ := wgtypes.GeneratePrivateKey()
+	theMesh := &membersMesh{
+		meshId:  "mesh",
+		members: map[string]bool{member.PublicKey().String(): true},
+	}
+
+	err := NewMeshAuthoriser().Authorise(peerContext(t, member.PublicKey()), theMesh, "")
+
+	if err != nil {
+		t.Fatalf(`expected member to be authorised got %s`, err.Error())
+	}
+}
+
+func TestAuthoriseNonMember(t *testing.T) {
+	_, theMesh, grantee := setUpCredential(t)
+
+	err := NewMeshAuthoriser().Authorise(peerContext(t, grantee), theMesh, "")
+	expectPermissionDenied(t, err)
+}
+
+func TestAuthoriseUnknownMesh(t *testing.T) {
+	key, _ := wgtypes.GeneratePrivateKey()
+
+	err := NewMeshAuthoriser().Authorise(peerContext(t, key.PublicKey()), nil, "")
+	expectPermissionDenied(t, err)
+}
+
+func TestAuthoriseNoCertificate(t *testing.T) {
+	_, theMesh, _ := setUpCredential(t)
+
+	err := NewMeshAuthoriser().Authorise(context.Background(), theMesh, "")
+	expectPermissionDenied(t, err)
+}
+
+func TestAuthoriseWithCredential(t *testing.T) {
+	credential, theMesh, grantee := setUpCredential(t)
+	encoded, _ := credential.Encode()
+
+	err := NewMeshAuthoriser().Authorise(peerContext(t, grantee), theMesh, encoded)
+
+	if err != nil {
+		t.Fatalf(`expected credential holder to be authorised got %s`, err.Error())
+	}
+}
+
+func TestAuthoriseStolenCredential(t *testing.T) {
+	credential, theMesh, _ := setUpCredential(t)
+	encoded, _ := credential.Encode()
+	thief, _ := wgtypes.GeneratePrivateKey()
+
+	err := NewMeshAuthoriser().Authorise(peerContext(t, thief.PublicKey()), theMesh, encoded)
+	expectPermissionDenied(t, err)
+}
+
+// adminNode: node carrying the genesis of the mesh and the
+// revocations and grants made by its admins
+type adminNode struct {
+	mesh.MeshNode
+	genesis     *mesh.Genesis
+	revocations []mesh.Revocation
+	grants      []mesh.Grant
+}
+
+func (n *adminNode) GetGenesis() *mesh.Genesis {
+	return n.genesis
+}
+
+func (n *adminNode) GetRevocations() []mesh.Revocation {
+	return n.revocations
+}
+
+func (n *adminNode) GetGrants() []mesh.Grant {
+	return n.grants
+}
+
+func (n *adminNode) GetSuccessions() []mesh.Succession {
+	return nil
+}
+
+func (n *adminNode) GetAclPolicy() *mesh.AclPolicy {
+	return nil
+}
+
+func (n *adminNode) GetRedemptions() []mesh.Redemption {
+	return nil
+}
+
+func TestAuthoriseEvictedMember(t *testing.T) {
+	creator, _ := wgtypes.GeneratePrivateKey()
+	evicted, _ := wgtypes.GeneratePrivateKey()
+	sign := func(message []byte) ([]byte, error) {
+		return lib.XEdDSASign(creator, message)
+	}
+
+	genesis := &mesh.Genesis{
+		Creator: creator.PublicKey().String(),
+		Nonce:   []byte{1},
+		Admins:  []string{creator.PublicKey().String()},
+	}
+	genesis.Sign(sign)
+
+	revocation := mesh.Revocation{
+		PublicKey: evicted.PublicKey().String(),
+		Revoker:   creator.PublicKey().String(),
+		Timestamp: time.Now().Unix(),
+	}
+	revocation.Sign(sign)
+
+	theMesh := &membersMesh{
+		meshId:    genesis.MeshId(),
+		members:   map[string]bool{evicted.PublicKey().String(): true},
+		admission: []mesh.MeshNode{&adminNode{genesis: genesis, revocations: []mesh.Revocation{revocation}}},
+	}
+
+	err := NewMeshAuthoriser().Authorise(peerContext(t, evicted.PublicKey()), theMesh, "")
+	expectPermissionDenied(t, err)
+}
+
+func TestRefuseEvictedRequiresCertificateKey(t *testing.T) {
+	member, _ := wgtypes.GeneratePrivateKey()
+	theMesh := &membersMesh{
+		meshId:  "mesh",
+		members: map[string]bool{member.PublicKey().String(): true},
+	}
+
+	if err := RefuseEvicted(peerContext(t, member.PublicKey()), theMesh); err != nil {
+		t.Fatalf(`expected member to be accepted got %s`, err.Error())
+	}
+
+	expectPermissionDenied(t, RefuseEvicted(context.Background(), theMesh))
+}
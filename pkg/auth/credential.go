@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// JoinCredential: grants a node that is not yet a member of a mesh
+// permission to fetch and sync the mesh. Issued and signed by a
+// member of the mesh
+type JoinCredential struct {
+	// MeshId: the mesh the credential grants access to
+	MeshId string `json:"m"`
+	// Issuer: public key of the member that issued the credential
+	Issuer string `json:"i"`
+	// Grantee: public key of the node the credential is for
+	Grantee string `json:"g"`
+	// Expires: UNIX time in seconds after which the credential is invalid
+	Expires int64 `json:"e"`
+	// Signature: XEdDSA signature of the issuer over the credential
+	Signature []byte `json:"s,omitempty"`
+}
+
+// signedBytes: the bytes of the credential covered by the signature
+func (c *JoinCredential) signedBytes() ([]byte, error) {
+	unsigned := *c
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the credential using the signer
+func (c *JoinCredential) Sign(sign func([]byte) ([]byte, error)) error {
+	message, err := c.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	c.Signature, err = sign(message)
+	return err
+}
+
+// Verify: verify the credential was issued by a member of the mesh
+// to the caller and has not expired
+func (c *JoinCredential) Verify(theMesh mesh.MeshProvider, caller string, now time.Time) error {
+	if c.MeshId != theMesh.GetMeshId() {
+		return errors.New("credential is for a different mesh")
+	}
+
+	if c.Grantee != caller {
+		return errors.New("credential was not issued to the caller")
+	}
+
+	if now.Unix() > c.Expires {
+		return errors.New("credential has expired")
+	}
+
+	if !theMesh.NodeExists(c.Issuer) {
+		return errors.New("credential issuer is not a member of the mesh")
+	}
+
+	if err := verifyIssuer(theMesh, c.Issuer); err != nil {
+		return err
+	}
+
+	issuer, err := wgtypes.ParseKey(c.Issuer)
+
+	if err != nil {
+		return fmt.Errorf("invalid issuer: %w", err)
+	}
+
+	message, err := c.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	return lib.XEdDSAVerify(issuer, message, c.Signature)
+}
+
+// Encode: encode the credential so that it can be passed on the command line
+func (c *JoinCredential) Encode() (string, error) {
+	bs, err := json.Marshal(c)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bs), nil
+}
+
+// DecodeJoinCredential: decode the encoded credential
+func DecodeJoinCredential(encoded string) (*JoinCredential, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(encoded)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential: %w", err)
+	}
+
+	var credential JoinCredential
+
+	if err := json.Unmarshal(bs, &credential); err != nil {
+		return nil, fmt.Errorf("invalid credential: %w", err)
+	}
+
+	return &credential, nil
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// membersMesh: mesh whose only behaviour is its id, members, the
+// invites redeemed through each member and the nodes admission is
+// determined from
+type membersMesh struct {
+	mesh.MeshProvider
+	meshId      string
+	members     map[string]bool
+	redemptions map[string][]mesh.Redemption
+	admission   []mesh.MeshNode
+}
+
+type redemptionsNode struct {
+	mesh.MeshNode
+	redemptions []mesh.Redemption
+}
+
+func (n *redemptionsNode) GetRedemptions() []mesh.Redemption {
+	return n.redemptions
+}
+
+type nodesSnapshot map[string]mesh.MeshNode
+
+func (s nodesSnapshot) GetNodes() map[string]mesh.MeshNode {
+	return s
+}
+
+func (m *membersMesh) GetMeshId() string {
+	return m.meshId
+}
+
+func (m *membersMesh) NodeExists(nodeId string) bool {
+	return m.members[nodeId]
+}
+
+func (m *membersMesh) GetMesh() (mesh.MeshSnapshot, error) {
+	snapshot := make(nodesSnapshot)
+
+	for member := range m.members {
+		snapshot[member] = &redemptionsNode{redemptions: m.redemptions[member]}
+	}
+
+	return snapshot, nil
+}
+
+func (m *membersMesh) GetAdmission() (*mesh.Admission, error) {
+	return mesh.NewAdmission(m.meshId, m.admission), nil
+}
+
+func (m *membersMesh) AddRedemption(nodeId string, redemption mesh.Redemption) error {
+	if m.redemptions == nil {
+		m.redemptions = make(map[string][]mesh.Redemption)
+	}
+
+	m.redemptions[nodeId] = append(m.redemptions[nodeId], redemption)
+	return nil
+}
+
+func setUpCredential(t *testing.T) (*JoinCredential, *membersMesh, wgtypes.Key) {
+	issuer, _ := wgtypes.GeneratePrivateKey()
+	grantee, _ := wgtypes.GeneratePrivateKey()
+
+	theMesh := &membersMesh{
+		meshId:  "mesh",
+		members: map[string]bool{issuer.PublicKey().String(): true},
+	}
+
+	credential := &JoinCredential{
+		MeshId:  "mesh",
+		Issuer:  issuer.PublicKey().String(),
+		Grantee: grantee.PublicKey().String(),
+		Expires: time.Now().Add(time.Hour).Unix(),
+	}
+
+	err := credential.Sign(func(message []byte) ([]byte, error) {
+		return lib.XEdDSASign(issuer, message)
+	})
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return credential, theMesh, grantee.PublicKey()
+}
+
+func TestVerifyValidCredential(t *testing.T) {
+	credential, theMesh, grantee := setUpCredential(t)
+
+	if err := credential.Verify(theMesh, grantee.String(), time.Now()); err != nil {
+		t.Fatalf(`expected credential to verify got %s`, err.Error())
+	}
+}
+
+func TestVerifyExpiredCredential(t *testing.T) {
+	credential, theMesh, grantee := setUpCredential(t)
+
+	if err := credential.Verify(theMesh, grantee.String(), time.Now().Add(2*time.Hour)); err == nil {
+		t.Fatalf(`expected expired credential to be rejected`)
+	}
+}
+
+func TestVerifyWrongGrantee(t *testing.T) {
+	credential, theMesh, _ := setUpCredential(t)
+	other, _ := wgtypes.GeneratePrivateKey()
+
+	if err := credential.Verify(theMesh, other.PublicKey().String(), time.Now()); err == nil {
+		t.Fatalf(`expected credential for another node to be rejected`)
+	}
+}
+
+func TestVerifyWrongMesh(t *testing.T) {
+	credential, theMesh, grantee := setUpCredential(t)
+	theMesh.meshId = "other"
+
+	if err := credential.Verify(theMesh, grantee.String(), time.Now()); err == nil {
+		t.Fatalf(`expected credential for another mesh to be rejected`)
+	}
+}
+
+func TestVerifyIssuerNotMember(t *testing.T) {
+	credential, theMesh, grantee := setUpCredential(t)
+	theMesh.members = map[string]bool{}
+
+	if err := credential.Verify(theMesh, grantee.String(), time.Now()); err == nil {
+		t.Fatalf(`expected credential from a non member to be rejected`)
+	}
+}
+
+func TestVerifyModifiedCredential(t *testing.T) {
+	credential, theMesh, grantee := setUpCredential(t)
+	credential.Expires += 3600
+
+	if err := credential.Verify(theMesh, grantee.String(), time.Now()); err == nil {
+		t.Fatalf(`expected modified credential to be rejected`)
+	}
+}
+
+func TestEncodeDecodeCredential(t *testing.T) {
+	credential, theMesh, grantee := setUpCredential(t)
+
+	encoded, err := credential.Encode()
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	decoded, err := DecodeJoinCredential(encoded)
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if err := decoded.Verify(theMesh, grantee.String(), time.Now()); err != nil {
+		t.Fatalf(`expected decoded credential to verify got %s`, err.Error())
+	}
+}
+
+func TestDecodeInvalidCredential(t *testing.T) {
+	if _, err := DecodeJoinCredential("not a credential"); err == nil {
+		t.Fatalf(`expected invalid credential to be rejected`)
+	}
+}
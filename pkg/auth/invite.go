@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const (
+	// INVITE_PREFIX: prefixes encoded invites to distinguish them from
+	// join credentials
+	INVITE_PREFIX = "smeginv1."
+)
+
+// Invite: allows a limited number of nodes to join a mesh before the
+// invite expires. Issued and signed by a member of the mesh
+type Invite struct {
+	// Id: unique id of the invite. Redemptions are recorded against it
+	Id string `json:"id"`
+	// MeshId: the mesh the invite is for
+	MeshId string `json:"m"`
+	// Issuer: public key of the member that issued the invite
+	Issuer string `json:"i"`
+	// Endpoints: gRPC endpoints of peers to bootstrap through
+	Endpoints []string `json:"p"`
+	// CaFingerprint: SHA-256 fingerprint of the CA certificate that
+	// signs the certificates of the mesh's peers
+	CaFingerprint []byte `json:"ca"`
+	// Role: role the node must join the mesh with, any role if empty
+	Role string `json:"r,omitempty"`
+	// Expires: UNIX time in seconds after which the invite is invalid
+	Expires int64 `json:"e"`
+	// Uses: number of distinct nodes that may redeem the invite
+	Uses int `json:"u"`
+	// Signature: XEdDSA signature of the issuer over the invite
+	Signature []byte `json:"s,omitempty"`
+}
+
+// signedBytes: the bytes of the invite covered by the signature
+func (i *Invite) signedBytes() ([]byte, error) {
+	unsigned := *i
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the invite using the signer
+func (i *Invite) Sign(sign func([]byte) ([]byte, error)) error {
+	message, err := i.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	i.Signature, err = sign(message)
+	return err
+}
+
+// Redeemers: the nodes that have redeemed the invite according to the
+// mesh. Concurrent redemptions through different peers are only
+// counted once the peers have synchronised
+func (i *Invite) Redeemers(theMesh mesh.MeshProvider) (map[string]bool, error) {
+	snapshot, err := theMesh.GetMesh()
+
+	if err != nil {
+		return nil, err
+	}
+
+	redeemers := make(map[string]bool)
+
+	for _, node := range snapshot.GetNodes() {
+		for _, redemption := range node.GetRedemptions() {
+			if redemption.Invite == i.Id {
+				redeemers[redemption.Grantee] = true
+			}
+		}
+	}
+
+	return redeemers, nil
+}
+
+// verifyRole: check the caller joins with the role the invite permits.
+// An empty role means the role the caller joins with is not yet known.
+// The role is then checked when the invite is redeemed and again by
+// the mesh's admission against the role the node advertises
+func (i *Invite) verifyRole(role string) error {
+	if i.Role == "" || role == "" || role == i.Role {
+		return nil
+	}
+
+	return fmt.Errorf("invite only permits the role %s", i.Role)
+}
+
+// Verify: verify the invite was issued by a member of the mesh, has
+// not expired, permits the role and can still be redeemed by the
+// caller. A caller that has already redeemed the invite may redeem it
+// again
+func (i *Invite) Verify(theMesh mesh.MeshProvider, caller string, role string, now time.Time) error {
+	if i.MeshId != theMesh.GetMeshId() {
+		return errors.New("invite is for a different mesh")
+	}
+
+	if now.Unix() > i.Expires {
+		return errors.New("invite has expired")
+	}
+
+	if !theMesh.NodeExists(i.Issuer) {
+		return errors.New("invite issuer is not a member of the mesh")
+	}
+
+	if err := verifyIssuer(theMesh, i.Issuer); err != nil {
+		return err
+	}
+
+	issuer, err := wgtypes.ParseKey(i.Issuer)
+
+	if err != nil {
+		return fmt.Errorf("invalid issuer: %w", err)
+	}
+
+	message, err := i.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	if err := lib.XEdDSAVerify(issuer, message, i.Signature); err != nil {
+		return err
+	}
+
+	if err := i.verifyRole(role); err != nil {
+		return err
+	}
+
+	redeemers, err := i.Redeemers(theMesh)
+
+	if err != nil {
+		return err
+	}
+
+	if !redeemers[caller] && len(redeemers) >= i.Uses {
+		return errors.New("invite has no uses remaining")
+	}
+
+	return nil
+}
+
+// Redeem: record that the grantee redeemed the invite through the
+// node with the given id. The redemption carries the role the invite
+// permits so that every node can hold the grantee to it
+func (i *Invite) Redeem(theMesh mesh.MeshProvider, nodeId, grantee string, now time.Time) error {
+	redeemers, err := i.Redeemers(theMesh)
+
+	if err != nil {
+		return err
+	}
+
+	if redeemers[grantee] {
+		return nil
+	}
+
+	return theMesh.AddRedemption(nodeId, mesh.Redemption{
+		Invite:    i.Id,
+		Grantee:   grantee,
+		Timestamp: now.Unix(),
+		Role:      i.Role,
+	})
+}
+
+// Encode: encode the invite so that it can be passed on the command line
+func (i *Invite) Encode() (string, error) {
+	bs, err := json.Marshal(i)
+
+	if err != nil {
+		return "", err
+	}
+
+	return INVITE_PREFIX + base64.RawURLEncoding.EncodeToString(bs), nil
+}
+
+// IsInvite: returns true if the encoded credential is an invite
+func IsInvite(encoded string) bool {
+	return strings.HasPrefix(encoded, INVITE_PREFIX)
+}
+
+// DecodeInvite: decode the encoded invite
+func DecodeInvite(encoded string) (*Invite, error) {
+	if !IsInvite(encoded) {
+		return nil, errors.New("invalid invite")
+	}
+
+	bs, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(encoded, INVITE_PREFIX))
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid invite: %w", err)
+	}
+
+	var invite Invite
+
+	if err := json.Unmarshal(bs, &invite); err != nil {
+		return nil, fmt.Errorf("invalid invite: %w", err)
+	}
+
+	return &invite, nil
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func setUpInvite(t *testing.T, uses int) (*Invite, *membersMesh, string) {
+	issuer, _ := wgtypes.GeneratePrivateKey()
+
+	theMesh := &membersMesh{
+		meshId:  "mesh",
+		members: map[string]bool{issuer.PublicKey().String(): true},
+	}
+
+	invite := &Invite{
+		Id:            "invite",
+		MeshId:        "mesh",
+		Issuer:        issuer.PublicKey().String(),
+		Endpoints:     []string{"peer:4000"},
+		CaFingerprint: []byte{1, 2, 3},
+		Role:          "client",
+		Expires:       time.Now().Add(time.Hour).Unix(),
+		Uses:          uses,
+	}
+
+	err := invite.Sign(func(message []byte) ([]byte, error) {
+		return lib.XEdDSASign(issuer, message)
+	})
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return invite, theMesh, issuer.PublicKey().String()
+}
+
+func newGrantee() string {
+	key, _ := wgtypes.GeneratePrivateKey()
+	return key.PublicKey().String()
+}
+
+func redeem(t *testing.T, invite *Invite, theMesh *membersMesh, issuer, grantee string) error {
+	if err := invite.Verify(theMesh, grantee, "client", time.Now()); err != nil {
+		return err
+	}
+
+	if err := invite.Redeem(theMesh, issuer, grantee, time.Now()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return nil
+}
+
+func TestInviteUsageLimit(t *testing.T) {
+	invite, theMesh, issuer := setUpInvite(t, 2)
+
+	for i := 0; i < 2; i++ {
+		if err := redeem(t, invite, theMesh, issuer, newGrantee()); err != nil {
+			t.Fatalf(`expected redemption %d to succeed got %s`, i, err.Error())
+		}
+	}
+
+	if err := redeem(t, invite, theMesh, issuer, newGrantee()); err == nil {
+		t.Fatalf(`expected redemption beyond the usage limit to be rejected`)
+	}
+}
+
+func TestInviteRedeemTwice(t *testing.T) {
+	invite, theMesh, issuer := setUpInvite(t, 1)
+	grantee := newGrantee()
+
+	for i := 0; i < 2; i++ {
+		if err := redeem(t, invite, theMesh, issuer, grantee); err != nil {
+			t.Fatalf(`expected grantee to redeem again got %s`, err.Error())
+		}
+	}
+
+	if len(theMesh.redemptions[issuer]) != 1 {
+		t.Fatalf(`expected one redemption got %d`, len(theMesh.redemptions[issuer]))
+	}
+}
+
+func TestInviteRedemptionsAcrossPeers(t *testing.T) {
+	invite, theMesh, issuer := setUpInvite(t, 1)
+	other := newGrantee()
+	theMesh.members[other] = true
+
+	if err := redeem(t, invite, theMesh, other, newGrantee()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if err := redeem(t, invite, theMesh, issuer, newGrantee()); err == nil {
+		t.Fatalf(`expected redemption through another peer to count towards the limit`)
+	}
+}
+
+func TestInviteWrongRole(t *testing.T) {
+	invite, theMesh, _ := setUpInvite(t, 1)
+
+	if err := invite.Verify(theMesh, newGrantee(), "peer", time.Now()); err == nil {
+		t.Fatalf(`expected a role the invite does not permit to be rejected`)
+	}
+}
+
+func TestInviteRedemptionRecordsRole(t *testing.T) {
+	invite, theMesh, issuer := setUpInvite(t, 1)
+
+	if err := redeem(t, invite, theMesh, issuer, newGrantee()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if theMesh.redemptions[issuer][0].Role != "client" {
+		t.Fatalf(`expected the redemption to record the role the invite permits`)
+	}
+}
+
+func TestInviteExpired(t *testing.T) {
+	invite, theMesh, _ := setUpInvite(t, 1)
+
+	if err := invite.Verify(theMesh, newGrantee(), "", time.Now().Add(2*time.Hour)); err == nil {
+		t.Fatalf(`expected expired invite to be rejected`)
+	}
+}
+
+func TestInviteModified(t *testing.T) {
+	invite, theMesh, _ := setUpInvite(t, 1)
+	invite.Uses = 100
+
+	if err := invite.Verify(theMesh, newGrantee(), "", time.Now()); err == nil {
+		t.Fatalf(`expected modified invite to be rejected`)
+	}
+}
+
+func TestEncodeDecodeInvite(t *testing.T) {
+	invite, theMesh, _ := setUpInvite(t, 1)
+
+	encoded, err := invite.Encode()
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if !IsInvite(encoded) {
+		t.Fatalf(`expected %s to be an invite`, encoded)
+	}
+
+	decoded, err := DecodeInvite(encoded)
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if err := decoded.Verify(theMesh, newGrantee(), "", time.Now()); err != nil {
+		t.Fatalf(`expected decoded invite to verify got %s`, err.Error())
+	}
+}
+
+func TestAuthoriseWithInvite(t *testing.T) {
+	invite, theMesh, _ := setUpInvite(t, 1)
+	encoded, _ := invite.Encode()
+	grantee, _ := wgtypes.GeneratePrivateKey()
+
+	err := NewMeshAuthoriser().Authorise(peerContext(t, grantee.PublicKey()), theMesh, encoded)
+
+	if err != nil {
+		t.Fatalf(`expected invite holder to be authorised got %s`, err.Error())
+	}
+}
+
+func TestInviteIssuerNotAdmin(t *testing.T) {
+	owner, _ := wgtypes.GeneratePrivateKey()
+	issuer, _ := wgtypes.GeneratePrivateKey()
+
+	genesis := &mesh.Genesis{
+		Creator: owner.PublicKey().String(),
+		Nonce:   []byte{1},
+	}
+	genesis.Sign(func(message []byte) ([]byte, error) {
+		return lib.XEdDSASign(owner, message)
+	})
+
+	theMesh := &membersMesh{
+		meshId:    genesis.MeshId(),
+		members:   map[string]bool{issuer.PublicKey().String(): true},
+		admission: []mesh.MeshNode{&adminNode{genesis: genesis}},
+	}
+
+	invite := &Invite{
+		Id:      "invite",
+		MeshId:  genesis.MeshId(),
+		Issuer:  issuer.PublicKey().String(),
+		Expires: time.Now().Add(time.Hour).Unix(),
+		Uses:    1,
+	}
+	invite.Sign(func(message []byte) ([]byte, error) {
+		return lib.XEdDSASign(issuer, message)
+	})
+
+	if err := invite.Verify(theMesh, newGrantee(), "", time.Now()); err == nil {
+		t.Fatalf(`expected invite issued by a node that is not an admin to be rejected`)
+	}
+}
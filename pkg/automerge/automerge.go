@@ -350,6 +350,82 @@ func (m *CrdtMeshManager) RemoveService(nodeId, key string) error {
 	return nil
 }
 
+// PutServiceRecord: automerge meshes do not support service records
+func (m *CrdtMeshManager) PutServiceRecord(nodeId string, service mesh.Service) error {
+	return fmt.Errorf("PutServiceRecord: service records are not supported")
+}
+
+// RemoveServiceRecord: automerge meshes do not support service records
+func (m *CrdtMeshManager) RemoveServiceRecord(nodeId, name string) error {
+	return fmt.Errorf("RemoveServiceRecord: service records are not supported")
+}
+
+// SetLabel: automerge meshes do not support labels
+func (m *CrdtMeshManager) SetLabel(nodeId, key, value string) error {
+	return fmt.Errorf("SetLabel: labels are not supported")
+}
+
+// RemoveLabel: automerge meshes do not support labels
+func (m *CrdtMeshManager) RemoveLabel(nodeId, key string) error {
+	return fmt.Errorf("RemoveLabel: labels are not supported")
+}
+
+// AddRedemption: automerge meshes do not support invites
+func (m *CrdtMeshManager) AddRedemption(nodeId string, redemption mesh.Redemption) error {
+	return fmt.Errorf("AddRedemption: invites are not supported")
+}
+
+// SetMeshInfo: automerge nodes do not carry the metadata of the mesh so
+// the metadata is dropped
+func (m *CrdtMeshManager) SetMeshInfo(nodeId string, info mesh.MeshInfo) error {
+	return nil
+}
+
+// SetGenesis: automerge meshes do not support join approval
+func (m *CrdtMeshManager) SetGenesis(nodeId string, genesis mesh.Genesis) error {
+	return fmt.Errorf("SetGenesis: join approval is not supported")
+}
+
+// AddJoinRequest: automerge meshes do not support join approval
+func (m *CrdtMeshManager) AddJoinRequest(nodeId string, request mesh.JoinRequest) error {
+	return fmt.Errorf("AddJoinRequest: join approval is not supported")
+}
+
+// AddJoinDecision: automerge meshes do not support join approval
+func (m *CrdtMeshManager) AddJoinDecision(nodeId string, decision mesh.JoinDecision) error {
+	return fmt.Errorf("AddJoinDecision: join approval is not supported")
+}
+
+// AddRevocation: automerge meshes do not support eviction
+func (m *CrdtMeshManager) AddRevocation(nodeId string, revocation mesh.Revocation) error {
+	return fmt.Errorf("AddRevocation: eviction is not supported")
+}
+
+// AddGrant: automerge meshes do not support admin rights
+func (m *CrdtMeshManager) AddGrant(nodeId string, grant mesh.Grant) error {
+	return fmt.Errorf("AddGrant: admin rights are not supported")
+}
+
+// AddSuccession: automerge meshes do not support key rotation
+func (m *CrdtMeshManager) AddSuccession(nodeId string, succession mesh.Succession) error {
+	return fmt.Errorf("AddSuccession: key rotation is not supported")
+}
+
+// SetAclPolicy: automerge meshes do not support access control policies
+func (m *CrdtMeshManager) SetAclPolicy(nodeId string, policy mesh.AclPolicy) error {
+	return fmt.Errorf("SetAclPolicy: access control policies are not supported")
+}
+
+// RotateNode: automerge meshes do not support key rotation
+func (m *CrdtMeshManager) RotateNode(nodeId string, successor string) error {
+	return fmt.Errorf("RotateNode: key rotation is not supported")
+}
+
+// GetAdmission: every node is admitted to an automerge mesh
+func (m *CrdtMeshManager) GetAdmission() (*mesh.Admission, error) {
+	return mesh.NewAdmission(m.MeshId, nil), nil
+}
+
 // AddRoutes: adds routes to the specific nodeId
 func (m *CrdtMeshManager) AddRoutes(nodeId string, routes ...mesh.Route) error {
 	nodeVal, err := m.doc.Path("nodes").Map().Get(nodeId)
@@ -389,7 +465,9 @@ func (m *CrdtMeshManager) AddRoutes(nodeId string, routes ...mesh.Route) error {
 				return err
 			}
 
-			slices.Equal(route.GetPath(), pathStr)
+			if slices.Equal(route.GetPath(), pathStr) {
+				continue
+			}
 		}
 
 		err = routeMap.Map().Set(route.GetDestination().String(), Route{
@@ -526,6 +604,11 @@ func (m *CrdtMeshManager) Prune() error {
 	return nil
 }
 
+// GetState: automerge does not expose a vector clock based state
+func (m *CrdtMeshManager) GetState() (*mesh.MeshState, error) {
+	return nil, fmt.Errorf("automerge: state inspection is not supported")
+}
+
 // Compare: compare two mesh node for equality
 func (m1 *MeshNodeCrdt) Compare(m2 *MeshNodeCrdt) int {
 	return strings.Compare(m1.PublicKey, m2.PublicKey)
@@ -604,6 +687,66 @@ func (m *MeshNodeCrdt) GetServices() map[string]string {
 
 // GetType refers to the type of the node. Peer means that the node is globally accessible
 // Client means the node is only accessible through another peer
+// GetVersion: automerge nodes do not advertise their version
+func (n *MeshNodeCrdt) GetVersion() string {
+	return ""
+}
+
+// GetRedemptions: automerge nodes do not record invite redemptions
+func (n *MeshNodeCrdt) GetRedemptions() []mesh.Redemption {
+	return nil
+}
+
+// GetMeshInfo: automerge nodes do not carry the metadata of the mesh
+func (n *MeshNodeCrdt) GetMeshInfo() *mesh.MeshInfo {
+	return nil
+}
+
+// GetGenesis: automerge meshes do not have a genesis
+func (n *MeshNodeCrdt) GetGenesis() *mesh.Genesis {
+	return nil
+}
+
+// GetJoinRequests: automerge nodes do not record join requests
+func (n *MeshNodeCrdt) GetJoinRequests() []mesh.JoinRequest {
+	return nil
+}
+
+// GetJoinDecisions: automerge nodes do not record join decisions
+func (n *MeshNodeCrdt) GetJoinDecisions() []mesh.JoinDecision {
+	return nil
+}
+
+// GetRevocations: automerge nodes do not record revocations
+func (n *MeshNodeCrdt) GetRevocations() []mesh.Revocation {
+	return nil
+}
+
+// GetGrants: automerge nodes do not record grants
+func (n *MeshNodeCrdt) GetGrants() []mesh.Grant {
+	return nil
+}
+
+// GetSuccessions: automerge nodes do not rotate their keys
+func (n *MeshNodeCrdt) GetSuccessions() []mesh.Succession {
+	return nil
+}
+
+// GetAclPolicy: automerge nodes do not carry access control policies
+func (n *MeshNodeCrdt) GetAclPolicy() *mesh.AclPolicy {
+	return nil
+}
+
+// GetLabels: automerge nodes do not carry labels
+func (n *MeshNodeCrdt) GetLabels() map[string]string {
+	return map[string]string{}
+}
+
+// GetServiceRecords: automerge nodes do not carry service records
+func (n *MeshNodeCrdt) GetServiceRecords() map[string]mesh.Service {
+	return map[string]mesh.Service{}
+}
+
 func (n *MeshNodeCrdt) GetType() conf.NodeType {
 	return conf.NodeType(n.Type)
 }
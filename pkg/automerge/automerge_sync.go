@@ -3,10 +3,16 @@
 package automerge
 
 import (
+	"fmt"
+	"slices"
+
 	"github.com/automerge/automerge-go"
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
 )
 
+// AUTOMERGE_ENCODING: automerge only speaks its own sync protocol
+const AUTOMERGE_ENCODING = "encoding/automerge"
+
 // AutomergeSync: defines a synchroniser to bi-directionally synchronise the
 // two states
 type AutomergeSync struct {
@@ -47,6 +53,20 @@ func (a *AutomergeSync) Complete() {
 	a.manager.SaveChanges()
 }
 
+// Capabilities: automerge only supports the automerge sync protocol
+func (a *AutomergeSync) Capabilities() []string {
+	return []string{AUTOMERGE_ENCODING}
+}
+
+// Negotiate: the peer must speak the automerge sync protocol
+func (a *AutomergeSync) Negotiate(capabilities []string) (string, error) {
+	if !slices.Contains(capabilities, AUTOMERGE_ENCODING) {
+		return "", fmt.Errorf("automerge: peer does not support %s", AUTOMERGE_ENCODING)
+	}
+
+	return AUTOMERGE_ENCODING, nil
+}
+
 // NewAutomergeSync: instantiates a new automerge syncer
 func NewAutomergeSync(manager *CrdtMeshManager) *AutomergeSync {
 	return &AutomergeSync{
@@ -0,0 +1,161 @@
+// ca issues short-lived certificates bound to WireGuard keys so that
+// nodes do not need certificates issued out of band
+package ca
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conn"
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const (
+	// CLOCK_SKEW: certificates are valid from slightly before they are
+	// issued to tolerate clocks that are behind
+	CLOCK_SKEW = 5 * time.Minute
+)
+
+// Authority: signs certificate requests using the CA's key pair
+type Authority struct {
+	certificate *x509.Certificate
+	signer      crypto.Signer
+	// ttl: lifetime of the certificates issued
+	ttl time.Duration
+	now func() time.Time
+}
+
+// LoadAuthority: load the CA certificate and private key from the
+// given PEM files. Issued certificates are valid for ttl
+func LoadAuthority(certificatePath, privateKeyPath string, ttl time.Duration) (*Authority, error) {
+	keyPair, err := tls.LoadX509KeyPair(certificatePath, privateKeyPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !certificate.IsCA {
+		return nil, fmt.Errorf("%s is not a CA certificate", certificatePath)
+	}
+
+	signer, ok := keyPair.PrivateKey.(crypto.Signer)
+
+	if !ok {
+		return nil, errors.New("CA private key cannot sign certificates")
+	}
+
+	return &Authority{
+		certificate: certificate,
+		signer:      signer,
+		ttl:         ttl,
+		now:         time.Now,
+	}, nil
+}
+
+// GetCertificate: the PEM encoded CA certificate
+func (a *Authority) GetCertificate() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: a.certificate.Raw})
+}
+
+// checkRequest: the request may only name the WireGuard key
+func checkRequest(request *x509.CertificateRequest, publicKey wgtypes.Key) error {
+	if len(request.DNSNames) != 0 || len(request.IPAddresses) != 0 || len(request.EmailAddresses) != 0 {
+		return errors.New("certificate request may not name hosts")
+	}
+
+	for _, uri := range request.URIs {
+		if uri.String() != conn.WgKeyURI(publicKey).String() {
+			return fmt.Errorf("certificate request may not name %s", uri.String())
+		}
+	}
+
+	subject := request.Subject.String()
+
+	if subject != "" && subject != (pkix.Name{CommonName: publicKey.String()}).String() {
+		return fmt.Errorf("certificate request may not name the subject %s", subject)
+	}
+
+	return nil
+}
+
+// Sign: issue a PEM encoded certificate for the request bound to the
+// WireGuard public key. The certificate names only the key as the hosts
+// a node publishes are not verified. The request must have been
+// verified first and may name nothing else
+func (a *Authority) Sign(request *x509.CertificateRequest, publicKey wgtypes.Key) ([]byte, error) {
+	if err := checkRequest(request, publicKey); err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := a.now()
+
+	if now.After(a.certificate.NotAfter) {
+		return nil, errors.New("CA certificate has expired")
+	}
+
+	notAfter := now.Add(a.ttl)
+
+	if notAfter.After(a.certificate.NotAfter) {
+		notAfter = a.certificate.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: publicKey.String()},
+		NotBefore:    now.Add(-CLOCK_SKEW),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{conn.WgKeyURI(publicKey)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.certificate, request.PublicKey, a.signer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// VerifyRequest: verify the DER encoded certificate request is self
+// signed and that the owner of the WireGuard key signed it
+func VerifyRequest(csr []byte, publicKey wgtypes.Key, signature []byte) (*x509.CertificateRequest, error) {
+	request, err := x509.ParseCertificateRequest(csr)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate request: %w", err)
+	}
+
+	if err := request.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid certificate request: %w", err)
+	}
+
+	if err := lib.XEdDSAVerify(publicKey, csr, signature); err != nil {
+		return nil, fmt.Errorf("certificate request not signed by %s: %w", publicKey.String(), err)
+	}
+
+	return request, nil
+}
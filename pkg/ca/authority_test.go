@@ -0,0 +1,191 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conn"
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func setUpAuthority(t *testing.T, expires time.Duration) *Authority {
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(expires),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	certificate, _ := x509.ParseCertificate(der)
+
+	return &Authority{
+		certificate: certificate,
+		signer:      privateKey,
+		ttl:         time.Hour,
+		now:         time.Now,
+	}
+}
+
+func newRequest(t *testing.T, key wgtypes.Key) *Request {
+	request, err := NewRequest(key.PublicKey(), func(message []byte) ([]byte, error) {
+		return lib.XEdDSASign(key, message)
+	})
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return request
+}
+
+func decodePEM(t *testing.T, contents []byte) []byte {
+	block, _ := pem.Decode(contents)
+
+	if block == nil {
+		t.Fatalf(`expected PEM encoded certificate`)
+	}
+
+	return block.Bytes
+}
+
+func issue(t *testing.T, authority *Authority, key wgtypes.Key) *x509.Certificate {
+	request := newRequest(t, key)
+	csr, err := VerifyRequest(request.Csr, key.PublicKey(), request.Signature)
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	certificatePEM, err := authority.Sign(csr, key.PublicKey())
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if err := conn.VerifyCertificatePEM(certificatePEM, key.PublicKey()); err != nil {
+		t.Fatalf(`expected certificate to be bound to the WireGuard key got %s`, err.Error())
+	}
+
+	certificate, _ := x509.ParseCertificate(decodePEM(t, certificatePEM))
+	return certificate
+}
+
+func TestSignBindsWgKey(t *testing.T) {
+	authority := setUpAuthority(t, 24*time.Hour)
+	key, _ := wgtypes.GeneratePrivateKey()
+
+	certificate := issue(t, authority, key)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(authority.certificate)
+
+	_, err := certificate.Verify(x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	if err != nil {
+		t.Fatalf(`expected certificate to chain to the CA got %s`, err.Error())
+	}
+
+	if len(certificate.IPAddresses) != 0 || len(certificate.DNSNames) != 0 || len(certificate.URIs) != 1 {
+		t.Fatalf(`expected the certificate to name only the WireGuard key`)
+	}
+}
+
+func TestSignCappedAtCaExpiry(t *testing.T) {
+	authority := setUpAuthority(t, time.Minute)
+	key, _ := wgtypes.GeneratePrivateKey()
+
+	certificate := issue(t, authority, key)
+
+	if certificate.NotAfter.After(authority.certificate.NotAfter) {
+		t.Fatalf(`expected certificate not to outlive the CA`)
+	}
+}
+
+func TestSignExpiredCa(t *testing.T) {
+	authority := setUpAuthority(t, time.Minute)
+	authority.now = func() time.Time { return time.Now().Add(time.Hour) }
+	key, _ := wgtypes.GeneratePrivateKey()
+
+	request := newRequest(t, key)
+	csr, _ := x509.ParseCertificateRequest(request.Csr)
+
+	if _, err := authority.Sign(csr, key.PublicKey()); err == nil {
+		t.Fatalf(`expected an expired CA to refuse to sign`)
+	}
+}
+
+func TestSignRejectsRequestedNames(t *testing.T) {
+	authority := setUpAuthority(t, 24*time.Hour)
+	key, _ := wgtypes.GeneratePrivateKey()
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	templates := []*x509.CertificateRequest{
+		{Subject: pkix.Name{CommonName: key.PublicKey().String()}, DNSNames: []string{"victim.example"}},
+		{Subject: pkix.Name{CommonName: key.PublicKey().String()}, IPAddresses: []net.IP{net.ParseIP("10.0.0.2")}},
+		{Subject: pkix.Name{CommonName: "victim.example"}},
+	}
+
+	for _, template := range templates {
+		der, _ := x509.CreateCertificateRequest(rand.Reader, template, privateKey)
+		csr, _ := x509.ParseCertificateRequest(der)
+
+		if _, err := authority.Sign(csr, key.PublicKey()); err == nil {
+			t.Fatalf(`expected a request naming %v to be rejected`, template)
+		}
+	}
+}
+
+func TestVerifyRequestWrongKey(t *testing.T) {
+	key, _ := wgtypes.GeneratePrivateKey()
+	other, _ := wgtypes.GeneratePrivateKey()
+
+	request := newRequest(t, key)
+
+	if _, err := VerifyRequest(request.Csr, other.PublicKey(), request.Signature); err == nil {
+		t.Fatalf(`expected request signed by another WireGuard key to be rejected`)
+	}
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+
+	certificate := &x509.Certificate{
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(2 * time.Hour),
+	}
+
+	if NeedsRenewal(certificate, now) {
+		t.Fatalf(`expected certificate with most of its lifetime left not to need renewal`)
+	}
+
+	if !NeedsRenewal(certificate, now.Add(90*time.Minute)) {
+		t.Fatalf(`expected certificate near expiry to need renewal`)
+	}
+
+	if !NeedsRenewal(nil, now) {
+		t.Fatalf(`expected a missing certificate to need renewal`)
+	}
+}
@@ -0,0 +1,143 @@
+package ca
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/conn"
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/rpc"
+)
+
+// Enroller: obtains the node's certificate from a CA of the mesh and
+// renews it before it expires
+type Enroller struct {
+	keyPair           *conn.KeyPair
+	connectionManager conn.ConnectionManager
+	meshManager       mesh.MeshManager
+	conf              *conf.DaemonConfiguration
+}
+
+// NewEnrollerParams: params to create a new enroller
+type NewEnrollerParams struct {
+	// KeyPair: the key pair to store issued certificates in
+	KeyPair           *conn.KeyPair
+	ConnectionManager conn.ConnectionManager
+	MeshManager       mesh.MeshManager
+	Conf              *conf.DaemonConfiguration
+}
+
+// NewEnroller: create a new enroller
+func NewEnroller(params *NewEnrollerParams) *Enroller {
+	return &Enroller{
+		keyPair:           params.KeyPair,
+		connectionManager: params.ConnectionManager,
+		meshManager:       params.MeshManager,
+		conf:              params.Conf,
+	}
+}
+
+// NeedsCertificate: returns true if the node has no certificate, its
+// certificate is close to expiry or names a key the node has rotated from
+func (e *Enroller) NeedsCertificate() bool {
+	leaf := e.keyPair.Leaf()
+
+	if NeedsRenewal(leaf, time.Now()) {
+		return true
+	}
+
+	publicKey := e.meshManager.GetPublicKey()
+
+	return conn.VerifyCertificate(leaf, *publicKey) != nil
+}
+
+// Enroll: request a certificate from the CA at the endpoint. credential
+// authorises the node if it is not yet a member of the mesh
+func (e *Enroller) Enroll(endpoint, meshId, credential string) error {
+	publicKey := e.meshManager.GetPublicKey()
+	request, err := NewRequest(*publicKey, e.meshManager.Sign)
+
+	if err != nil {
+		return err
+	}
+
+	peerConnection, err := e.connectionManager.GetConnection(endpoint)
+
+	if err != nil {
+		return err
+	}
+
+	// The connection was established with the previous certificate
+	defer e.connectionManager.RemoveConnection(endpoint)
+
+	client, err := peerConnection.GetClient()
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.conf.Timeout)*time.Second)
+	defer cancel()
+
+	reply, err := rpc.NewMeshCtrlServerClient(client).SignCertificate(ctx, &rpc.SignCertificateRequest{
+		MeshId:     meshId,
+		PublicKey:  publicKey.String(),
+		Csr:        request.Csr,
+		Signature:  request.Signature,
+		Credential: credential,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := conn.VerifyCertificatePEM(reply.Certificate, *publicKey); err != nil {
+		return fmt.Errorf("CA issued an invalid certificate: %w", err)
+	}
+
+	return e.keyPair.Store(reply.Certificate, request.PrivateKey)
+}
+
+// Renew: renew the certificate if it is close to expiry through the
+// first peer that acts as a CA
+func (e *Enroller) Renew() error {
+	if !e.NeedsCertificate() {
+		return nil
+	}
+
+	self := e.meshManager.GetPublicKey().String()
+	meshes := e.meshManager.GetMeshes()
+
+	if len(meshes) == 0 {
+		return nil
+	}
+
+	for meshId, theMesh := range meshes {
+		for _, peer := range theMesh.GetPeers() {
+			if peer == self {
+				continue
+			}
+
+			node, err := theMesh.GetNode(peer)
+
+			if err != nil {
+				continue
+			}
+
+			err = e.Enroll(node.GetHostEndpoint(), meshId, e.meshManager.GetCredential(meshId))
+
+			if err == nil {
+				logging.Log.WriteInfof("renewed certificate through %s", node.GetHostEndpoint())
+				return nil
+			}
+
+			logging.Log.WriteInfof("could not renew certificate through %s: %s", node.GetHostEndpoint(), err.Error())
+		}
+	}
+
+	return errors.New("no CA could renew the certificate")
+}
@@ -0,0 +1,74 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Request: a certificate request for a newly generated private key
+// signed by the node's WireGuard key
+type Request struct {
+	// PrivateKey: PEM encoded private key the certificate is for
+	PrivateKey []byte
+	// Csr: DER encoded certificate request
+	Csr []byte
+	// Signature: XEdDSA signature of the WireGuard key over Csr
+	Signature []byte
+}
+
+// NewRequest: generate a new private key and a certificate request for
+// it signed by the owner of the WireGuard public key. The request names
+// only the key. The CA decides the hosts the certificate names
+func NewRequest(publicKey wgtypes.Key, sign func([]byte) ([]byte, error)) (*Request, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: publicKey.String()},
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, privateKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := sign(csr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(privateKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Request{
+		PrivateKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+		Csr:        csr,
+		Signature:  signature,
+	}, nil
+}
+
+// NeedsRenewal: returns true if there is no certificate or less than a
+// third of its lifetime remains
+func NeedsRenewal(certificate *x509.Certificate, now time.Time) bool {
+	if certificate == nil {
+		return true
+	}
+
+	lifetime := certificate.NotAfter.Sub(certificate.NotBefore)
+	return certificate.NotAfter.Sub(now) < lifetime/3
+}
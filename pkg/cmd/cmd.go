@@ -2,6 +2,8 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -34,3 +36,18 @@ func (l *UnixCmdRunner) RunCommands(commands ...string) error {
 
 	return nil
 }
+
+// RunHook: runs the hook in a shell with the given environment
+// variables added to the daemon's environment
+func RunHook(hook string, env []string) error {
+	c := exec.Command("/bin/sh", "-c", hook)
+	c.Env = append(os.Environ(), env...)
+
+	output, err := c.CombinedOutput()
+
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -2,9 +2,14 @@
 package conf
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"os"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/miekg/dns"
 	"gopkg.in/yaml.v3"
 )
 
@@ -63,6 +68,83 @@ type WgConfiguration struct {
 	PostDown []string `yaml:"postDown"`
 }
 
+// CheckType: how a health check determines the health of a service
+type CheckType string
+
+const (
+	// TCP_CHECK: the service is passing if a TCP connection can be made
+	TCP_CHECK CheckType = "tcp"
+	// HTTP_CHECK: the service is passing if a GET returns 2xx and
+	// warning if it returns 429
+	HTTP_CHECK CheckType = "http"
+	// EXEC_CHECK: the service is passing if the command exits with 0 and
+	// warning if it exits with 1
+	EXEC_CHECK CheckType = "exec"
+)
+
+// CheckConfiguration: a local health check of a service
+type CheckConfiguration struct {
+	// Type is the type of check either tcp, http or exec
+	Type CheckType `yaml:"type" validate:"required,eq=tcp|eq=http|eq=exec"`
+	// Target is the address to connect to for tcp checks or the URL to GET
+	// for http checks. Defaults to the service's port on localhost
+	Target string `yaml:"target"`
+	// Command is the command and its arguments to run for exec checks
+	Command []string `yaml:"command" validate:"required_if=Type exec"`
+	// Interval is the number of seconds between running the check. Defaults
+	// to 10 seconds
+	Interval int `yaml:"interval" validate:"gte=0"`
+	// Timeout is the number of seconds after which the check is critical.
+	// Defaults to 5 seconds
+	Timeout int `yaml:"timeout" validate:"gte=0"`
+}
+
+// ServiceConfiguration: a service the node offers in its meshes
+type ServiceConfiguration struct {
+	// Name is the name of the service unique to the node
+	Name string `yaml:"name" validate:"required"`
+	// Port is the port the service listens on
+	Port int `yaml:"port" validate:"required,gte=1,lte=65535"`
+	// Protocol is the protocol of the service either tcp or udp. Defaults to tcp
+	Protocol string `yaml:"protocol" validate:"omitempty,eq=tcp|eq=udp"`
+	// Tags are tags consumers filter the service by
+	Tags []string `yaml:"tags"`
+	// Metadata is free-form key value pairs describing the service
+	Metadata map[string]string `yaml:"metadata"`
+	// Ttl is the number of seconds the service is discoverable for without
+	// being refreshed. Defaults to three times the shortest check interval
+	Ttl int `yaml:"ttl" validate:"gte=0"`
+	// Meshes are the meshes to offer the service in. Offered in every mesh
+	// if not specified
+	Meshes []string `yaml:"meshes"`
+	// Checks are the health checks of the service. The service is passing
+	// if it has no checks
+	Checks []CheckConfiguration `yaml:"checks" validate:"dive"`
+}
+
+// Interval: the number of seconds between checking the service
+func (s *ServiceConfiguration) Interval() int {
+	interval := DEFAULT_CHECK_INTERVAL
+
+	for i, check := range s.Checks {
+		if i == 0 || check.Interval < interval {
+			interval = check.Interval
+		}
+	}
+
+	return interval
+}
+
+// DNS_SERVICE: name of the service advertised by nodes that serve DNS
+const DNS_SERVICE = "dns"
+
+const (
+	// DEFAULT_CHECK_INTERVAL: default number of seconds between checks
+	DEFAULT_CHECK_INTERVAL = 10
+	// DEFAULT_CHECK_TIMEOUT: default number of seconds before a check times out
+	DEFAULT_CHECK_TIMEOUT = 5
+)
+
 type DaemonConfiguration struct {
 	// CertificatePath is the path to the certificate to use in mTLS
 	CertificatePath string `yaml:"certificatePath" validate:"required"`
@@ -70,9 +152,43 @@ type DaemonConfiguration struct {
 	PrivateKeyPath string `yaml:"privateKeyPath" validate:"required"`
 	// CaCeritifcatePath path to the certificate of the trust certificate authority
 	CaCertificatePath string `yaml:"caCertificatePath" validate:"required"`
+	// CaPrivateKeyPath is the path to the private key of the certificate
+	// authority. If specified the node acts as a CA and issues certificates
+	// bound to their WireGuard key to nodes that join its meshes
+	CaPrivateKeyPath string `yaml:"caPrivateKeyPath"`
+	// CertificateTtl is the number of seconds the certificates the node issues
+	// as a CA are valid for. Defaults to a day
+	CertificateTtl int `yaml:"certificateTtl" validate:"gte=0"`
+	// RequestCertificates specifies that the node requests its certificate from
+	// the CA of the mesh it joins and renews it before it expires. The
+	// certificate and private key are written to certificatePath and
+	// privateKeyPath
+	RequestCertificates bool `yaml:"requestCertificates"`
+	// CertificateReloadInterval is the number of seconds between checking the
+	// certificate, private key and CA certificate for changes. Changed files are
+	// reloaded without restarting the daemon. Defaults to 10 seconds
+	CertificateReloadInterval int `yaml:"certificateReloadInterval" validate:"gte=0"`
 	// SkipCertVerification specify to skip certificate verification. Should only be used
 	// in test environments
 	SkipCertVerification bool `yaml:"skipCertVerification"`
+	// WgPrivateKeyPath is the path to the WireGuard private key of the node.
+	// The key is generated if the file does not exist. If not specified a new
+	// key is generated every time the daemon starts
+	WgPrivateKeyPath string `yaml:"wgPrivateKeyPath" validate:"required_if=BindCertificates true"`
+	// KeyRotationOverlap is the number of seconds peers configure both the old
+	// and new WireGuard key when the node rotates its key. Defaults to 5 minutes
+	KeyRotationOverlap int `yaml:"keyRotationOverlap" validate:"gte=0"`
+	// PresharedKeyRotation is the number of seconds a WireGuard pre-shared key
+	// is used with a node before a new key is agreed in meshes that require
+	// pre-shared keys. Defaults to an hour
+	PresharedKeyRotation int `yaml:"presharedKeyRotation" validate:"gte=0"`
+	// BindCertificates specifies that the certificate of every node must name the
+	// node's WireGuard public key in a URI SAN. Peers can then only sync or serve
+	// as the node their certificate names
+	BindCertificates bool `yaml:"bindCertificates"`
+	// MeshAuthorisation specifies that only current members of a mesh or nodes
+	// holding a join credential may fetch or sync the mesh. Requires bindCertificates
+	MeshAuthorisation bool `yaml:"meshAuthorisation"`
 	// Port to run the GrpcServer on
 	GrpcPort int `yaml:"gRPCPort" validate:"required"`
 	// Timeout number of seconds without response that a node is considered unreachable by gRPC
@@ -99,6 +215,54 @@ type DaemonConfiguration struct {
 	BaseConfiguration WgConfiguration `yaml:"baseConfiguration" validate:"required"`
 	// LogLevel specifies the log level to output, defaults is warning
 	LogLevel LogLevel `yaml:"logLevel" validate:"eq=info|eq=warning|eq=error"`
+	// HistoryPath: directory to store the history of each mesh in. History
+	// is not recorded if not specified
+	HistoryPath string `yaml:"historyPath"`
+	// HistoryMaxEntries: maximum number of snapshots to keep per mesh
+	HistoryMaxEntries int `yaml:"historyMaxEntries" validate:"gte=0"`
+	// HistoryRetention: number of seconds to keep snapshots for
+	HistoryRetention int `yaml:"historyRetention" validate:"gte=0"`
+	// HistoryCompactAfter: number of seconds after which snapshots are thinned out
+	HistoryCompactAfter int `yaml:"historyCompactAfter" validate:"gte=0"`
+	// HistoryCompactInterval: once compacted keep at most one snapshot per interval in seconds
+	HistoryCompactInterval int `yaml:"historyCompactInterval" validate:"gte=0"`
+	// Services are the services the node offers in its meshes and the health
+	// checks that decide whether they are discoverable
+	Services []ServiceConfiguration `yaml:"services" validate:"dive"`
+	// Dns configures the daemon to answer DNS queries for the nodes in its
+	// meshes on localhost and on its address in each mesh. The node
+	// advertises itself as a DNS server of its meshes. DNS is not served if
+	// not specified
+	Dns *DnsConfiguration `yaml:"dns"`
+	// DnsHook is a shell command run whenever the DNS servers of a mesh
+	// change so that the host can send queries for the mesh to them. The
+	// mesh and its servers are passed in SMEG_ environment variables. Only
+	// admins serving the zone in dns, smeg. if not specified, are passed
+	DnsHook string `yaml:"dnsHook"`
+}
+
+// DnsConfiguration: configuration of the DNS server answering for the
+// nodes in the meshes
+type DnsConfiguration struct {
+	// Port is the port to serve DNS on over UDP and TCP. Defaults to 53
+	Port int `yaml:"port" validate:"gte=0,lte=65535"`
+	// Zone is the zone the server is authoritative for. Defaults to smeg.
+	Zone string `yaml:"zone"`
+	// Upstreams are the resolvers as host:port that queries outside the
+	// mesh zones are forwarded to. Queries are refused if not specified
+	Upstreams []string `yaml:"upstreams"`
+	// RecordTtl is the number of seconds answers may be cached for.
+	// Defaults to 60 seconds
+	RecordTtl int `yaml:"recordTtl" validate:"gte=0"`
+	// NegativeTtl is the number of seconds NXDOMAIN and NODATA responses
+	// may be cached for. Defaults to 30 seconds
+	NegativeTtl int `yaml:"negativeTtl" validate:"gte=0"`
+	// CacheSize is the maximum number of forwarded responses to cache.
+	// Defaults to 10000
+	CacheSize int `yaml:"cacheSize" validate:"gte=0"`
+	// WordsFile is the path to the words that name nodes without an alias.
+	// Nodes are named by the hex of their address if not specified
+	WordsFile string `yaml:"wordsFile"`
 }
 
 // ValdiateMeshConfiguration: validates the mesh configuration
@@ -136,11 +300,175 @@ func ValidateDaemonConfiguration(conf *DaemonConfiguration) error {
 		conf.LogLevel = WARNING
 	}
 
+	if conf.HistoryMaxEntries == 0 {
+		conf.HistoryMaxEntries = 1000
+	}
+
+	if conf.HistoryRetention == 0 {
+		conf.HistoryRetention = 7 * 24 * 60 * 60
+	}
+
+	if conf.HistoryCompactAfter == 0 {
+		conf.HistoryCompactAfter = 60 * 60
+	}
+
+	if conf.HistoryCompactInterval == 0 {
+		conf.HistoryCompactInterval = 5 * 60
+	}
+
+	if conf.CertificateTtl == 0 {
+		conf.CertificateTtl = 24 * 60 * 60
+	}
+
+	if conf.CertificateReloadInterval == 0 {
+		conf.CertificateReloadInterval = 10
+	}
+
+	if conf.KeyRotationOverlap == 0 {
+		conf.KeyRotationOverlap = 5 * 60
+	}
+
+	if conf.PresharedKeyRotation == 0 {
+		conf.PresharedKeyRotation = 60 * 60
+	}
+
+	if conf.Dns != nil {
+		if err := ValidateDnsConfiguration(conf.Dns); err != nil {
+			return err
+		}
+
+		conf.Services = append(conf.Services, dnsService(conf.Dns))
+	}
+
+	if err := validateServices(conf.Services); err != nil {
+		return err
+	}
+
+	if conf.RequestCertificates && conf.WgPrivateKeyPath == "" {
+		return errors.New("requestCertificates requires wgPrivateKeyPath")
+	}
+
+	if conf.MeshAuthorisation && !conf.BindCertificates {
+		return errors.New("meshAuthorisation requires bindCertificates")
+	}
+
 	validate := validator.New(validator.WithRequiredStructEnabled())
 	err := validate.Struct(conf)
 	return err
 }
 
+// dnsService: the service advertising that the node serves DNS. The
+// service is critical if the node stops answering
+func dnsService(conf *DnsConfiguration) ServiceConfiguration {
+	return ServiceConfiguration{
+		Name:     DNS_SERVICE,
+		Port:     conf.Port,
+		Protocol: "udp",
+		Metadata: map[string]string{"zone": conf.Zone},
+		Checks: []CheckConfiguration{{
+			Type:   TCP_CHECK,
+			Target: net.JoinHostPort("127.0.0.1", fmt.Sprint(conf.Port)),
+		}},
+	}
+}
+
+// validateServices: defaults the services' checks and ensures every
+// service has a unique name
+func validateServices(services []ServiceConfiguration) error {
+	names := make(map[string]bool)
+
+	for i := range services {
+		service := &services[i]
+
+		if names[service.Name] {
+			return fmt.Errorf("service %s is defined more than once", service.Name)
+		}
+
+		names[service.Name] = true
+
+		if service.Protocol == "" {
+			service.Protocol = "tcp"
+		}
+
+		for j := range service.Checks {
+			check := &service.Checks[j]
+
+			if check.Interval == 0 {
+				check.Interval = DEFAULT_CHECK_INTERVAL
+			}
+
+			if check.Timeout == 0 {
+				check.Timeout = DEFAULT_CHECK_TIMEOUT
+			}
+		}
+
+		if service.Ttl == 0 {
+			service.Ttl = 3 * service.Interval()
+		}
+	}
+
+	return nil
+}
+
+// ValidateDnsConfiguration: defaults and validates the DNS configuration
+func ValidateDnsConfiguration(conf *DnsConfiguration) error {
+	if conf.Port == 0 {
+		conf.Port = 53
+	}
+
+	if conf.Zone == "" {
+		conf.Zone = "smeg."
+	}
+
+	if !strings.HasSuffix(conf.Zone, ".") {
+		conf.Zone += "."
+	}
+
+	if conf.RecordTtl == 0 {
+		conf.RecordTtl = 60
+	}
+
+	if conf.NegativeTtl == 0 {
+		conf.NegativeTtl = 30
+	}
+
+	if conf.CacheSize == 0 {
+		conf.CacheSize = 10000
+	}
+
+	if _, ok := dns.IsDomainName(conf.Zone); !ok {
+		return fmt.Errorf("invalid zone %s", conf.Zone)
+	}
+
+	for _, upstream := range conf.Upstreams {
+		if _, _, err := net.SplitHostPort(upstream); err != nil {
+			return fmt.Errorf("invalid upstream %s: %w", upstream, err)
+		}
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	return validate.Struct(conf)
+}
+
+// ParseDnsConfiguration: parses and validates the DNS configuration
+func ParseDnsConfiguration(filePath string) (*DnsConfiguration, error) {
+	var conf DnsConfiguration
+
+	yamlBytes, err := os.ReadFile(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(yamlBytes, &conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &conf, ValidateDnsConfiguration(&conf)
+}
+
 // ParseDaemonConfiguration parses the mesh configuration and validates the configuration
 func ParseDaemonConfiguration(filePath string) (*DaemonConfiguration, error) {
 	var conf DaemonConfiguration
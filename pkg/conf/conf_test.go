@@ -234,3 +234,137 @@ func TestValidConfiguration(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestHistoryDefaultsApplied(t *testing.T) {
+	conf := getExampleConfiguration()
+
+	err := ValidateDaemonConfiguration(conf)
+
+	if err != nil {
+		t.Fatal(`error should not be thrown`)
+	}
+
+	if conf.HistoryMaxEntries == 0 || conf.HistoryRetention == 0 {
+		t.Fatal(`history defaults should have been set`)
+	}
+}
+
+func TestBindCertificatesRequiresWgPrivateKeyPath(t *testing.T) {
+	conf := getExampleConfiguration()
+	conf.BindCertificates = true
+
+	err := ValidateDaemonConfiguration(conf)
+
+	if err == nil {
+		t.Fatal(`error should be thrown`)
+	}
+
+	conf.WgPrivateKeyPath = "/var/lib/smegmesh/wg.key"
+
+	if err := ValidateDaemonConfiguration(conf); err != nil {
+		t.Fatalf(`error should not be thrown: %s`, err.Error())
+	}
+}
+
+func TestMeshAuthorisationRequiresBindCertificates(t *testing.T) {
+	conf := getExampleConfiguration()
+	conf.MeshAuthorisation = true
+
+	err := ValidateDaemonConfiguration(conf)
+
+	if err == nil {
+		t.Fatal(`error should be thrown`)
+	}
+}
+
+func TestHistoryMaxEntriesNegative(t *testing.T) {
+	conf := getExampleConfiguration()
+	conf.HistoryMaxEntries = -1
+
+	err := ValidateDaemonConfiguration(conf)
+
+	if err == nil {
+		t.Fatal(`error should be thrown`)
+	}
+}
+
+func TestServiceDefaultsApplied(t *testing.T) {
+	conf := getExampleConfiguration()
+	conf.Services = []ServiceConfiguration{{
+		Name: "db",
+		Port: 5432,
+		Checks: []CheckConfiguration{
+			{Type: TCP_CHECK},
+			{Type: HTTP_CHECK, Interval: 2},
+		},
+	}}
+
+	if err := ValidateDaemonConfiguration(conf); err != nil {
+		t.Fatalf(`error should not be thrown: %s`, err.Error())
+	}
+
+	service := conf.Services[0]
+
+	if service.Protocol != "tcp" || service.Checks[0].Interval != DEFAULT_CHECK_INTERVAL || service.Ttl != 6 {
+		t.Fatalf(`service defaults should have been set`)
+	}
+}
+
+func TestServiceExecCheckRequiresCommand(t *testing.T) {
+	conf := getExampleConfiguration()
+	conf.Services = []ServiceConfiguration{{
+		Name:   "db",
+		Port:   5432,
+		Checks: []CheckConfiguration{{Type: EXEC_CHECK}},
+	}}
+
+	if err := ValidateDaemonConfiguration(conf); err == nil {
+		t.Fatal(`error should be thrown`)
+	}
+}
+
+func TestServiceDefinedTwice(t *testing.T) {
+	conf := getExampleConfiguration()
+	conf.Services = []ServiceConfiguration{{Name: "db", Port: 5432}, {Name: "db", Port: 5433}}
+
+	if err := ValidateDaemonConfiguration(conf); err == nil {
+		t.Fatal(`error should be thrown`)
+	}
+}
+
+func TestDnsDefaultsApplied(t *testing.T) {
+	conf := DnsConfiguration{Zone: "mesh.internal"}
+
+	if err := ValidateDnsConfiguration(&conf); err != nil {
+		t.Fatalf(`error should not be thrown: %s`, err.Error())
+	}
+
+	if conf.Port != 53 || conf.Zone != "mesh.internal." || conf.RecordTtl != 60 || conf.NegativeTtl != 30 {
+		t.Fatalf(`dns defaults should have been set`)
+	}
+}
+
+func TestDnsInvalidUpstream(t *testing.T) {
+	conf := DnsConfiguration{Upstreams: []string{"1.1.1.1"}}
+
+	if err := ValidateDnsConfiguration(&conf); err == nil {
+		t.Fatal(`error should be thrown`)
+	}
+}
+
+func TestDnsAdvertisedAsService(t *testing.T) {
+	conf := getExampleConfiguration()
+	conf.Dns = &DnsConfiguration{Port: 5353}
+
+	if err := ValidateDaemonConfiguration(conf); err != nil {
+		t.Fatalf(`error should not be thrown: %s`, err.Error())
+	}
+
+	if len(conf.Services) != 1 || conf.Services[0].Name != DNS_SERVICE || conf.Services[0].Port != 5353 {
+		t.Fatalf(`expected the dns service to be advertised`)
+	}
+
+	if conf.Services[0].Metadata["zone"] != "smeg." {
+		t.Fatalf(`expected the zone in the service's metadata`)
+	}
+}
@@ -0,0 +1,121 @@
+package conn
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
+)
+
+// CaPool: the CA bundle peers' certificates are verified against. The
+// bundle may be replaced whilst the daemon is running so TLS
+// configurations read it on every handshake
+type CaPool struct {
+	path string
+	lock sync.RWMutex
+	pool *x509.CertPool
+}
+
+// LoadCaPool: load the PEM encoded CA bundle at the given path
+func LoadCaPool(path string) (*CaPool, error) {
+	if path == "" {
+		return nil, errors.New("CA Cert is not specified")
+	}
+
+	caPool := &CaPool{path: path}
+
+	if err := caPool.Load(); err != nil {
+		return nil, err
+	}
+
+	return caPool, nil
+}
+
+// Load: load the CA bundle from its PEM file. The previous bundle is
+// kept if the file does not contain a valid certificate
+func (c *CaPool) Load() error {
+	contents, err := os.ReadFile(c.path)
+
+	if err != nil {
+		return err
+	}
+
+	pool := x509.NewCertPool()
+	var expires time.Time
+
+	for block, rest := pem.Decode(contents); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+
+		if err != nil {
+			return fmt.Errorf("could not parse CA certificate %s: %w", c.path, err)
+		}
+
+		if expires.IsZero() || cert.NotAfter.Before(expires) {
+			expires = cert.NotAfter
+		}
+
+		pool.AddCert(cert)
+	}
+
+	if expires.IsZero() {
+		return errors.New("could not parse PEM")
+	}
+
+	c.lock.Lock()
+	c.pool = pool
+	c.lock.Unlock()
+
+	logging.Log.WriteInfof("loaded CA bundle %s expiring %s", c.path, expires.Format(time.RFC3339))
+	return nil
+}
+
+// Pool: the current CA bundle
+func (c *CaPool) Pool() *x509.CertPool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.pool
+}
+
+// VerifyServer: verify the server's certificate chains to the current
+// CA bundle. Certificates bound to a WireGuard key name no hosts so the
+// server is identified by its key once the call is made. Certificates
+// naming no key must name the server. Used in place of the static
+// RootCAs
+func (c *CaPool) VerifyServer(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server did not present a certificate")
+	}
+
+	leaf := state.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	options := x509.VerifyOptions{
+		Roots:         c.Pool(),
+		Intermediates: intermediates,
+	}
+
+	_, err := CertificateWgKey(leaf)
+
+	if errors.Is(err, ErrNoIdentity) {
+		options.DNSName = state.ServerName
+	} else if err != nil {
+		return err
+	}
+
+	_, err = leaf.Verify(options)
+	return err
+}
@@ -2,12 +2,11 @@ package conn
 
 import (
 	"crypto/tls"
-	"crypto/x509"
-	"errors"
-	"os"
 	"sync"
+	"time"
 
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
 // ConnectionManager defines an interface for maintaining peer connections
@@ -24,10 +23,17 @@ type ConnectionManager interface {
 	HasConnection(endPoint string) bool
 	// Removes a connection if it exists
 	RemoveConnection(endPoint string) error
+	// Redial replaces every connection with a new connection so that
+	// they use the current certificates
+	Redial() error
 	// Goes through all the connections and closes eachone
 	Close() error
 }
 
+// REDIAL_GRACE_PERIOD: how long a replaced connection remains open for
+// calls in progress to complete
+const REDIAL_GRACE_PERIOD = 30 * time.Second
+
 // ConnectionManager manages connections between other peers
 // in the control plane.
 type ConnectionManagerImpl struct {
@@ -48,40 +54,57 @@ type NewConnectionManagerParams struct {
 	SkipCertVerification bool
 	CaCert               string
 	ConnFactory          PeerConnectionFactory
+	// KeyPair: the node's key pair. Loaded from CertificatePath and
+	// PrivateKey if not provided
+	KeyPair *KeyPair
+	// CaPool: the CA bundle. Loaded from CaCert if not provided
+	CaPool *CaPool
+	// RefuseKey: if set refuses servers whose certificate names a
+	// WireGuard key it returns an error for during the TLS handshake
+	RefuseKey func(key wgtypes.Key) error
 }
 
 // NewConnectionManager: Creates a new instance of a ConnectionManager or an error
 // if something went wrong.
 func NewConnectionManager(params *NewConnectionManagerParams) (ConnectionManager, error) {
-	cert, err := tls.LoadX509KeyPair(params.CertificatePath, params.PrivateKey)
+	keyPair := params.KeyPair
 
-	if err != nil {
-		logging.Log.WriteErrorf("Failed to load key pair: %s\n", err.Error())
-		logging.Log.WriteErrorf("Certificate Path: %s\n", params.CertificatePath)
-		logging.Log.WriteErrorf("Private Key Path: %s\n", params.PrivateKey)
-		return nil, err
+	if keyPair == nil {
+		var err error
+		keyPair, err = LoadKeyPair(params.CertificatePath, params.PrivateKey)
+
+		if err != nil {
+			logging.Log.WriteErrorf("Failed to load key pair: %s\n", err.Error())
+			logging.Log.WriteErrorf("Certificate Path: %s\n", params.CertificatePath)
+			logging.Log.WriteErrorf("Private Key Path: %s\n", params.PrivateKey)
+			return nil, err
+		}
 	}
 
-	certPool := x509.NewCertPool()
+	caPool := params.CaPool
 
-	if params.CaCert == "" {
-		return nil, errors.New("CA Cert is not specified")
-	}
+	if caPool == nil {
+		var err error
+		caPool, err = LoadCaPool(params.CaCert)
 
-	caCert, err := os.ReadFile(params.CaCert)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if err != nil {
-		return nil, err
+	// The server is verified against the CA bundle at the time of the
+	// handshake rather than a static set of roots
+	clientConfig := &tls.Config{
+		InsecureSkipVerify:   true,
+		GetClientCertificate: keyPair.GetClientCertificate,
 	}
 
-	if ok := certPool.AppendCertsFromPEM(caCert); !ok {
-		return nil, errors.New("could not parse PEM")
+	if !params.SkipCertVerification {
+		clientConfig.VerifyConnection = caPool.VerifyServer
 	}
 
-	clientConfig := &tls.Config{
-		InsecureSkipVerify: params.SkipCertVerification,
-		Certificates:       []tls.Certificate{cert},
-		RootCAs:            certPool,
+	if params.RefuseKey != nil {
+		clientConfig.VerifyPeerCertificate = RefuseKeys(params.RefuseKey)
 	}
 
 	connections := make(map[string]PeerConnection)
@@ -153,6 +176,34 @@ func (m *ConnectionManagerImpl) RemoveConnection(endPoint string) error {
 	m.conLoc.Unlock()
 	return err
 }
+
+// Redial: replace every connection with a new connection. The previous
+// connections are closed once calls in progress have had time to complete
+func (m *ConnectionManagerImpl) Redial() error {
+	m.conLoc.Lock()
+	previous := m.clientConnections
+	m.clientConnections = make(map[string]PeerConnection)
+	m.conLoc.Unlock()
+
+	for endpoint := range previous {
+		if _, err := m.AddConnection(endpoint); err != nil {
+			logging.Log.WriteWarnf("could not redial %s: %s", endpoint, err.Error())
+		}
+	}
+
+	if len(previous) != 0 {
+		logging.Log.WriteInfof("redialled %d connections", len(previous))
+	}
+
+	time.AfterFunc(REDIAL_GRACE_PERIOD, func() {
+		for _, connection := range previous {
+			connection.Close()
+		}
+	})
+
+	return nil
+}
+
 func (m *ConnectionManagerImpl) Close() error {
 	for _, conn := range m.clientConnections {
 		if err := conn.Close(); err != nil {
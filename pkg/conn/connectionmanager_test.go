@@ -5,6 +5,8 @@ import (
 	"errors"
 	"log"
 	"testing"
+
+	"google.golang.org/grpc"
 )
 
 func getConnectionManagerParams() *NewConnectionManagerParams {
@@ -143,3 +145,41 @@ func TestHasConnectionConnectionExists(t *testing.T) {
 		t.Fatal(`should return that the connection exists`)
 	}
 }
+
+type countingConnection struct {
+	closed bool
+}
+
+func (c *countingConnection) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *countingConnection) GetClient() (*grpc.ClientConn, error) {
+	return nil, nil
+}
+
+func TestRedialReplacesConnections(t *testing.T) {
+	params := getConnectionManagerParams()
+	params.ConnFactory = func(clientConfig *tls.Config, server string) (PeerConnection, error) {
+		return &countingConnection{}, nil
+	}
+
+	m, _ := NewConnectionManager(params)
+
+	previous, _ := m.GetConnection("abc-123.com")
+
+	if err := m.Redial(); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	current, _ := m.GetConnection("abc-123.com")
+
+	if current == previous {
+		t.Fatalf(`expected connection to be redialled`)
+	}
+
+	if previous.(*countingConnection).closed {
+		t.Fatalf(`expected previous connection to remain open for calls in progress`)
+	}
+}
@@ -1,20 +1,26 @@
 package conn
 
 import (
+	"context"
 	"crypto/tls"
-	"crypto/x509"
-	"errors"
 	"fmt"
 	"net"
-	"os"
 
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
 	"github.com/tim-beatham/smegmesh/pkg/rpc"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
+// SIGN_CERTIFICATE_METHOD: the only call a peer may make without
+// presenting a certificate
+const SIGN_CERTIFICATE_METHOD = "/rpctypes.MeshCtrlServer/SignCertificate"
+
 // ConnectionServer manages gRPC server peer connections
 type ConnectionServer struct {
 	// server an instance of the grpc server
@@ -32,15 +38,29 @@ type NewConnectionServerParams struct {
 	Conf         *conf.DaemonConfiguration
 	CtrlProvider rpc.MeshCtrlServerServer
 	SyncProvider rpc.SyncServiceServer
+	// KeyPair: the node's key pair. Loaded from the configured
+	// certificate and private key if not provided
+	KeyPair *KeyPair
+	// CaPool: the CA bundle. Loaded from the configured CA certificate
+	// if not provided
+	CaPool *CaPool
+	// RefuseKey: if set refuses clients whose certificate names a
+	// WireGuard key it returns an error for during the TLS handshake
+	RefuseKey func(key wgtypes.Key) error
 }
 
 // NewConnectionServer: create a new gRPC connection server instance
 func NewConnectionServer(params *NewConnectionServerParams) (*ConnectionServer, error) {
-	cert, err := tls.LoadX509KeyPair(params.Conf.CertificatePath, params.Conf.PrivateKeyPath)
+	keyPair := params.KeyPair
 
-	if err != nil {
-		logging.Log.WriteErrorf("Failed to load key pair: %s\n", err.Error())
-		return nil, err
+	if keyPair == nil {
+		var err error
+		keyPair, err = LoadKeyPair(params.Conf.CertificatePath, params.Conf.PrivateKeyPath)
+
+		if err != nil {
+			logging.Log.WriteErrorf("Failed to load key pair: %s\n", err.Error())
+			return nil, err
+		}
 	}
 
 	serverAuth := tls.RequireAndVerifyClientCert
@@ -49,30 +69,49 @@ func NewConnectionServer(params *NewConnectionServerParams) (*ConnectionServer,
 		serverAuth = tls.RequireAnyClientCert
 	}
 
-	certPool := x509.NewCertPool()
+	// Nodes without a certificate may connect to a CA to request one.
+	// Every other call requires a certificate
+	if params.Conf.CaPrivateKeyPath != "" {
+		serverAuth = tls.VerifyClientCertIfGiven
 
-	if params.Conf.CaCertificatePath == "" {
-		return nil, errors.New("CA Cert is not specified")
+		if params.Conf.SkipCertVerification {
+			serverAuth = tls.RequestClientCert
+		}
 	}
 
-	caCert, err := os.ReadFile(params.Conf.CaCertificatePath)
+	caPool := params.CaPool
 
-	if err != nil {
-		return nil, err
-	}
+	if caPool == nil {
+		var err error
+		caPool, err = LoadCaPool(params.Conf.CaCertificatePath)
 
-	if ok := certPool.AppendCertsFromPEM(caCert); !ok {
-		return nil, errors.New("could not parse PEM")
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	serverConfig := &tls.Config{
-		ClientAuth:   serverAuth,
-		Certificates: []tls.Certificate{cert},
-		ClientCAs:    certPool,
+		ClientAuth:     serverAuth,
+		GetCertificate: keyPair.GetCertificate,
+		ClientCAs:      caPool.Pool(),
+	}
+
+	if params.RefuseKey != nil {
+		serverConfig.VerifyPeerCertificate = RefuseKeys(params.RefuseKey)
+	}
+
+	// Clients are verified against the CA bundle at the time of the
+	// handshake
+	serverConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		config := serverConfig.Clone()
+		config.ClientCAs = caPool.Pool()
+		return config, nil
 	}
 
 	server := grpc.NewServer(
 		grpc.Creds(credentials.NewTLS(serverConfig)),
+		grpc.UnaryInterceptor(requireCertificateUnary),
+		grpc.StreamInterceptor(requireCertificateStream),
 	)
 
 	ctrlProvider := params.CtrlProvider
@@ -88,6 +127,44 @@ func NewConnectionServer(params *NewConnectionServerParams) (*ConnectionServer,
 	return &connServer, nil
 }
 
+// requireCertificate: refuse calls from peers that did not present a
+// certificate unless the call requests a certificate
+func requireCertificate(ctx context.Context, method string) error {
+	if method == SIGN_CERTIFICATE_METHOD {
+		return nil
+	}
+
+	p, ok := peer.FromContext(ctx)
+
+	if !ok {
+		return status.Error(codes.Unauthenticated, "no peer in context")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return status.Error(codes.Unauthenticated, "peer did not present a certificate")
+	}
+
+	return nil
+}
+
+func requireCertificateUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := requireCertificate(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func requireCertificateStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := requireCertificate(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, stream)
+}
+
 // Listen for incoming requests. Returns an error if something went wrong.
 func (s *ConnectionServer) Listen() error {
 	rpc.RegisterMeshCtrlServerServer(s.server, s.ctrlProvider)
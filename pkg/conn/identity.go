@@ -0,0 +1,272 @@
+package conn
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// WG_KEY_URI_SCHEME: scheme of the URI SAN that binds a certificate
+	// to a WireGuard public key. smegmesh://wg/<base64url public key>
+	WG_KEY_URI_SCHEME = "smegmesh"
+	WG_KEY_URI_HOST   = "wg"
+)
+
+// ErrNoIdentity: the certificate does not name a WireGuard public key
+var ErrNoIdentity = errors.New("certificate does not name a WireGuard public key")
+
+// WgKeyURI: the URI SAN to place in a certificate to bind it to the
+// given WireGuard public key
+func WgKeyURI(key wgtypes.Key) *url.URL {
+	return &url.URL{
+		Scheme: WG_KEY_URI_SCHEME,
+		Host:   WG_KEY_URI_HOST,
+		Path:   "/" + base64.RawURLEncoding.EncodeToString(key[:]),
+	}
+}
+
+// CertificateWgKey: get the WireGuard public key named by the certificate
+func CertificateWgKey(cert *x509.Certificate) (*wgtypes.Key, error) {
+	var key *wgtypes.Key
+
+	for _, uri := range cert.URIs {
+		if uri.Scheme != WG_KEY_URI_SCHEME || uri.Host != WG_KEY_URI_HOST {
+			continue
+		}
+
+		bs, err := base64.RawURLEncoding.DecodeString(uri.Path[min(1, len(uri.Path)):])
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid WireGuard key in certificate: %w", err)
+		}
+
+		uriKey, err := wgtypes.NewKey(bs)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid WireGuard key in certificate: %w", err)
+		}
+
+		if key != nil && *key != uriKey {
+			return nil, errors.New("certificate names more than one WireGuard key")
+		}
+
+		key = &uriKey
+	}
+
+	if key == nil {
+		return nil, ErrNoIdentity
+	}
+
+	return key, nil
+}
+
+// PeerWgKey: get the WireGuard public key named by the peer's
+// certificate in the context of a gRPC call or stream
+func PeerWgKey(ctx context.Context) (*wgtypes.Key, error) {
+	p, ok := peer.FromContext(ctx)
+
+	if !ok {
+		return nil, errors.New("no peer in context")
+	}
+
+	return WgKeyFromPeer(p)
+}
+
+// WgKeyFromPeer: get the WireGuard public key named by the peer's
+// certificate
+func WgKeyFromPeer(p *peer.Peer) (*wgtypes.Key, error) {
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil, errors.New("peer did not present a certificate")
+	}
+
+	return CertificateWgKey(tlsInfo.State.PeerCertificates[0])
+}
+
+// VerifyPeerWgKey: verify the peer's certificate names the expected
+// WireGuard public key
+func VerifyPeerWgKey(ctx context.Context, expected string) error {
+	key, err := PeerWgKey(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if key.String() != expected {
+		return fmt.Errorf("peer certificate names %s expected %s", key.String(), expected)
+	}
+
+	return nil
+}
+
+// RefuseKeys: returns a tls.Config VerifyPeerCertificate function that
+// refuses the peer's certificate if refuse returns an error for the
+// WireGuard key it names. Certificates that name no key are left to the
+// checks made once the call is authenticated
+func RefuseKeys(refuse func(key wgtypes.Key) error) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return nil
+		}
+
+		cert, err := x509.ParseCertificate(rawCerts[0])
+
+		if err != nil {
+			return err
+		}
+
+		key, err := CertificateWgKey(cert)
+
+		if errors.Is(err, ErrNoIdentity) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return refuse(*key)
+	}
+}
+
+// VerifyCertificateFile: verify the certificate at the given path names
+// the WireGuard public key
+func VerifyCertificateFile(certificatePath string, key wgtypes.Key) error {
+	cert, err := loadCertificate(certificatePath)
+
+	if err != nil {
+		return err
+	}
+
+	certKey, err := CertificateWgKey(cert)
+
+	if err != nil || *certKey != key {
+		return fmt.Errorf("certificate %s must name the WireGuard key %s with the URI SAN %s",
+			certificatePath, key.String(), WgKeyURI(key).String())
+	}
+
+	return nil
+}
+
+// CertificateFingerprint: SHA-256 fingerprint of the certificate
+func CertificateFingerprint(cert *x509.Certificate) []byte {
+	fingerprint := sha256.Sum256(cert.Raw)
+	return fingerprint[:]
+}
+
+// loadCertificate: load the PEM encoded certificate at the given path
+func loadCertificate(certificatePath string) (*x509.Certificate, error) {
+	contents, err := os.ReadFile(certificatePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCertificate(contents, certificatePath)
+}
+
+// parseCertificate: parse the PEM encoded certificate
+func parseCertificate(contents []byte, name string) (*x509.Certificate, error) {
+	block, _ := pem.Decode(contents)
+
+	if block == nil {
+		return nil, fmt.Errorf("could not parse PEM %s", name)
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// VerifyCertificatePEM: verify the PEM encoded certificate names the
+// WireGuard public key
+func VerifyCertificatePEM(contents []byte, key wgtypes.Key) error {
+	cert, err := parseCertificate(contents, "certificate")
+
+	if err != nil {
+		return err
+	}
+
+	return VerifyCertificate(cert, key)
+}
+
+// VerifyCertificate: verify the certificate names the WireGuard
+// public key
+func VerifyCertificate(cert *x509.Certificate, key wgtypes.Key) error {
+	certKey, err := CertificateWgKey(cert)
+
+	if err != nil {
+		return err
+	}
+
+	if *certKey != key {
+		return fmt.Errorf("certificate names %s not %s", certKey.String(), key.String())
+	}
+
+	return nil
+}
+
+// CertificateFileFingerprint: SHA-256 fingerprint of the certificate
+// at the given path
+func CertificateFileFingerprint(certificatePath string) ([]byte, error) {
+	cert, err := loadCertificate(certificatePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return CertificateFingerprint(cert), nil
+}
+
+// VerifyPeerCa: verify the peer's certificate is signed by the CA
+// certificate at caPath and that the CA has the expected fingerprint.
+// Verified regardless of skipCertVerification
+func VerifyPeerCa(p *peer.Peer, caPath string, fingerprint []byte) error {
+	ca, err := loadCertificate(caPath)
+
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(CertificateFingerprint(ca), fingerprint) {
+		return fmt.Errorf("CA certificate %s does not have the expected fingerprint", caPath)
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return errors.New("peer did not present a certificate")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	intermediates := x509.NewCertPool()
+
+	for _, cert := range tlsInfo.State.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = tlsInfo.State.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+
+	if err != nil {
+		return fmt.Errorf("peer certificate is not signed by the CA: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package conn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func createCertificate(t *testing.T, uris ...*url.URL) *x509.Certificate {
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "node"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         uris,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func TestCertificateWgKeyReturnsBoundKey(t *testing.T) {
+	key, _ := wgtypes.GeneratePrivateKey()
+	cert := createCertificate(t, WgKeyURI(key.PublicKey()))
+
+	certKey, err := CertificateWgKey(cert)
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if *certKey != key.PublicKey() {
+		t.Fatalf(`expected %s got %s`, key.PublicKey().String(), certKey.String())
+	}
+}
+
+func TestCertificateWgKeyNoIdentity(t *testing.T) {
+	other, _ := url.Parse("https://example.com")
+	cert := createCertificate(t, other)
+
+	_, err := CertificateWgKey(cert)
+
+	if !errors.Is(err, ErrNoIdentity) {
+		t.Fatalf(`expected no identity error`)
+	}
+}
+
+func TestCertificateWgKeyMultipleKeysReturnsError(t *testing.T) {
+	key1, _ := wgtypes.GeneratePrivateKey()
+	key2, _ := wgtypes.GeneratePrivateKey()
+	cert := createCertificate(t, WgKeyURI(key1.PublicKey()), WgKeyURI(key2.PublicKey()))
+
+	if _, err := CertificateWgKey(cert); err == nil {
+		t.Fatalf(`a certificate naming two keys should be rejected`)
+	}
+}
+
+func TestVerifyCertificateFile(t *testing.T) {
+	key, _ := wgtypes.GeneratePrivateKey()
+	other, _ := wgtypes.GeneratePrivateKey()
+	cert := createCertificate(t, WgKeyURI(key.PublicKey()))
+
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600)
+
+	if err := VerifyCertificateFile(path, key.PublicKey()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if err := VerifyCertificateFile(path, other.PublicKey()); err == nil {
+		t.Fatalf(`certificate should not name a different key`)
+	}
+}
+
+func TestRefuseKeys(t *testing.T) {
+	refused, _ := wgtypes.GeneratePrivateKey()
+	accepted, _ := wgtypes.GeneratePrivateKey()
+	other, _ := url.Parse("https://example.com")
+
+	verify := RefuseKeys(func(key wgtypes.Key) error {
+		if key == refused.PublicKey() {
+			return errors.New("refused")
+		}
+
+		return nil
+	})
+
+	if err := verify([][]byte{createCertificate(t, WgKeyURI(refused.PublicKey())).Raw}, nil); err == nil {
+		t.Fatalf(`expected the certificate naming the refused key to be rejected`)
+	}
+
+	if err := verify([][]byte{createCertificate(t, WgKeyURI(accepted.PublicKey())).Raw}, nil); err != nil {
+		t.Fatalf(`expected the certificate to be accepted got %s`, err.Error())
+	}
+
+	if err := verify([][]byte{createCertificate(t, other).Raw}, nil); err != nil {
+		t.Fatalf(`expected a certificate naming no key to be left to later checks got %s`, err.Error())
+	}
+}
+
+func TestVerifyServerIdentifiesKeyBoundCertificatesByKey(t *testing.T) {
+	key, _ := wgtypes.GeneratePrivateKey()
+
+	bound := createCertificate(t, WgKeyURI(key.PublicKey()))
+	unbound := createCertificate(t)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(bound)
+	pool.AddCert(unbound)
+	caPool := &CaPool{pool: pool}
+
+	err := caPool.VerifyServer(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{bound},
+		ServerName:       "peer.example",
+	})
+
+	if err != nil {
+		t.Fatalf(`expected a certificate bound to a key to verify without naming the server got %s`, err.Error())
+	}
+
+	err = caPool.VerifyServer(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{unbound},
+		ServerName:       "peer.example",
+	})
+
+	if err == nil {
+		t.Fatalf(`expected a certificate naming no key to have to name the server`)
+	}
+}
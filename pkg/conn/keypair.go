@@ -0,0 +1,245 @@
+package conn
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
+)
+
+// KeyPair: the certificate and private key the node presents in mTLS.
+// The key pair may be replaced whilst the daemon is running so TLS
+// configurations read it on every handshake
+type KeyPair struct {
+	certificatePath string
+	privateKeyPath  string
+	lock            sync.RWMutex
+	certificate     *tls.Certificate
+	// verifier: additional checks a replacement certificate must pass
+	verifier func(*x509.Certificate) error
+}
+
+// LoadKeyPair: load the key pair from the given PEM files
+func LoadKeyPair(certificatePath, privateKeyPath string) (*KeyPair, error) {
+	keyPair := &KeyPair{
+		certificatePath: certificatePath,
+		privateKeyPath:  privateKeyPath,
+	}
+
+	if err := keyPair.Load(); err != nil {
+		return nil, err
+	}
+
+	return keyPair, nil
+}
+
+// NewKeyPair: load the key pair from the given PEM files if they
+// exist. Otherwise the key pair is empty until a certificate is stored
+func NewKeyPair(certificatePath, privateKeyPath string) (*KeyPair, error) {
+	keyPair := &KeyPair{
+		certificatePath: certificatePath,
+		privateKeyPath:  privateKeyPath,
+	}
+
+	err := keyPair.Load()
+
+	if errors.Is(err, os.ErrNotExist) {
+		return keyPair, nil
+	}
+
+	return keyPair, err
+}
+
+// Load: load the key pair from its PEM files
+func (k *KeyPair) Load() error {
+	certificate, err := k.read()
+
+	if err != nil {
+		return err
+	}
+
+	k.set(certificate)
+	return nil
+}
+
+// SetVerifier: check every replacement certificate with verifier
+// before using it
+func (k *KeyPair) SetVerifier(verifier func(*x509.Certificate) error) {
+	k.lock.Lock()
+	k.verifier = verifier
+	k.lock.Unlock()
+}
+
+// Reload: replace the key pair with the contents of its PEM files.
+// The previous key pair is kept if the new one is invalid or expired
+func (k *KeyPair) Reload() error {
+	certificate, err := k.read()
+
+	if err != nil {
+		return err
+	}
+
+	if err := k.validate(certificate); err != nil {
+		return err
+	}
+
+	k.set(certificate)
+	return nil
+}
+
+// Store: validate the PEM encoded key pair, write it to the key pair's
+// files and use it for every subsequent handshake
+func (k *KeyPair) Store(certificatePEM, privateKeyPEM []byte) error {
+	certificate, err := parseKeyPair(certificatePEM, privateKeyPEM)
+
+	if err != nil {
+		return err
+	}
+
+	if err := k.validate(certificate); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(k.privateKeyPath, privateKeyPEM, 0600); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(k.certificatePath, certificatePEM, 0644); err != nil {
+		return err
+	}
+
+	k.set(certificate)
+	return nil
+}
+
+func (k *KeyPair) read() (*tls.Certificate, error) {
+	certificatePEM, err := os.ReadFile(k.certificatePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	privateKeyPEM, err := os.ReadFile(k.privateKeyPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseKeyPair(certificatePEM, privateKeyPEM)
+}
+
+// parseKeyPair: parse the PEM encoded key pair and its leaf certificate
+func parseKeyPair(certificatePEM, privateKeyPEM []byte) (*tls.Certificate, error) {
+	certificate, err := tls.X509KeyPair(certificatePEM, privateKeyPEM)
+
+	if err != nil {
+		return nil, err
+	}
+
+	certificate.Leaf, err = x509.ParseCertificate(certificate.Certificate[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificate, nil
+}
+
+// validate: check the certificate may replace the current key pair
+func (k *KeyPair) validate(certificate *tls.Certificate) error {
+	if time.Now().After(certificate.Leaf.NotAfter) {
+		return fmt.Errorf("certificate %s expired %s", k.certificatePath,
+			certificate.Leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	k.lock.RLock()
+	verifier := k.verifier
+	k.lock.RUnlock()
+
+	if verifier != nil {
+		return verifier(certificate.Leaf)
+	}
+
+	return nil
+}
+
+func (k *KeyPair) set(certificate *tls.Certificate) {
+	k.lock.Lock()
+	k.certificate = certificate
+	k.lock.Unlock()
+
+	logging.Log.WriteInfof("loaded certificate %s expiring %s", k.certificatePath,
+		certificate.Leaf.NotAfter.Format(time.RFC3339))
+}
+
+// Leaf: the node's certificate. Nil if the key pair is empty
+func (k *KeyPair) Leaf() *x509.Certificate {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if k.certificate == nil {
+		return nil
+	}
+
+	return k.certificate.Leaf
+}
+
+// GetCertificate: the certificate to present to clients
+func (k *KeyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if k.certificate == nil {
+		return nil, errors.New("no certificate has been issued")
+	}
+
+	return k.certificate, nil
+}
+
+// GetClientCertificate: the certificate to present to servers. No
+// certificate is presented if the key pair is empty or has expired so
+// that the node can still request a new certificate
+func (k *KeyPair) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if k.certificate == nil || time.Now().After(k.certificate.Leaf.NotAfter) {
+		return &tls.Certificate{}, nil
+	}
+
+	return k.certificate, nil
+}
+
+// writeFileAtomic: write the file by renaming a temporary file over it
+// so that readers never see a partially written file
+func writeFileAtomic(path string, contents []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(contents); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,171 @@
+package conn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func createKeyPairPEM(t *testing.T, notAfter time.Time) ([]byte, []byte) {
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	keyDer, _ := x509.MarshalECPrivateKey(privateKey)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestNewKeyPairMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	keyPair, err := NewKeyPair(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "priv.pem"))
+
+	if err != nil {
+		t.Fatalf(`expected missing key pair to be tolerated got %s`, err.Error())
+	}
+
+	if _, err := keyPair.GetCertificate(nil); err == nil {
+		t.Fatalf(`expected an empty key pair to have no server certificate`)
+	}
+
+	certificate, _ := keyPair.GetClientCertificate(nil)
+
+	if len(certificate.Certificate) != 0 {
+		t.Fatalf(`expected an empty key pair to present no client certificate`)
+	}
+}
+
+func TestKeyPairStore(t *testing.T) {
+	dir := t.TempDir()
+	certificatePath := filepath.Join(dir, "cert.pem")
+	privateKeyPath := filepath.Join(dir, "priv.pem")
+
+	keyPair, _ := NewKeyPair(certificatePath, privateKeyPath)
+	certificatePEM, privateKeyPEM := createKeyPairPEM(t, time.Now().Add(time.Hour))
+
+	if err := keyPair.Store(certificatePEM, privateKeyPEM); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if keyPair.Leaf() == nil {
+		t.Fatalf(`expected stored certificate to be used`)
+	}
+
+	loaded, err := LoadKeyPair(certificatePath, privateKeyPath)
+
+	if err != nil {
+		t.Fatalf(`expected stored key pair to be written to disk got %s`, err.Error())
+	}
+
+	if !loaded.Leaf().Equal(keyPair.Leaf()) {
+		t.Fatalf(`expected the stored certificate to be loaded`)
+	}
+}
+
+func TestKeyPairStoreMismatchedKey(t *testing.T) {
+	dir := t.TempDir()
+	keyPair, _ := NewKeyPair(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "priv.pem"))
+
+	certificatePEM, _ := createKeyPairPEM(t, time.Now().Add(time.Hour))
+	_, privateKeyPEM := createKeyPairPEM(t, time.Now().Add(time.Hour))
+
+	if err := keyPair.Store(certificatePEM, privateKeyPEM); err == nil {
+		t.Fatalf(`expected certificate for a different key to be rejected`)
+	}
+
+	if keyPair.Leaf() != nil {
+		t.Fatalf(`expected rejected certificate not to be used`)
+	}
+}
+
+func writeKeyPair(t *testing.T, dir string, notAfter time.Time) (string, string) {
+	certificatePath := filepath.Join(dir, "cert.pem")
+	privateKeyPath := filepath.Join(dir, "priv.pem")
+	certificatePEM, privateKeyPEM := createKeyPairPEM(t, notAfter)
+
+	if err := os.WriteFile(certificatePath, certificatePEM, 0644); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if err := os.WriteFile(privateKeyPath, privateKeyPEM, 0600); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return certificatePath, privateKeyPath
+}
+
+func TestKeyPairExpiredClientCertificate(t *testing.T) {
+	keyPair, err := LoadKeyPair(writeKeyPair(t, t.TempDir(), time.Now().Add(-time.Minute)))
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	certificate, _ := keyPair.GetClientCertificate(nil)
+
+	if len(certificate.Certificate) != 0 {
+		t.Fatalf(`expected an expired certificate not to be presented`)
+	}
+}
+
+func TestKeyPairReload(t *testing.T) {
+	dir := t.TempDir()
+	keyPair, _ := LoadKeyPair(writeKeyPair(t, dir, time.Now().Add(time.Hour)))
+	previous := keyPair.Leaf()
+
+	writeKeyPair(t, dir, time.Now().Add(2*time.Hour))
+
+	if err := keyPair.Reload(); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if keyPair.Leaf().Equal(previous) {
+		t.Fatalf(`expected the replaced certificate to be used`)
+	}
+}
+
+func TestKeyPairReloadKeepsPreviousIfInvalid(t *testing.T) {
+	dir := t.TempDir()
+	keyPair, _ := LoadKeyPair(writeKeyPair(t, dir, time.Now().Add(time.Hour)))
+	previous := keyPair.Leaf()
+
+	writeKeyPair(t, dir, time.Now().Add(-time.Minute))
+
+	if err := keyPair.Reload(); err == nil {
+		t.Fatalf(`expected an expired certificate to be rejected`)
+	}
+
+	writeKeyPair(t, dir, time.Now().Add(time.Hour))
+	keyPair.SetVerifier(func(*x509.Certificate) error {
+		return errors.New("does not name the WireGuard key")
+	})
+
+	if err := keyPair.Reload(); err == nil {
+		t.Fatalf(`expected a certificate the verifier rejects to be rejected`)
+	}
+
+	if !keyPair.Leaf().Equal(previous) {
+		t.Fatalf(`expected the previous certificate to be kept`)
+	}
+}
@@ -36,6 +36,10 @@ func (s *ConnectionManagerStub) HasConnection(endPoint string) bool {
 	return ok
 }
 
+func (s *ConnectionManagerStub) Redial() error {
+	return nil
+}
+
 func (s *ConnectionManagerStub) Close() error {
 	return nil
 }
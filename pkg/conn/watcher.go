@@ -0,0 +1,91 @@
+package conn
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
+)
+
+// fileState: what is compared to detect a file has changed. The zero
+// value represents a file that does not exist
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size()}
+}
+
+type watch struct {
+	paths  []string
+	states []fileState
+	reload func() error
+}
+
+// Watcher: polls files on disk and reloads them when they change
+type Watcher struct {
+	lock    sync.Mutex
+	watches []*watch
+}
+
+// NewWatcher: create a watcher that is not watching any files
+func NewWatcher() *Watcher {
+	return &Watcher{}
+}
+
+// Watch: call reload whenever any of the files at paths change
+func (w *Watcher) Watch(reload func() error, paths ...string) {
+	states := make([]fileState, len(paths))
+
+	for i, path := range paths {
+		states[i] = statFile(path)
+	}
+
+	w.lock.Lock()
+	w.watches = append(w.watches, &watch{paths: paths, states: states, reload: reload})
+	w.lock.Unlock()
+}
+
+// Poll: reload the files that have changed since the last poll.
+// Returns true if anything was reloaded. A reload that fails keeps the
+// previous contents and is retried once the files change again
+func (w *Watcher) Poll() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	reloaded := false
+
+	for _, watch := range w.watches {
+		states := make([]fileState, len(watch.paths))
+		changed := false
+
+		for i, path := range watch.paths {
+			states[i] = statFile(path)
+			changed = changed || states[i] != watch.states[i]
+		}
+
+		if !changed {
+			continue
+		}
+
+		watch.states = states
+
+		if err := watch.reload(); err != nil {
+			logging.Log.WriteErrorf("could not reload %v: %s", watch.paths, err.Error())
+			continue
+		}
+
+		reloaded = true
+	}
+
+	return reloaded
+}
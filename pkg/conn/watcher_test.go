@@ -0,0 +1,63 @@
+package conn
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherReloadsChangedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	os.WriteFile(path, []byte("a"), 0600)
+
+	reloads := 0
+	watcher := NewWatcher()
+	watcher.Watch(func() error {
+		reloads++
+		return nil
+	}, path)
+
+	if watcher.Poll() || reloads != 0 {
+		t.Fatalf(`expected unchanged file not to be reloaded`)
+	}
+
+	os.WriteFile(path, []byte("bb"), 0600)
+
+	if !watcher.Poll() || reloads != 1 {
+		t.Fatalf(`expected changed file to be reloaded`)
+	}
+
+	if watcher.Poll() || reloads != 1 {
+		t.Fatalf(`expected file to be reloaded once`)
+	}
+}
+
+func TestWatcherCreatedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cert.pem")
+
+	watcher := NewWatcher()
+	watcher.Watch(func() error { return nil }, path)
+
+	os.WriteFile(path, []byte("a"), 0600)
+
+	if !watcher.Poll() {
+		t.Fatalf(`expected created file to be reloaded`)
+	}
+}
+
+func TestWatcherFailedReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	os.WriteFile(path, []byte("a"), 0600)
+
+	watcher := NewWatcher()
+	watcher.Watch(func() error { return errors.New("invalid certificate") }, path)
+
+	os.WriteFile(path, []byte("bb"), 0600)
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+
+	if watcher.Poll() {
+		t.Fatalf(`expected failed reload not to be reported`)
+	}
+}
@@ -5,13 +5,31 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
+	"github.com/tim-beatham/smegmesh/pkg/auth"
 	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/conn"
 	"github.com/tim-beatham/smegmesh/pkg/ctrlserver"
+	"github.com/tim-beatham/smegmesh/pkg/history"
 	"github.com/tim-beatham/smegmesh/pkg/ipc"
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
 	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/query"
 	"github.com/tim-beatham/smegmesh/pkg/rpc"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// INVITE_ENDPOINTS: maximum number of bootstrap endpoints in an invite
+const INVITE_ENDPOINTS = 3
+
+const (
+	// WATCH_TIMEOUT: maximum time a watch waits for the nodes to change
+	WATCH_TIMEOUT = 30 * time.Second
 )
 
 // IpcHandler: represents a handler for ipc calls
@@ -19,6 +37,33 @@ type IpcHandler struct {
 	Server ctrlserver.CtrlServer
 }
 
+// verifyPeer: if certificates are bound verify the peer we called is
+// a member of the mesh. If endpoint is provided the member must be
+// listening at the endpoint
+func (n *IpcHandler) verifyPeer(theMesh mesh.MeshProvider, endpoint string, p *peer.Peer) error {
+	if !n.Server.GetConfiguration().BindCertificates {
+		return nil
+	}
+
+	key, err := conn.WgKeyFromPeer(p)
+
+	if err != nil {
+		return err
+	}
+
+	node, err := theMesh.GetNode(key.String())
+
+	if err != nil {
+		return fmt.Errorf("%s is not a member of the mesh", key.String())
+	}
+
+	if endpoint != "" && node.GetHostEndpoint() != endpoint {
+		return fmt.Errorf("%s does not belong to %s", endpoint, key.String())
+	}
+
+	return nil
+}
+
 // getOverrideConfiguration: override any specific WireGuard configuration
 func getOverrideConfiguration(args *ipc.WireGuardArgs) conf.WgConfiguration {
 	overrideConf := conf.WgConfiguration{}
@@ -42,17 +87,34 @@ func getOverrideConfiguration(args *ipc.WireGuardArgs) conf.WgConfiguration {
 	return overrideConf
 }
 
+// joinRole: the role the node joins a mesh with
+func (n *IpcHandler) joinRole(args *ipc.WireGuardArgs) string {
+	if args.Role != "" {
+		return args.Role
+	}
+
+	if role := n.Server.GetConfiguration().BaseConfiguration.Role; role != nil {
+		return string(*role)
+	}
+
+	return ""
+}
+
 // CreateMesh: create a new mesh network
 func (n *IpcHandler) CreateMesh(args *ipc.NewMeshArgs, reply *string) error {
 	overrideConf := getOverrideConfiguration(&args.WgArgs)
 
 	meshId, err := n.Server.GetMeshManager().CreateMesh(&mesh.CreateMeshParams{
-		Port: args.WgArgs.WgPort,
-		Conf: &overrideConf,
+		Port:            args.WgArgs.WgPort,
+		Conf:            &overrideConf,
+		RequireApproval: args.RequireApproval,
+		PresharedKeys:   args.PresharedKeys,
+		Name:            args.Name,
+		Description:     args.Description,
 	})
 
 	if err != nil {
-		return errors.New("could not create mesh")
+		return fmt.Errorf("could not create mesh: %w", err)
 	}
 
 	err = n.Server.GetMeshManager().AddSelf(&mesh.AddSelfParams{
@@ -72,64 +134,179 @@ func (n *IpcHandler) CreateMesh(args *ipc.NewMeshArgs, reply *string) error {
 // ListMeshes: list mesh networks
 func (n *IpcHandler) ListMeshes(_ string, reply *ipc.ListMeshReply) error {
 	meshNames := make([]string, len(n.Server.GetMeshManager().GetMeshes()))
+	infos := make(map[string]mesh.MeshInfo)
 
 	i := 0
-	for meshId := range n.Server.GetMeshManager().GetMeshes() {
+	for meshId, theMesh := range n.Server.GetMeshManager().GetMeshes() {
 		meshNames[i] = meshId
 		i++
+
+		info, err := mesh.GetMeshInfo(theMesh)
+
+		if err != nil {
+			return err
+		}
+
+		if info != nil {
+			infos[meshId] = *info
+		}
 	}
 
 	slices.Sort(meshNames)
-	*reply = ipc.ListMeshReply{Meshes: meshNames}
+	*reply = ipc.ListMeshReply{Meshes: meshNames, Infos: infos}
 	return nil
 }
 
+// getMesh: fetch the mesh from the peer at the given endpoint
+func (n *IpcHandler) getMesh(endpoint string, request *rpc.GetMeshRequest, meshPeer *peer.Peer) (*rpc.GetMeshReply, error) {
+	peerConnection, err := n.Server.GetConnectionManager().GetConnection(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := peerConnection.GetClient()
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := rpc.NewMeshCtrlServerClient(client)
+
+	configuration := n.Server.GetConfiguration()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(configuration.Timeout))
+	defer cancel()
+
+	return c.GetMesh(ctx, request, grpc.Peer(meshPeer))
+}
+
+// applyInvite: take the mesh, role and bootstrap endpoints from the invite
+func applyInvite(args *ipc.JoinMeshArgs) (*auth.Invite, []string, error) {
+	invite, err := auth.DecodeInvite(args.Invite)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if args.MeshId != "" && args.MeshId != invite.MeshId {
+		return nil, nil, fmt.Errorf("invite is for mesh %s", invite.MeshId)
+	}
+
+	args.MeshId = invite.MeshId
+
+	if invite.Role != "" {
+		if args.WgArgs.Role != "" && args.WgArgs.Role != invite.Role {
+			return nil, nil, fmt.Errorf("invite only permits the role %s", invite.Role)
+		}
+
+		args.WgArgs.Role = invite.Role
+	}
+
+	endpoints := invite.Endpoints
+
+	if args.IpAddress != "" {
+		endpoints = append([]string{args.IpAddress}, endpoints...)
+	}
+
+	return invite, endpoints, nil
+}
+
 // JoinMesh: join a mesh network
 func (n *IpcHandler) JoinMesh(args *ipc.JoinMeshArgs, reply *string) error {
+	var invite *auth.Invite
+	endpoints := []string{args.IpAddress}
+	credential := args.Credential
+
+	if args.Invite != "" {
+		var err error
+		invite, endpoints, err = applyInvite(args)
+
+		if err != nil {
+			return fmt.Errorf("could not join mesh: %s", err.Error())
+		}
+
+		credential = args.Invite
+	}
+
 	overrideConf := getOverrideConfiguration(&args.WgArgs)
 
 	if n.Server.GetMeshManager().GetMesh(args.MeshId) != nil {
 		return fmt.Errorf("user is already a part of the mesh")
 	}
 
-	peerConnection, err := n.Server.GetConnectionManager().GetConnection(args.IpAddress)
+	if args.Alias != "" {
+		if err := mesh.ValidateAlias(args.Alias); err != nil {
+			return fmt.Errorf("could not join mesh: %w", err)
+		}
+	}
 
-	if err != nil {
-		return fmt.Errorf("could not join mesh %s", args.MeshId)
+	request := &rpc.GetMeshRequest{
+		MeshId:     args.MeshId,
+		Credential: args.Credential,
+		Invite:     args.Invite,
+		Role:       n.joinRole(&args.WgArgs),
 	}
 
-	client, err := peerConnection.GetClient()
+	var meshPeer peer.Peer
+	var meshReply *rpc.GetMeshReply
+	var joinedThrough string
+	var err error = fmt.Errorf("no endpoint to join through")
 
-	if err != nil {
-		return fmt.Errorf("could not join mesh %s", args.MeshId)
-	}
+	enroller := n.Server.GetEnroller()
 
-	c := rpc.NewMeshCtrlServerClient(client)
+	for _, endpoint := range endpoints {
+		if enroller != nil && enroller.NeedsCertificate() {
+			if err = enroller.Enroll(endpoint, args.MeshId, credential); err != nil {
+				logging.Log.WriteWarnf("could not request certificate from %s: %s", endpoint, err.Error())
+				continue
+			}
+		}
+
+		meshReply, err = n.getMesh(endpoint, request, &meshPeer)
+
+		if err == nil {
+			joinedThrough = endpoint
+			break
+		}
+
+		logging.Log.WriteWarnf("could not get mesh %s from %s: %s", args.MeshId, endpoint, err.Error())
+	}
 
 	if err != nil {
 		return fmt.Errorf("could not join mesh %s", args.MeshId)
 	}
 
-	configuration := n.Server.GetConfiguration()
+	if invite != nil {
+		err := conn.VerifyPeerCa(&meshPeer, n.Server.GetConfiguration().CaCertificatePath, invite.CaFingerprint)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(configuration.Timeout))
-	defer cancel()
+		if err != nil {
+			return fmt.Errorf("could not join mesh %s: %s", args.MeshId, err.Error())
+		}
+	}
 
-	meshReply, err := c.GetMesh(ctx, &rpc.GetMeshRequest{MeshId: args.MeshId})
+	err = n.Server.GetMeshManager().AddMesh(&mesh.AddMeshParams{
+		MeshId:     args.MeshId,
+		WgPort:     args.WgArgs.WgPort,
+		MeshBytes:  meshReply.Mesh,
+		Conf:       &overrideConf,
+		Credential: credential,
+	})
 
 	if err != nil {
 		return fmt.Errorf("could not join mesh %s", args.MeshId)
 	}
 
-	err = n.Server.GetMeshManager().AddMesh(&mesh.AddMeshParams{
-		MeshId:    args.MeshId,
-		WgPort:    args.WgArgs.WgPort,
-		MeshBytes: meshReply.Mesh,
-		Conf:      &overrideConf,
-	})
+	theMesh := n.Server.GetMeshManager().GetMesh(args.MeshId)
+	err = n.verifyPeer(theMesh, "", &meshPeer)
+
+	if err == nil && invite != nil {
+		err = invite.Verify(theMesh, n.Server.GetMeshManager().GetPublicKey().String(), request.Role, time.Now())
+	}
 
 	if err != nil {
-		return fmt.Errorf("could not join mesh %s", args.MeshId)
+		n.Server.GetMeshManager().LeaveMesh(args.MeshId)
+		return fmt.Errorf("could not join mesh %s: %s", args.MeshId, err.Error())
 	}
 
 	err = n.Server.GetMeshManager().AddSelf(&mesh.AddSelfParams{
@@ -142,12 +319,96 @@ func (n *IpcHandler) JoinMesh(args *ipc.JoinMeshArgs, reply *string) error {
 		return fmt.Errorf("could not join mesh %s", args.MeshId)
 	}
 
+	if args.Alias != "" {
+		n.Server.GetMeshManager().SetAlias(args.MeshId, args.Alias)
+	}
+
+	if args.Description != "" {
+		n.Server.GetMeshManager().SetDescription(args.MeshId, args.Description)
+	}
+
+	approved, err := n.requestJoin(theMesh, joinedThrough, args)
+
+	if err != nil {
+		return fmt.Errorf("could not request to join mesh %s: %s", args.MeshId, err.Error())
+	}
+
+	if !approved {
+		*reply = fmt.Sprintf("Requested to join %s: awaiting approval", args.MeshId)
+		return nil
+	}
+
 	*reply = fmt.Sprintf("Successfully Joined: %s", args.MeshId)
 	return nil
 }
 
+// requestJoin: if the mesh requires approval ask the peer we joined
+// through to pass our request on to the mesh's admins. Returns true if
+// we have been approved
+func (n *IpcHandler) requestJoin(theMesh mesh.MeshProvider, endpoint string, args *ipc.JoinMeshArgs) (bool, error) {
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return false, err
+	}
+
+	if admission.IsApproved(n.Server.GetMeshManager().GetPublicKey().String()) {
+		return true, nil
+	}
+
+	peerConnection, err := n.Server.GetConnectionManager().GetConnection(endpoint)
+
+	if err != nil {
+		return false, err
+	}
+
+	client, err := peerConnection.GetClient()
+
+	if err != nil {
+		return false, err
+	}
+
+	c := rpc.NewMeshCtrlServerClient(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(n.Server.GetConfiguration().Timeout))
+	defer cancel()
+
+	request := mesh.JoinRequest{
+		PublicKey:   n.Server.GetMeshManager().GetPublicKey().String(),
+		Role:        string(*theMesh.GetConfiguration().Role),
+		Alias:       args.Alias,
+		Description: args.Description,
+		Timestamp:   time.Now().Unix(),
+	}
+
+	signature, err := request.Sign(args.MeshId, n.Server.GetMeshManager().Sign)
+
+	if err != nil {
+		return false, err
+	}
+
+	reply, err := c.RequestJoin(ctx, &rpc.RequestJoinRequest{
+		MeshId:      args.MeshId,
+		Role:        request.Role,
+		Alias:       request.Alias,
+		Description: request.Description,
+		Timestamp:   request.Timestamp,
+		Signature:   signature,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return reply.Approved, nil
+}
+
 // LeaveMesh: leaves a mesh network
 func (n *IpcHandler) LeaveMesh(meshId string, reply *string) error {
+	if err := n.resolveMesh(&meshId); err != nil {
+		return err
+	}
+
 	err := n.Server.GetMeshManager().LeaveMesh(meshId)
 
 	if err == nil {
@@ -158,6 +419,10 @@ func (n *IpcHandler) LeaveMesh(meshId string, reply *string) error {
 
 // GetMesh: get a mesh network at the given meshid
 func (n *IpcHandler) GetMesh(meshId string, reply *ipc.GetMeshReply) error {
+	if err := n.resolveMesh(&meshId); err != nil {
+		return err
+	}
+
 	theMesh := n.Server.GetMeshManager().GetMesh(meshId)
 
 	if theMesh == nil {
@@ -174,23 +439,44 @@ func (n *IpcHandler) GetMesh(meshId string, reply *ipc.GetMeshReply) error {
 		return errors.New("mesh does not exist")
 	}
 
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
 	nodes := make([]ctrlserver.MeshNode, len(meshSnapshot.GetNodes()))
+	aliases, _ := mesh.ResolveAliases(admission, meshSnapshot.GetNodes())
 
 	i := 0
 	for _, node := range meshSnapshot.GetNodes() {
 		node := ctrlserver.NewCtrlNode(theMesh, node)
 
+		if alias, ok := aliases[node.PublicKey]; ok {
+			node.Alias = alias
+		}
+
 		nodes[i] = *node
 		i += 1
 	}
 
-	*reply = ipc.GetMeshReply{Nodes: nodes}
+	info, err := mesh.GetMeshInfo(theMesh)
+
+	if err != nil {
+		return err
+	}
+
+	*reply = ipc.GetMeshReply{MeshId: meshId, Info: info, Nodes: nodes}
 	return nil
 }
 
 // Query: perform a jmespath query
 func (n *IpcHandler) Query(params ipc.QueryMesh, reply *string) error {
-	queryResponse, err := n.Server.GetQuerier().Query(params.MeshId, params.Query)
+	if err := n.resolveMesh(&params.MeshId); err != nil {
+		return err
+	}
+
+	queryResponse, err := n.Server.GetQuerier().Query(params.MeshId, params.Selector, params.Query)
 
 	if err != nil {
 		return err
@@ -202,6 +488,10 @@ func (n *IpcHandler) Query(params ipc.QueryMesh, reply *string) error {
 
 // PutDescription: change your description in the mesh
 func (n *IpcHandler) PutDescription(args ipc.PutDescriptionArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
 	err := n.Server.GetMeshManager().SetDescription(args.MeshId, args.Description)
 
 	if err != nil {
@@ -214,6 +504,10 @@ func (n *IpcHandler) PutDescription(args ipc.PutDescriptionArgs, reply *string)
 
 // PutAlias: put your aliasin the mesh
 func (n *IpcHandler) PutAlias(args ipc.PutAliasArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
 	if args.Alias == "" {
 		return fmt.Errorf("alias not provided")
 	}
@@ -221,7 +515,7 @@ func (n *IpcHandler) PutAlias(args ipc.PutAliasArgs, reply *string) error {
 	err := n.Server.GetMeshManager().SetAlias(args.MeshId, args.Alias)
 
 	if err != nil {
-		return fmt.Errorf("could not set alias: %s", args.Alias)
+		return fmt.Errorf("could not set alias %s: %w", args.Alias, err)
 	}
 
 	*reply = fmt.Sprintf("Set alias to %s", args.Alias)
@@ -230,6 +524,10 @@ func (n *IpcHandler) PutAlias(args ipc.PutAliasArgs, reply *string) error {
 
 // PutService: place a service in the mesh
 func (n *IpcHandler) PutService(service ipc.PutServiceArgs, reply *string) error {
+	if err := n.resolveMesh(&service.MeshId); err != nil {
+		return err
+	}
+
 	err := n.Server.GetMeshManager().SetService(service.MeshId, service.Service, service.Value)
 
 	if err != nil {
@@ -240,8 +538,44 @@ func (n *IpcHandler) PutService(service ipc.PutServiceArgs, reply *string) error
 	return nil
 }
 
+// PutLabel: place a label on ourselves in the mesh
+func (n *IpcHandler) PutLabel(args ipc.PutLabelArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	err := n.Server.GetMeshManager().SetLabel(args.MeshId, args.Key, args.Value)
+
+	if err != nil {
+		return err
+	}
+
+	*reply = fmt.Sprintf("Set label %s in %s to %s", args.Key, args.MeshId, args.Value)
+	return nil
+}
+
+// DeleteLabel: remove a label from ourselves in the mesh
+func (n *IpcHandler) DeleteLabel(args ipc.DeleteLabelArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	err := n.Server.GetMeshManager().RemoveLabel(args.MeshId, args.Key)
+
+	if err != nil {
+		return err
+	}
+
+	*reply = fmt.Sprintf("Removed label %s from %s", args.Key, args.MeshId)
+	return nil
+}
+
 // DeleteService: withtract a service in the mesh
 func (n *IpcHandler) DeleteService(service ipc.DeleteServiceArgs, reply *string) error {
+	if err := n.resolveMesh(&service.MeshId); err != nil {
+		return err
+	}
+
 	err := n.Server.GetMeshManager().RemoveService(service.MeshId, service.Service)
 
 	if err != nil {
@@ -252,13 +586,807 @@ func (n *IpcHandler) DeleteService(service ipc.DeleteServiceArgs, reply *string)
 	return nil
 }
 
-// RobinIpcParams: parameters required to construct a new mesh network
-type RobinIpcParams struct {
-	CtrlServer ctrlserver.CtrlServer
+// DiffState: compare our replicated store with the store of the peer
+// entry by entry
+func (n *IpcHandler) DiffState(args ipc.DiffStateArgs, reply *ipc.DiffStateReply) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	theMesh := n.Server.GetMeshManager().GetMesh(args.MeshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", args.MeshId)
+	}
+
+	localState, err := theMesh.GetState()
+
+	if err != nil {
+		return err
+	}
+
+	peerConnection, err := n.Server.GetConnectionManager().GetConnection(args.Peer)
+
+	if err != nil {
+		return fmt.Errorf("could not connect to %s", args.Peer)
+	}
+
+	client, err := peerConnection.GetClient()
+
+	if err != nil {
+		return fmt.Errorf("could not connect to %s", args.Peer)
+	}
+
+	c := rpc.NewMeshCtrlServerClient(client)
+
+	configuration := n.Server.GetConfiguration()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(configuration.Timeout))
+	defer cancel()
+
+	var statePeer peer.Peer
+	stateReply, err := c.GetState(ctx, &rpc.GetStateRequest{MeshId: args.MeshId}, grpc.Peer(&statePeer))
+
+	if err != nil {
+		return fmt.Errorf("could not get state from %s: %s", args.Peer, err.Error())
+	}
+
+	if err := n.verifyPeer(theMesh, args.Peer, &statePeer); err != nil {
+		return fmt.Errorf("could not get state from %s: %s", args.Peer, err.Error())
+	}
+
+	remoteState := &mesh.MeshState{
+		Vectors:      stateReply.Vectors,
+		HighestStale: stateReply.HighestStale,
+		Entries:      make(map[uint64]mesh.MeshStateEntry),
+	}
+
+	for _, entry := range stateReply.Entries {
+		remoteState.Entries[entry.Key] = mesh.MeshStateEntry{
+			Key:         entry.Key,
+			InAdd:       entry.InAdd,
+			AddClock:    entry.AddClock,
+			InRemove:    entry.InRemove,
+			RemoveClock: entry.RemoveClock,
+			Gravestone:  entry.Gravestone,
+			PublicKey:   entry.PublicKey,
+			Alias:       entry.Alias,
+			WgHost:      entry.WgHost,
+			Timestamp:   entry.Timestamp,
+		}
+	}
+
+	*reply = ipc.DiffStateReply{
+		LocalHighestStale:  localState.HighestStale,
+		RemoteHighestStale: remoteState.HighestStale,
+		Differences:        mesh.DiffState(localState, remoteState),
+	}
+	return nil
 }
 
-func NewRobinIpc(ipcParams RobinIpcParams) IpcHandler {
-	return IpcHandler{
-		Server: ipcParams.CtrlServer,
+// getHistory: get the history store returning an error if history
+// is not being recorded
+func (n *IpcHandler) getHistory() (history.Store, error) {
+	store := n.Server.GetHistory()
+
+	if store == nil {
+		return nil, fmt.Errorf("history is not recorded, set historyPath in the configuration")
+	}
+
+	return store, nil
+}
+
+// History: list the snapshots recorded for the mesh
+func (n *IpcHandler) History(meshId string, reply *ipc.HistoryReply) error {
+	if err := n.resolveMesh(&meshId); err != nil {
+		return err
+	}
+
+	store, err := n.getHistory()
+
+	if err != nil {
+		return err
+	}
+
+	entries, err := store.List(meshId)
+
+	if err != nil {
+		return err
 	}
+
+	*reply = ipc.HistoryReply{Entries: entries}
+	return nil
+}
+
+// ShowAt: get the state of the mesh at the given point in time
+func (n *IpcHandler) ShowAt(args ipc.ShowAtArgs, reply *history.Snapshot) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	store, err := n.getHistory()
+
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := store.At(args.MeshId, time.Unix(args.At, 0))
+
+	if err != nil {
+		return err
+	}
+
+	*reply = *snapshot
+	return nil
+}
+
+// DiffHistory: get the joins, leaves, route and service changes between
+// two points in time
+func (n *IpcHandler) DiffHistory(args ipc.DiffHistoryArgs, reply *history.Diff) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	store, err := n.getHistory()
+
+	if err != nil {
+		return err
+	}
+
+	from, err := store.At(args.MeshId, time.Unix(args.From, 0))
+
+	if err != nil {
+		return err
+	}
+
+	to, err := store.At(args.MeshId, time.Unix(args.To, 0))
+
+	if err != nil {
+		return err
+	}
+
+	*reply = *history.DiffSnapshots(from, to)
+	return nil
+}
+
+// RobinIpcParams: parameters required to construct a new mesh network
+type RobinIpcParams struct {
+	CtrlServer ctrlserver.CtrlServer
+}
+
+// GrantJoin: issue a credential granting the node with the given
+// public key access to the mesh
+func (n *IpcHandler) GrantJoin(args ipc.GrantJoinArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	manager := n.Server.GetMeshManager()
+	theMesh := manager.GetMesh(args.MeshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", args.MeshId)
+	}
+
+	if err := n.requireIssuer(theMesh); err != nil {
+		return err
+	}
+
+	grantee, err := wgtypes.ParseKey(args.PublicKey)
+
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	if args.Ttl <= 0 {
+		return fmt.Errorf("ttl must be positive")
+	}
+
+	credential := auth.JoinCredential{
+		MeshId:  args.MeshId,
+		Issuer:  manager.GetPublicKey().String(),
+		Grantee: grantee.String(),
+		Expires: time.Now().Add(time.Second * time.Duration(args.Ttl)).Unix(),
+	}
+
+	if err := credential.Sign(manager.Sign); err != nil {
+		return err
+	}
+
+	encoded, err := credential.Encode()
+
+	if err != nil {
+		return err
+	}
+
+	*reply = encoded
+	return nil
+}
+
+// CreateInvite: issue an invite to the mesh that may be redeemed by a
+// limited number of nodes
+func (n *IpcHandler) CreateInvite(args ipc.CreateInviteArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	manager := n.Server.GetMeshManager()
+	theMesh := manager.GetMesh(args.MeshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", args.MeshId)
+	}
+
+	if args.Ttl <= 0 {
+		return fmt.Errorf("ttl must be positive")
+	}
+
+	if args.Uses <= 0 {
+		return fmt.Errorf("uses must be positive")
+	}
+
+	if err := n.requireIssuer(theMesh); err != nil {
+		return err
+	}
+
+	if args.Role != "" && args.Role != string(conf.PEER_ROLE) && args.Role != string(conf.CLIENT_ROLE) {
+		return fmt.Errorf("invalid role %s", args.Role)
+	}
+
+	fingerprint, err := conn.CertificateFileFingerprint(n.Server.GetConfiguration().CaCertificatePath)
+
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := bootstrapEndpoints(theMesh, manager.GetPublicKey().String())
+
+	if err != nil {
+		return err
+	}
+
+	id, err := (&lib.ShortIDGenerator{}).GetId()
+
+	if err != nil {
+		return err
+	}
+
+	invite := auth.Invite{
+		Id:            id,
+		MeshId:        args.MeshId,
+		Issuer:        manager.GetPublicKey().String(),
+		Endpoints:     endpoints,
+		CaFingerprint: fingerprint,
+		Role:          args.Role,
+		Expires:       time.Now().Add(time.Second * time.Duration(args.Ttl)).Unix(),
+		Uses:          args.Uses,
+	}
+
+	if err := invite.Sign(manager.Sign); err != nil {
+		return err
+	}
+
+	encoded, err := invite.Encode()
+
+	if err != nil {
+		return err
+	}
+
+	*reply = encoded
+	return nil
+}
+
+// bootstrapEndpoints: gRPC endpoints to place in an invite. Our own
+// endpoint followed by those of other peers in the mesh
+func bootstrapEndpoints(theMesh mesh.MeshProvider, self string) ([]string, error) {
+	snapshot, err := theMesh.GetMesh()
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := snapshot.GetNodes()
+	endpoints := make([]string, 0, INVITE_ENDPOINTS)
+
+	if node, ok := nodes[self]; ok {
+		endpoints = append(endpoints, node.GetHostEndpoint())
+	}
+
+	keys := lib.MapKeys(nodes)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		node := nodes[key]
+
+		if len(endpoints) >= INVITE_ENDPOINTS {
+			break
+		}
+
+		if key == self || node.GetType() != conf.PEER_ROLE || node.GetHostEndpoint() == "" {
+			continue
+		}
+
+		endpoints = append(endpoints, node.GetHostEndpoint())
+	}
+
+	return endpoints, nil
+}
+
+// PendingJoins: list requests to join the mesh an admin has not
+// decided on
+func (n *IpcHandler) PendingJoins(meshId string, reply *ipc.PendingJoinsReply) error {
+	if err := n.resolveMesh(&meshId); err != nil {
+		return err
+	}
+
+	theMesh := n.Server.GetMeshManager().GetMesh(meshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", meshId)
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	if !admission.RequiresApproval() {
+		return fmt.Errorf("mesh %s does not require approval", meshId)
+	}
+
+	*reply = ipc.PendingJoinsReply{Requests: admission.Pending()}
+	return nil
+}
+
+// DecideJoin: approve or reject a node. Only admins of the mesh may
+// decide. The decision is signed and recorded in our node
+func (n *IpcHandler) DecideJoin(args ipc.DecideJoinArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	manager := n.Server.GetMeshManager()
+	theMesh := manager.GetMesh(args.MeshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", args.MeshId)
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	self := manager.GetPublicKey().String()
+
+	if !admission.IsAdmin(self) {
+		return fmt.Errorf("only admins of mesh %s may approve nodes", args.MeshId)
+	}
+
+	key, err := wgtypes.ParseKey(args.PublicKey)
+
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	decision := mesh.JoinDecision{
+		PublicKey: key.String(),
+		Approved:  args.Approve,
+		Approver:  self,
+		Timestamp: time.Now().Unix(),
+	}
+
+	for _, request := range admission.Pending() {
+		if request.PublicKey == decision.PublicKey {
+			decision.Role = request.Role
+		}
+	}
+
+	if err := decision.Sign(manager.Sign); err != nil {
+		return err
+	}
+
+	if err := theMesh.AddJoinDecision(self, decision); err != nil {
+		return err
+	}
+
+	if args.Approve {
+		*reply = fmt.Sprintf("Approved %s", decision.PublicKey)
+	} else {
+		*reply = fmt.Sprintf("Rejected %s", decision.PublicKey)
+	}
+
+	return nil
+}
+
+// Evict: evict a node from the mesh. Only admins of the mesh may evict
+// nodes. Every peer removes the node and refuses it once the
+// revocation has been replicated
+func (n *IpcHandler) Evict(args ipc.EvictArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	manager := n.Server.GetMeshManager()
+	theMesh := manager.GetMesh(args.MeshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", args.MeshId)
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	self := manager.GetPublicKey().String()
+
+	if !admission.IsAdmin(self) {
+		return fmt.Errorf("only admins of mesh %s may evict nodes", args.MeshId)
+	}
+
+	key, err := wgtypes.ParseKey(args.PublicKey)
+
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	if key.String() == self {
+		return fmt.Errorf("cannot evict ourselves, leave the mesh instead")
+	}
+
+	if key.String() == admission.GetGenesis().Creator {
+		return fmt.Errorf("cannot evict the creator of mesh %s", args.MeshId)
+	}
+
+	revocation := mesh.Revocation{
+		PublicKey: key.String(),
+		Revoker:   self,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if err := revocation.Sign(manager.Sign); err != nil {
+		return err
+	}
+
+	if err := theMesh.AddRevocation(self, revocation); err != nil {
+		return err
+	}
+
+	*reply = fmt.Sprintf("Evicted %s", revocation.PublicKey)
+	return nil
+}
+
+// GrantAdmin: grant or revoke a node's admin rights. Only the owner of
+// the mesh may change admin rights
+func (n *IpcHandler) GrantAdmin(args ipc.GrantAdminArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	manager := n.Server.GetMeshManager()
+	theMesh := manager.GetMesh(args.MeshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", args.MeshId)
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	self := manager.GetPublicKey().String()
+
+	if !admission.IsOwner(self) {
+		return fmt.Errorf("only the owner of mesh %s may change admin rights", args.MeshId)
+	}
+
+	key, err := wgtypes.ParseKey(args.PublicKey)
+
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	if key.String() == self {
+		return fmt.Errorf("the owner of mesh %s is always an admin", args.MeshId)
+	}
+
+	grant := mesh.Grant{
+		PublicKey: key.String(),
+		Admin:     args.Admin,
+		Granter:   self,
+		Timestamp: time.Now().Unix(),
+	}
+
+	if err := grant.Sign(manager.Sign); err != nil {
+		return err
+	}
+
+	if err := theMesh.AddGrant(self, grant); err != nil {
+		return err
+	}
+
+	if args.Admin {
+		*reply = fmt.Sprintf("Granted admin rights to %s", grant.PublicKey)
+	} else {
+		*reply = fmt.Sprintf("Revoked admin rights from %s", grant.PublicKey)
+	}
+
+	return nil
+}
+
+// SetAcl: set or clear the access control policy of the mesh. Meshes
+// with a genesis only accept policies set by their admins
+func (n *IpcHandler) SetAcl(args ipc.SetAclArgs, reply *string) error {
+	if err := n.resolveMesh(&args.MeshId); err != nil {
+		return err
+	}
+
+	manager := n.Server.GetMeshManager()
+	theMesh := manager.GetMesh(args.MeshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", args.MeshId)
+	}
+
+	if args.Policy != nil {
+		if err := args.Policy.Validate(); err != nil {
+			return fmt.Errorf("invalid policy: %w", err)
+		}
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	self := manager.GetPublicKey().String()
+
+	if admission.GetGenesis() != nil && !admission.IsAdmin(self) {
+		return fmt.Errorf("only admins of mesh %s may set the access control policy", args.MeshId)
+	}
+
+	policy := mesh.AclPolicy{
+		Policy:    args.Policy,
+		Author:    self,
+		Timestamp: time.Now().Unix(),
+	}
+
+	// The policy must supersede the current policy even if the clocks
+	// of the nodes disagree
+	if current := admission.GetAclPolicy(); current != nil && current.Timestamp >= policy.Timestamp {
+		policy.Timestamp = current.Timestamp + 1
+	}
+
+	if err := policy.Sign(manager.Sign); err != nil {
+		return err
+	}
+
+	if err := theMesh.SetAclPolicy(self, policy); err != nil {
+		return err
+	}
+
+	if args.Policy == nil {
+		*reply = fmt.Sprintf("Cleared the access control policy of %s", args.MeshId)
+	} else {
+		*reply = fmt.Sprintf("Set the access control policy of %s", args.MeshId)
+	}
+
+	return nil
+}
+
+// GetAcl: get the access control policy of the mesh
+func (n *IpcHandler) GetAcl(meshId string, reply *ipc.GetAclReply) error {
+	if err := n.resolveMesh(&meshId); err != nil {
+		return err
+	}
+
+	theMesh := n.Server.GetMeshManager().GetMesh(meshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", meshId)
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	if policy := admission.GetAclPolicy(); policy != nil {
+		*reply = ipc.GetAclReply{
+			Policy:    policy.Policy,
+			Author:    policy.Author,
+			Timestamp: policy.Timestamp,
+		}
+	}
+
+	return nil
+}
+
+// Watch: wait until the nodes in the meshes differ from the caller's
+// version. Returns the nodes unchanged after WATCH_TIMEOUT so that the
+// caller can tell the daemon is still running
+func (n *IpcHandler) Watch(args ipc.WatchArgs, reply *ipc.WatchReply) error {
+	manager := n.Server.GetMeshManager()
+	timeout := time.NewTimer(WATCH_TIMEOUT)
+	defer timeout.Stop()
+
+	for {
+		// Wait on the notification from before the nodes were read so
+		// that a change while reading them is not missed
+		updated := manager.Updated()
+		meshes, names, version, err := query.Nodes(manager)
+
+		if err != nil {
+			return err
+		}
+
+		if version != args.Version {
+			*reply = ipc.WatchReply{Version: version, Meshes: meshes, Names: names}
+			return nil
+		}
+
+		select {
+		case <-updated:
+		case <-timeout.C:
+			*reply = ipc.WatchReply{Version: version, Meshes: meshes, Names: names}
+			return nil
+		}
+	}
+}
+
+// ListAdmins: list the owner and admins of the mesh
+func (n *IpcHandler) ListAdmins(meshId string, reply *ipc.ListAdminsReply) error {
+	if err := n.resolveMesh(&meshId); err != nil {
+		return err
+	}
+
+	theMesh := n.Server.GetMeshManager().GetMesh(meshId)
+
+	if theMesh == nil {
+		return fmt.Errorf("mesh %s does not exist", meshId)
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	if admission.GetGenesis() != nil {
+		reply.Owner = admission.GetGenesis().Creator
+	}
+
+	reply.Admins = admission.GetAdmins()
+	return nil
+}
+
+// requireIssuer: meshes with a genesis only accept credentials and
+// invites issued by their admins so refuse to issue them otherwise
+func (n *IpcHandler) requireIssuer(theMesh mesh.MeshProvider) error {
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	self := n.Server.GetMeshManager().GetPublicKey().String()
+
+	if admission.GetGenesis() != nil && !admission.IsAdmin(self) {
+		return fmt.Errorf("only admins of mesh %s may issue credentials and invites", theMesh.GetMeshId())
+	}
+
+	return nil
+}
+
+func NewRobinIpc(ipcParams RobinIpcParams) IpcHandler {
+	return IpcHandler{
+		Server: ipcParams.CtrlServer,
+	}
+}
+
+// RotateKey: replace the node's WireGuard key in every mesh. Peers
+// accept both keys until the successor activates
+func (n *IpcHandler) RotateKey(_ string, reply *ipc.RotateKeyReply) error {
+	configuration := n.Server.GetConfiguration()
+
+	// A certificate must be issued for the successor before it activates
+	if configuration.BindCertificates && !configuration.RequestCertificates {
+		return fmt.Errorf("cannot rotate a key bound to a certificate unless requestCertificates is set")
+	}
+
+	overlap := time.Duration(configuration.KeyRotationOverlap) * time.Second
+	succession, err := n.Server.GetMeshManager().RotateKey(overlap)
+
+	if err != nil {
+		return err
+	}
+
+	*reply = ipc.RotateKeyReply{
+		PublicKey: succession.PublicKey,
+		Successor: succession.Successor,
+		Activates: succession.Activates,
+	}
+	return nil
+}
+
+// resolveMesh: replace the mesh name with the id of the mesh so that
+// either can be given
+func (n *IpcHandler) resolveMesh(nameOrId *string) error {
+	meshId, err := mesh.ResolveMeshId(n.Server.GetMeshManager(), *nameOrId)
+
+	if err != nil {
+		return err
+	}
+
+	*nameOrId = meshId
+	return nil
+}
+
+// Status: the state of the node in the mesh or in every mesh if no mesh
+// is given and the events observed in them
+func (n *IpcHandler) Status(meshId string, reply *ipc.StatusReply) error {
+	if err := n.resolveMesh(&meshId); err != nil {
+		return err
+	}
+
+	manager := n.Server.GetMeshManager()
+	self := manager.GetPublicKey().String()
+	meshes := manager.GetMeshes()
+
+	if meshId != "" {
+		theMesh, ok := meshes[meshId]
+
+		if !ok {
+			return fmt.Errorf("mesh %s does not exist", meshId)
+		}
+
+		meshes = map[string]mesh.MeshProvider{meshId: theMesh}
+	}
+
+	reply.PublicKey = self
+	reply.Meshes = make([]ipc.MeshStatus, 0)
+
+	for id, theMesh := range meshes {
+		snapshot, err := theMesh.GetMesh()
+
+		if err != nil {
+			return err
+		}
+
+		admission, err := theMesh.GetAdmission()
+
+		if err != nil {
+			return err
+		}
+
+		nodes := snapshot.GetNodes()
+		aliases, conflicts := mesh.ResolveAliases(admission, nodes)
+		status := ipc.MeshStatus{
+			MeshId:         id,
+			Nodes:          len(nodes),
+			ResolvedAlias:  aliases[self],
+			AliasConflicts: conflicts,
+		}
+
+		if node := manager.GetNode(id, self); node != nil {
+			status.Alias = node.GetAlias()
+		}
+
+		reply.Meshes = append(reply.Meshes, status)
+	}
+
+	slices.SortFunc(reply.Meshes, func(m1, m2 ipc.MeshStatus) int {
+		return strings.Compare(m1.MeshId, m2.MeshId)
+	})
+
+	reply.Events = n.Server.GetEvents().List(meshId)
+	return nil
 }
@@ -3,23 +3,120 @@ package robin
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
 
+	"github.com/tim-beatham/smegmesh/pkg/auth"
+	"github.com/tim-beatham/smegmesh/pkg/ca"
+	"github.com/tim-beatham/smegmesh/pkg/conn"
 	"github.com/tim-beatham/smegmesh/pkg/ctrlserver"
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
 	"github.com/tim-beatham/smegmesh/pkg/rpc"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // WgRpc: represents a WireGuard rpc call
 type WgRpc struct {
 	rpc.UnimplementedMeshCtrlServerServer
 	Server *ctrlserver.MeshCtrlServer
+	// Authoriser: if set authorises callers per mesh
+	Authoriser auth.Authoriser
+	// Authority: if set the node acts as a CA and issues certificates
+	Authority *ca.Authority
+	// redeemLock: serialises redemptions so an invite is not redeemed
+	// more times than it permits
+	redeemLock sync.Mutex
+}
+
+// authenticate: if per mesh authorisation is on the caller must be a
+// member of the mesh or hold a valid credential. Otherwise if
+// certificates are bound the key named by the peer's certificate must
+// not have been evicted. If member is true the key must belong to a node
+// in the mesh
+func (m *WgRpc) authenticate(ctx context.Context, mesh mesh.MeshProvider, credential string, member bool) error {
+	if m.Authoriser != nil {
+		return m.Authoriser.Authorise(ctx, mesh, credential)
+	}
+
+	if mesh == nil {
+		return errors.New("mesh does not exist")
+	}
+
+	// Peers can only be identified if certificates are bound
+	if !m.Server.Conf.BindCertificates {
+		return nil
+	}
+
+	if err := auth.RefuseEvicted(ctx, mesh); err != nil {
+		return err
+	}
+
+	key, err := conn.PeerWgKey(ctx)
+
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if member && !mesh.NodeExists(key.String()) {
+		return status.Errorf(codes.PermissionDenied, "%s is not a member of the mesh", key.String())
+	}
+
+	return nil
+}
+
+// redeem: verify the caller may redeem the invite with the role it
+// joins with and record the redemption in our node so the usage limit
+// and role hold across all peers
+func (m *WgRpc) redeem(ctx context.Context, theMesh mesh.MeshProvider, encoded string, role string) error {
+	if theMesh == nil {
+		return status.Error(codes.PermissionDenied, "not authorised to access the mesh")
+	}
+
+	if err := auth.RefuseEvicted(ctx, theMesh); err != nil {
+		return err
+	}
+
+	key, err := conn.PeerWgKey(ctx)
+
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	invite, err := auth.DecodeInvite(encoded)
+
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	m.redeemLock.Lock()
+	defer m.redeemLock.Unlock()
+
+	now := time.Now()
+
+	if err := invite.Verify(theMesh, key.String(), role, now); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return invite.Redeem(theMesh, m.Server.MeshManager.GetPublicKey().String(), key.String(), now)
 }
 
 // GetMesh: serialise the mesh network into bytes
 func (m *WgRpc) GetMesh(ctx context.Context, request *rpc.GetMeshRequest) (*rpc.GetMeshReply, error) {
 	mesh := m.Server.MeshManager.GetMesh(request.MeshId)
 
-	if mesh == nil {
-		return nil, errors.New("mesh does not exist")
+	var err error
+
+	if request.Invite != "" {
+		err = m.redeem(ctx, mesh, request.Invite, request.Role)
+	} else {
+		err = m.authenticate(ctx, mesh, request.Credential, false)
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
 	meshBytes := mesh.Save()
@@ -30,3 +127,227 @@ func (m *WgRpc) GetMesh(ctx context.Context, request *rpc.GetMeshRequest) (*rpc.
 
 	return &reply, nil
 }
+
+// GetState: get the internal state of the replicated store for
+// diagnosing divergence between nodes
+func (m *WgRpc) GetState(ctx context.Context, request *rpc.GetStateRequest) (*rpc.GetStateReply, error) {
+	mesh := m.Server.MeshManager.GetMesh(request.MeshId)
+
+	if err := m.authenticate(ctx, mesh, "", true); err != nil {
+		return nil, err
+	}
+
+	state, err := mesh.GetState()
+
+	if err != nil {
+		return nil, err
+	}
+
+	reply := rpc.GetStateReply{
+		Vectors:        state.Vectors,
+		AddContents:    make(map[uint64]uint64),
+		RemoveContents: make(map[uint64]uint64),
+		HighestStale:   state.HighestStale,
+		Entries:        make([]*rpc.StateEntry, 0, len(state.Entries)),
+	}
+
+	for key, entry := range state.Entries {
+		if entry.InAdd {
+			reply.AddContents[key] = entry.AddClock
+		}
+
+		if entry.InRemove {
+			reply.RemoveContents[key] = entry.RemoveClock
+		}
+
+		reply.Entries = append(reply.Entries, &rpc.StateEntry{
+			Key:         entry.Key,
+			InAdd:       entry.InAdd,
+			AddClock:    entry.AddClock,
+			InRemove:    entry.InRemove,
+			RemoveClock: entry.RemoveClock,
+			Gravestone:  entry.Gravestone,
+			PublicKey:   entry.PublicKey,
+			Alias:       entry.Alias,
+			WgHost:      entry.WgHost,
+			Timestamp:   entry.Timestamp,
+		})
+	}
+
+	return &reply, nil
+}
+
+// RequestJoin: record the caller's request to be admitted to the mesh
+// in our node so that it is replicated to the mesh's admins. The caller
+// proves it holds the key named by its certificate by signing the
+// request
+func (m *WgRpc) RequestJoin(ctx context.Context, request *rpc.RequestJoinRequest) (*rpc.RequestJoinReply, error) {
+	theMesh := m.Server.MeshManager.GetMesh(request.MeshId)
+
+	if theMesh == nil {
+		return nil, errors.New("mesh does not exist")
+	}
+
+	key, err := conn.PeerWgKey(ctx)
+
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !admission.RequiresApproval() {
+		return nil, status.Error(codes.FailedPrecondition, "mesh does not require approval")
+	}
+
+	if admission.IsRevoked(key.String()) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s has been evicted from the mesh", key.String())
+	}
+
+	if admission.IsApproved(key.String()) {
+		return &rpc.RequestJoinReply{Approved: true}, nil
+	}
+
+	joinRequest := mesh.JoinRequest{
+		PublicKey:   key.String(),
+		Role:        request.Role,
+		Alias:       request.Alias,
+		Description: request.Description,
+		Timestamp:   request.Timestamp,
+	}
+
+	if err := joinRequest.Verify(request.MeshId, request.Signature); err != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "request is not signed by %s: %s", key.String(), err.Error())
+	}
+
+	signed := time.Unix(request.Timestamp, 0)
+
+	if time.Since(signed).Abs() > mesh.JOIN_REQUEST_SKEW {
+		return nil, status.Error(codes.InvalidArgument, "request was not signed recently")
+	}
+
+	err = theMesh.AddJoinRequest(m.Server.MeshManager.GetPublicKey().String(), joinRequest)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.RequestJoinReply{Approved: false}, nil
+}
+
+// SignCertificate: issue a certificate bound to the caller's WireGuard
+// key. The caller proves it holds the key by signing the request. It
+// must be a member of the mesh or hold a valid credential or invite
+func (m *WgRpc) SignCertificate(ctx context.Context, request *rpc.SignCertificateRequest) (*rpc.SignCertificateReply, error) {
+	if m.Authority == nil {
+		return nil, status.Error(codes.FailedPrecondition, "node is not a certificate authority")
+	}
+
+	theMesh := m.Server.MeshManager.GetMesh(request.MeshId)
+
+	if theMesh == nil {
+		return nil, status.Error(codes.PermissionDenied, "not authorised to access the mesh")
+	}
+
+	key, err := wgtypes.ParseKey(request.PublicKey)
+
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid public key: %s", err.Error())
+	}
+
+	csr, err := ca.VerifyRequest(request.Csr, key, request.Signature)
+
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if err := auth.NewMeshAuthoriser().AuthoriseKey(theMesh, key.String(), request.Credential); err != nil {
+		return nil, err
+	}
+
+	certificate, err := m.Authority.Sign(csr, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &rpc.SignCertificateReply{
+		Certificate:   certificate,
+		CaCertificate: m.Authority.GetCertificate(),
+	}, nil
+}
+
+// ExchangePresharedKey: use the pre-shared key the caller chose for the
+// tunnel between us. The caller must be a member of the mesh and sign
+// the key with its WireGuard key
+func (m *WgRpc) ExchangePresharedKey(ctx context.Context, request *rpc.ExchangePresharedKeyRequest) (*rpc.ExchangePresharedKeyReply, error) {
+	theMesh := m.Server.MeshManager.GetMesh(request.MeshId)
+
+	if err := m.authenticate(ctx, theMesh, "", false); err != nil {
+		return nil, err
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !admission.RequiresPresharedKeys() {
+		return nil, status.Error(codes.FailedPrecondition, "mesh does not require pre-shared keys")
+	}
+
+	exchange := mesh.PresharedKeyExchange{
+		MeshId:    request.MeshId,
+		Initiator: request.Initiator,
+		Responder: request.Responder,
+		Key:       request.Key,
+		Timestamp: request.Timestamp,
+		Signature: request.Signature,
+	}
+
+	if err := exchange.Verify(); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	self := m.Server.MeshManager.GetPublicKey().String()
+
+	if !admission.SameNode(exchange.Responder, self) {
+		return nil, status.Errorf(codes.InvalidArgument, "key is for %s not %s", exchange.Responder, self)
+	}
+
+	if !theMesh.NodeExists(admission.CurrentKey(exchange.Initiator)) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s is not a member of the mesh", exchange.Initiator)
+	}
+
+	if m.Server.Conf.BindCertificates {
+		key, err := conn.PeerWgKey(ctx)
+
+		if err != nil || !admission.SameNode(key.String(), exchange.Initiator) {
+			return nil, status.Errorf(codes.PermissionDenied, "certificate does not name %s", exchange.Initiator)
+		}
+	}
+
+	key, err := wgtypes.NewKey(exchange.Key)
+
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid pre-shared key: %s", err.Error())
+	}
+
+	presharedKeys := m.Server.MeshManager.GetPresharedKeys()
+	installed := presharedKeys.Set(exchange.MeshId, admission.Identity(exchange.Initiator), key, exchange.Timestamp)
+
+	// The key is used once the configuration is next applied if it
+	// cannot be applied now
+	if installed {
+		if err := m.Server.MeshManager.ApplyConfig(); err != nil {
+			logging.Log.WriteErrorf("could not apply pre-shared key: %s", err.Error())
+		}
+	}
+
+	return &rpc.ExchangePresharedKeyReply{Installed: installed}, nil
+}
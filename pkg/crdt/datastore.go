@@ -2,11 +2,11 @@ package crdt
 
 import (
 	"bytes"
-	"encoding/gob"
 	"fmt"
 	"net"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/tim-beatham/smegmesh/pkg/conf"
@@ -53,6 +53,30 @@ type MeshNode struct {
 	Services     map[string]string
 	Type         string
 	Tombstone    bool
+	// Version: software version the node is running
+	Version string
+	// Redemptions: invites redeemed through the node
+	Redemptions []mesh.Redemption
+	// Genesis: settings of the mesh signed by its creator
+	Genesis *mesh.Genesis
+	// JoinRequests: join requests received by the node
+	JoinRequests []mesh.JoinRequest
+	// JoinDecisions: join decisions made or carried by the node
+	JoinDecisions []mesh.JoinDecision
+	// Revocations: evictions made or carried by the node
+	Revocations []mesh.Revocation
+	// Grants: admin grants made or carried by the node
+	Grants []mesh.Grant
+	// Successions: keys the node has rotated through
+	Successions []mesh.Succession
+	// AclPolicy: the latest access control policy seen by the node
+	AclPolicy *mesh.AclPolicy
+	// Labels: key value pairs the node places on itself
+	Labels map[string]string
+	// ServiceRecords: structured services offered by the node
+	ServiceRecords map[string]mesh.Service
+	// MeshInfo: metadata describing the mesh carried by the node
+	MeshInfo *mesh.MeshInfo
 }
 
 // Mark: marks the node is unreachable. This is not broadcast on
@@ -126,10 +150,70 @@ func (n *MeshNode) GetServices() map[string]string {
 	return n.Services
 }
 
+// GetLabels: returns the labels the node has placed on itself
+func (n *MeshNode) GetLabels() map[string]string {
+	return n.Labels
+}
+
+// GetServiceRecords: returns the structured services offered by the node
+func (n *MeshNode) GetServiceRecords() map[string]mesh.Service {
+	return n.ServiceRecords
+}
+
 func (n *MeshNode) GetType() conf.NodeType {
 	return conf.NodeType(n.Type)
 }
 
+// GetVersion: returns the software version the node is running
+func (n *MeshNode) GetVersion() string {
+	return n.Version
+}
+
+// GetRedemptions: returns the invites redeemed through the node
+func (n *MeshNode) GetRedemptions() []mesh.Redemption {
+	return n.Redemptions
+}
+
+// GetMeshInfo: returns the metadata of the mesh if the node carries it
+func (n *MeshNode) GetMeshInfo() *mesh.MeshInfo {
+	return n.MeshInfo
+}
+
+// GetGenesis: returns the genesis of the mesh if the node carries it
+func (n *MeshNode) GetGenesis() *mesh.Genesis {
+	return n.Genesis
+}
+
+// GetJoinRequests: returns the join requests received by the node
+func (n *MeshNode) GetJoinRequests() []mesh.JoinRequest {
+	return n.JoinRequests
+}
+
+// GetJoinDecisions: returns the join decisions carried by the node
+func (n *MeshNode) GetJoinDecisions() []mesh.JoinDecision {
+	return n.JoinDecisions
+}
+
+// GetRevocations: returns the revocations carried by the node
+func (n *MeshNode) GetRevocations() []mesh.Revocation {
+	return n.Revocations
+}
+
+// GetGrants: returns the admin grants carried by the node
+func (n *MeshNode) GetGrants() []mesh.Grant {
+	return n.Grants
+}
+
+// GetSuccessions: returns the keys the node has rotated through
+func (n *MeshNode) GetSuccessions() []mesh.Succession {
+	return n.Successions
+}
+
+// GetAclPolicy: returns the access control policy carried by the node
+func (n *MeshNode) GetAclPolicy() *mesh.AclPolicy {
+	return n.AclPolicy
+}
+
 type MeshSnapshot struct {
 	Nodes map[string]MeshNode
 }
@@ -140,16 +224,28 @@ func (m *MeshSnapshot) GetNodes() map[string]mesh.MeshNode {
 
 	for key, value := range m.Nodes {
 		newMap[key] = &MeshNode{
-			HostEndpoint: value.HostEndpoint,
-			PublicKey:    value.PublicKey,
-			WgHost:       value.WgHost,
-			WgEndpoint:   value.WgEndpoint,
-			Timestamp:    value.Timestamp,
-			Routes:       value.Routes,
-			Alias:        value.Alias,
-			Description:  value.Description,
-			Services:     value.Services,
-			Type:         value.Type,
+			HostEndpoint:   value.HostEndpoint,
+			PublicKey:      value.PublicKey,
+			WgHost:         value.WgHost,
+			WgEndpoint:     value.WgEndpoint,
+			Timestamp:      value.Timestamp,
+			Routes:         value.Routes,
+			Alias:          value.Alias,
+			Description:    value.Description,
+			Services:       value.Services,
+			Type:           value.Type,
+			Version:        value.Version,
+			Redemptions:    value.Redemptions,
+			Genesis:        value.Genesis,
+			JoinRequests:   value.JoinRequests,
+			JoinDecisions:  value.JoinDecisions,
+			Revocations:    value.Revocations,
+			Grants:         value.Grants,
+			Successions:    value.Successions,
+			AclPolicy:      value.AclPolicy,
+			Labels:         value.Labels,
+			ServiceRecords: value.ServiceRecords,
+			MeshInfo:       value.MeshInfo,
 		}
 	}
 
@@ -160,10 +256,152 @@ type TwoPhaseStoreMeshManager struct {
 	MeshId     string
 	IfName     string
 	Client     *wgctrl.Client
-	LastClock  uint64
+	LastClock  []byte
 	Conf       *conf.WgConfiguration
 	DaemonConf *conf.DaemonConfiguration
 	store      *TwoPhaseMap[string, MeshNode]
+	// host: holds the WireGuard private key used to sign our node
+	host *mesh.HostParameters
+	// admission: nodes admitted to the mesh as of admissionDigest
+	admission       *mesh.Admission
+	admissionDigest []byte
+	admissionLock   sync.Mutex
+}
+
+// put: puts the node into the store. The store signs the entry if the
+// node is ours. Other nodes reject unsigned entries when merging
+func (m *TwoPhaseStoreMeshManager) put(node MeshNode) {
+	m.store.Put(node.PublicKey, node)
+}
+
+// sign: sign the entry with our WireGuard key. Only entries for our
+// own node and removals are signed. Returns the bytes signed so that
+// they are carried with the entry
+func (m *TwoPhaseStoreMeshManager) sign(entry SignedEntry[MeshNode]) (string, []byte, []byte, error) {
+	if m.host == nil {
+		return "", nil, nil, nil
+	}
+
+	// Read the key once as the node may rotate it while signing
+	privateKey := m.host.GetPrivateKey()
+	publicKey := privateKey.PublicKey().String()
+
+	if !entry.Removed && entry.Contents.PublicKey != publicKey {
+		return "", nil, nil, nil
+	}
+
+	payload, err := signedBytes(entry)
+
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	signature, err := lib.XEdDSASign(privateKey, payload)
+	return publicKey, payload, signature, err
+}
+
+// verifyEntry: verify the payload is signed by the signer's WireGuard
+// key and return the entry it encodes. A node must be stored under its
+// own public key and signed by it
+func verifyEntry(signer string, payload, signature []byte) (SignedEntry[MeshNode], error) {
+	publicKey, err := wgtypes.ParseKey(signer)
+
+	if err != nil {
+		return SignedEntry[MeshNode]{}, fmt.Errorf("datastore: invalid public key %s", signer)
+	}
+
+	if err := lib.XEdDSAVerify(publicKey, payload, signature); err != nil {
+		return SignedEntry[MeshNode]{}, fmt.Errorf("datastore: entry signed by %s: %w", signer, err)
+	}
+
+	entry, err := entryFromSignedBytes(payload)
+
+	if err != nil {
+		return entry, err
+	}
+
+	if !entry.Removed {
+		if hashKey(entry.Contents.PublicKey) != entry.Key {
+			return entry, fmt.Errorf("datastore: node %s stored under the wrong key", entry.Contents.PublicKey)
+		}
+
+		if entry.Contents.PublicKey != signer {
+			return entry, fmt.Errorf("datastore: node %s signed by %s", entry.Contents.PublicKey, signer)
+		}
+	}
+
+	return entry, nil
+}
+
+// verifier: prepare a verifier for the entries of a snapshot. The
+// admission is computed once from our nodes and the incoming nodes
+// with a valid signature rather than after every entry merged
+func (m *TwoPhaseStoreMeshManager) verifier(snapshot TwoPhaseMapSnapshot[string, MeshNode]) Verifier[MeshNode] {
+	nodes := make(map[uint64]MeshNode)
+
+	for _, node := range m.store.AsList() {
+		nodes[hashKey(node.PublicKey)] = node
+	}
+
+	for key, value := range snapshot.Add {
+		if value.Vector <= m.store.addMap.get(key).Vector || value.Vector < m.store.removeMap.get(key).Vector {
+			continue
+		}
+
+		entry, err := verifyEntry(value.Signer, value.Payload, value.Signature)
+
+		if err == nil && !entry.Removed && entry.Key == key && entry.Vector == value.Vector {
+			nodes[key] = entry.Contents
+		}
+	}
+
+	merged := make([]mesh.MeshNode, 0, len(nodes))
+
+	for _, node := range nodes {
+		node := node
+		merged = append(merged, &node)
+	}
+
+	admission := mesh.NewAdmission(m.MeshId, merged)
+
+	return func(signer string, payload, signature []byte) (SignedEntry[MeshNode], error) {
+		entry, err := verifyEntry(signer, payload, signature)
+
+		if err != nil {
+			return entry, err
+		}
+
+		return entry, m.verify(admission, entry, signer)
+	}
+}
+
+// verify: verify the signer may make an entry received from another
+// node. Refuses nodes that have been evicted so that they cannot
+// re-announce themselves. A node may only be removed by itself, by an
+// admin or by any node once the node has been evicted or has rotated
+// its key
+func (m *TwoPhaseStoreMeshManager) verify(admission *mesh.Admission, entry SignedEntry[MeshNode], signer string) error {
+	if !entry.Removed {
+		if admission.IsRevoked(entry.Contents.PublicKey) {
+			return fmt.Errorf("datastore: node %s has been evicted", entry.Contents.PublicKey)
+		}
+
+		return nil
+	}
+
+	if hashKey(signer) == entry.Key || admission.IsAdmin(signer) {
+		return nil
+	}
+
+	// The node removed may only be identified if we hold it
+	if node := m.store.addMap.get(entry.Key).Contents; hashKey(node.PublicKey) == entry.Key {
+		if admission.SameNode(signer, node.PublicKey) || admission.IsRevoked(node.PublicKey) ||
+			admission.IsSuperseded(node.PublicKey) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("datastore: %s may not remove entry %d", signer, entry.Key)
 }
 
 // AddNode() adds a node to the mesh
@@ -176,18 +414,30 @@ func (m *TwoPhaseStoreMeshManager) AddNode(node mesh.MeshNode) {
 
 	crdt.Routes = make(map[string]Route)
 	crdt.Services = make(map[string]string)
+	crdt.Labels = make(map[string]string)
+	crdt.ServiceRecords = make(map[string]mesh.Service)
 	crdt.Timestamp = time.Now().Unix()
 
-	m.store.Put(crdt.PublicKey, *crdt)
+	m.put(*crdt)
 }
 
 // GetMesh() returns a snapshot of the mesh provided by the mesh provider.
 func (m *TwoPhaseStoreMeshManager) GetMesh() (mesh.MeshSnapshot, error) {
 	nodes := m.store.AsList()
 
+	admission, err := m.GetAdmission()
+
+	if err != nil {
+		return nil, err
+	}
+
 	snapshot := make(map[string]MeshNode)
 
 	for _, node := range nodes {
+		if !m.admitted(admission, &node) {
+			continue
+		}
+
 		snapshot[node.PublicKey] = node
 	}
 
@@ -205,27 +455,60 @@ func (m *TwoPhaseStoreMeshManager) GetMeshId() string {
 func (m *TwoPhaseStoreMeshManager) Save() []byte {
 	snapshot := m.store.Snapshot()
 
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err := enc.Encode(*snapshot)
+	bs, err := encodeSnapshot(snapshot)
 
 	if err != nil {
 		logging.Log.WriteInfof(err.Error())
 	}
 
-	return buf.Bytes()
+	return bs
 }
 
-// Load() loads a mesh network
+// Load() loads a mesh network. Reads both protobuf and
+// legacy gob encoded snapshots
 func (m *TwoPhaseStoreMeshManager) Load(bs []byte) error {
-	buf := bytes.NewBuffer(bs)
-	dec := gob.NewDecoder(buf)
+	snapshot, err := decodeSnapshot(bs)
 
-	var snapshot TwoPhaseMapSnapshot[string, MeshNode]
-	err := dec.Decode(&snapshot)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.HasPrefix(bs, snapshotMagic) {
+		m.migrate(snapshot)
+		return nil
+	}
 
-	m.store.Merge(snapshot)
-	return err
+	m.store.Merge(*snapshot)
+	return nil
+}
+
+// migrate: migrate a legacy snapshot. Legacy entries are unsigned so
+// our own node is signed again and the other nodes are synced again
+// once their owners upgrade and sign their nodes
+func (m *TwoPhaseStoreMeshManager) migrate(snapshot *TwoPhaseMapSnapshot[string, MeshNode]) {
+	if m.host == nil {
+		return
+	}
+
+	publicKey := m.host.GetPublicKey()
+	key := m.store.Clock.hashFunc(publicKey)
+	bucket, ok := snapshot.Add[key]
+
+	if !ok || bucket.Contents.PublicKey != publicKey {
+		logging.Log.WriteWarnf("legacy snapshot of mesh %s does not contain our node", m.MeshId)
+		return
+	}
+
+	if removed, ok := snapshot.Remove[key]; ok && removed.Vector > bucket.Vector {
+		return
+	}
+
+	// A node that is already signed is newer than the legacy one
+	if !m.store.Contains(publicKey) {
+		m.put(bucket.Contents)
+	}
+
+	logging.Log.WriteInfof("signed our node from the legacy snapshot of mesh %s", m.MeshId)
 }
 
 // GetDevice() get the device corresponding with the mesh
@@ -241,13 +524,13 @@ func (m *TwoPhaseStoreMeshManager) GetDevice() (*wgtypes.Device, error) {
 
 // HasChanges returns true if we have changes since last time we synced
 func (m *TwoPhaseStoreMeshManager) HasChanges() bool {
-	clockValue := m.store.GetHash()
-	return clockValue != m.LastClock
+	clockValue := m.store.GetDigest()
+	return !bytes.Equal(clockValue, m.LastClock)
 }
 
 // Record that we have changes and save the corresponding changes
 func (m *TwoPhaseStoreMeshManager) SaveChanges() {
-	clockValue := m.store.GetHash()
+	clockValue := m.store.GetDigest()
 	m.LastClock = clockValue
 }
 
@@ -285,7 +568,7 @@ func (m *TwoPhaseStoreMeshManager) UpdateTimeStamp(nodeId string) error {
 	// Refresh causing node to update it's time stamp
 	node := m.store.Get(nodeId)
 	node.Timestamp = time.Now().Unix()
-	m.store.Put(nodeId, node)
+	m.put(node)
 	return nil
 }
 
@@ -319,7 +602,7 @@ func (m *TwoPhaseStoreMeshManager) AddRoutes(nodeId string, routes ...mesh.Route
 	// Only add nodes on changes. Otherwise the node will advertise new
 	// information whenever they get new routes
 	if changes {
-		m.store.Put(nodeId, node)
+		m.put(node)
 	}
 
 	return nil
@@ -346,7 +629,7 @@ func (m *TwoPhaseStoreMeshManager) RemoveRoutes(nodeId string, routes ...mesh.Ro
 	}
 
 	if changes {
-		m.store.Put(nodeId, node)
+		m.put(node)
 	}
 
 	return nil
@@ -359,7 +642,7 @@ func (m *TwoPhaseStoreMeshManager) GetSyncer() mesh.MeshSyncer {
 
 // GetNode: get a particular not within the mesh network
 func (m *TwoPhaseStoreMeshManager) GetNode(nodeId string) (mesh.MeshNode, error) {
-	if !m.store.Contains(nodeId) {
+	if !m.NodeExists(nodeId) {
 		return nil, fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
 	}
 
@@ -367,9 +650,21 @@ func (m *TwoPhaseStoreMeshManager) GetNode(nodeId string) (mesh.MeshNode, error)
 	return &node, nil
 }
 
-// NodeExists: returns true if a particular node exists false otherwise
+// NodeExists: returns true if a particular node exists and has been
+// admitted to the mesh false otherwise
 func (m *TwoPhaseStoreMeshManager) NodeExists(nodeId string) bool {
-	return m.store.Contains(nodeId)
+	if !m.store.Contains(nodeId) {
+		return false
+	}
+
+	admission, err := m.GetAdmission()
+
+	if err != nil {
+		return false
+	}
+
+	node := m.store.Get(nodeId)
+	return m.admitted(admission, &node)
 }
 
 // SetDescription: sets the description of this automerge data type
@@ -381,7 +676,7 @@ func (m *TwoPhaseStoreMeshManager) SetDescription(nodeId string, description str
 	node := m.store.Get(nodeId)
 	node.Description = description
 
-	m.store.Put(nodeId, node)
+	m.put(node)
 	return nil
 }
 
@@ -392,9 +687,10 @@ func (m *TwoPhaseStoreMeshManager) SetAlias(nodeId string, alias string) error {
 	}
 
 	node := m.store.Get(nodeId)
+
 	node.Alias = alias
 
-	m.store.Put(nodeId, node)
+	m.put(node)
 	return nil
 }
 
@@ -406,7 +702,7 @@ func (m *TwoPhaseStoreMeshManager) AddService(nodeId string, key string, value s
 
 	node := m.store.Get(nodeId)
 	node.Services[key] = value
-	m.store.Put(nodeId, node)
+	m.put(node)
 	return nil
 }
 
@@ -423,25 +719,313 @@ func (m *TwoPhaseStoreMeshManager) RemoveService(nodeId string, key string) erro
 	}
 
 	delete(node.Services, key)
-	m.store.Put(nodeId, node)
+	m.put(node)
+	return nil
+}
+
+// PutServiceRecord: places the service record in the given node
+// replacing any record with the same name
+func (m *TwoPhaseStoreMeshManager) PutServiceRecord(nodeId string, service mesh.Service) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+
+	if node.ServiceRecords == nil {
+		node.ServiceRecords = make(map[string]mesh.Service)
+	}
+
+	node.ServiceRecords[service.Name] = service
+	m.put(node)
+	return nil
+}
+
+// RemoveServiceRecord: removes the service record from a node, throws
+// an error if the record does not exist
+func (m *TwoPhaseStoreMeshManager) RemoveServiceRecord(nodeId string, name string) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+
+	if _, ok := node.ServiceRecords[name]; !ok {
+		return fmt.Errorf("datastore: node does not contain service record %s", name)
+	}
+
+	delete(node.ServiceRecords, name)
+	m.put(node)
+	return nil
+}
+
+// SetLabel: places the label on the given node replacing any value
+// the label had
+func (m *TwoPhaseStoreMeshManager) SetLabel(nodeId string, key string, value string) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+
+	if node.Labels == nil {
+		node.Labels = make(map[string]string)
+	}
+
+	node.Labels[key] = value
+	m.put(node)
+	return nil
+}
+
+// RemoveLabel: removes the label from a node, throws an error if the
+// node does not have the label
+func (m *TwoPhaseStoreMeshManager) RemoveLabel(nodeId string, key string) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+
+	if _, ok := node.Labels[key]; !ok {
+		return fmt.Errorf("datastore: node does not have label %s", key)
+	}
+
+	delete(node.Labels, key)
+	m.put(node)
+	return nil
+}
+
+// AddRedemption: records that an invite was redeemed through the node
+func (m *TwoPhaseStoreMeshManager) AddRedemption(nodeId string, redemption mesh.Redemption) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	node.Redemptions = append(slices.Clone(node.Redemptions), redemption)
+	m.put(node)
+	return nil
+}
+
+// SetGenesis: places the genesis of the mesh in the node
+func (m *TwoPhaseStoreMeshManager) SetGenesis(nodeId string, genesis mesh.Genesis) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	node.Genesis = &genesis
+	m.put(node)
+	return nil
+}
+
+// SetMeshInfo: places the metadata of the mesh in the node
+func (m *TwoPhaseStoreMeshManager) SetMeshInfo(nodeId string, info mesh.MeshInfo) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	node.MeshInfo = &info
+	m.put(node)
+	return nil
+}
+
+// AddJoinRequest: records a join request received by the node
+// replacing any earlier request by the same node
+func (m *TwoPhaseStoreMeshManager) AddJoinRequest(nodeId string, request mesh.JoinRequest) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	now := time.Now()
+
+	requests := lib.Filter(node.JoinRequests, func(r mesh.JoinRequest) bool {
+		return r.PublicKey != request.PublicKey && !r.Expired(now)
+	})
+
+	if len(requests) >= mesh.MAX_JOIN_REQUESTS {
+		return fmt.Errorf("datastore: %s carries too many pending join requests", nodeId)
+	}
+
+	node.JoinRequests = append(requests, request)
+	m.put(node)
+	return nil
+}
+
+// AddJoinDecision: records a join decision in the node replacing any
+// earlier decision about the same node
+func (m *TwoPhaseStoreMeshManager) AddJoinDecision(nodeId string, decision mesh.JoinDecision) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	decisions := lib.Filter(node.JoinDecisions, func(d mesh.JoinDecision) bool {
+		return d.PublicKey != decision.PublicKey
+	})
+
+	node.JoinDecisions = append(decisions, decision)
+	m.put(node)
+	return nil
+}
+
+// AddRevocation: records the eviction of a node in the node replacing
+// any earlier revocation of the same node
+func (m *TwoPhaseStoreMeshManager) AddRevocation(nodeId string, revocation mesh.Revocation) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	revocations := lib.Filter(node.Revocations, func(r mesh.Revocation) bool {
+		return r.PublicKey != revocation.PublicKey
+	})
+
+	node.Revocations = append(revocations, revocation)
+	m.put(node)
+	return nil
+}
+
+// AddGrant: records a grant of admin rights in the node replacing any
+// earlier grant about the same node
+func (m *TwoPhaseStoreMeshManager) AddGrant(nodeId string, grant mesh.Grant) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	grants := lib.Filter(node.Grants, func(g mesh.Grant) bool {
+		return g.PublicKey != grant.PublicKey
+	})
+
+	node.Grants = append(grants, grant)
+	m.put(node)
+	return nil
+}
+
+// AddSuccession: announce the successor to the node's key. Replaces
+// any succession the node is yet to complete
+func (m *TwoPhaseStoreMeshManager) AddSuccession(nodeId string, succession mesh.Succession) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	successions := lib.Filter(node.Successions, func(s mesh.Succession) bool {
+		return s.PublicKey != nodeId
+	})
+
+	node.Successions = append(successions, succession)
+	m.put(node)
+	return nil
+}
+
+// SetAclPolicy: places the access control policy in the node
+func (m *TwoPhaseStoreMeshManager) SetAclPolicy(nodeId string, policy mesh.AclPolicy) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	node.AclPolicy = &policy
+	m.put(node)
 	return nil
 }
 
+// RotateNode: move the node to its successor key. The node keeps its
+// address, routes and everything it carries. The node under the
+// previous key is removed
+func (m *TwoPhaseStoreMeshManager) RotateNode(nodeId string, successor string) error {
+	if !m.store.Contains(nodeId) {
+		return fmt.Errorf("datastore: %s does not exist in the mesh", nodeId)
+	}
+
+	node := m.store.Get(nodeId)
+	node.PublicKey = successor
+	node.Timestamp = time.Now().Unix()
+
+	m.store.SetProcessId(successor)
+	m.put(node)
+	m.store.Remove(nodeId)
+	return nil
+}
+
+// GetAdmission: determine which nodes have been admitted to the mesh.
+// Cached until the store changes
+func (m *TwoPhaseStoreMeshManager) GetAdmission() (*mesh.Admission, error) {
+	m.admissionLock.Lock()
+	defer m.admissionLock.Unlock()
+
+	digest := m.store.GetDigest()
+
+	if m.admission != nil && bytes.Equal(digest, m.admissionDigest) {
+		return m.admission, nil
+	}
+
+	nodes := make([]mesh.MeshNode, 0)
+
+	for _, node := range m.store.AsList() {
+		node := node
+		nodes = append(nodes, &node)
+	}
+
+	m.admission = mesh.NewAdmission(m.MeshId, nodes)
+	m.admissionDigest = digest
+	return m.admission, nil
+}
+
+// admitted: returns true if the node should be visible to the rest of
+// the system. Our own node is always visible. Nodes under a key that
+// has since been rotated are hidden
+func (m *TwoPhaseStoreMeshManager) admitted(admission *mesh.Admission, node *MeshNode) bool {
+	if m.host != nil && node.PublicKey == m.host.GetPublicKey() {
+		return true
+	}
+
+	return !admission.IsSuperseded(node.PublicKey) && admission.Admits(node)
+}
+
 // Prune: prunes all nodes that have not updated their vector clock in a given amount
-// of time
+// of time and removes nodes that have been evicted or have rotated their key
 func (m *TwoPhaseStoreMeshManager) Prune() error {
 	m.store.Prune()
+
+	admission, err := m.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	for _, node := range m.store.AsList() {
+		if admission.IsRevoked(node.PublicKey) || admission.IsSuperseded(node.PublicKey) {
+			m.store.Remove(node.PublicKey)
+		}
+	}
+
 	return nil
 }
 
 // GetPeers: get a list of contactable peers
 func (m *TwoPhaseStoreMeshManager) GetPeers() []string {
+	admission, err := m.GetAdmission()
+
+	if err != nil {
+		return []string{}
+	}
+
 	nodes := m.store.AsList()
 	nodes = lib.Filter(nodes, func(mn MeshNode) bool {
 		if mn.Type != string(conf.PEER_ROLE) {
 			return false
 		}
 
+		// Do not sync with nodes that have not been admitted
+		if !m.admitted(admission, &mn) {
+			return false
+		}
+
 		// If the node is marked as unreachable don't consider it a peer.
 		// this help to optimize convergence time for unreachable nodes.
 		// However advertising it to other nodes could result in flapping.
@@ -527,3 +1111,41 @@ func (m *TwoPhaseStoreMeshManager) RemoveNode(nodeId string) error {
 func (m *TwoPhaseStoreMeshManager) GetConfiguration() *conf.WgConfiguration {
 	return m.Conf
 }
+
+// GetState: get the vector clock, add and remove clocks of the store
+// alongside the decoded contents of every entry
+func (m *TwoPhaseStoreMeshManager) GetState() (*mesh.MeshState, error) {
+	snapshot := m.store.Snapshot()
+	entries := make(map[uint64]mesh.MeshStateEntry)
+
+	for key, bucket := range snapshot.Add {
+		entries[key] = mesh.MeshStateEntry{
+			Key:        key,
+			InAdd:      true,
+			AddClock:   bucket.Vector,
+			Gravestone: bucket.Gravestone,
+			PublicKey:  bucket.Contents.PublicKey,
+			Alias:      bucket.Contents.Alias,
+			WgHost:     bucket.Contents.WgHost,
+			Timestamp:  bucket.Contents.Timestamp,
+		}
+	}
+
+	for key, bucket := range snapshot.Remove {
+		entry, ok := entries[key]
+
+		if !ok {
+			entry = mesh.MeshStateEntry{Key: key}
+		}
+
+		entry.InRemove = true
+		entry.RemoveClock = bucket.Vector
+		entries[key] = entry
+	}
+
+	return &mesh.MeshState{
+		Vectors:      m.store.Clock.GetClock(),
+		HighestStale: m.store.Clock.GetStaleCount(),
+		Entries:      entries,
+	}, nil
+}
@@ -1,15 +1,19 @@
 package crdt
 
 import (
+	"bytes"
 	"net"
+	"os"
 	"slices"
 	"testing"
 	"time"
 
+	"github.com/tim-beatham/smegmesh/pkg/acl"
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	"github.com/tim-beatham/smegmesh/pkg/lib"
 	"github.com/tim-beatham/smegmesh/pkg/mesh"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
 type TestParams struct {
@@ -18,6 +22,15 @@ type TestParams struct {
 }
 
 func setUpTests() *TestParams {
+	return setUpTestsWithNodeId("bob")
+}
+
+func setUpTestsWithNodeId(nodeId string) *TestParams {
+	key, _ := wgtypes.GeneratePrivateKey()
+	return setUpTestsWithKey(nodeId, key)
+}
+
+func setUpTestsWithKey(nodeId string, key wgtypes.Key) *TestParams {
 	advertiseRoutes := false
 	advertiseDefaultRoute := false
 	role := conf.PEER_ROLE
@@ -46,15 +59,14 @@ func setUpTests() *TestParams {
 		},
 	}
 
-	key, _ := wgtypes.GeneratePrivateKey()
-
 	mesh, _ := factory.CreateMesh(&mesh.MeshProviderFactoryParams{
 		DevName:    "bob",
 		MeshId:     "meshid123",
 		Client:     nil,
 		Conf:       &factory.Config.BaseConfiguration,
 		DaemonConf: factory.Config,
-		NodeID:     "bob",
+		NodeID:     nodeId,
+		Host:       mesh.NewHostParameters(key),
 	})
 
 	publicKey := key.PublicKey()
@@ -123,6 +135,283 @@ func TestSaveThenLoad(t *testing.T) {
 	}
 }
 
+// loadLegacySnapshot: load the snapshot saved by the gob encoding of an older
+// version. Contains the nodes ours and other whose keys are below
+func loadLegacySnapshot(t *testing.T, privateKey string) *TestParams {
+	key, _ := wgtypes.ParseKey(privateKey)
+	testParams := setUpTestsWithKey("bob", key)
+
+	bs, err := os.ReadFile("./test/legacy_snapshot.gob")
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if err := testParams.manager.Load(bs); err != nil {
+		t.Fatalf(`error loading legacy snapshot: %s`, err.Error())
+	}
+
+	return testParams
+}
+
+const (
+	legacyOurKey   = "SPYwTr68oIJtvlz0WUHu+31wNJVUR14OFxX6MMDHxUk="
+	legacyOtherKey = "KOn+BzATb6fhfUmsj8Bco+5CiARTHLMN61Xe1DNATWg="
+)
+
+func TestLoadLegacyGobSnapshot(t *testing.T) {
+	testParams := loadLegacySnapshot(t, legacyOurKey)
+	publicKey := testParams.publicKey.String()
+
+	node, err := testParams.manager.GetNode(publicKey)
+
+	if err != nil {
+		t.Fatalf(`our node should survive migrating the legacy snapshot: %s`, err.Error())
+	}
+
+	if node.GetAlias() != "ours" || node.GetHostEndpoint() != "legacy-endpoint:8080" {
+		t.Fatalf(`node should keep its contents, got alias %s endpoint %s`,
+			node.GetAlias(), node.GetHostEndpoint())
+	}
+
+	snapshot, _ := testParams.manager.GetMesh()
+
+	if len(snapshot.GetNodes()) != 1 {
+		t.Fatalf(`unsigned nodes of other owners should not be loaded`)
+	}
+
+	loaded := setUpTestsWithNodeId("alice")
+
+	if err := loaded.manager.Load(testParams.manager.Save()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if !loaded.manager.NodeExists(publicKey) {
+		t.Fatalf(`migrated node should be signed so that other nodes accept it`)
+	}
+}
+
+func TestLoadLegacyGobSnapshotOtherNodesSyncOnceMigrated(t *testing.T) {
+	ours := loadLegacySnapshot(t, legacyOurKey)
+	other := loadLegacySnapshot(t, legacyOtherKey)
+
+	if err := ours.manager.Load(other.manager.Save()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	node, err := ours.manager.GetNode(other.publicKey.String())
+
+	if err != nil {
+		t.Fatalf(`other node should be synced once its owner migrates: %s`, err.Error())
+	}
+
+	if node.GetAlias() != "other" {
+		t.Fatalf(`expected alias other got %s`, node.GetAlias())
+	}
+
+	if !ours.manager.NodeExists(ours.publicKey.String()) {
+		t.Fatalf(`our node should survive syncing`)
+	}
+}
+
+func TestLoadRejectsUnsignedNode(t *testing.T) {
+	testParams := setUpTests()
+	node := getRandomNode()
+	testParams.manager.AddNode(node)
+
+	loaded := setUpTestsWithNodeId("alice")
+
+	if err := loaded.manager.Load(testParams.manager.Save()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if loaded.manager.NodeExists(node.PublicKey) {
+		t.Fatalf(`unsigned node should have been rejected`)
+	}
+}
+
+func TestLoadRejectsModifiedNode(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+	testParams.manager.AddNode(node)
+
+	manager := testParams.manager.(*TwoPhaseStoreMeshManager)
+	snapshot := manager.store.Snapshot()
+
+	for key, bucket := range snapshot.Add {
+		bucket.Payload = bytes.ReplaceAll(bucket.Payload, []byte("public-endpoint"), []byte("attacker-endpoint"))
+		snapshot.Add[key] = bucket
+	}
+
+	loaded := setUpTestsWithNodeId("alice")
+	loaded.manager.(*TwoPhaseStoreMeshManager).store.Merge(*snapshot)
+
+	if loaded.manager.NodeExists(node.PublicKey) {
+		t.Fatalf(`modified node should have been rejected`)
+	}
+}
+
+func TestLoadKeepsSignedContents(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+	testParams.manager.AddNode(node)
+
+	manager := testParams.manager.(*TwoPhaseStoreMeshManager)
+	snapshot := manager.store.Snapshot()
+
+	for key, bucket := range snapshot.Add {
+		bucket.Contents.HostEndpoint = "attacker-endpoint:8080"
+		snapshot.Add[key] = bucket
+	}
+
+	loaded := setUpTestsWithNodeId("alice")
+	loaded.manager.(*TwoPhaseStoreMeshManager).store.Merge(*snapshot)
+	loadedNode, err := loaded.manager.GetNode(node.PublicKey)
+
+	if err != nil {
+		t.Fatalf(`signed node should have been accepted`)
+	}
+
+	if loadedNode.GetHostEndpoint() != node.HostEndpoint {
+		t.Fatalf(`expected the signed endpoint %s got %s`, node.HostEndpoint, loadedNode.GetHostEndpoint())
+	}
+}
+
+func TestLoadVerifiesFieldsAddedByNewerVersions(t *testing.T) {
+	key, _ := wgtypes.GeneratePrivateKey()
+	node := getRandomNode()
+	node.PublicKey = key.PublicKey().String()
+
+	entry := SignedEntry[MeshNode]{Key: hashKey(node.PublicKey), Vector: 1, Contents: *node}
+	payload, _ := signedBytes(entry)
+
+	// A field this version does not know of
+	payload = protowire.AppendTag(payload, 99, protowire.BytesType)
+	payload = protowire.AppendString(payload, "added by a newer version")
+	signature, _ := lib.XEdDSASign(key, payload)
+
+	snapshot := TwoPhaseMapSnapshot[string, MeshNode]{
+		Add: map[uint64]Bucket[MeshNode]{entry.Key: {
+			Vector:    entry.Vector,
+			Signer:    node.PublicKey,
+			Payload:   payload,
+			Signature: signature,
+		}},
+		Remove: map[uint64]Bucket[bool]{},
+	}
+
+	loaded := setUpTestsWithNodeId("alice")
+	loaded.manager.(*TwoPhaseStoreMeshManager).store.Merge(snapshot)
+
+	if !loaded.manager.NodeExists(node.PublicKey) {
+		t.Fatalf(`node signed by a newer version should have been accepted`)
+	}
+
+	relayed := setUpTestsWithNodeId("eve")
+
+	if err := relayed.manager.Load(loaded.manager.Save()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if !relayed.manager.NodeExists(node.PublicKey) {
+		t.Fatalf(`node should still verify once relayed`)
+	}
+}
+
+func TestLoadAcceptsSignedNode(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+	testParams.manager.AddNode(node)
+	testParams.manager.SetAlias(node.PublicKey, "bob")
+
+	loaded := setUpTestsWithNodeId("alice")
+
+	if err := loaded.manager.Load(testParams.manager.Save()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	loadedNode, err := loaded.manager.GetNode(node.PublicKey)
+
+	if err != nil {
+		t.Fatalf(`signed node should have been accepted`)
+	}
+
+	if loadedNode.GetAlias() != "bob" {
+		t.Fatalf(`expected alias bob got %s`, loadedNode.GetAlias())
+	}
+}
+
+func TestLoadRejectsReplayedVector(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+	testParams.manager.AddNode(node)
+
+	manager := testParams.manager.(*TwoPhaseStoreMeshManager)
+	snapshot := manager.store.Snapshot()
+
+	for key, bucket := range snapshot.Add {
+		bucket.Vector += 100
+		snapshot.Add[key] = bucket
+	}
+
+	loaded := setUpTestsWithNodeId("alice")
+	loaded.manager.(*TwoPhaseStoreMeshManager).store.Merge(*snapshot)
+
+	if loaded.manager.NodeExists(node.PublicKey) {
+		t.Fatalf(`entry with a bumped vector should have been rejected`)
+	}
+}
+
+func TestLoadRejectsRemovalByAnotherNode(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+	testParams.manager.AddNode(node)
+
+	loaded := setUpTestsWithNodeId("alice")
+	loaded.manager.Load(testParams.manager.Save())
+
+	attacker := setUpTestsWithNodeId("eve")
+	attacker.manager.Load(testParams.manager.Save())
+	attacker.manager.AddNode(getOurNode(attacker))
+
+	if err := attacker.manager.RemoveNode(node.PublicKey); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	loaded.manager.Load(attacker.manager.Save())
+
+	if !loaded.manager.NodeExists(node.PublicKey) {
+		t.Fatalf(`removal by another node should have been rejected`)
+	}
+}
+
+func TestLoadAcceptsRemovalByTheNode(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+	testParams.manager.AddNode(node)
+
+	loaded := setUpTestsWithNodeId("alice")
+	loaded.manager.Load(testParams.manager.Save())
+
+	if err := testParams.manager.RemoveNode(node.PublicKey); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	loaded.manager.Load(testParams.manager.Save())
+
+	if loaded.manager.NodeExists(node.PublicKey) {
+		t.Fatalf(`removal by the node itself should have been accepted`)
+	}
+}
+
+func TestLoadCorruptSnapshotReturnsError(t *testing.T) {
+	testParams := setUpTests()
+
+	if err := testParams.manager.Load(append(bytes.Clone(snapshotMagic), 0xff)); err == nil {
+		t.Fatalf(`loading a corrupt snapshot should fail`)
+	}
+}
+
 func TestHasChangesReturnsTrueWhenThereAreChangesInTheMesh(t *testing.T) {
 	testParams := setUpTests()
 
@@ -376,6 +665,89 @@ func TestAddServiceNodeExists(t *testing.T) {
 	}
 }
 
+func TestAddRedemptionNodeDoesNotExist(t *testing.T) {
+	testParams := setUpTests()
+
+	err := testParams.manager.AddRedemption("djdjdj", mesh.Redemption{Invite: "invite"})
+
+	if err == nil {
+		t.Fatalf(`error should be thrown`)
+	}
+}
+
+func TestAddRedemptionIsReplicated(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+	testParams.manager.AddNode(node)
+
+	redemption := mesh.Redemption{Invite: "invite", Grantee: "grantee", Timestamp: 10}
+	err := testParams.manager.AddRedemption(node.PublicKey, redemption)
+
+	if err != nil {
+		t.Fatalf(`error %s thrown`, err.Error())
+	}
+
+	loaded := setUpTestsWithNodeId("alice")
+
+	if err := loaded.manager.Load(testParams.manager.Save()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	loadedNode, err := loaded.manager.GetNode(node.PublicKey)
+
+	if err != nil {
+		t.Fatalf(`node with a redemption should have been accepted`)
+	}
+
+	redemptions := loadedNode.GetRedemptions()
+
+	if len(redemptions) != 1 || redemptions[0] != redemption {
+		t.Fatalf(`expected redemption %v got %v`, redemption, redemptions)
+	}
+}
+
+func TestSetAclPolicyIsReplicated(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+	testParams.manager.AddNode(node)
+
+	author, _ := wgtypes.GeneratePrivateKey()
+
+	policy := mesh.AclPolicy{
+		Policy: &acl.Policy{
+			Tags:   map[string]string{"db": "role=db"},
+			Labels: map[string]map[string]string{node.PublicKey: {"role": "db"}},
+			Rules:  []acl.Rule{{From: []string{acl.ANY}, To: []string{"tag:db"}}},
+		},
+		Author:    author.PublicKey().String(),
+		Timestamp: 10,
+	}
+
+	policy.Sign(func(message []byte) ([]byte, error) {
+		return lib.XEdDSASign(author, message)
+	})
+
+	if err := testParams.manager.SetAclPolicy(node.PublicKey, policy); err != nil {
+		t.Fatalf(`error %s thrown`, err.Error())
+	}
+
+	loaded := setUpTestsWithNodeId("alice")
+
+	if err := loaded.manager.Load(testParams.manager.Save()); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	loadedNode, err := loaded.manager.GetNode(node.PublicKey)
+
+	if err != nil {
+		t.Fatalf(`node with a policy should have been accepted`)
+	}
+
+	if err := loadedNode.GetAclPolicy().Verify(); err != nil {
+		t.Fatalf(`expected the replicated policy to verify got %s`, err.Error())
+	}
+}
+
 func TestRemoveServiceDoesNotExists(t *testing.T) {
 	testParams := setUpTests()
 
@@ -396,6 +768,32 @@ func TestRemoveServiceServiceDoesNotExist(t *testing.T) {
 	}
 }
 
+func TestSetLabelNodeExists(t *testing.T) {
+	testParams := setUpTests()
+	testParams.manager.AddNode(getOurNode(testParams))
+	err := testParams.manager.SetLabel(testParams.publicKey.String(), "env", "prod")
+
+	if err != nil {
+		t.Fatalf(`error %s thrown`, err.Error())
+	}
+
+	node, _ := testParams.manager.GetNode(testParams.publicKey.String())
+
+	if value, ok := node.GetLabels()["env"]; !ok || value != "prod" {
+		t.Fatalf(`label not added to the data store`)
+	}
+}
+
+func TestRemoveLabelLabelDoesNotExist(t *testing.T) {
+	testParams := setUpTests()
+
+	testParams.manager.AddNode(getOurNode(testParams))
+
+	if err := testParams.manager.RemoveLabel(testParams.publicKey.String(), "env"); err == nil {
+		t.Fatalf(`error should be thrown`)
+	}
+}
+
 func TestGetPeersReturnsAllPeersInTheMesh(t *testing.T) {
 	testParams := setUpTests()
 
@@ -437,3 +835,84 @@ func TestRemoveNodeReturnsErrorIfNodeDoesNotExist(t *testing.T) {
 		t.Fatalf(`error should have returned`)
 	}
 }
+
+func TestGetStateRemovedNodeIsTombstoned(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+
+	testParams.manager.AddNode(node)
+	testParams.manager.RemoveNode(node.PublicKey)
+
+	state, err := testParams.manager.GetState()
+
+	if err != nil {
+		t.Fatalf(`error should not have returned`)
+	}
+
+	if len(state.Entries) != 1 {
+		t.Fatalf(`expected 1 entry got %d`, len(state.Entries))
+	}
+
+	for _, entry := range state.Entries {
+		if entry.PublicKey != node.PublicKey {
+			t.Fatalf(`expected public key %s got %s`, node.PublicKey, entry.PublicKey)
+		}
+
+		if !entry.IsRemoved() {
+			t.Fatalf(`entry should have been tombstoned`)
+		}
+	}
+}
+
+func TestRotateNodeKeepsTheNodesAddress(t *testing.T) {
+	testParams := setUpTests()
+	node := getOurNode(testParams)
+	testParams.manager.AddNode(node)
+
+	successor, _ := wgtypes.GeneratePrivateKey()
+	successorKey := successor.PublicKey().String()
+
+	if err := testParams.manager.RotateNode(node.PublicKey, successorKey); err != nil {
+		t.Fatalf(`error %s thrown`, err.Error())
+	}
+
+	if testParams.manager.NodeExists(node.PublicKey) {
+		t.Fatalf(`expected the node's old key to be removed`)
+	}
+
+	rotated, err := testParams.manager.GetNode(successorKey)
+
+	if err != nil {
+		t.Fatalf(`expected the node to exist under its successor key`)
+	}
+
+	if rotated.GetWgHost().String() != node.WgHost {
+		t.Fatalf(`expected address %s got %s`, node.WgHost, rotated.GetWgHost().String())
+	}
+}
+
+func TestAddJoinRequestCapsPendingRequests(t *testing.T) {
+	testParams := setUpTests()
+	testParams.manager.AddNode(getOurNode(testParams))
+	nodeId := testParams.publicKey.String()
+
+	expired := mesh.JoinRequest{PublicKey: getRandomNode().PublicKey, Timestamp: 1}
+
+	if err := testParams.manager.AddJoinRequest(nodeId, expired); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	for i := 0; i < mesh.MAX_JOIN_REQUESTS; i++ {
+		request := mesh.JoinRequest{PublicKey: getRandomNode().PublicKey, Timestamp: time.Now().Unix()}
+
+		if err := testParams.manager.AddJoinRequest(nodeId, request); err != nil {
+			t.Fatalf(`expected expired requests not to count towards the limit: %s`, err.Error())
+		}
+	}
+
+	request := mesh.JoinRequest{PublicKey: getRandomNode().PublicKey, Timestamp: time.Now().Unix()}
+
+	if err := testParams.manager.AddJoinRequest(nodeId, request); err == nil {
+		t.Fatalf(`expected the request to be refused once the limit is reached`)
+	}
+}
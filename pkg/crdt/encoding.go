@@ -0,0 +1,669 @@
+package crdt
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/tim-beatham/smegmesh/pkg/acl"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/rpc"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// PROTOBUF_ENCODING: messages are encoded using the protobuf
+	// definitions in pkg/grpc/crdt.proto
+	PROTOBUF_ENCODING = "encoding/protobuf"
+)
+
+// snapshotMagic: prefixes protobuf encoded snapshots to distinguish
+// them from legacy gob encoded snapshots
+var snapshotMagic = []byte("SMEGPB\x00\x01")
+
+func routeToProto(route Route) *rpc.Route {
+	return &rpc.Route{
+		Destination: route.Destination,
+		Path:        route.Path,
+	}
+}
+
+func routeFromProto(route *rpc.Route) Route {
+	return Route{
+		Destination: route.GetDestination(),
+		Path:        route.GetPath(),
+	}
+}
+
+func redemptionToProto(redemption mesh.Redemption) *rpc.Redemption {
+	return &rpc.Redemption{
+		Invite:    redemption.Invite,
+		Grantee:   redemption.Grantee,
+		Timestamp: redemption.Timestamp,
+		Role:      redemption.Role,
+	}
+}
+
+func redemptionFromProto(redemption *rpc.Redemption) mesh.Redemption {
+	return mesh.Redemption{
+		Invite:    redemption.GetInvite(),
+		Grantee:   redemption.GetGrantee(),
+		Timestamp: redemption.GetTimestamp(),
+		Role:      redemption.GetRole(),
+	}
+}
+
+func genesisToProto(genesis *mesh.Genesis) *rpc.Genesis {
+	if genesis == nil {
+		return nil
+	}
+
+	return &rpc.Genesis{
+		Creator:         genesis.Creator,
+		Nonce:           genesis.Nonce,
+		RequireApproval: genesis.RequireApproval,
+		Admins:          genesis.Admins,
+		Signature:       genesis.Signature,
+		PresharedKeys:   genesis.PresharedKeys,
+	}
+}
+
+func genesisFromProto(genesis *rpc.Genesis) *mesh.Genesis {
+	if genesis == nil {
+		return nil
+	}
+
+	return &mesh.Genesis{
+		Creator:         genesis.GetCreator(),
+		Nonce:           genesis.GetNonce(),
+		RequireApproval: genesis.GetRequireApproval(),
+		Admins:          genesis.GetAdmins(),
+		Signature:       genesis.GetSignature(),
+		PresharedKeys:   genesis.GetPresharedKeys(),
+	}
+}
+
+func meshInfoToProto(info *mesh.MeshInfo) *rpc.MeshInfo {
+	if info == nil {
+		return nil
+	}
+
+	return &rpc.MeshInfo{
+		MeshId:      info.MeshId,
+		Name:        info.Name,
+		Creator:     info.Creator,
+		Created:     info.Created,
+		Description: info.Description,
+		Signature:   info.Signature,
+	}
+}
+
+func meshInfoFromProto(info *rpc.MeshInfo) *mesh.MeshInfo {
+	if info == nil {
+		return nil
+	}
+
+	return &mesh.MeshInfo{
+		MeshId:      info.GetMeshId(),
+		Name:        info.GetName(),
+		Creator:     info.GetCreator(),
+		Created:     info.GetCreated(),
+		Description: info.GetDescription(),
+		Signature:   info.GetSignature(),
+	}
+}
+
+func joinRequestToProto(request mesh.JoinRequest) *rpc.JoinRequest {
+	return &rpc.JoinRequest{
+		PublicKey:   request.PublicKey,
+		Role:        request.Role,
+		Alias:       request.Alias,
+		Description: request.Description,
+		Timestamp:   request.Timestamp,
+	}
+}
+
+func joinRequestFromProto(request *rpc.JoinRequest) mesh.JoinRequest {
+	return mesh.JoinRequest{
+		PublicKey:   request.GetPublicKey(),
+		Role:        request.GetRole(),
+		Alias:       request.GetAlias(),
+		Description: request.GetDescription(),
+		Timestamp:   request.GetTimestamp(),
+	}
+}
+
+func joinDecisionToProto(decision mesh.JoinDecision) *rpc.JoinDecision {
+	return &rpc.JoinDecision{
+		PublicKey: decision.PublicKey,
+		Approved:  decision.Approved,
+		Role:      decision.Role,
+		Approver:  decision.Approver,
+		Timestamp: decision.Timestamp,
+		Signature: decision.Signature,
+	}
+}
+
+func joinDecisionFromProto(decision *rpc.JoinDecision) mesh.JoinDecision {
+	return mesh.JoinDecision{
+		PublicKey: decision.GetPublicKey(),
+		Approved:  decision.GetApproved(),
+		Role:      decision.GetRole(),
+		Approver:  decision.GetApprover(),
+		Timestamp: decision.GetTimestamp(),
+		Signature: decision.GetSignature(),
+	}
+}
+
+func revocationToProto(revocation mesh.Revocation) *rpc.Revocation {
+	return &rpc.Revocation{
+		PublicKey: revocation.PublicKey,
+		Revoker:   revocation.Revoker,
+		Timestamp: revocation.Timestamp,
+		Signature: revocation.Signature,
+	}
+}
+
+func revocationFromProto(revocation *rpc.Revocation) mesh.Revocation {
+	return mesh.Revocation{
+		PublicKey: revocation.GetPublicKey(),
+		Revoker:   revocation.GetRevoker(),
+		Timestamp: revocation.GetTimestamp(),
+		Signature: revocation.GetSignature(),
+	}
+}
+
+func grantToProto(grant mesh.Grant) *rpc.Grant {
+	return &rpc.Grant{
+		PublicKey: grant.PublicKey,
+		Admin:     grant.Admin,
+		Granter:   grant.Granter,
+		Timestamp: grant.Timestamp,
+		Signature: grant.Signature,
+	}
+}
+
+func grantFromProto(grant *rpc.Grant) mesh.Grant {
+	return mesh.Grant{
+		PublicKey: grant.GetPublicKey(),
+		Admin:     grant.GetAdmin(),
+		Granter:   grant.GetGranter(),
+		Timestamp: grant.GetTimestamp(),
+		Signature: grant.GetSignature(),
+	}
+}
+
+func successionToProto(succession mesh.Succession) *rpc.Succession {
+	return &rpc.Succession{
+		PublicKey:          succession.PublicKey,
+		Successor:          succession.Successor,
+		Activates:          succession.Activates,
+		Signature:          succession.Signature,
+		SuccessorSignature: succession.SuccessorSignature,
+	}
+}
+
+func successionFromProto(succession *rpc.Succession) mesh.Succession {
+	return mesh.Succession{
+		PublicKey:          succession.GetPublicKey(),
+		Successor:          succession.GetSuccessor(),
+		Activates:          succession.GetActivates(),
+		Signature:          succession.GetSignature(),
+		SuccessorSignature: succession.GetSuccessorSignature(),
+	}
+}
+
+func aclToProto(policy *acl.Policy) *rpc.Acl {
+	if policy == nil {
+		return nil
+	}
+
+	tags := make(map[string]*rpc.AclTag)
+
+	for tag, selector := range policy.Tags {
+		tags[tag] = &rpc.AclTag{Selector: selector}
+	}
+
+	var rules []*rpc.AclRule
+
+	for _, rule := range policy.Rules {
+		rules = append(rules, &rpc.AclRule{
+			From:     rule.From,
+			To:       rule.To,
+			Protocol: rule.Protocol,
+			Ports:    rule.Ports,
+		})
+	}
+
+	granted := make(map[string]*rpc.AclLabels)
+
+	for node, labels := range policy.Labels {
+		granted[node] = &rpc.AclLabels{Labels: labels}
+	}
+
+	return &rpc.Acl{Tags: tags, Rules: rules, Labels: granted}
+}
+
+func aclFromProto(policy *rpc.Acl) *acl.Policy {
+	if policy == nil {
+		return nil
+	}
+
+	var tags map[string]string
+
+	if len(policy.GetTags()) != 0 {
+		tags = make(map[string]string)
+	}
+
+	for tag, selector := range policy.GetTags() {
+		tags[tag] = selector.GetSelector()
+	}
+
+	var granted map[string]map[string]string
+
+	if len(policy.GetLabels()) != 0 {
+		granted = make(map[string]map[string]string)
+	}
+
+	for node, labels := range policy.GetLabels() {
+		granted[node] = labels.GetLabels()
+	}
+
+	var rules []acl.Rule
+
+	for _, rule := range policy.GetRules() {
+		rules = append(rules, acl.Rule{
+			From:     rule.GetFrom(),
+			To:       rule.GetTo(),
+			Protocol: rule.GetProtocol(),
+			Ports:    rule.GetPorts(),
+		})
+	}
+
+	return &acl.Policy{Tags: tags, Rules: rules, Labels: granted}
+}
+
+func aclPolicyToProto(policy *mesh.AclPolicy) *rpc.AclPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	return &rpc.AclPolicy{
+		Policy:    aclToProto(policy.Policy),
+		Author:    policy.Author,
+		Timestamp: policy.Timestamp,
+		Signature: policy.Signature,
+	}
+}
+
+func aclPolicyFromProto(policy *rpc.AclPolicy) *mesh.AclPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	return &mesh.AclPolicy{
+		Policy:    aclFromProto(policy.GetPolicy()),
+		Author:    policy.GetAuthor(),
+		Timestamp: policy.GetTimestamp(),
+		Signature: policy.GetSignature(),
+	}
+}
+
+func serviceRecordToProto(service mesh.Service) *rpc.ServiceRecord {
+	return &rpc.ServiceRecord{
+		Name:     service.Name,
+		Port:     uint32(service.Port),
+		Protocol: service.Protocol,
+		Tags:     service.Tags,
+		Metadata: service.Metadata,
+		Ttl:      service.TTL,
+		Status:   string(service.Status),
+		Updated:  service.Updated,
+	}
+}
+
+func serviceRecordFromProto(service *rpc.ServiceRecord) mesh.Service {
+	return mesh.Service{
+		Name:     service.GetName(),
+		Port:     int(service.GetPort()),
+		Protocol: service.GetProtocol(),
+		Tags:     service.GetTags(),
+		Metadata: service.GetMetadata(),
+		TTL:      service.GetTtl(),
+		Status:   mesh.ServiceStatus(service.GetStatus()),
+		Updated:  service.GetUpdated(),
+	}
+}
+
+func meshNodeToProto(node *MeshNode) *rpc.MeshNode {
+	routes := make(map[string]*rpc.Route)
+
+	for destination, route := range node.Routes {
+		routes[destination] = routeToProto(route)
+	}
+
+	var redemptions []*rpc.Redemption
+
+	for _, redemption := range node.Redemptions {
+		redemptions = append(redemptions, redemptionToProto(redemption))
+	}
+
+	var requests []*rpc.JoinRequest
+
+	for _, request := range node.JoinRequests {
+		requests = append(requests, joinRequestToProto(request))
+	}
+
+	var decisions []*rpc.JoinDecision
+
+	for _, decision := range node.JoinDecisions {
+		decisions = append(decisions, joinDecisionToProto(decision))
+	}
+
+	var revocations []*rpc.Revocation
+
+	for _, revocation := range node.Revocations {
+		revocations = append(revocations, revocationToProto(revocation))
+	}
+
+	var grants []*rpc.Grant
+
+	for _, grant := range node.Grants {
+		grants = append(grants, grantToProto(grant))
+	}
+
+	var successions []*rpc.Succession
+
+	for _, succession := range node.Successions {
+		successions = append(successions, successionToProto(succession))
+	}
+
+	var records map[string]*rpc.ServiceRecord
+
+	for name, service := range node.ServiceRecords {
+		if records == nil {
+			records = make(map[string]*rpc.ServiceRecord)
+		}
+
+		records[name] = serviceRecordToProto(service)
+	}
+
+	return &rpc.MeshNode{
+		HostEndpoint:   node.HostEndpoint,
+		WgEndpoint:     node.WgEndpoint,
+		PublicKey:      node.PublicKey,
+		WgHost:         node.WgHost,
+		Timestamp:      node.Timestamp,
+		Routes:         routes,
+		Alias:          node.Alias,
+		Description:    node.Description,
+		Services:       node.Services,
+		Type:           node.Type,
+		Tombstone:      node.Tombstone,
+		Version:        node.Version,
+		Redemptions:    redemptions,
+		Genesis:        genesisToProto(node.Genesis),
+		JoinRequests:   requests,
+		JoinDecisions:  decisions,
+		Revocations:    revocations,
+		Grants:         grants,
+		Successions:    successions,
+		AclPolicy:      aclPolicyToProto(node.AclPolicy),
+		Labels:         node.Labels,
+		ServiceRecords: records,
+		MeshInfo:       meshInfoToProto(node.MeshInfo),
+	}
+}
+
+func meshNodeFromProto(node *rpc.MeshNode) MeshNode {
+	routes := make(map[string]Route)
+
+	for destination, route := range node.GetRoutes() {
+		routes[destination] = routeFromProto(route)
+	}
+
+	services := node.GetServices()
+
+	if services == nil {
+		services = make(map[string]string)
+	}
+
+	var redemptions []mesh.Redemption
+
+	for _, redemption := range node.GetRedemptions() {
+		redemptions = append(redemptions, redemptionFromProto(redemption))
+	}
+
+	var requests []mesh.JoinRequest
+
+	for _, request := range node.GetJoinRequests() {
+		requests = append(requests, joinRequestFromProto(request))
+	}
+
+	var decisions []mesh.JoinDecision
+
+	for _, decision := range node.GetJoinDecisions() {
+		decisions = append(decisions, joinDecisionFromProto(decision))
+	}
+
+	var revocations []mesh.Revocation
+
+	for _, revocation := range node.GetRevocations() {
+		revocations = append(revocations, revocationFromProto(revocation))
+	}
+
+	var grants []mesh.Grant
+
+	for _, grant := range node.GetGrants() {
+		grants = append(grants, grantFromProto(grant))
+	}
+
+	var successions []mesh.Succession
+
+	for _, succession := range node.GetSuccessions() {
+		successions = append(successions, successionFromProto(succession))
+	}
+
+	var records map[string]mesh.Service
+
+	for name, service := range node.GetServiceRecords() {
+		if records == nil {
+			records = make(map[string]mesh.Service)
+		}
+
+		records[name] = serviceRecordFromProto(service)
+	}
+
+	return MeshNode{
+		HostEndpoint:   node.GetHostEndpoint(),
+		WgEndpoint:     node.GetWgEndpoint(),
+		PublicKey:      node.GetPublicKey(),
+		WgHost:         node.GetWgHost(),
+		Timestamp:      node.GetTimestamp(),
+		Routes:         routes,
+		Alias:          node.GetAlias(),
+		Description:    node.GetDescription(),
+		Services:       services,
+		Type:           node.GetType(),
+		Tombstone:      node.GetTombstone(),
+		Version:        node.GetVersion(),
+		Redemptions:    redemptions,
+		Genesis:        genesisFromProto(node.GetGenesis()),
+		JoinRequests:   requests,
+		JoinDecisions:  decisions,
+		Revocations:    revocations,
+		Grants:         grants,
+		Successions:    successions,
+		AclPolicy:      aclPolicyFromProto(node.GetAclPolicy()),
+		Labels:         node.GetLabels(),
+		ServiceRecords: records,
+		MeshInfo:       meshInfoFromProto(node.GetMeshInfo()),
+	}
+}
+
+// signedBytes: the bytes of the entry we sign. Carried with the entry
+// as other nodes may not be able to encode the entry the same way
+func signedBytes(entry SignedEntry[MeshNode]) ([]byte, error) {
+	message := &rpc.SignedEntry{
+		Key:     entry.Key,
+		Vector:  entry.Vector,
+		Removed: entry.Removed,
+	}
+
+	if !entry.Removed {
+		message.Contents = meshNodeToProto(&entry.Contents)
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(message)
+}
+
+// entryFromSignedBytes: decode the entry a signature covers
+func entryFromSignedBytes(payload []byte) (SignedEntry[MeshNode], error) {
+	var message rpc.SignedEntry
+
+	if err := proto.Unmarshal(payload, &message); err != nil {
+		return SignedEntry[MeshNode]{}, fmt.Errorf("crdt: could not decode signed entry: %w", err)
+	}
+
+	entry := SignedEntry[MeshNode]{
+		Key:     message.GetKey(),
+		Vector:  message.GetVector(),
+		Removed: message.GetRemoved(),
+	}
+
+	if !entry.Removed {
+		entry.Contents = meshNodeFromProto(message.GetContents())
+	}
+
+	return entry, nil
+}
+
+func stateToProto(state *TwoPhaseMapState[string]) *rpc.TwoPhaseMapState {
+	return &rpc.TwoPhaseMapState{
+		Vectors:        state.Vectors,
+		AddContents:    state.AddContents,
+		RemoveContents: state.RemoveContents,
+	}
+}
+
+// emptyIfNil: gob and protobuf decode empty maps as nil
+func emptyIfNil(m map[uint64]uint64) map[uint64]uint64 {
+	if m == nil {
+		return make(map[uint64]uint64)
+	}
+
+	return m
+}
+
+func stateFromProto(state *rpc.TwoPhaseMapState) *TwoPhaseMapState[string] {
+	return &TwoPhaseMapState[string]{
+		Vectors:        emptyIfNil(state.GetVectors()),
+		AddContents:    emptyIfNil(state.GetAddContents()),
+		RemoveContents: emptyIfNil(state.GetRemoveContents()),
+	}
+}
+
+func snapshotToProto(snapshot *TwoPhaseMapSnapshot[string, MeshNode]) *rpc.TwoPhaseMapSnapshot {
+	add := make(map[uint64]*rpc.NodeBucket)
+	remove := make(map[uint64]*rpc.RemoveBucket)
+
+	for key, bucket := range snapshot.Add {
+		add[key] = &rpc.NodeBucket{
+			Vector:     bucket.Vector,
+			Gravestone: bucket.Gravestone,
+			Signer:     bucket.Signer,
+			Signature:  bucket.Signature,
+			Payload:    bucket.Payload,
+		}
+
+		// The node is carried in the payload if the entry is signed
+		if bucket.Payload == nil {
+			add[key].Contents = meshNodeToProto(&bucket.Contents)
+		}
+	}
+
+	for key, bucket := range snapshot.Remove {
+		remove[key] = &rpc.RemoveBucket{
+			Vector:     bucket.Vector,
+			Contents:   bucket.Contents,
+			Gravestone: bucket.Gravestone,
+			Signer:     bucket.Signer,
+			Signature:  bucket.Signature,
+			Payload:    bucket.Payload,
+		}
+	}
+
+	return &rpc.TwoPhaseMapSnapshot{Add: add, Remove: remove}
+}
+
+func snapshotFromProto(snapshot *rpc.TwoPhaseMapSnapshot) *TwoPhaseMapSnapshot[string, MeshNode] {
+	add := make(map[uint64]Bucket[MeshNode])
+	remove := make(map[uint64]Bucket[bool])
+
+	for key, bucket := range snapshot.GetAdd() {
+		contents := meshNodeFromProto(bucket.GetContents())
+
+		// The payload is verified when the snapshot is merged
+		if bucket.GetPayload() != nil {
+			entry, _ := entryFromSignedBytes(bucket.GetPayload())
+			contents = entry.Contents
+		}
+
+		add[key] = Bucket[MeshNode]{
+			Vector:     bucket.GetVector(),
+			Contents:   contents,
+			Gravestone: bucket.GetGravestone(),
+			Signer:     bucket.GetSigner(),
+			Payload:    bucket.GetPayload(),
+			Signature:  bucket.GetSignature(),
+		}
+	}
+
+	for key, bucket := range snapshot.GetRemove() {
+		remove[key] = Bucket[bool]{
+			Vector:     bucket.GetVector(),
+			Contents:   bucket.GetContents(),
+			Gravestone: bucket.GetGravestone(),
+			Signer:     bucket.GetSigner(),
+			Payload:    bucket.GetPayload(),
+			Signature:  bucket.GetSignature(),
+		}
+	}
+
+	return &TwoPhaseMapSnapshot[string, MeshNode]{Add: add, Remove: remove}
+}
+
+// encodeSnapshot: encode the snapshot to persist or send to a
+// joining node
+func encodeSnapshot(snapshot *TwoPhaseMapSnapshot[string, MeshNode]) ([]byte, error) {
+	bs, err := proto.Marshal(snapshotToProto(snapshot))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(bytes.Clone(snapshotMagic), bs...), nil
+}
+
+// decodeSnapshot: decode a snapshot. Falls back to gob if the
+// snapshot was saved by an older version
+func decodeSnapshot(bs []byte) (*TwoPhaseMapSnapshot[string, MeshNode], error) {
+	if !bytes.HasPrefix(bs, snapshotMagic) {
+		var snapshot TwoPhaseMapSnapshot[string, MeshNode]
+		dec := gob.NewDecoder(bytes.NewBuffer(bs))
+
+		if err := dec.Decode(&snapshot); err != nil {
+			return nil, fmt.Errorf("crdt: could not decode legacy snapshot: %w", err)
+		}
+
+		return &snapshot, nil
+	}
+
+	var snapshot rpc.TwoPhaseMapSnapshot
+
+	if err := proto.Unmarshal(bs[len(snapshotMagic):], &snapshot); err != nil {
+		return nil, fmt.Errorf("crdt: could not decode snapshot: %w", err)
+	}
+
+	return snapshotFromProto(&snapshot), nil
+}
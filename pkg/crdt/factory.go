@@ -17,18 +17,28 @@ type TwoPhaseMapFactory struct {
 
 // CreateMesh: create a new mesh network
 func (f *TwoPhaseMapFactory) CreateMesh(params *mesh.MeshProviderFactoryParams) (mesh.MeshProvider, error) {
-	return &TwoPhaseStoreMeshManager{
+	store := NewTwoPhaseMap[string, MeshNode](params.NodeID, hashKey, uint64(3*f.Config.Heartbeat))
+
+	manager := &TwoPhaseStoreMeshManager{
 		MeshId:     params.MeshId,
 		IfName:     params.DevName,
 		Client:     params.Client,
 		Conf:       params.Conf,
 		DaemonConf: params.DaemonConf,
-		store: NewTwoPhaseMap[string, MeshNode](params.NodeID, func(s string) uint64 {
-			h := fnv.New64a()
-			h.Write([]byte(s))
-			return h.Sum64()
-		}, uint64(3*f.Config.Heartbeat)),
-	}, nil
+		store:      store,
+		host:       params.Host,
+	}
+
+	store.SetSigner(manager.sign, manager.verifier)
+	store.SetOnUpdate(params.OnUpdate)
+	return manager, nil
+}
+
+// hashKey: hashes the public key of a node to its key in the store
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
 }
 
 // MeshNodeFactory: create a new node in the mesh network
@@ -57,6 +67,7 @@ func (f *MeshNodeFactory) Build(params *mesh.MeshNodeFactoryParams) mesh.MeshNod
 		Description:  "",
 		Alias:        "",
 		Type:         string(*params.MeshConfig.Role),
+		Version:      lib.Version,
 	}
 }
 
@@ -3,7 +3,12 @@ package crdt
 
 import (
 	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
 	"sync"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
 )
 
 // Bucket: bucket represents a value in the grow only map
@@ -11,6 +16,12 @@ type Bucket[D any] struct {
 	Vector     uint64
 	Contents   D
 	Gravestone bool
+	// Signer: public key of the node that signed the bucket
+	Signer string
+	// Payload: the bytes of the bucket's entry as signed by the signer
+	Payload []byte
+	// Signature: signature of the signer over the bucket's entry
+	Signature []byte
 }
 
 // GMap is a set that can only grow in size
@@ -22,15 +33,27 @@ type GMap[K cmp.Ordered, D any] struct {
 
 // Put: put a new entry in the grow-only-map
 func (g *GMap[K, D]) Put(key K, value D) {
+	g.PutSigned(key, value, nil)
+}
+
+// PutSigned: put a new entry in the grow-only-map. sign, if given, is
+// called with the entry once its vector is assigned
+func (g *GMap[K, D]) PutSigned(key K, value D, sign func(uint64, *Bucket[D])) {
 	g.lock.Lock()
 
 	clock := g.clock.IncrementClock()
 
-	g.contents[g.clock.hashFunc(key)] = Bucket[D]{
+	bucket := Bucket[D]{
 		Vector:   clock,
 		Contents: value,
 	}
 
+	if sign != nil {
+		sign(g.clock.hashFunc(key), &bucket)
+	}
+
+	g.contents[g.clock.hashFunc(key)] = bucket
+
 	g.lock.Unlock()
 }
 
@@ -50,14 +73,18 @@ func (g *GMap[K, D]) contains(key uint64) bool {
 	return ok
 }
 
-func (g *GMap[K, D]) put(key uint64, b Bucket[D]) {
+// put: put the bucket in the map if it is newer than the bucket held.
+// Returns true if the bucket was put
+func (g *GMap[K, D]) put(key uint64, b Bucket[D]) bool {
 	g.lock.Lock()
+	defer g.lock.Unlock()
 
 	if g.contents[key].Vector < b.Vector {
 		g.contents[key] = b
+		return true
 	}
 
-	g.lock.Unlock()
+	return false
 }
 
 func (g *GMap[K, D]) get(key uint64) Bucket[D] {
@@ -177,6 +204,27 @@ func (g *GMap[K, D]) GetHash() uint64 {
 	return hash
 }
 
+// GetDigest: get the SHA-256 digest of the vectors of the g_map
+// sorted by key
+func (g *GMap[K, D]) GetDigest() []byte {
+	g.lock.RLock()
+
+	keys := lib.MapKeys(g.contents)
+	slices.Sort(keys)
+
+	digest := sha256.New()
+	entry := make([]byte, 16)
+
+	for _, key := range keys {
+		binary.BigEndian.PutUint64(entry[:8], key)
+		binary.BigEndian.PutUint64(entry[8:], g.contents[key].Vector)
+		digest.Write(entry)
+	}
+
+	g.lock.RUnlock()
+	return digest.Sum(nil)
+}
+
 // Prune: prune all stale entries
 func (g *GMap[K, D]) Prune() {
 	stale := g.clock.getStale()
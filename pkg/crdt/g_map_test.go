@@ -207,6 +207,37 @@ func TestGetHashChangesHashOnValueAdded(t *testing.T) {
 	}
 }
 
+func TestGetDigestChangesOnValueAdded(t *testing.T) {
+	gMap := NewGmap()
+	gMap.Put("a", false)
+	prevDigest := gMap.GetDigest()
+
+	gMap.Put("b", true)
+
+	if slices.Equal(prevDigest, gMap.GetDigest()) {
+		t.Fatalf(`digest should be different`)
+	}
+}
+
+func TestGetDigestClocksThatCollideInHashDiffer(t *testing.T) {
+	clock1 := NewVectorClock("a", func(key string) uint64 { return 0 }, 1)
+	clock2 := NewVectorClock("a", func(key string) uint64 { return 0 }, 1)
+
+	// the sum of key * (clock + 1) is 12 for both clocks
+	clock1.put(2, 1)
+	clock1.put(4, 1)
+	clock2.put(1, 3)
+	clock2.put(4, 1)
+
+	if clock1.GetHash() != clock2.GetHash() {
+		t.Fatalf(`expected the hashes to collide`)
+	}
+
+	if slices.Equal(clock1.GetDigest(), clock2.GetDigest()) {
+		t.Fatalf(`digests should differ for different clocks`)
+	}
+}
+
 func TestPruneGarbageCollectsValuesThatHaveNotBeenUpdated(t *testing.T) {
 	gMap := NewGmap()
 	gMap.clock.Put("c", 12)
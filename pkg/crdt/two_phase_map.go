@@ -2,8 +2,11 @@ package crdt
 
 import (
 	"cmp"
+	"crypto/sha256"
+	"errors"
 
 	"github.com/tim-beatham/smegmesh/pkg/lib"
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
 )
 
 // TwoPhaseMap: comprises of two grow-only maps
@@ -12,8 +15,31 @@ type TwoPhaseMap[K cmp.Ordered, D any] struct {
 	removeMap *GMap[K, bool]
 	Clock     *VectorClock[K]
 	processId K
+	// sign: signs entries put into or removed from the map. Returns
+	// the signer, the bytes signed and the signature
+	sign func(entry SignedEntry[D]) (string, []byte, []byte, error)
+	// verifier: prepares a verifier for the entries of a snapshot
+	// before they are merged into the map
+	verifier func(snapshot TwoPhaseMapSnapshot[K, D]) Verifier[D]
+	// onUpdate: called whenever the contents of the map change
+	onUpdate func()
 }
 
+// SignedEntry: an entry put into or removed from the map as covered
+// by its signature. Covering the vector and whether the key is removed
+// stops an old entry being replayed as a newer one or as a removal
+type SignedEntry[D any] struct {
+	Key     uint64
+	Vector  uint64
+	Removed bool
+	// Contents: the value put into the map. Empty if the key is removed
+	Contents D
+}
+
+// Verifier: verifies the signer signed the payload of an entry received
+// from another node and returns the entry the payload encodes
+type Verifier[D any] func(signer string, payload, signature []byte) (SignedEntry[D], error)
+
 type TwoPhaseMapSnapshot[K cmp.Ordered, D any] struct {
 	Add    map[uint64]Bucket[D]
 	Remove map[uint64]Bucket[bool]
@@ -66,7 +92,39 @@ func (m *TwoPhaseMap[K, D]) get(key uint64) D {
 func (m *TwoPhaseMap[K, D]) Put(key K, data D) {
 	msgSequence := m.Clock.IncrementClock()
 	m.Clock.Put(key, msgSequence)
-	m.addMap.Put(key, data)
+	m.addMap.PutSigned(key, data, func(hash uint64, bucket *Bucket[D]) {
+		m.signBucket(SignedEntry[D]{Key: hash, Vector: bucket.Vector, Contents: data},
+			&bucket.Signer, &bucket.Payload, &bucket.Signature)
+	})
+	m.updated()
+}
+
+// updated: notify that the contents of the map changed
+func (m *TwoPhaseMap[K, D]) updated() {
+	if m.onUpdate != nil {
+		m.onUpdate()
+	}
+}
+
+// signBucket: sign the entry storing the bytes signed and the
+// signature in the bucket
+func (m *TwoPhaseMap[K, D]) signBucket(entry SignedEntry[D], signer *string, payload *[]byte, signature *[]byte) {
+	if m.sign == nil {
+		return
+	}
+
+	var err error
+	*signer, *payload, *signature, err = m.sign(entry)
+
+	if err != nil {
+		logging.Log.WriteErrorf("could not sign entry %d: %s", entry.Key, err.Error())
+	}
+}
+
+// SetProcessId: record subsequent changes under the given process id
+func (m *TwoPhaseMap[K, D]) SetProcessId(processId K) {
+	m.processId = processId
+	m.Clock.SetProcessId(processId)
 }
 
 // Mark: marks the status of the node as undetermiend
@@ -76,7 +134,11 @@ func (m *TwoPhaseMap[K, D]) Mark(key K) {
 
 // Remove: removes the value from the map
 func (m *TwoPhaseMap[K, D]) Remove(key K) {
-	m.removeMap.Put(key, true)
+	m.removeMap.PutSigned(key, true, func(hash uint64, bucket *Bucket[bool]) {
+		m.signBucket(SignedEntry[D]{Key: hash, Vector: bucket.Vector, Removed: true},
+			&bucket.Signer, &bucket.Payload, &bucket.Signature)
+	})
+	m.updated()
 }
 
 func (m *TwoPhaseMap[K, D]) keys() []uint64 {
@@ -152,6 +214,14 @@ func (m *TwoPhaseMap[K, D]) GetHash() uint64 {
 	return (m.addMap.GetHash() + 1) * (m.removeMap.GetHash() + 1)
 }
 
+// GetDigest: get the SHA-256 digest of the state of the map
+func (m *TwoPhaseMap[K, D]) GetDigest() []byte {
+	digest := sha256.New()
+	digest.Write(m.addMap.GetDigest())
+	digest.Write(m.removeMap.GetDigest())
+	return digest.Sum(nil)
+}
+
 // GetState: get the current vector clock of the add and remove
 // map
 func (m *TwoPhaseMap[K, D]) GenerateMessage() *TwoPhaseMapState[K] {
@@ -192,19 +262,92 @@ func (m *TwoPhaseMapState[K]) Difference(highestStale uint64, state *TwoPhaseMap
 	return mapState
 }
 
-// Merge: merge a snapshot into the map
+// SetSigner: set the functions used to sign entries put into or
+// removed from the map and to verify entries received from other
+// nodes. The verifier is prepared once per merged snapshot. Entries
+// that fail verification are not merged
+func (m *TwoPhaseMap[K, D]) SetSigner(sign func(entry SignedEntry[D]) (string, []byte, []byte, error),
+	verifier func(snapshot TwoPhaseMapSnapshot[K, D]) Verifier[D]) {
+	m.sign = sign
+	m.verifier = verifier
+}
+
+// SetOnUpdate: set the function called whenever the contents of the
+// map change either locally or by merging another node's snapshot
+func (m *TwoPhaseMap[K, D]) SetOnUpdate(onUpdate func()) {
+	m.onUpdate = onUpdate
+}
+
+// verifyEntry: verify an entry received from another node and return
+// the entry its payload encodes. The payload is verified rather than
+// the entry as decoded so that fields this node does not know of are
+// covered. The payload must encode the entry it is carried with
+func (m *TwoPhaseMap[K, D]) verifyEntry(verify Verifier[D], entry SignedEntry[D], signer string, payload, signature []byte) (SignedEntry[D], error) {
+	if verify == nil {
+		return entry, nil
+	}
+
+	if len(signature) == 0 {
+		return entry, errors.New("entry is not signed")
+	}
+
+	signed, err := verify(signer, payload, signature)
+
+	if err != nil {
+		return entry, err
+	}
+
+	if signed.Key != entry.Key || signed.Vector != entry.Vector || signed.Removed != entry.Removed {
+		return entry, errors.New("entry does not match its payload")
+	}
+
+	return signed, nil
+}
+
+// Merge: merge a snapshot into the map. Both entries that put and
+// entries that remove a key must be signed. Entries put hold the
+// contents encoded in their payload
 func (m *TwoPhaseMap[K, D]) Merge(snapshot TwoPhaseMapSnapshot[K, D]) {
+	var verify Verifier[D]
+
+	if m.verifier != nil {
+		verify = m.verifier(snapshot)
+	}
+
+	merged := false
+
 	for key, value := range snapshot.Add {
+		entry := SignedEntry[D]{Key: key, Vector: value.Vector, Contents: value.Contents}
+		entry, err := m.verifyEntry(verify, entry, value.Signer, value.Payload, value.Signature)
+
+		if err != nil {
+			logging.Log.WriteWarnf("rejecting entry %d: %s", key, err.Error())
+			continue
+		}
+
+		value.Contents = entry.Contents
+
 		// Gravestone is local only to that node.
 		// Discover ourselves if the node is alive
-		m.addMap.put(key, value)
+		merged = m.addMap.put(key, value) || merged
 		m.Clock.put(key, value.Vector)
 	}
 
 	for key, value := range snapshot.Remove {
-		m.removeMap.put(key, value)
+		entry := SignedEntry[D]{Key: key, Vector: value.Vector, Removed: true}
+
+		if _, err := m.verifyEntry(verify, entry, value.Signer, value.Payload, value.Signature); err != nil {
+			logging.Log.WriteWarnf("rejecting removal of entry %d: %s", key, err.Error())
+			continue
+		}
+
+		merged = m.removeMap.put(key, value) || merged
 		m.Clock.put(key, value.Vector)
 	}
+
+	if merged {
+		m.updated()
+	}
 }
 
 // Prune: garbage collect all stale entries in the map
@@ -212,6 +355,7 @@ func (m *TwoPhaseMap[K, D]) Prune() {
 	m.addMap.Prune()
 	m.removeMap.Prune()
 	m.Clock.Prune()
+	m.updated()
 }
 
 // NewTwoPhaseMap: create a new two phase map. Consists of two maps
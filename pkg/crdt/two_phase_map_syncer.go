@@ -3,8 +3,12 @@ package crdt
 import (
 	"bytes"
 	"encoding/gob"
+	"fmt"
+	"slices"
 
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
+	"github.com/tim-beatham/smegmesh/pkg/rpc"
+	"google.golang.org/protobuf/proto"
 )
 
 type SyncState int
@@ -18,53 +22,143 @@ const (
 	FINISHED
 )
 
+const (
+	// GOB_ENCODING: messages are encoded using encoding/gob. Spoken
+	// by legacy nodes that do not negotiate
+	GOB_ENCODING = "encoding/gob"
+)
+
 // TwoPhaseSyncer is a type to sync a TwoPhase data store
 type TwoPhaseSyncer struct {
 	manager            *TwoPhaseStoreMeshManager
 	generateMessageFSM SyncFSM
 	state              SyncState
 	mapState           *TwoPhaseMapState[string]
-	peerMsg            []byte
+	// encoding: the negotiated encoding of messages
+	encoding string
+	// recvState: the state of the next message we expect from the peer
+	recvState      SyncState
+	peerHash       *TwoPhaseHash
+	peerState      *TwoPhaseMapState[string]
+	peerDifference *TwoPhaseMapState[string]
+	peerSnapshot   *TwoPhaseMapSnapshot[string, MeshNode]
 }
 
 type TwoPhaseHash struct {
+	// Hash: legacy hash of the vector clock. Prone to collisions
 	Hash uint64
+	// Digest: SHA-256 digest of the vector clock. Empty if the
+	// peer is a legacy node
+	Digest []byte
 }
 
 type SyncFSM map[SyncState]func(*TwoPhaseSyncer) ([]byte, bool)
 
-func hash(syncer *TwoPhaseSyncer) ([]byte, bool) {
-	hash := TwoPhaseHash{
-		Hash: syncer.manager.store.Clock.GetHash(),
+// encode: encode the message in the negotiated encoding
+func (t *TwoPhaseSyncer) encode(msg any) []byte {
+	if t.encoding == PROTOBUF_ENCODING {
+		var message proto.Message
+
+		switch value := msg.(type) {
+		case TwoPhaseHash:
+			message = &rpc.TwoPhaseHash{Hash: value.Hash, Digest: value.Digest}
+		case TwoPhaseMapState[string]:
+			message = stateToProto(&value)
+		case TwoPhaseMapSnapshot[string, MeshNode]:
+			message = snapshotToProto(&value)
+		default:
+			panic(fmt.Sprintf("twophasesyncer: cannot encode %T", msg))
+		}
+
+		bs, err := proto.Marshal(message)
+
+		if err != nil {
+			logging.Log.WriteErrorf(err.Error())
+		}
+
+		return bs
 	}
 
 	var buffer bytes.Buffer
 	enc := gob.NewEncoder(&buffer)
 
-	err := enc.Encode(hash)
+	err := enc.Encode(msg)
 
 	if err != nil {
 		logging.Log.WriteErrorf(err.Error())
 	}
 
+	return buffer.Bytes()
+}
+
+// decode: decode the message in the negotiated encoding
+func (t *TwoPhaseSyncer) decode(msg []byte, value any) error {
+	if t.encoding != PROTOBUF_ENCODING {
+		dec := gob.NewDecoder(bytes.NewBuffer(msg))
+		return dec.Decode(value)
+	}
+
+	switch value := value.(type) {
+	case *TwoPhaseHash:
+		var hash rpc.TwoPhaseHash
+
+		if err := proto.Unmarshal(msg, &hash); err != nil {
+			return err
+		}
+
+		value.Hash = hash.GetHash()
+		value.Digest = hash.GetDigest()
+	case *TwoPhaseMapState[string]:
+		var state rpc.TwoPhaseMapState
+
+		if err := proto.Unmarshal(msg, &state); err != nil {
+			return err
+		}
+
+		*value = *stateFromProto(&state)
+	case *TwoPhaseMapSnapshot[string, MeshNode]:
+		var snapshot rpc.TwoPhaseMapSnapshot
+
+		if err := proto.Unmarshal(msg, &snapshot); err != nil {
+			return err
+		}
+
+		*value = *snapshotFromProto(&snapshot)
+	default:
+		return fmt.Errorf("twophasesyncer: cannot decode %T", value)
+	}
+
+	return nil
+}
+
+func hash(syncer *TwoPhaseSyncer) ([]byte, bool) {
+	hash := TwoPhaseHash{
+		Hash:   syncer.manager.store.Clock.GetHash(),
+		Digest: syncer.manager.store.Clock.GetDigest(),
+	}
+
 	syncer.IncrementState()
-	return buffer.Bytes(), true
+	return syncer.encode(hash), true
 }
 
-func prepare(syncer *TwoPhaseSyncer) ([]byte, bool) {
-	var recvBuffer = bytes.NewBuffer(syncer.peerMsg)
-	dec := gob.NewDecoder(recvBuffer)
+// clocksEqual: returns true if the peer's vector clock is equal to
+// ours. Falls back to the legacy hash if the peer sent no digest
+func (t *TwoPhaseSyncer) clocksEqual() bool {
+	if len(t.peerHash.Digest) == 0 {
+		return t.peerHash.Hash == t.manager.store.Clock.GetHash()
+	}
 
-	var hash TwoPhaseHash
-	err := dec.Decode(&hash)
+	return bytes.Equal(t.peerHash.Digest, t.manager.store.Clock.GetDigest())
+}
 
-	if err != nil {
-		logging.Log.WriteErrorf(err.Error())
+func prepare(syncer *TwoPhaseSyncer) ([]byte, bool) {
+	if syncer.peerHash == nil {
+		panic("peer hash is nil")
 	}
 
 	// If vector clocks are equal then no need to merge state
 	// Helps to reduce bandwidth by detecting early
-	if hash.Hash == syncer.manager.store.Clock.GetHash() {
+	if syncer.clocksEqual() {
 		return nil, false
 	}
 
@@ -72,81 +166,42 @@ func prepare(syncer *TwoPhaseSyncer) ([]byte, bool) {
 	// distributed to everyone else in the mesh
 	syncer.manager.store.Clock.IncrementClock()
 
-	var buffer bytes.Buffer
-	enc := gob.NewEncoder(&buffer)
-
 	mapState := syncer.manager.store.GenerateMessage()
-
 	syncer.mapState = mapState
-	err = enc.Encode(*syncer.mapState)
-
-	if err != nil {
-		logging.Log.WriteErrorf(err.Error())
-	}
 
 	syncer.IncrementState()
-	return buffer.Bytes(), true
+	return syncer.encode(*syncer.mapState), true
 }
 
 func present(syncer *TwoPhaseSyncer) ([]byte, bool) {
-	if syncer.peerMsg == nil {
-		panic("peer msg is nil")
+	if syncer.peerState == nil {
+		panic("peer state is nil")
 	}
 
-	var recvBuffer = bytes.NewBuffer(syncer.peerMsg)
-	dec := gob.NewDecoder(recvBuffer)
-
-	var mapState TwoPhaseMapState[string]
-	err := dec.Decode(&mapState)
-
-	if err != nil {
-		logging.Log.WriteErrorf(err.Error())
-	}
-
-	difference := syncer.mapState.Difference(syncer.manager.store.Clock.GetStaleCount(), &mapState)
-	syncer.manager.store.Clock.Merge(mapState.Vectors)
-
-	var sendBuffer bytes.Buffer
-	enc := gob.NewEncoder(&sendBuffer)
-	enc.Encode(*difference)
+	difference := syncer.mapState.Difference(syncer.manager.store.Clock.GetStaleCount(), syncer.peerState)
+	syncer.manager.store.Clock.Merge(syncer.peerState.Vectors)
 
 	syncer.IncrementState()
-	return sendBuffer.Bytes(), true
+	return syncer.encode(*difference), true
 }
 
 func exchange(syncer *TwoPhaseSyncer) ([]byte, bool) {
-	if syncer.peerMsg == nil {
-		panic("peer msg is nil")
+	if syncer.peerDifference == nil {
+		panic("peer difference is nil")
 	}
 
-	var recvBuffer = bytes.NewBuffer(syncer.peerMsg)
-	dec := gob.NewDecoder(recvBuffer)
-
-	var mapState TwoPhaseMapState[string]
-	dec.Decode(&mapState)
-
-	snapshot := syncer.manager.store.SnapShotFromState(&mapState)
-
-	var sendBuffer bytes.Buffer
-	enc := gob.NewEncoder(&sendBuffer)
-	enc.Encode(*snapshot)
+	snapshot := syncer.manager.store.SnapShotFromState(syncer.peerDifference)
 
 	syncer.IncrementState()
-	return sendBuffer.Bytes(), true
+	return syncer.encode(*snapshot), true
 }
 
 func merge(syncer *TwoPhaseSyncer) ([]byte, bool) {
-	if syncer.peerMsg == nil {
-		panic("peer msg is nil")
+	if syncer.peerSnapshot == nil {
+		panic("peer snapshot is nil")
 	}
 
-	var recvBuffer = bytes.NewBuffer(syncer.peerMsg)
-	dec := gob.NewDecoder(recvBuffer)
-
-	var snapshot TwoPhaseMapSnapshot[string, MeshNode]
-	dec.Decode(&snapshot)
-
-	syncer.manager.store.Merge(snapshot)
+	syncer.manager.store.Merge(*syncer.peerSnapshot)
 	return nil, false
 }
 
@@ -164,8 +219,33 @@ func (t *TwoPhaseSyncer) GenerateMessage() ([]byte, bool) {
 	return fsmFunc(t)
 }
 
+// RecvMessage: decode the message the peer sent in its current state.
+// Returns an error if the message could not be decoded
 func (t *TwoPhaseSyncer) RecvMessage(msg []byte) error {
-	t.peerMsg = msg
+	var err error
+
+	switch t.recvState {
+	case HASH:
+		t.peerHash = &TwoPhaseHash{}
+		err = t.decode(msg, t.peerHash)
+	case PREPARE:
+		t.peerState = &TwoPhaseMapState[string]{}
+		err = t.decode(msg, t.peerState)
+	case PRESENT:
+		t.peerDifference = &TwoPhaseMapState[string]{}
+		err = t.decode(msg, t.peerDifference)
+	case EXCHANGE:
+		t.peerSnapshot = &TwoPhaseMapSnapshot[string, MeshNode]{}
+		err = t.decode(msg, t.peerSnapshot)
+	default:
+		return fmt.Errorf("twophasesyncer: unexpected message in state %d", t.recvState)
+	}
+
+	if err != nil {
+		return fmt.Errorf("twophasesyncer: could not decode message in state %d: %w", t.recvState, err)
+	}
+
+	t.recvState++
 	return nil
 }
 
@@ -173,6 +253,24 @@ func (t *TwoPhaseSyncer) Complete() {
 	logging.Log.WriteInfof("SYNC COMPLETED")
 }
 
+// Capabilities: the encodings the syncer supports in order of preference
+func (t *TwoPhaseSyncer) Capabilities() []string {
+	return []string{PROTOBUF_ENCODING, GOB_ENCODING}
+}
+
+// Negotiate: select the most preferred encoding that the peer
+// also supports
+func (t *TwoPhaseSyncer) Negotiate(capabilities []string) (string, error) {
+	for _, encoding := range t.Capabilities() {
+		if slices.Contains(capabilities, encoding) {
+			t.encoding = encoding
+			return encoding, nil
+		}
+	}
+
+	return "", fmt.Errorf("twophasesyncer: no common encoding with peer capabilities %v", capabilities)
+}
+
 func NewTwoPhaseSyncer(manager *TwoPhaseStoreMeshManager) *TwoPhaseSyncer {
 	var generateMessageFsm SyncFSM = SyncFSM{
 		HASH:     hash,
@@ -185,6 +283,8 @@ func NewTwoPhaseSyncer(manager *TwoPhaseStoreMeshManager) *TwoPhaseSyncer {
 	return &TwoPhaseSyncer{
 		manager:            manager,
 		state:              HASH,
+		recvState:          HASH,
+		encoding:           GOB_ENCODING,
 		generateMessageFSM: generateMessageFsm,
 	}
 }
@@ -0,0 +1,123 @@
+package crdt
+
+import (
+	"testing"
+)
+
+// syncManagers: run the sync protocol between the two syncers in the
+// order messages are exchanged over the stream
+func syncManagers(t *testing.T, client, server *TwoPhaseSyncer) {
+	for {
+		clientMsg, clientMore := client.GenerateMessage()
+		serverMsg, serverMore := server.GenerateMessage()
+
+		if len(clientMsg) != 0 {
+			if err := server.RecvMessage(clientMsg); err != nil {
+				t.Fatalf(`server recv error %s`, err.Error())
+			}
+		}
+
+		if len(serverMsg) != 0 {
+			if err := client.RecvMessage(serverMsg); err != nil {
+				t.Fatalf(`client recv error %s`, err.Error())
+			}
+		}
+
+		if !clientMore || !serverMore {
+			return
+		}
+	}
+}
+
+func TestNegotiateSelectsCommonEncoding(t *testing.T) {
+	testParams := setUpTests()
+	syncer := testParams.manager.GetSyncer()
+
+	encoding, err := syncer.Negotiate([]string{"encoding/unknown", GOB_ENCODING})
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if encoding != GOB_ENCODING {
+		t.Fatalf(`expected %s got %s`, GOB_ENCODING, encoding)
+	}
+}
+
+func TestNegotiateNoCommonEncodingReturnsError(t *testing.T) {
+	testParams := setUpTests()
+	syncer := testParams.manager.GetSyncer()
+
+	_, err := syncer.Negotiate([]string{"encoding/unknown"})
+
+	if err == nil {
+		t.Fatalf(`negotiate should fail with no common encoding`)
+	}
+}
+
+func TestRecvMessageCorruptMessageReturnsError(t *testing.T) {
+	testParams := setUpTests()
+	syncer := testParams.manager.GetSyncer()
+
+	err := syncer.RecvMessage([]byte("not a valid message"))
+
+	if err == nil {
+		t.Fatalf(`corrupt message should return an error`)
+	}
+}
+
+// testSyncMergesBothMeshes: sync two meshes with the given capabilities
+// and check both nodes exist in both meshes
+func testSyncMergesBothMeshes(t *testing.T, capabilities []string) {
+	testParams1 := setUpTestsWithNodeId("alice")
+	testParams2 := setUpTestsWithNodeId("bob")
+
+	node1 := getOurNode(testParams1)
+	node2 := getOurNode(testParams2)
+
+	testParams1.manager.AddNode(node1)
+	testParams2.manager.AddNode(node2)
+
+	client := testParams1.manager.GetSyncer().(*TwoPhaseSyncer)
+	server := testParams2.manager.GetSyncer().(*TwoPhaseSyncer)
+
+	if capabilities != nil {
+		encoding, err := server.Negotiate(capabilities)
+
+		if err != nil {
+			t.Fatalf(`%s`, err.Error())
+		}
+
+		client.Negotiate([]string{encoding})
+	}
+
+	syncManagers(t, client, server)
+
+	for _, manager := range []*TwoPhaseStoreMeshManager{
+		testParams1.manager.(*TwoPhaseStoreMeshManager),
+		testParams2.manager.(*TwoPhaseStoreMeshManager),
+	} {
+		if !manager.NodeExists(node1.PublicKey) || !manager.NodeExists(node2.PublicKey) {
+			t.Fatalf(`both nodes should exist after syncing`)
+		}
+	}
+}
+
+func TestSyncMergesBothMeshesLegacy(t *testing.T) {
+	testSyncMergesBothMeshes(t, nil)
+}
+
+func TestSyncMergesBothMeshesProtobuf(t *testing.T) {
+	testSyncMergesBothMeshes(t, []string{PROTOBUF_ENCODING, GOB_ENCODING})
+}
+
+func TestNegotiatePrefersProtobuf(t *testing.T) {
+	testParams := setUpTests()
+	syncer := testParams.manager.GetSyncer()
+
+	encoding, _ := syncer.Negotiate([]string{GOB_ENCODING, PROTOBUF_ENCODING})
+
+	if encoding != PROTOBUF_ENCODING {
+		t.Fatalf(`expected %s got %s`, PROTOBUF_ENCODING, encoding)
+	}
+}
@@ -212,3 +212,27 @@ func TestMergeMergesValuesThatAreGreaterThanCurrentClock(t *testing.T) {
 		t.Fatalf(`c should be in the map`)
 	}
 }
+
+func TestMergeOnlyNotifiesWhenTheMapChanges(t *testing.T) {
+	map1 := NewMap("a")
+	map2 := NewMap("b")
+	map2.Put("c", "meh")
+
+	updates := 0
+	map1.SetOnUpdate(func() {
+		updates++
+	})
+
+	map1.Merge(*map2.Snapshot())
+	map1.Merge(*map2.Snapshot())
+
+	if updates != 1 {
+		t.Fatalf(`expected 1 update got %d`, updates)
+	}
+
+	map1.Put("a", "ssms")
+
+	if updates != 2 {
+		t.Fatalf(`expected putting a value to notify`)
+	}
+}
@@ -2,6 +2,9 @@ package crdt
 
 import (
 	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
 	"sync"
 	"time"
 
@@ -51,6 +54,13 @@ func (m *VectorClock[K]) IncrementClock() uint64 {
 	return maxClock
 }
 
+// SetProcessId: increment the clock of the given process from now on
+func (m *VectorClock[K]) SetProcessId(processID K) {
+	m.lock.Lock()
+	m.processID = processID
+	m.lock.Unlock()
+}
+
 // GetHash: gets the hash of the vector clock used to determine if there
 // are any changes
 func (m *VectorClock[K]) GetHash() uint64 {
@@ -66,6 +76,27 @@ func (m *VectorClock[K]) GetHash() uint64 {
 	return hash
 }
 
+// GetDigest: gets the SHA-256 digest of the vector clock over the
+// entries sorted by key. Unlike GetHash distinct clocks do not collide
+func (m *VectorClock[K]) GetDigest() []byte {
+	m.lock.RLock()
+
+	keys := lib.MapKeys(m.vectors)
+	slices.Sort(keys)
+
+	digest := sha256.New()
+	entry := make([]byte, 16)
+
+	for _, key := range keys {
+		binary.BigEndian.PutUint64(entry[:8], key)
+		binary.BigEndian.PutUint64(entry[8:], m.vectors[key].clock)
+		digest.Write(entry)
+	}
+
+	m.lock.RUnlock()
+	return digest.Sum(nil)
+}
+
 // Merge: merge two clocks together
 func (m *VectorClock[K]) Merge(vectors map[uint64]uint64) {
 	for key, value := range vectors {
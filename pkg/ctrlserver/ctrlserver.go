@@ -1,18 +1,29 @@
 package ctrlserver
 
 import (
+	"crypto/x509"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/auth"
+	"github.com/tim-beatham/smegmesh/pkg/ca"
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	"github.com/tim-beatham/smegmesh/pkg/conn"
 	"github.com/tim-beatham/smegmesh/pkg/crdt"
+	smegdns "github.com/tim-beatham/smegmesh/pkg/dns"
+	"github.com/tim-beatham/smegmesh/pkg/events"
+	"github.com/tim-beatham/smegmesh/pkg/health"
+	"github.com/tim-beatham/smegmesh/pkg/history"
 	"github.com/tim-beatham/smegmesh/pkg/ip"
 	"github.com/tim-beatham/smegmesh/pkg/lib"
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
 	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/psk"
 	"github.com/tim-beatham/smegmesh/pkg/query"
 	"github.com/tim-beatham/smegmesh/pkg/rpc"
 	"github.com/tim-beatham/smegmesh/pkg/sync"
 	"github.com/tim-beatham/smegmesh/pkg/wg"
 	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
 // NewCtrlServerParams are the params required to create a new ctrl server
@@ -43,6 +54,51 @@ func NewCtrlServer(params *NewCtrlServerParams) (*MeshCtrlServer, error) {
 	configApplier := mesh.NewWgMeshConfigApplier()
 
 	var syncer sync.Syncer
+	var privateKey *wgtypes.Key
+
+	if params.Conf.WgPrivateKeyPath != "" {
+		key, err := wg.LoadOrCreatePrivateKey(params.Conf.WgPrivateKeyPath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		privateKey = key
+	}
+
+	var keyPair *conn.KeyPair
+	var err error
+
+	if params.Conf.RequestCertificates {
+		keyPair, err = conn.NewKeyPair(params.Conf.CertificatePath, params.Conf.PrivateKeyPath)
+	} else {
+		keyPair, err = conn.LoadKeyPair(params.Conf.CertificatePath, params.Conf.PrivateKeyPath)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// A certificate is yet to be requested if the key pair is empty
+	if params.Conf.BindCertificates && keyPair.Leaf() != nil {
+		err := conn.VerifyCertificateFile(params.Conf.CertificatePath, privateKey.PublicKey())
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if params.Conf.BindCertificates {
+		keyPair.SetVerifier(func(certificate *x509.Certificate) error {
+			return conn.VerifyCertificate(certificate, privateKey.PublicKey())
+		})
+	}
+
+	caPool, err := conn.LoadCaPool(params.Conf.CaCertificatePath)
+
+	if err != nil {
+		return nil, err
+	}
 
 	meshManagerParams := &mesh.NewMeshManagerParams{
 		Conf:                 *params.Conf,
@@ -53,6 +109,7 @@ func NewCtrlServer(params *NewCtrlServerParams) (*MeshCtrlServer, error) {
 		IPAllocator:          ipAllocator,
 		InterfaceManipulator: interfaceManipulator,
 		ConfigApplier:        configApplier,
+		PrivateKey:           privateKey,
 		OnDelete: func(mesh mesh.MeshProvider) {
 			_, err := syncer.Sync(mesh)
 
@@ -66,12 +123,19 @@ func NewCtrlServer(params *NewCtrlServerParams) (*MeshCtrlServer, error) {
 	configApplier.SetMeshManager(ctrlServer.MeshManager)
 
 	ctrlServer.Conf = params.Conf
+
+	// Evicted nodes are refused during the TLS handshake
+	refuseKey := auth.RefuseEvictedCertificates(ctrlServer.MeshManager)
+
 	connManagerParams := conn.NewConnectionManagerParams{
 		CertificatePath:      params.Conf.CertificatePath,
 		PrivateKey:           params.Conf.PrivateKeyPath,
 		SkipCertVerification: params.Conf.SkipCertVerification,
 		CaCert:               params.Conf.CaCertificatePath,
 		ConnFactory:          conn.NewWgCtrlConnection,
+		KeyPair:              keyPair,
+		CaPool:               caPool,
+		RefuseKey:            refuseKey,
 	}
 
 	connMgr, err := conn.NewConnectionManager(&connManagerParams)
@@ -85,6 +149,9 @@ func NewCtrlServer(params *NewCtrlServerParams) (*MeshCtrlServer, error) {
 		Conf:         params.Conf,
 		CtrlProvider: params.CtrlProvider,
 		SyncProvider: params.SyncProvider,
+		KeyPair:      keyPair,
+		CaPool:       caPool,
+		RefuseKey:    refuseKey,
 	}
 
 	connServer, err := conn.NewConnectionServer(&connServerParams)
@@ -93,10 +160,27 @@ func NewCtrlServer(params *NewCtrlServerParams) (*MeshCtrlServer, error) {
 		return nil, err
 	}
 
+	if params.Conf.HistoryPath != "" {
+		historyStore, err := history.NewFileStore(&history.FileStoreParams{
+			Path:            params.Conf.HistoryPath,
+			MaxEntries:      params.Conf.HistoryMaxEntries,
+			Retention:       time.Duration(params.Conf.HistoryRetention) * time.Second,
+			CompactAfter:    time.Duration(params.Conf.HistoryCompactAfter) * time.Second,
+			CompactInterval: time.Duration(params.Conf.HistoryCompactInterval) * time.Second,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		ctrlServer.History = historyStore
+	}
+
 	syncer = sync.NewSyncer(&sync.NewSyncerParams{
 		MeshManager:       ctrlServer.MeshManager,
 		ConnectionManager: ctrlServer.ConnectionManager,
 		Configuration:     params.Conf,
+		History:           ctrlServer.History,
 	})
 
 	// Check any syncs every 1 second
@@ -115,7 +199,147 @@ func NewCtrlServer(params *NewCtrlServerParams) (*MeshCtrlServer, error) {
 		return ctrlServer.MeshManager.UpdateTimeStamp()
 	}, params.Conf.Heartbeat)
 
-	ctrlServer.timers = append(ctrlServer.timers, syncTimer, heartbeatTimer)
+	// Pick up certificates replaced on disk and redial peers so that
+	// existing connections present them
+	watcher := conn.NewWatcher()
+	watcher.Watch(keyPair.Reload, params.Conf.CertificatePath, params.Conf.PrivateKeyPath)
+	watcher.Watch(caPool.Load, params.Conf.CaCertificatePath)
+
+	reloadTimer := lib.NewTimer(func() error {
+		if watcher.Poll() {
+			return ctrlServer.ConnectionManager.Redial()
+		}
+
+		return nil
+	}, params.Conf.CertificateReloadInterval)
+
+	ctrlServer.timers = append(ctrlServer.timers, syncTimer, heartbeatTimer, reloadTimer)
+
+	if params.Conf.RequestCertificates {
+		ctrlServer.Enroller = ca.NewEnroller(&ca.NewEnrollerParams{
+			KeyPair:           keyPair,
+			ConnectionManager: ctrlServer.ConnectionManager,
+			MeshManager:       ctrlServer.MeshManager,
+			Conf:              params.Conf,
+		})
+
+		renewTimer := lib.NewTimer(func() error {
+			if err := ctrlServer.Enroller.Renew(); err != nil {
+				logging.Log.WriteErrorf(err.Error())
+			}
+
+			return nil
+		}, params.Conf.Heartbeat)
+
+		ctrlServer.timers = append(ctrlServer.timers, renewTimer)
+	}
+
+	exchanger := psk.NewExchanger(&psk.NewExchangerParams{
+		ConnectionManager: ctrlServer.ConnectionManager,
+		MeshManager:       ctrlServer.MeshManager,
+		Conf:              params.Conf,
+	})
+
+	// Agree pre-shared keys with nodes soon after they join
+	exchangeTimer := lib.NewTimer(func() error {
+		if err := exchanger.Exchange(); err != nil {
+			logging.Log.WriteErrorf(err.Error())
+		}
+
+		return nil
+	}, params.Conf.SyncInterval)
+
+	ctrlServer.timers = append(ctrlServer.timers, exchangeTimer)
+
+	monitor := health.NewMonitor(&health.NewMonitorParams{
+		MeshManager: ctrlServer.MeshManager,
+		Conf:        params.Conf,
+	})
+
+	// Check each service at the interval of its most frequent check
+	for _, service := range params.Conf.Services {
+		name := service.Name
+
+		checkTimer := lib.NewTimer(func() error {
+			if err := monitor.Check(name); err != nil {
+				logging.Log.WriteErrorf("could not publish service %s: %s", name, err.Error())
+			}
+
+			return nil
+		}, service.Interval())
+
+		ctrlServer.timers = append(ctrlServer.timers, checkTimer)
+	}
+
+	ctrlServer.Events = events.NewLog()
+	aliasMonitor := mesh.NewAliasMonitor(&mesh.NewAliasMonitorParams{
+		MeshManager: ctrlServer.MeshManager,
+		Events:      ctrlServer.Events,
+	})
+
+	// Notice nodes claiming the same alias once their changes merge
+	aliasTimer := lib.NewTimer(func() error {
+		if err := aliasMonitor.Check(); err != nil {
+			logging.Log.WriteErrorf(err.Error())
+		}
+
+		return nil
+	}, params.Conf.SyncInterval)
+
+	ctrlServer.timers = append(ctrlServer.timers, aliasTimer)
+
+	if params.Conf.Dns != nil {
+		store := smegdns.NewMeshStore(ctrlServer.MeshManager)
+		dnsServer, err := smegdns.NewServer(&smegdns.NewServerParams{
+			Conf:       params.Conf.Dns,
+			Lookup:     store.Lookup,
+			ListMeshes: store.ListMeshes,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		ctrlServer.Dns = dnsServer
+
+		// Serve on localhost and on the node's address in each mesh
+		// once it has been added to the mesh
+		bindTimer := lib.NewTimer(func() error {
+			addresses := append([]string{"127.0.0.1", "::1"}, store.Addresses()...)
+
+			if err := dnsServer.Bind(addresses...); err != nil {
+				logging.Log.WriteWarnf(err.Error())
+			}
+
+			return nil
+		}, params.Conf.SyncInterval)
+
+		ctrlServer.timers = append(ctrlServer.timers, bindTimer)
+	}
+
+	if params.Conf.DnsHook != "" {
+		var zone string
+
+		if params.Conf.Dns != nil {
+			zone = params.Conf.Dns.Zone
+		}
+
+		hook := smegdns.NewSplitDnsHook(&smegdns.NewSplitDnsHookParams{
+			MeshManager: ctrlServer.MeshManager,
+			Hook:        params.Conf.DnsHook,
+			Zone:        zone,
+		})
+
+		hookTimer := lib.NewTimer(func() error {
+			if err := hook.Apply(); err != nil {
+				logging.Log.WriteErrorf(err.Error())
+			}
+
+			return nil
+		}, params.Conf.SyncInterval)
+
+		ctrlServer.timers = append(ctrlServer.timers, hookTimer)
+	}
 
 	ctrlServer.Querier = query.NewJmesQuerier(ctrlServer.MeshManager)
 	ctrlServer.ConnectionServer = connServer
@@ -147,6 +371,23 @@ func (s *MeshCtrlServer) GetConnectionManager() conn.ConnectionManager {
 	return s.ConnectionManager
 }
 
+// GetHistory: returns the history of each mesh. Nil if history
+// is not recorded
+func (s *MeshCtrlServer) GetHistory() history.Store {
+	return s.History
+}
+
+// GetEnroller: returns the enroller that obtains the node's
+// certificate. Nil if the node does not request certificates
+func (s *MeshCtrlServer) GetEnroller() *ca.Enroller {
+	return s.Enroller
+}
+
+// GetEvents: the events the daemon has observed in its meshes
+func (s *MeshCtrlServer) GetEvents() *events.Log {
+	return s.Events
+}
+
 // Close closes the ctrl server tearing down any connections that exist
 func (s *MeshCtrlServer) Close() error {
 	if err := s.ConnectionManager.Close(); err != nil {
@@ -161,6 +402,12 @@ func (s *MeshCtrlServer) Close() error {
 		logging.Log.WriteErrorf(err.Error())
 	}
 
+	if s.Dns != nil {
+		if err := s.Dns.Close(); err != nil {
+			logging.Log.WriteErrorf(err.Error())
+		}
+	}
+
 	for _, timer := range s.timers {
 		err := timer.Stop()
 
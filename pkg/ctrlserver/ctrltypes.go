@@ -4,8 +4,12 @@ import (
 	"net"
 	"time"
 
+	"github.com/tim-beatham/smegmesh/pkg/ca"
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	"github.com/tim-beatham/smegmesh/pkg/conn"
+	smegdns "github.com/tim-beatham/smegmesh/pkg/dns"
+	"github.com/tim-beatham/smegmesh/pkg/events"
+	"github.com/tim-beatham/smegmesh/pkg/history"
 	"github.com/tim-beatham/smegmesh/pkg/lib"
 	"github.com/tim-beatham/smegmesh/pkg/mesh"
 	"github.com/tim-beatham/smegmesh/pkg/query"
@@ -40,7 +44,11 @@ type MeshNode struct {
 	Description  string
 	Alias        string
 	Services     map[string]string
-	Stats        WireGuardStats
+	Labels       map[string]string
+	// ServiceRecords: services the node offers that are discoverable
+	ServiceRecords []mesh.Service
+	Stats          WireGuardStats
+	Version        string
 }
 
 // Mesh: Represents a WireGuard Mesh network that can be sent
@@ -57,6 +65,9 @@ type CtrlServer interface {
 	GetMeshManager() mesh.MeshManager
 	Close() error
 	GetConnectionManager() conn.ConnectionManager
+	GetHistory() history.Store
+	GetEnroller() *ca.Enroller
+	GetEvents() *events.Log
 }
 
 // MeshCtrlServer: Represents a ctrlserver to be used in WireGuard
@@ -67,6 +78,10 @@ type MeshCtrlServer struct {
 	ConnectionServer  *conn.ConnectionServer
 	Conf              *conf.DaemonConfiguration
 	Querier           query.Querier
+	History           history.Store
+	Enroller          *ca.Enroller
+	Dns               *smegdns.Server
+	Events            *events.Log
 	timers            []*lib.Timer
 }
 
@@ -87,9 +102,12 @@ func NewCtrlNode(provider mesh.MeshProvider, node mesh.MeshNode) *MeshNode {
 				Path:        r.GetPath(),
 			}
 		}),
-		Description: node.GetDescription(),
-		Alias:       node.GetAlias(),
-		Services:    node.GetServices(),
+		Description:    node.GetDescription(),
+		Alias:          node.GetAlias(),
+		Services:       node.GetServices(),
+		Labels:         node.GetLabels(),
+		ServiceRecords: mesh.DiscoverableServices(node, time.Now()),
+		Version:        node.GetVersion(),
 	}
 
 	device, err := provider.GetDevice()
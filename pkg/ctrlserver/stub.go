@@ -1,8 +1,11 @@
 package ctrlserver
 
 import (
+	"github.com/tim-beatham/smegmesh/pkg/ca"
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	"github.com/tim-beatham/smegmesh/pkg/conn"
+	"github.com/tim-beatham/smegmesh/pkg/events"
+	"github.com/tim-beatham/smegmesh/pkg/history"
 	"github.com/tim-beatham/smegmesh/pkg/mesh"
 	"github.com/tim-beatham/smegmesh/pkg/query"
 	"golang.zx2c4.com/wireguard/wgctrl"
@@ -12,6 +15,7 @@ type CtrlServerStub struct {
 	manager           mesh.MeshManager
 	querier           query.Querier
 	connectionManager conn.ConnectionManager
+	events            *events.Log
 }
 
 func NewCtrlServerStub() *CtrlServerStub {
@@ -20,6 +24,7 @@ func NewCtrlServerStub() *CtrlServerStub {
 		manager:           manager,
 		querier:           query.NewJmesQuerier(manager),
 		connectionManager: &conn.ConnectionManagerStub{},
+		events:            events.NewLog(),
 	}
 }
 
@@ -49,3 +54,15 @@ func (c *CtrlServerStub) Close() error {
 func (c *CtrlServerStub) GetConnectionManager() conn.ConnectionManager {
 	return c.connectionManager
 }
+
+func (c *CtrlServerStub) GetHistory() history.Store {
+	return nil
+}
+
+func (c *CtrlServerStub) GetEnroller() *ca.Enroller {
+	return nil
+}
+
+func (c *CtrlServerStub) GetEvents() *events.Log {
+	return c.events
+}
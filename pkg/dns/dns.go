@@ -1,114 +1,228 @@
-// smegdns: example of how to implement dns in the mesh
+// smegdns: answers DNS queries for the nodes and services in the mesh
 package smegdns
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"sync"
 
 	"github.com/miekg/dns"
-	"github.com/tim-beatham/smegmesh/pkg/ipc"
-	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"github.com/tim-beatham/smegmesh/pkg/conf"
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
-	"github.com/tim-beatham/smegmesh/pkg/query"
+	"github.com/tim-beatham/smegmesh/pkg/what8words"
 )
 
-const MeshRegularExpression = `(?P<meshId>.+)\.(?P<alias>.+)\.smeg\.`
+// Server: serves DNS over UDP and TCP at a set of addresses. Questions
+// in the mesh zones are answered by the resolver and the rest are
+// forwarded to the upstreams
+type Server struct {
+	resolver  *Resolver
+	forwarder *Forwarder
+	port      int
+	mux       *dns.ServeMux
+	// lock: guards listeners
+	lock sync.Mutex
+	// listeners: the UDP and TCP servers listening at each address
+	listeners map[string][]*dns.Server
+}
+
+// NewServerParams: params to create a new DNS server
+type NewServerParams struct {
+	Conf *conf.DnsConfiguration
+	// Lookup: finds the nodes of a mesh
+	Lookup Lookup
+	// ListMeshes: lists the meshes the node has joined
+	ListMeshes ListMeshes
+}
+
+// maxSize: maximum size of a response to the request over the network
+func maxSize(w dns.ResponseWriter, r *dns.Msg) int {
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		return dns.MaxMsgSize
+	}
+
+	if opt := r.IsEdns0(); opt != nil {
+		return int(opt.UDPSize())
+	}
 
-type DNSHandler struct {
-	client *ipc.SmegmeshIpc
-	server *dns.Server
+	return dns.MinMsgSize
 }
 
-// queryMesh: queries the mesh network for the given meshId and node
-// with alias
-func (d *DNSHandler) queryMesh(meshId, alias string) net.IP {
-	var reply string
+// handleQuery: handles a DNS query. Questions outside the mesh zones
+// are forwarded to the upstreams
+func (s *Server) handleQuery(r *dns.Msg) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetReply(r)
+
+	if len(r.Question) != 1 {
+		msg.Rcode = dns.RcodeFormatError
+		return msg
+	}
+
+	q := r.Question[0]
+	logging.Log.WriteInfof("Query for %s %s", dns.TypeToString[q.Qtype], q.Name)
+
+	if s.resolver.Resolve(msg, q) {
+		return msg
+	}
+
+	response, err := s.forwarder.Forward(r)
 
-	err := d.client.Query(ipc.QueryMesh{
-		MeshId: meshId,
-		Query:  fmt.Sprintf("[?alias == '%s'] | [0]", alias),
-	}, &reply)
+	if errors.Is(err, ErrNoUpstreams) {
+		msg.Rcode = dns.RcodeRefused
+		return msg
+	}
 
 	if err != nil {
-		return nil
+		logging.Log.WriteErrorf("failed to forward %s: %s", q.Name, err.Error())
+		msg.Rcode = dns.RcodeServerFailure
+		return msg
 	}
 
-	var node *query.QueryNode
+	return response
+}
 
-	err = json.Unmarshal([]byte(reply), &node)
+// handleDnsRequest: handle a DNS request
+func (s *Server) handleDnsRequest(w dns.ResponseWriter, r *dns.Msg) {
+	var msg *dns.Msg
 
-	if err != nil || node == nil {
-		return nil
+	switch r.Opcode {
+	case dns.OpcodeQuery:
+		msg = s.handleQuery(r)
+	default:
+		msg = new(dns.Msg)
+		msg.SetRcode(r, dns.RcodeNotImplemented)
 	}
 
-	ip, _, _ := net.ParseCIDR(node.WgHost)
-	return ip
+	msg.Truncate(maxSize(w, r))
+	w.WriteMsg(msg)
 }
 
-// handleQuery: handles a DNS query
-func (d *DNSHandler) handleQuery(m *dns.Msg) {
-	for _, q := range m.Question {
-		switch q.Qtype {
-		case dns.TypeAAAA:
-			logging.Log.WriteInfof("Query for %s", q.Name)
+// listen: serve DNS over UDP and TCP at the address
+func (s *Server) listen(address string) ([]*dns.Server, error) {
+	hostPort := net.JoinHostPort(address, strconv.Itoa(s.port))
+	packetConn, err := net.ListenPacket("udp", hostPort)
 
-			groups := lib.MatchCaptureGroup(MeshRegularExpression, q.Name)
+	if err != nil {
+		return nil, err
+	}
 
-			if len(groups) == 0 {
-				continue
-			}
+	listener, err := net.Listen("tcp", hostPort)
 
-			ip := d.queryMesh(groups["meshId"], groups["alias"])
+	if err != nil {
+		packetConn.Close()
+		return nil, err
+	}
 
-			rr, err := dns.NewRR(fmt.Sprintf("%s AAAA %s", q.Name, ip))
+	servers := []*dns.Server{
+		{PacketConn: packetConn, Handler: s.mux},
+		{Listener: listener, Handler: s.mux},
+	}
 
-			if err != nil {
-				logging.Log.WriteErrorf(err.Error())
+	for _, server := range servers {
+		go func(server *dns.Server) {
+			if err := server.ActivateAndServe(); err != nil {
+				logging.Log.WriteErrorf("stopped serving DNS on %s: %s", hostPort, err.Error())
 			}
+		}(server)
+	}
 
-			if err == nil {
-				m.Answer = append(m.Answer, rr)
-			}
+	return servers, nil
+}
+
+// stop: stop the servers listening at an address
+func stop(servers []*dns.Server) {
+	for _, server := range servers {
+		if server.Shutdown() == nil {
+			continue
+		}
+
+		// The server has not started serving yet
+		if server.PacketConn != nil {
+			server.PacketConn.Close()
+		}
+
+		if server.Listener != nil {
+			server.Listener.Close()
 		}
 	}
 }
 
-// handleDNS query: handle a DNS request
-func (h *DNSHandler) handleDnsRequest(w dns.ResponseWriter, r *dns.Msg) {
-	msg := new(dns.Msg)
-	msg.SetReply(r)
-	msg.Authoritative = true
+// Bind: serve DNS at exactly the given addresses. Starts listening at
+// addresses that are new and stops at addresses that are no longer
+// given. The empty address listens on every address
+func (s *Server) Bind(addresses ...string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
-	switch r.Opcode {
-	case dns.OpcodeQuery:
-		h.handleQuery(msg)
+	wanted := make(map[string]bool)
+	var errs []error
+
+	for _, address := range addresses {
+		wanted[address] = true
+
+		if _, ok := s.listeners[address]; ok {
+			continue
+		}
+
+		servers, err := s.listen(address)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not serve DNS on %s: %w", address, err))
+			continue
+		}
+
+		logging.Log.WriteInfof("serving DNS on %s", net.JoinHostPort(address, strconv.Itoa(s.port)))
+		s.listeners[address] = servers
 	}
 
-	w.WriteMsg(msg)
-}
+	for address, servers := range s.listeners {
+		if !wanted[address] {
+			stop(servers)
+			delete(s.listeners, address)
+		}
+	}
 
-func (h *DNSHandler) Listen() error {
-	return h.server.ListenAndServe()
+	return errors.Join(errs...)
 }
 
-func (h *DNSHandler) Close() error {
-	return h.server.Shutdown()
+// Close: stop serving DNS at every address
+func (s *Server) Close() error {
+	return s.Bind()
 }
 
-func NewDns(udpPort int) (*DNSHandler, error) {
-	client, err := ipc.NewClientIpc()
+// NewServer: create a DNS server with the given configuration. The
+// server does not listen until it is bound to addresses
+func NewServer(params *NewServerParams) (*Server, error) {
+	config := params.Conf
+	var words *what8words.What8Words
+	var err error
 
-	if err != nil {
-		return nil, err
-	}
+	if config.WordsFile != "" {
+		words, err = what8words.NewWhat8Words(config.WordsFile)
 
-	dnsHander := DNSHandler{
-		client: client,
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	dns.HandleFunc("smeg.", dnsHander.handleDnsRequest)
+	server := &Server{
+		resolver: NewResolver(&NewResolverParams{
+			Lookup:      params.Lookup,
+			ListMeshes:  params.ListMeshes,
+			Words:       words,
+			Zone:        config.Zone,
+			RecordTtl:   config.RecordTtl,
+			NegativeTtl: config.NegativeTtl,
+		}),
+		forwarder: NewForwarder(config.Upstreams, NewCache(config.CacheSize, uint32(config.NegativeTtl))),
+		port:      config.Port,
+		mux:       dns.NewServeMux(),
+		listeners: make(map[string][]*dns.Server),
+	}
 
-	dnsHander.server = &dns.Server{Addr: fmt.Sprintf(":%d", udpPort), Net: "udp"}
-	return &dnsHander, nil
+	server.mux.HandleFunc(".", server.handleDnsRequest)
+	return server, nil
 }
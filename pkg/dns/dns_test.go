@@ -0,0 +1,89 @@
+package smegdns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/query"
+)
+
+// freePort: a port that is free over UDP and TCP on localhost
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestServerAnswersOverUDPAndTCP(t *testing.T) {
+	config := &conf.DnsConfiguration{Port: freePort(t)}
+	conf.ValidateDnsConfiguration(config)
+
+	server, err := NewServer(&NewServerParams{
+		Conf: config,
+		Lookup: func(meshId string) ([]query.QueryNode, error) {
+			return nil, ErrMeshNotFound
+		},
+		ListMeshes: func() ([]string, error) {
+			return nil, nil
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := server.Bind("127.0.0.1"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer server.Close()
+
+	address := (&NameServer{Address: "127.0.0.1", Port: config.Port}).Endpoint()
+
+	for _, network := range []string{"udp", "tcp"} {
+		request := new(dns.Msg)
+		request.SetQuestion("smeg.", dns.TypeSOA)
+
+		client := dns.Client{Net: network}
+		response, _, err := client.Exchange(request, address)
+
+		if err != nil {
+			t.Fatalf(`%s: %s`, network, err.Error())
+		}
+
+		if len(response.Answer) != 1 || !response.Authoritative {
+			t.Fatalf(`%s: expected the SOA got %s`, network, response)
+		}
+	}
+}
+
+func TestServerBindStopsRemovedAddresses(t *testing.T) {
+	config := &conf.DnsConfiguration{Port: freePort(t)}
+	conf.ValidateDnsConfiguration(config)
+
+	server, _ := NewServer(&NewServerParams{Conf: config})
+
+	if err := server.Bind("127.0.0.1"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	server.Bind()
+
+	if len(server.listeners) != 0 {
+		t.Fatalf(`expected the server to stop listening`)
+	}
+
+	// The address can be bound again once released
+	if err := server.Bind("127.0.0.1"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	server.Close()
+}
@@ -0,0 +1,236 @@
+package smegdns
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// FORWARD_TIMEOUT: time to wait for an upstream to answer
+const FORWARD_TIMEOUT = 2 * time.Second
+
+// ErrNoUpstreams: returned if there are no upstreams to forward to
+var ErrNoUpstreams = errors.New("no upstream resolvers")
+
+// cacheKey: identifies the question a response answers
+type cacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+// cacheEntry: a cached response and when it expires
+type cacheEntry struct {
+	response *dns.Msg
+	stored   time.Time
+	expires  time.Time
+}
+
+// Cache: caches responses from upstreams for the TTL of their records.
+// NXDOMAIN and NODATA responses are cached for the minimum TTL of the
+// SOA in their authority section
+type Cache struct {
+	lock    sync.Mutex
+	size    int
+	entries map[cacheKey]cacheEntry
+	// negativeTtl: TTL of negative responses without an SOA
+	negativeTtl uint32
+	now         func() time.Time
+}
+
+// NewCache: create a cache holding at most size responses
+func NewCache(size int, negativeTtl uint32) *Cache {
+	return &Cache{
+		size:        size,
+		entries:     make(map[cacheKey]cacheEntry),
+		negativeTtl: negativeTtl,
+		now:         time.Now,
+	}
+}
+
+// key: the key of the question
+func key(q dns.Question) cacheKey {
+	return cacheKey{name: strings.ToLower(q.Name), qtype: q.Qtype, qclass: q.Qclass}
+}
+
+// ttl: number of seconds the response can be cached for. Returns false
+// if the response must not be cached
+func (c *Cache) ttl(response *dns.Msg) (uint32, bool) {
+	if response.Truncated {
+		return 0, false
+	}
+
+	switch response.Rcode {
+	case dns.RcodeSuccess:
+		if len(response.Answer) != 0 {
+			return minTtl(response.Answer), true
+		}
+	case dns.RcodeNameError:
+	default:
+		return 0, false
+	}
+
+	for _, rr := range response.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return min(soa.Hdr.Ttl, soa.Minttl), true
+		}
+	}
+
+	return c.negativeTtl, true
+}
+
+// minTtl: the smallest TTL of the records
+func minTtl(records []dns.RR) uint32 {
+	ttl := records[0].Header().Ttl
+
+	for _, rr := range records[1:] {
+		ttl = min(ttl, rr.Header().Ttl)
+	}
+
+	return ttl
+}
+
+// evict: make room for a response. Removes expired responses and an
+// arbitrary response if none have expired
+func (c *Cache) evict(now time.Time) {
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	for k := range c.entries {
+		if len(c.entries) < c.size {
+			return
+		}
+
+		delete(c.entries, k)
+	}
+}
+
+// Put: cache the response to the question
+func (c *Cache) Put(q dns.Question, response *dns.Msg) {
+	ttl, ok := c.ttl(response)
+
+	if !ok || ttl == 0 || c.size == 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.now()
+
+	if len(c.entries) >= c.size {
+		c.evict(now)
+	}
+
+	c.entries[key(q)] = cacheEntry{
+		response: response.Copy(),
+		stored:   now,
+		expires:  now.Add(time.Duration(ttl) * time.Second),
+	}
+}
+
+// Get: the cached response to the question with its TTLs reduced by
+// the time it has been cached for
+func (c *Cache) Get(q dns.Question) (*dns.Msg, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.now()
+	entry, ok := c.entries[key(q)]
+
+	if !ok {
+		return nil, false
+	}
+
+	if !now.Before(entry.expires) {
+		delete(c.entries, key(q))
+		return nil, false
+	}
+
+	response := entry.response.Copy()
+	elapsed := uint32(now.Sub(entry.stored) / time.Second)
+
+	for _, section := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
+		for _, rr := range section {
+			header := rr.Header()
+
+			// The TTL of the OPT record holds flags
+			if header.Rrtype == dns.TypeOPT {
+				continue
+			}
+
+			header.Ttl -= min(header.Ttl, elapsed)
+		}
+	}
+
+	return response, true
+}
+
+// Forwarder: forwards questions outside the mesh zones to upstream
+// resolvers and caches the responses
+type Forwarder struct {
+	upstreams []string
+	cache     *Cache
+	exchange  func(request *dns.Msg, upstream string) (*dns.Msg, error)
+}
+
+// NewForwarder: create a forwarder to the upstreams
+func NewForwarder(upstreams []string, cache *Cache) *Forwarder {
+	return &Forwarder{
+		upstreams: upstreams,
+		cache:     cache,
+		exchange:  exchange,
+	}
+}
+
+// exchange: send the request to the upstream over UDP and retry over
+// TCP if the response is truncated
+func exchange(request *dns.Msg, upstream string) (*dns.Msg, error) {
+	client := dns.Client{Net: "udp", Timeout: FORWARD_TIMEOUT}
+	response, _, err := client.Exchange(request, upstream)
+
+	if err == nil && response.Truncated {
+		client.Net = "tcp"
+		response, _, err = client.Exchange(request, upstream)
+	}
+
+	return response, err
+}
+
+// Forward: answer the request from the cache or the first upstream
+// that responds
+func (f *Forwarder) Forward(request *dns.Msg) (*dns.Msg, error) {
+	if len(f.upstreams) == 0 {
+		return nil, ErrNoUpstreams
+	}
+
+	q := request.Question[0]
+
+	if response, ok := f.cache.Get(q); ok {
+		response.Id = request.Id
+		return response, nil
+	}
+
+	var errs []error
+
+	for _, upstream := range f.upstreams {
+		response, err := f.exchange(request, upstream)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", upstream, err))
+			continue
+		}
+
+		f.cache.Put(q, response)
+		return response, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
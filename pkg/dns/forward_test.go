@@ -0,0 +1,161 @@
+package smegdns
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func answer(request *dns.Msg, ttl uint32) *dns.Msg {
+	response := new(dns.Msg)
+	response.SetReply(request)
+
+	rr, _ := dns.NewRR(request.Question[0].Name + " A 192.0.2.1")
+	rr.Header().Ttl = ttl
+	response.Answer = append(response.Answer, rr)
+	return response
+}
+
+func nxdomain(request *dns.Msg, minTtl uint32) *dns.Msg {
+	response := new(dns.Msg)
+	response.SetRcode(request, dns.RcodeNameError)
+	response.Ns = append(response.Ns, &dns.SOA{
+		Hdr:    dns.RR_Header{Name: "com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+		Minttl: minTtl,
+	})
+	return response
+}
+
+func setUpForwarder(respond func(request *dns.Msg) *dns.Msg) (*Forwarder, *time.Time, *int) {
+	now := time.Unix(1000, 0)
+	exchanges := 0
+
+	cache := NewCache(10, DEFAULT_NEGATIVE_TTL)
+	cache.now = func() time.Time { return now }
+
+	forwarder := NewForwarder([]string{"192.0.2.53:53"}, cache)
+	forwarder.exchange = func(request *dns.Msg, upstream string) (*dns.Msg, error) {
+		exchanges++
+		return respond(request), nil
+	}
+
+	return forwarder, &now, &exchanges
+}
+
+func request(name string) *dns.Msg {
+	request := new(dns.Msg)
+	request.SetQuestion(name, dns.TypeA)
+	return request
+}
+
+func TestForwardCachesAnswers(t *testing.T) {
+	forwarder, now, exchanges := setUpForwarder(func(request *dns.Msg) *dns.Msg {
+		return answer(request, 60)
+	})
+
+	forwarder.Forward(request("example.com."))
+	*now = now.Add(10 * time.Second)
+	response, err := forwarder.Forward(request("example.com."))
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if *exchanges != 1 {
+		t.Fatalf(`expected the answer to be cached`)
+	}
+
+	if response.Answer[0].Header().Ttl != 50 {
+		t.Fatalf(`expected the TTL to be reduced to 50 got %d`, response.Answer[0].Header().Ttl)
+	}
+}
+
+func TestForwardCacheExpires(t *testing.T) {
+	forwarder, now, exchanges := setUpForwarder(func(request *dns.Msg) *dns.Msg {
+		return answer(request, 60)
+	})
+
+	forwarder.Forward(request("example.com."))
+	*now = now.Add(61 * time.Second)
+	forwarder.Forward(request("example.com."))
+
+	if *exchanges != 2 {
+		t.Fatalf(`expected the answer to expire`)
+	}
+}
+
+func TestForwardCachesNegativeResponses(t *testing.T) {
+	forwarder, now, exchanges := setUpForwarder(func(request *dns.Msg) *dns.Msg {
+		return nxdomain(request, 5)
+	})
+
+	forwarder.Forward(request("missing.com."))
+	*now = now.Add(4 * time.Second)
+	response, _ := forwarder.Forward(request("missing.com."))
+
+	if *exchanges != 1 || response.Rcode != dns.RcodeNameError {
+		t.Fatalf(`expected NXDOMAIN to be cached`)
+	}
+
+	*now = now.Add(2 * time.Second)
+	forwarder.Forward(request("missing.com."))
+
+	if *exchanges != 2 {
+		t.Fatalf(`expected NXDOMAIN to be cached for the SOA minimum`)
+	}
+}
+
+func TestForwardDoesNotCacheServFail(t *testing.T) {
+	forwarder, _, exchanges := setUpForwarder(func(request *dns.Msg) *dns.Msg {
+		response := new(dns.Msg)
+		response.SetRcode(request, dns.RcodeServerFailure)
+		return response
+	})
+
+	forwarder.Forward(request("example.com."))
+	forwarder.Forward(request("example.com."))
+
+	if *exchanges != 2 {
+		t.Fatalf(`expected SERVFAIL not to be cached`)
+	}
+}
+
+func TestForwardTriesNextUpstream(t *testing.T) {
+	forwarder, _, _ := setUpForwarder(nil)
+	forwarder.upstreams = []string{"192.0.2.53:53", "192.0.2.54:53"}
+	forwarder.exchange = func(request *dns.Msg, upstream string) (*dns.Msg, error) {
+		if upstream == "192.0.2.53:53" {
+			return nil, errors.New("timeout")
+		}
+
+		return answer(request, 60), nil
+	}
+
+	response, err := forwarder.Forward(request("example.com."))
+
+	if err != nil || len(response.Answer) != 1 {
+		t.Fatalf(`expected the second upstream to answer`)
+	}
+}
+
+func TestForwardWithoutUpstreams(t *testing.T) {
+	forwarder := NewForwarder(nil, NewCache(10, DEFAULT_NEGATIVE_TTL))
+
+	if _, err := forwarder.Forward(request("example.com.")); !errors.Is(err, ErrNoUpstreams) {
+		t.Fatalf(`expected ErrNoUpstreams`)
+	}
+}
+
+func TestCacheEvictsWhenFull(t *testing.T) {
+	cache := NewCache(2, DEFAULT_NEGATIVE_TTL)
+
+	for _, name := range []string{"a.com.", "b.com.", "c.com."} {
+		cache.Put(request(name).Question[0], answer(request(name), 60))
+	}
+
+	if len(cache.entries) != 2 {
+		t.Fatalf(`expected the cache to hold 2 responses got %d`, len(cache.entries))
+	}
+}
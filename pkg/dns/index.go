@@ -0,0 +1,91 @@
+package smegdns
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"github.com/tim-beatham/smegmesh/pkg/query"
+)
+
+// ErrIndexNotReady: returned by the index before it has received the
+// nodes from the daemon
+var ErrIndexNotReady = errors.New("index has not received the nodes")
+
+// Index: in-memory copy of the nodes in every mesh that answers the
+// resolver's lookups without calling the daemon
+type Index struct {
+	lock    sync.RWMutex
+	ready   bool
+	version string
+	meshes  map[string][]query.QueryNode
+	// names: the id of each named mesh keyed by its lowercased name
+	names map[string]string
+}
+
+// NewIndex: create an empty index
+func NewIndex() *Index {
+	return &Index{meshes: make(map[string][]query.QueryNode), names: make(map[string]string)}
+}
+
+// Update: replace the nodes and mesh names in the index. Returns false
+// if the index already has the version
+func (i *Index) Update(version string, meshes map[string][]query.QueryNode, names map[string]string) bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.ready && i.version == version {
+		return false
+	}
+
+	i.ready = true
+	i.version = version
+	i.meshes = meshes
+	i.names = names
+	return true
+}
+
+// Version: the version of the nodes in the index
+func (i *Index) Version() string {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.version
+}
+
+// Lookup: the nodes in the mesh with the given id or name. Names are
+// compared ignoring case
+func (i *Index) Lookup(nameOrId string) ([]query.QueryNode, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	if !i.ready {
+		return nil, ErrIndexNotReady
+	}
+
+	if nodes, ok := i.meshes[nameOrId]; ok {
+		return nodes, nil
+	}
+
+	if meshId, ok := i.names[strings.ToLower(nameOrId)]; ok {
+		return i.meshes[meshId], nil
+	}
+
+	return nil, ErrMeshNotFound
+}
+
+// ListMeshes: the meshes in the index ordered by id
+func (i *Index) ListMeshes() ([]string, error) {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	if !i.ready {
+		return nil, ErrIndexNotReady
+	}
+
+	meshes := lib.MapKeys(i.meshes)
+	slices.Sort(meshes)
+	return meshes, nil
+}
@@ -0,0 +1,51 @@
+package smegdns
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tim-beatham/smegmesh/pkg/query"
+)
+
+func TestIndexNotReady(t *testing.T) {
+	index := NewIndex()
+
+	if _, err := index.Lookup("mesh1"); !errors.Is(err, ErrIndexNotReady) {
+		t.Fatalf(`expected ErrIndexNotReady`)
+	}
+}
+
+func TestIndexUpdate(t *testing.T) {
+	index := NewIndex()
+	meshes := map[string][]query.QueryNode{"mesh1": {{PublicKey: "key1"}}}
+
+	if !index.Update("v1", meshes, nil) {
+		t.Fatalf(`expected the index to be updated`)
+	}
+
+	if index.Update("v1", meshes, nil) {
+		t.Fatalf(`expected the same version not to update the index`)
+	}
+
+	nodes, err := index.Lookup("mesh1")
+
+	if err != nil || len(nodes) != 1 {
+		t.Fatalf(`expected the nodes of mesh1`)
+	}
+
+	if _, err := index.Lookup("mesh2"); !errors.Is(err, ErrMeshNotFound) {
+		t.Fatalf(`expected ErrMeshNotFound`)
+	}
+}
+
+func TestIndexLookupByName(t *testing.T) {
+	index := NewIndex()
+	meshes := map[string][]query.QueryNode{"mesh1": {{PublicKey: "key1"}}}
+	index.Update("v1", meshes, map[string]string{"prod-eu": "mesh1"})
+
+	nodes, err := index.Lookup("Prod-EU")
+
+	if err != nil || len(nodes) != 1 {
+		t.Fatalf(`expected the nodes of the mesh named prod-eu`)
+	}
+}
@@ -0,0 +1,420 @@
+package smegdns
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/query"
+	"github.com/tim-beatham/smegmesh/pkg/what8words"
+)
+
+const (
+	// DEFAULT_ZONE: the zone the resolver is authoritative for if no
+	// zone is given
+	DEFAULT_ZONE = "smeg."
+	// DEFAULT_RECORD_TTL: number of seconds answers may be cached for
+	DEFAULT_RECORD_TTL = 60
+	// DEFAULT_NEGATIVE_TTL: number of seconds NXDOMAIN and NODATA
+	// responses may be cached for
+	DEFAULT_NEGATIVE_TTL = 30
+	// MAX_TXT_LENGTH: maximum length of a TXT character string
+	MAX_TXT_LENGTH = 255
+)
+
+// SRV priorities. Clients prefer lower priorities so passing services
+// are tried before degraded ones
+const (
+	PASSING_PRIORITY = 0
+	WARNING_PRIORITY = 10
+	SRV_WEIGHT       = 1
+)
+
+// ErrMeshNotFound: returned by a lookup if the mesh does not exist
+var ErrMeshNotFound = errors.New("mesh not found")
+
+// Lookup: returns the nodes of the mesh. Returns ErrMeshNotFound if the
+// mesh does not exist
+type Lookup func(meshId string) ([]query.QueryNode, error)
+
+// ListMeshes: returns the meshes the node has joined
+type ListMeshes func() ([]string, error)
+
+// Resolver: answers questions in its zone from the nodes in each mesh
+// and PTR questions for the addresses of the nodes. Nodes are named
+// <alias>.<mesh>.<zone> and services _<service>._<proto>.<mesh>.<zone>
+type Resolver struct {
+	lookup      Lookup
+	listMeshes  ListMeshes
+	words       *what8words.What8Words
+	zone        string
+	recordTtl   uint32
+	negativeTtl uint32
+}
+
+// NewResolverParams: params to create a new resolver
+type NewResolverParams struct {
+	Lookup     Lookup
+	ListMeshes ListMeshes
+	// Words: names nodes without an alias by their address. Nodes are
+	// named by the hex of their address if nil
+	Words *what8words.What8Words
+	// Zone: the zone to answer for. Defaults to DEFAULT_ZONE
+	Zone string
+	// RecordTtl: TTL of answers in seconds. Defaults to DEFAULT_RECORD_TTL
+	RecordTtl int
+	// NegativeTtl: TTL of negative responses in seconds. Defaults to
+	// DEFAULT_NEGATIVE_TTL
+	NegativeTtl int
+}
+
+// NewResolver: create a new resolver
+func NewResolver(params *NewResolverParams) *Resolver {
+	resolver := &Resolver{
+		lookup:      params.Lookup,
+		listMeshes:  params.ListMeshes,
+		words:       params.Words,
+		zone:        dns.CanonicalName(params.Zone),
+		recordTtl:   uint32(params.RecordTtl),
+		negativeTtl: uint32(params.NegativeTtl),
+	}
+
+	if params.Zone == "" {
+		resolver.zone = DEFAULT_ZONE
+	}
+
+	if resolver.recordTtl == 0 {
+		resolver.recordTtl = DEFAULT_RECORD_TTL
+	}
+
+	if resolver.negativeTtl == 0 {
+		resolver.negativeTtl = DEFAULT_NEGATIVE_TTL
+	}
+
+	return resolver
+}
+
+// Zone: the zone the resolver answers for
+func (r *Resolver) Zone() string {
+	return r.zone
+}
+
+// addressLabel: label naming a node that has no usable alias. The
+// what8words identifier of the node's address or the hex of the address
+// if there are no words
+func (r *Resolver) addressLabel(node *query.QueryNode) string {
+	if r.words != nil {
+		identifier, err := r.words.ConvertIdentifier(node.WgHost)
+
+		if err == nil {
+			return strings.ReplaceAll(identifier, ".", "-")
+		}
+	}
+
+	ip, _, err := net.ParseCIDR(node.WgHost)
+
+	if err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(ip.To16())
+}
+
+// hostLabel: the label naming the node in its mesh. The node's alias if
+// it is a valid label and a label derived from its address otherwise
+func (r *Resolver) hostLabel(node *query.QueryNode) string {
+	if mesh.IsDnsLabel(node.Alias) {
+		return node.Alias
+	}
+
+	return r.addressLabel(node)
+}
+
+// NodeName: fully qualified name of the node in the mesh
+func (r *Resolver) NodeName(meshId string, node *query.QueryNode) string {
+	return fmt.Sprintf("%s.%s.%s", r.hostLabel(node), meshId, r.zone)
+}
+
+// header: header of an answer for name
+func (r *Resolver) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: r.recordTtl}
+}
+
+// soa: start of authority of the zone. Returned in the authority section
+// of negative responses so that resolvers know how long to cache them
+func (r *Resolver) soa(zone string) dns.RR {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: r.negativeTtl},
+		Ns:      "ns." + r.zone,
+		Mbox:    "hostmaster." + r.zone,
+		Serial:  1,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  r.negativeTtl,
+	}
+}
+
+// addressRecord: the AAAA record of the node or nil if it has no
+// address
+func (r *Resolver) addressRecord(name string, node *query.QueryNode) dns.RR {
+	ip, _, err := net.ParseCIDR(node.WgHost)
+
+	if err != nil || ip.To16() == nil {
+		return nil
+	}
+
+	return &dns.AAAA{Hdr: r.header(name, dns.TypeAAAA), AAAA: ip}
+}
+
+// txtString: a TXT character string truncated to the maximum length
+func txtString(key, value string) string {
+	txt := fmt.Sprintf("%s=%s", key, value)
+
+	if len(txt) > MAX_TXT_LENGTH {
+		return txt[:MAX_TXT_LENGTH]
+	}
+
+	return txt
+}
+
+// textRecord: TXT record exposing the node's description, public key
+// and labels
+func (r *Resolver) textRecord(name string, node *query.QueryNode) dns.RR {
+	txt := []string{txtString("publicKey", node.PublicKey)}
+
+	if node.Description != "" {
+		txt = append(txt, txtString("description", node.Description))
+	}
+
+	labels := make([]string, 0, len(node.Labels))
+
+	for key, value := range node.Labels {
+		labels = append(labels, txtString("label:"+key, value))
+	}
+
+	slices.Sort(labels)
+	return &dns.TXT{Hdr: r.header(name, dns.TypeTXT), Txt: append(txt, labels...)}
+}
+
+// offers: returns the services of the node with the given name and
+// protocol
+func offers(node *query.QueryNode, service, protocol string) []query.QueryService {
+	return slices.DeleteFunc(slices.Clone(node.ServiceRecords), func(s query.QueryService) bool {
+		return !strings.EqualFold(s.Name, service) || !strings.EqualFold(s.Protocol, protocol)
+	})
+}
+
+// response: the records answering a question and whether the name
+// exists
+type response struct {
+	// zone: the zone the name is in. Empty if the resolver is not
+	// authoritative for the name
+	zone   string
+	answer []dns.RR
+	extra  []dns.RR
+	exists bool
+}
+
+// resolveNode: answer a question for the node named <alias>.<mesh>
+func (r *Resolver) resolveNode(qtype uint16, name string, nodes []query.QueryNode, alias string) response {
+	var res response
+
+	for i := range nodes {
+		node := &nodes[i]
+
+		if !strings.EqualFold(r.hostLabel(node), alias) {
+			continue
+		}
+
+		res.exists = true
+
+		if qtype == dns.TypeAAAA || qtype == dns.TypeANY {
+			if rr := r.addressRecord(name, node); rr != nil {
+				res.answer = append(res.answer, rr)
+			}
+		}
+
+		if qtype == dns.TypeTXT || qtype == dns.TypeANY {
+			res.answer = append(res.answer, r.textRecord(name, node))
+		}
+	}
+
+	return res
+}
+
+// resolveService: answer a question for _<service>._<proto>.<mesh>.
+// Targets are named after the nodes offering the service and their
+// addresses are returned as additional records
+func (r *Resolver) resolveService(qtype uint16, name, meshId string, nodes []query.QueryNode, service, protocol string) response {
+	var res response
+
+	for i := range nodes {
+		node := &nodes[i]
+		services := offers(node, service, protocol)
+
+		if len(services) == 0 {
+			continue
+		}
+
+		res.exists = true
+
+		if qtype != dns.TypeSRV && qtype != dns.TypeANY {
+			continue
+		}
+
+		target := r.NodeName(meshId, node)
+
+		for _, s := range services {
+			priority := PASSING_PRIORITY
+
+			if s.Status == mesh.SERVICE_WARNING {
+				priority = WARNING_PRIORITY
+			}
+
+			res.answer = append(res.answer, &dns.SRV{
+				Hdr:      r.header(name, dns.TypeSRV),
+				Priority: uint16(priority),
+				Weight:   SRV_WEIGHT,
+				Port:     uint16(s.Port),
+				Target:   target,
+			})
+		}
+
+		if rr := r.addressRecord(target, node); rr != nil {
+			res.extra = append(res.extra, rr)
+		}
+	}
+
+	return res
+}
+
+// resolveProtocol: answer a question for _<proto>.<mesh>. The name
+// exists if any node offers a service over the protocol
+func (r *Resolver) resolveProtocol(nodes []query.QueryNode, protocol string) response {
+	for i := range nodes {
+		for _, s := range nodes[i].ServiceRecords {
+			if strings.EqualFold(s.Protocol, protocol) {
+				return response{exists: true}
+			}
+		}
+	}
+
+	return response{}
+}
+
+// resolve: answer the question given the labels of the name below the
+// zone
+func (r *Resolver) resolve(q dns.Question, labels []string) (response, error) {
+	switch len(labels) {
+	case 0:
+		if q.Qtype == dns.TypeSOA || q.Qtype == dns.TypeANY {
+			return response{answer: []dns.RR{r.soa(r.zone)}, exists: true}, nil
+		}
+
+		return response{exists: true}, nil
+	case 1:
+		// Both the mesh in <alias>.<mesh> and the alias in the
+		// deprecated <mesh>.<alias> are empty non-terminals
+		return response{exists: true}, nil
+	case 2:
+		protocol, isService := strings.CutPrefix(labels[0], "_")
+
+		if isService {
+			nodes, err := r.lookup(labels[1])
+
+			if err != nil {
+				return response{}, err
+			}
+
+			return r.resolveProtocol(nodes, protocol), nil
+		}
+
+		nodes, err := r.lookup(labels[1])
+
+		if err == nil {
+			return r.resolveNode(q.Qtype, q.Name, nodes, labels[0]), nil
+		}
+
+		if !errors.Is(err, ErrMeshNotFound) {
+			return response{}, err
+		}
+
+		// Fall back to nodes named <mesh>.<alias>
+		nodes, err = r.lookup(labels[0])
+
+		if err != nil {
+			return response{}, err
+		}
+
+		return r.resolveNode(q.Qtype, q.Name, nodes, labels[1]), nil
+	case 3:
+		service, isService := strings.CutPrefix(labels[0], "_")
+		protocol, isProtocol := strings.CutPrefix(labels[1], "_")
+
+		if !isService || !isProtocol {
+			return response{}, nil
+		}
+
+		nodes, err := r.lookup(labels[2])
+
+		if err != nil {
+			return response{}, err
+		}
+
+		return r.resolveService(q.Qtype, q.Name, labels[2], nodes, service, protocol), nil
+	}
+
+	return response{}, nil
+}
+
+// Resolve: answer the question in the reply. Sets NXDOMAIN if the name
+// does not exist and returns NODATA if the name exists but has no
+// records of the type. Returns false without answering if the question
+// is outside the resolver's zone and the reverse zones of the meshes
+func (r *Resolver) Resolve(reply *dns.Msg, q dns.Question) bool {
+	var res response
+	var err error
+
+	switch {
+	case dns.IsSubDomain(r.zone, q.Name):
+		labels := dns.SplitDomainName(q.Name)
+		res, err = r.resolve(q, labels[:len(labels)-dns.CountLabel(r.zone)])
+		res.zone = r.zone
+	case dns.IsSubDomain(REVERSE_ZONE, q.Name):
+		res, err = r.resolveReverse(q)
+	}
+
+	if errors.Is(err, ErrMeshNotFound) {
+		err = nil
+	}
+
+	if err != nil {
+		reply.Rcode = dns.RcodeServerFailure
+		return true
+	}
+
+	if res.zone == "" {
+		return false
+	}
+
+	reply.Authoritative = true
+
+	if !res.exists {
+		reply.Rcode = dns.RcodeNameError
+	}
+
+	if len(res.answer) == 0 {
+		reply.Ns = append(reply.Ns, r.soa(res.zone))
+		return true
+	}
+
+	reply.Answer = append(reply.Answer, res.answer...)
+	reply.Extra = append(reply.Extra, res.extra...)
+	return true
+}
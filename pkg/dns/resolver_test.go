@@ -0,0 +1,249 @@
+package smegdns
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/tim-beatham/smegmesh/pkg/ip"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/query"
+)
+
+// meshAddress: the address of the node with the interface id in the
+// mesh
+func meshAddress(meshId string, id byte) net.IP {
+	builder := ip.ULABuilder{}
+	prefix, _ := builder.GetIPNet(meshId)
+
+	address := make(net.IP, net.IPv6len)
+	copy(address, prefix.IP.To16())
+	address[net.IPv6len-1] = id
+	return address
+}
+
+func setUpResolver() *Resolver {
+	meshes := map[string][]query.QueryNode{
+		"mesh1": {
+			{
+				PublicKey:   "key1",
+				WgHost:      meshAddress("mesh1", 1).String() + "/128",
+				Alias:       "web",
+				Description: "web server",
+				Labels:      map[string]string{"env": "prod", "role": "web"},
+				ServiceRecords: []query.QueryService{
+					{Name: "http", Port: 80, Protocol: "tcp", Status: mesh.SERVICE_PASSING},
+				},
+			},
+			{
+				PublicKey: "key2",
+				WgHost:    meshAddress("mesh1", 2).String() + "/128",
+				ServiceRecords: []query.QueryService{
+					{Name: "http", Port: 8080, Protocol: "tcp", Status: mesh.SERVICE_WARNING},
+				},
+			},
+		},
+	}
+
+	return NewResolver(&NewResolverParams{
+		Lookup: func(meshId string) ([]query.QueryNode, error) {
+			nodes, ok := meshes[meshId]
+
+			if !ok {
+				return nil, ErrMeshNotFound
+			}
+
+			return nodes, nil
+		},
+		ListMeshes: func() ([]string, error) {
+			return []string{"mesh1"}, nil
+		},
+	})
+}
+
+func resolve(resolver *Resolver, name string, qtype uint16) *dns.Msg {
+	request := new(dns.Msg)
+	request.SetQuestion(name, qtype)
+
+	reply := new(dns.Msg)
+	reply.SetReply(request)
+
+	if !resolver.Resolve(reply, request.Question[0]) {
+		return nil
+	}
+
+	return reply
+}
+
+func TestResolveAAAAReturnsAddress(t *testing.T) {
+	reply := resolve(setUpResolver(), "web.mesh1.smeg.", dns.TypeAAAA)
+
+	if reply.Rcode != dns.RcodeSuccess || len(reply.Answer) != 1 {
+		t.Fatalf(`expected a single answer got %s`, reply)
+	}
+
+	if !reply.Answer[0].(*dns.AAAA).AAAA.Equal(meshAddress("mesh1", 1)) {
+		t.Fatalf(`expected %s got %s`, meshAddress("mesh1", 1), reply.Answer[0])
+	}
+}
+
+func TestResolveAAAAMeshFirstName(t *testing.T) {
+	reply := resolve(setUpResolver(), "mesh1.web.smeg.", dns.TypeAAAA)
+
+	if len(reply.Answer) != 1 {
+		t.Fatalf(`expected a single answer got %s`, reply)
+	}
+}
+
+func TestResolveIsCaseInsensitive(t *testing.T) {
+	reply := resolve(setUpResolver(), "WEB.mesh1.SMEG.", dns.TypeAAAA)
+
+	if len(reply.Answer) != 1 {
+		t.Fatalf(`expected a single answer got %s`, reply)
+	}
+}
+
+func TestResolveNodeWithoutAliasByAddress(t *testing.T) {
+	name := hex.EncodeToString(meshAddress("mesh1", 2)) + ".mesh1.smeg."
+	reply := resolve(setUpResolver(), name, dns.TypeAAAA)
+
+	if len(reply.Answer) != 1 {
+		t.Fatalf(`expected a single answer got %s`, reply)
+	}
+}
+
+func TestResolveUnknownAliasIsNXDomain(t *testing.T) {
+	reply := resolve(setUpResolver(), "db.mesh1.smeg.", dns.TypeAAAA)
+
+	if reply.Rcode != dns.RcodeNameError {
+		t.Fatalf(`expected NXDOMAIN got %s`, dns.RcodeToString[reply.Rcode])
+	}
+
+	if len(reply.Answer) != 0 || len(reply.Ns) != 1 {
+		t.Fatalf(`expected an SOA in the authority section got %s`, reply)
+	}
+}
+
+func TestResolveUnknownMeshIsNXDomain(t *testing.T) {
+	reply := resolve(setUpResolver(), "web.mesh2.smeg.", dns.TypeAAAA)
+
+	if reply.Rcode != dns.RcodeNameError {
+		t.Fatalf(`expected NXDOMAIN got %s`, dns.RcodeToString[reply.Rcode])
+	}
+}
+
+func TestResolveOtherTypeIsNoData(t *testing.T) {
+	reply := resolve(setUpResolver(), "web.mesh1.smeg.", dns.TypeA)
+
+	if reply.Rcode != dns.RcodeSuccess {
+		t.Fatalf(`expected NOERROR got %s`, dns.RcodeToString[reply.Rcode])
+	}
+
+	if len(reply.Answer) != 0 || len(reply.Ns) != 1 {
+		t.Fatalf(`expected NODATA got %s`, reply)
+	}
+}
+
+func TestResolveTXTExposesNode(t *testing.T) {
+	reply := resolve(setUpResolver(), "web.mesh1.smeg.", dns.TypeTXT)
+
+	if len(reply.Answer) != 1 {
+		t.Fatalf(`expected a single answer got %s`, reply)
+	}
+
+	txt := reply.Answer[0].(*dns.TXT).Txt
+	expected := []string{"publicKey=key1", "description=web server", "label:env=prod", "label:role=web"}
+
+	if len(txt) != len(expected) {
+		t.Fatalf(`expected %v got %v`, expected, txt)
+	}
+
+	for i := range expected {
+		if txt[i] != expected[i] {
+			t.Fatalf(`expected %v got %v`, expected, txt)
+		}
+	}
+}
+
+func TestResolveSRVReturnsServices(t *testing.T) {
+	reply := resolve(setUpResolver(), "_http._tcp.mesh1.smeg.", dns.TypeSRV)
+
+	if len(reply.Answer) != 2 {
+		t.Fatalf(`expected two answers got %s`, reply)
+	}
+
+	first := reply.Answer[0].(*dns.SRV)
+
+	if first.Port != 80 || first.Target != "web.mesh1.smeg." || first.Priority != PASSING_PRIORITY {
+		t.Fatalf(`unexpected record %s`, first)
+	}
+
+	second := reply.Answer[1].(*dns.SRV)
+
+	if second.Port != 8080 || second.Priority != WARNING_PRIORITY {
+		t.Fatalf(`unexpected record %s`, second)
+	}
+
+	if len(reply.Extra) != 2 {
+		t.Fatalf(`expected the addresses of the targets got %s`, reply)
+	}
+}
+
+func TestResolveUnknownServiceIsNXDomain(t *testing.T) {
+	reply := resolve(setUpResolver(), "_ssh._tcp.mesh1.smeg.", dns.TypeSRV)
+
+	if reply.Rcode != dns.RcodeNameError {
+		t.Fatalf(`expected NXDOMAIN got %s`, dns.RcodeToString[reply.Rcode])
+	}
+}
+
+func TestResolveProtocolIsNoData(t *testing.T) {
+	reply := resolve(setUpResolver(), "_tcp.mesh1.smeg.", dns.TypeSRV)
+
+	if reply.Rcode != dns.RcodeSuccess || len(reply.Answer) != 0 {
+		t.Fatalf(`expected NODATA got %s`, reply)
+	}
+}
+
+func TestResolveLookupFailureIsServFail(t *testing.T) {
+	resolver := NewResolver(&NewResolverParams{
+		Lookup: func(meshId string) ([]query.QueryNode, error) {
+			return nil, errors.New("daemon is down")
+		},
+	})
+
+	reply := resolve(resolver, "web.mesh1.smeg.", dns.TypeAAAA)
+
+	if reply.Rcode != dns.RcodeServerFailure {
+		t.Fatalf(`expected SERVFAIL got %s`, dns.RcodeToString[reply.Rcode])
+	}
+}
+
+func TestResolveOutsideZoneIsNotAnswered(t *testing.T) {
+	reply := resolve(setUpResolver(), "example.com.", dns.TypeA)
+
+	if reply != nil {
+		t.Fatalf(`expected no answer got %s`, reply)
+	}
+}
+
+func TestResolveConfiguredZone(t *testing.T) {
+	resolver := setUpResolver()
+	resolver.zone = "mesh.internal."
+
+	reply := resolve(resolver, "_http._tcp.mesh1.mesh.internal.", dns.TypeSRV)
+
+	if reply == nil || len(reply.Answer) != 2 {
+		t.Fatalf(`expected two answers got %s`, reply)
+	}
+
+	if reply.Answer[0].(*dns.SRV).Target != "web.mesh1.mesh.internal." {
+		t.Fatalf(`expected the target in the zone got %s`, reply.Answer[0])
+	}
+
+	if reply := resolve(resolver, "web.mesh1.smeg.", dns.TypeAAAA); reply != nil {
+		t.Fatalf(`expected no answer outside the zone got %s`, reply)
+	}
+}
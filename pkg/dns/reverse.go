@@ -0,0 +1,144 @@
+package smegdns
+
+import (
+	"encoding/hex"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/tim-beatham/smegmesh/pkg/ip"
+	"github.com/tim-beatham/smegmesh/pkg/query"
+)
+
+// REVERSE_ZONE: the zone PTR questions for IPv6 addresses are asked in
+const REVERSE_ZONE = "ip6.arpa."
+
+// nibbles: the nibbles of the reverse name most significant first.
+// Returns false if the name is not a valid reverse name
+func nibbles(name string) (string, bool) {
+	labels := dns.SplitDomainName(name)
+	labels = labels[:len(labels)-dns.CountLabel(REVERSE_ZONE)]
+
+	if len(labels) > 2*net.IPv6len {
+		return "", false
+	}
+
+	var nibbles strings.Builder
+
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := strings.ToLower(labels[i])
+
+		if len(label) != 1 || !strings.Contains("0123456789abcdef", label) {
+			return "", false
+		}
+
+		nibbles.WriteString(label)
+	}
+
+	return nibbles.String(), true
+}
+
+// reverseName: the name of the nibbles in the reverse zone
+func reverseName(nibbles string) string {
+	var name strings.Builder
+
+	for i := len(nibbles) - 1; i >= 0; i-- {
+		name.WriteByte(nibbles[i])
+		name.WriteByte('.')
+	}
+
+	return name.String() + REVERSE_ZONE
+}
+
+// meshZone: the nibbles of the reverse zone covering the mesh's prefix
+func meshZone(meshId string) (string, error) {
+	builder := ip.ULABuilder{}
+	prefix, err := builder.GetIPNet(meshId)
+
+	if err != nil {
+		return "", err
+	}
+
+	ones, _ := prefix.Mask.Size()
+	return hex.EncodeToString(prefix.IP.To16())[:ones/4], nil
+}
+
+// resolvePointer: answer a question for the address of a node in the
+// mesh
+func (r *Resolver) resolvePointer(q dns.Question, meshId string, nodes []query.QueryNode, address net.IP) response {
+	var res response
+
+	for i := range nodes {
+		node := &nodes[i]
+		nodeIP, _, err := net.ParseCIDR(node.WgHost)
+
+		if err != nil || !nodeIP.Equal(address) {
+			continue
+		}
+
+		res.exists = true
+
+		if q.Qtype == dns.TypePTR || q.Qtype == dns.TypeANY {
+			res.answer = append(res.answer, &dns.PTR{
+				Hdr: r.header(q.Name, dns.TypePTR),
+				Ptr: r.NodeName(meshId, node),
+			})
+		}
+	}
+
+	return res
+}
+
+// resolveReverse: answer a question in the reverse zone of one of the
+// meshes the node has joined. The resolver is authoritative for the
+// reverse zone of each mesh's /64
+func (r *Resolver) resolveReverse(q dns.Question) (response, error) {
+	address, ok := nibbles(q.Name)
+
+	if !ok {
+		return response{}, nil
+	}
+
+	meshes, err := r.listMeshes()
+
+	if err != nil {
+		return response{}, err
+	}
+
+	for _, meshId := range meshes {
+		zone, err := meshZone(meshId)
+
+		if err != nil {
+			return response{}, err
+		}
+
+		if !strings.HasPrefix(address, zone) {
+			continue
+		}
+
+		// Names shorter than an address are the apex of the zone or
+		// empty non-terminals
+		if len(address) < 2*net.IPv6len {
+			res := response{zone: reverseName(zone), exists: true}
+
+			if len(address) == len(zone) && (q.Qtype == dns.TypeSOA || q.Qtype == dns.TypeANY) {
+				res.answer = []dns.RR{r.soa(res.zone)}
+			}
+
+			return res, nil
+		}
+
+		nodes, err := r.lookup(meshId)
+
+		if err != nil {
+			return response{}, err
+		}
+
+		addressBytes, _ := hex.DecodeString(address)
+		res := r.resolvePointer(q, meshId, nodes, net.IP(addressBytes))
+		res.zone = reverseName(zone)
+		return res, nil
+	}
+
+	return response{}, nil
+}
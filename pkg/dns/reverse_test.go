@@ -0,0 +1,93 @@
+package smegdns
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/tim-beatham/smegmesh/pkg/query"
+	"github.com/tim-beatham/smegmesh/pkg/what8words"
+)
+
+func TestResolvePTRReturnsAlias(t *testing.T) {
+	name, _ := dns.ReverseAddr(meshAddress("mesh1", 1).String())
+	reply := resolve(setUpResolver(), name, dns.TypePTR)
+
+	if reply.Rcode != dns.RcodeSuccess || len(reply.Answer) != 1 {
+		t.Fatalf(`expected a single answer got %s`, reply)
+	}
+
+	if reply.Answer[0].(*dns.PTR).Ptr != "web.mesh1.smeg." {
+		t.Fatalf(`expected web.mesh1.smeg. got %s`, reply.Answer[0])
+	}
+}
+
+func TestResolvePTRFallsBackToWhat8Words(t *testing.T) {
+	words, err := what8words.NewWhat8Words("../../cmd/api/words.txt")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	node := query.QueryNode{WgHost: meshAddress("mesh1", 2).String() + "/128"}
+	resolver := NewResolver(&NewResolverParams{
+		Lookup: func(meshId string) ([]query.QueryNode, error) {
+			return []query.QueryNode{node}, nil
+		},
+		ListMeshes: func() ([]string, error) {
+			return []string{"mesh1"}, nil
+		},
+		Words: words,
+	})
+
+	identifier, _ := words.ConvertIdentifier(node.WgHost)
+	expected := dns.Fqdn(strings.ReplaceAll(identifier, ".", "-") + ".mesh1.smeg")
+
+	name, _ := dns.ReverseAddr(meshAddress("mesh1", 2).String())
+	reply := resolve(resolver, name, dns.TypePTR)
+
+	if len(reply.Answer) != 1 || reply.Answer[0].(*dns.PTR).Ptr != expected {
+		t.Fatalf(`expected %s got %s`, expected, reply)
+	}
+
+	// The name the PTR points to must resolve back to the address
+	reply = resolve(resolver, expected, dns.TypeAAAA)
+
+	if len(reply.Answer) != 1 || !reply.Answer[0].(*dns.AAAA).AAAA.Equal(meshAddress("mesh1", 2)) {
+		t.Fatalf(`expected %s got %s`, meshAddress("mesh1", 2), reply)
+	}
+}
+
+func TestResolvePTRUnknownAddressIsNXDomain(t *testing.T) {
+	name, _ := dns.ReverseAddr(meshAddress("mesh1", 3).String())
+	reply := resolve(setUpResolver(), name, dns.TypePTR)
+
+	if reply.Rcode != dns.RcodeNameError {
+		t.Fatalf(`expected NXDOMAIN got %s`, dns.RcodeToString[reply.Rcode])
+	}
+
+	zone, _ := meshZone("mesh1")
+
+	if len(reply.Ns) != 1 || reply.Ns[0].Header().Name != reverseName(zone) {
+		t.Fatalf(`expected the SOA of the mesh's zone got %s`, reply)
+	}
+}
+
+func TestResolvePTRZoneApexHasSOA(t *testing.T) {
+	zone, _ := meshZone("mesh1")
+	reply := resolve(setUpResolver(), reverseName(zone), dns.TypeSOA)
+
+	if reply.Rcode != dns.RcodeSuccess || len(reply.Answer) != 1 {
+		t.Fatalf(`expected the SOA got %s`, reply)
+	}
+}
+
+func TestResolvePTROutsideMeshesIsNotAnswered(t *testing.T) {
+	name, _ := dns.ReverseAddr(net.ParseIP("2001:db8::1").String())
+	reply := resolve(setUpResolver(), name, dns.TypePTR)
+
+	if reply != nil {
+		t.Fatalf(`expected no answer got %s`, reply)
+	}
+}
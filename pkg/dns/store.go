@@ -0,0 +1,265 @@
+package smegdns
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/tim-beatham/smegmesh/pkg/cmd"
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/query"
+)
+
+// MeshStore: looks up nodes in the daemon's meshes. Used when the
+// daemon serves DNS itself
+type MeshStore struct {
+	manager mesh.MeshManager
+}
+
+// NewMeshStore: create a store reading from the manager's meshes
+func NewMeshStore(manager mesh.MeshManager) *MeshStore {
+	return &MeshStore{manager: manager}
+}
+
+// Lookup: the nodes in the mesh with the given id or name
+func (s *MeshStore) Lookup(nameOrId string) ([]query.QueryNode, error) {
+	meshId, err := mesh.ResolveMeshId(s.manager, nameOrId)
+
+	// Several meshes have the name
+	if err != nil {
+		return nil, ErrMeshNotFound
+	}
+
+	theMesh := s.manager.GetMesh(meshId)
+
+	if theMesh == nil {
+		return nil, ErrMeshNotFound
+	}
+
+	snapshot, err := theMesh.GetMesh()
+
+	if err != nil {
+		return nil, err
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lib.Map(query.SnapshotToQueryNodes(admission, snapshot), func(node *query.QueryNode) query.QueryNode {
+		return *node
+	}), nil
+}
+
+// ListMeshes: the meshes the node has joined ordered by id
+func (s *MeshStore) ListMeshes() ([]string, error) {
+	meshes := lib.MapKeys(s.manager.GetMeshes())
+	slices.Sort(meshes)
+	return meshes, nil
+}
+
+// Addresses: the node's address in each mesh it has been added to
+func (s *MeshStore) Addresses() []string {
+	self := s.manager.GetPublicKey().String()
+	addresses := make([]string, 0)
+
+	for meshId := range s.manager.GetMeshes() {
+		node := s.manager.GetNode(meshId, self)
+
+		if node == nil || node.GetWgHost() == nil {
+			continue
+		}
+
+		addresses = append(addresses, node.GetWgHost().IP.String())
+	}
+
+	slices.Sort(addresses)
+	return slices.Compact(addresses)
+}
+
+// NameServer: a node that answers DNS queries for the mesh
+type NameServer struct {
+	Address string
+	Port    int
+}
+
+// Endpoint: the address and port of the name server
+func (n *NameServer) Endpoint() string {
+	return net.JoinHostPort(n.Address, strconv.Itoa(n.Port))
+}
+
+// NameServers: the approved nodes that advertise a healthy DNS service
+// for the zone ordered by address. Any node may advertise the service
+// so nodes that are not approved are ignored
+func NameServers(nodes []query.QueryNode, zone string, approved func(publicKey string) bool) []NameServer {
+	servers := make([]NameServer, 0)
+	zone = dns.CanonicalName(zone)
+
+	for _, node := range nodes {
+		address, _, err := net.ParseCIDR(node.WgHost)
+
+		if err != nil || !approved(node.PublicKey) {
+			continue
+		}
+
+		for _, service := range node.ServiceRecords {
+			if service.Name != conf.DNS_SERVICE {
+				continue
+			}
+
+			serviceZone := service.Metadata["zone"]
+
+			if serviceZone == "" {
+				serviceZone = DEFAULT_ZONE
+			}
+
+			if dns.CanonicalName(serviceZone) != zone {
+				continue
+			}
+
+			servers = append(servers, NameServer{Address: address.String(), Port: service.Port})
+		}
+	}
+
+	slices.SortFunc(servers, func(s1, s2 NameServer) int {
+		return strings.Compare(s1.Endpoint(), s2.Endpoint())
+	})
+
+	return servers
+}
+
+// SplitDnsHook: runs a hook whenever the name servers of a mesh change
+// so that the host sends queries for the mesh to the mesh's servers
+type SplitDnsHook struct {
+	lookup  Lookup
+	manager mesh.MeshManager
+	hook    string
+	// zone: the zone the mesh's names are under
+	zone string
+	// applied: the environment the hook was last run with for each mesh
+	applied map[string]string
+	run     func(hook string, env []string) error
+}
+
+// NewSplitDnsHookParams: params to create a new split DNS hook
+type NewSplitDnsHookParams struct {
+	MeshManager mesh.MeshManager
+	// Hook: shell command to run
+	Hook string
+	// Zone: the zone the mesh's names are under. Only servers answering
+	// for the zone are used. Defaults to DEFAULT_ZONE
+	Zone string
+}
+
+// NewSplitDnsHook: create a new split DNS hook
+func NewSplitDnsHook(params *NewSplitDnsHookParams) *SplitDnsHook {
+	hook := &SplitDnsHook{
+		lookup:  NewMeshStore(params.MeshManager).Lookup,
+		manager: params.MeshManager,
+		hook:    params.Hook,
+		zone:    dns.CanonicalName(params.Zone),
+		applied: make(map[string]string),
+		run:     cmd.RunHook,
+	}
+
+	if params.Zone == "" {
+		hook.zone = DEFAULT_ZONE
+	}
+
+	return hook
+}
+
+// approved: returns a function deciding whether the node may serve DNS
+// for the mesh. In meshes with admins only admins may serve DNS
+func (h *SplitDnsHook) approved(meshId string) (func(string) bool, error) {
+	admission, err := h.manager.GetMesh(meshId).GetAdmission()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(publicKey string) bool {
+		return admission.GetGenesis() == nil || admission.IsAdmin(publicKey)
+	}, nil
+}
+
+// environment: the environment variables describing the mesh's name
+// servers
+func (h *SplitDnsHook) environment(meshId string, servers []NameServer) ([]string, error) {
+	var ifName string
+
+	if device, err := h.manager.GetMesh(meshId).GetDevice(); err == nil && device != nil {
+		ifName = device.Name
+	}
+
+	reverseZone, err := meshZone(meshId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := lib.Map(servers, func(s NameServer) string { return s.Address })
+	endpoints := lib.Map(servers, func(s NameServer) string { return s.Endpoint() })
+
+	return []string{
+		fmt.Sprintf("SMEG_MESH_ID=%s", meshId),
+		fmt.Sprintf("SMEG_INTERFACE=%s", ifName),
+		fmt.Sprintf("SMEG_DNS_DOMAIN=%s", strings.TrimSuffix(meshId+"."+h.zone, ".")),
+		fmt.Sprintf("SMEG_DNS_REVERSE_DOMAIN=%s", strings.TrimSuffix(reverseName(reverseZone), ".")),
+		fmt.Sprintf("SMEG_DNS_SERVERS=%s", strings.Join(slices.Compact(addresses), " ")),
+		fmt.Sprintf("SMEG_DNS_ENDPOINTS=%s", strings.Join(endpoints, " ")),
+	}, nil
+}
+
+// Apply: run the hook for every mesh whose name servers changed since
+// the hook was last run
+func (h *SplitDnsHook) Apply() error {
+	meshes := h.manager.GetMeshes()
+
+	for meshId := range h.applied {
+		if _, ok := meshes[meshId]; !ok {
+			delete(h.applied, meshId)
+		}
+	}
+
+	for meshId := range meshes {
+		nodes, err := h.lookup(meshId)
+
+		if err != nil {
+			return err
+		}
+
+		approved, err := h.approved(meshId)
+
+		if err != nil {
+			return err
+		}
+
+		env, err := h.environment(meshId, NameServers(nodes, h.zone, approved))
+
+		if err != nil {
+			return err
+		}
+
+		applied := strings.Join(env, "\n")
+
+		if h.applied[meshId] == applied {
+			continue
+		}
+
+		if err := h.run(h.hook, env); err != nil {
+			return fmt.Errorf("dns hook failed for %s: %w", meshId, err)
+		}
+
+		h.applied[meshId] = applied
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package smegdns
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/query"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// providerStub: a mesh attached to the interface wg0
+type providerStub struct {
+	mesh.MeshProvider
+}
+
+func (p *providerStub) GetDevice() (*wgtypes.Device, error) {
+	return &wgtypes.Device{Name: "wg0"}, nil
+}
+
+func (p *providerStub) GetAdmission() (*mesh.Admission, error) {
+	return mesh.NewAdmission("mesh1", nil), nil
+}
+
+// managerStub: a node that has joined mesh1
+type managerStub struct {
+	mesh.MeshManager
+}
+
+func (m *managerStub) GetMeshes() map[string]mesh.MeshProvider {
+	return map[string]mesh.MeshProvider{"mesh1": &providerStub{}}
+}
+
+func (m *managerStub) GetMesh(meshId string) mesh.MeshProvider {
+	return &providerStub{}
+}
+
+func approveAll(publicKey string) bool {
+	return true
+}
+
+func dnsNode(id byte, port int) query.QueryNode {
+	return query.QueryNode{
+		PublicKey: fmt.Sprintf("node%d", id),
+		WgHost:    meshAddress("mesh1", id).String() + "/128",
+		ServiceRecords: []query.QueryService{{
+			Name:     conf.DNS_SERVICE,
+			Port:     port,
+			Protocol: "udp",
+			Metadata: map[string]string{"zone": "smeg."},
+		}},
+	}
+}
+
+func TestNameServersOrderedByAddress(t *testing.T) {
+	nodes := []query.QueryNode{dnsNode(2, 53), {WgHost: meshAddress("mesh1", 3).String() + "/128"}, dnsNode(1, 5353)}
+	servers := NameServers(nodes, DEFAULT_ZONE, approveAll)
+
+	if len(servers) != 2 {
+		t.Fatalf(`expected 2 name servers got %d`, len(servers))
+	}
+
+	if servers[0].Address != meshAddress("mesh1", 1).String() || servers[0].Port != 5353 {
+		t.Fatalf(`unexpected name server %v`, servers[0])
+	}
+}
+
+func TestNameServersOnlyApprovedServersOfTheZone(t *testing.T) {
+	otherZone := dnsNode(3, 53)
+	otherZone.ServiceRecords[0].Metadata["zone"] = "example.com."
+	nodes := []query.QueryNode{dnsNode(1, 53), dnsNode(2, 53), otherZone}
+
+	servers := NameServers(nodes, "smeg", func(publicKey string) bool {
+		return publicKey != "node2"
+	})
+
+	if len(servers) != 1 || servers[0].Address != meshAddress("mesh1", 1).String() {
+		t.Fatalf(`expected only the approved server of the zone got %v`, servers)
+	}
+}
+
+func setUpHook(nodes *[]query.QueryNode) (*SplitDnsHook, *[][]string) {
+	runs := make([][]string, 0)
+
+	hook := NewSplitDnsHook(&NewSplitDnsHookParams{MeshManager: &managerStub{}, Hook: "true"})
+	hook.lookup = func(meshId string) ([]query.QueryNode, error) {
+		return *nodes, nil
+	}
+	hook.run = func(hook string, env []string) error {
+		runs = append(runs, env)
+		return nil
+	}
+
+	return hook, &runs
+}
+
+func TestSplitDnsHookRunsWhenServersChange(t *testing.T) {
+	nodes := []query.QueryNode{dnsNode(1, 53)}
+	hook, runs := setUpHook(&nodes)
+
+	hook.Apply()
+	hook.Apply()
+
+	if len(*runs) != 1 {
+		t.Fatalf(`expected the hook to run once got %d`, len(*runs))
+	}
+
+	env := (*runs)[0]
+	expected := []string{
+		"SMEG_MESH_ID=mesh1",
+		"SMEG_INTERFACE=wg0",
+		"SMEG_DNS_DOMAIN=mesh1.smeg",
+		"SMEG_DNS_SERVERS=" + meshAddress("mesh1", 1).String(),
+	}
+
+	for _, variable := range expected {
+		if !slices.Contains(env, variable) {
+			t.Fatalf(`expected %s in %v`, variable, env)
+		}
+	}
+
+	nodes = append(nodes, dnsNode(2, 53))
+	hook.Apply()
+
+	if len(*runs) != 2 {
+		t.Fatalf(`expected the hook to run when the servers changed`)
+	}
+}
+
+func TestSplitDnsHookRetriesOnFailure(t *testing.T) {
+	nodes := []query.QueryNode{dnsNode(1, 53)}
+	hook, _ := setUpHook(&nodes)
+
+	attempts := 0
+	hook.run = func(hook string, env []string) error {
+		attempts++
+		return errors.New("resolvectl failed")
+	}
+
+	if err := hook.Apply(); err == nil {
+		t.Fatalf(`expected the failure to be returned`)
+	}
+
+	hook.Apply()
+
+	if attempts != 2 {
+		t.Fatalf(`expected the hook to be retried`)
+	}
+}
@@ -0,0 +1,83 @@
+// events records notable changes the daemon observes in its meshes so
+// that operators can see them without reading the logs
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
+)
+
+// MAX_EVENTS: number of events kept before the oldest are dropped
+const MAX_EVENTS = 100
+
+// EventType: kind of event
+type EventType string
+
+const (
+	// ALIAS_CONFLICT: several nodes in a mesh claim the same alias
+	ALIAS_CONFLICT EventType = "alias-conflict"
+	// ALIAS_CONFLICT_RESOLVED: nodes no longer claim the same alias
+	ALIAS_CONFLICT_RESOLVED EventType = "alias-conflict-resolved"
+)
+
+// Event: something that happened in a mesh
+type Event struct {
+	// Timestamp: UNIX time the event was recorded
+	Timestamp int64
+	MeshId    string
+	Type      EventType
+	Message   string
+}
+
+// Log: the most recent events in every mesh
+type Log struct {
+	lock   sync.RWMutex
+	events []Event
+	now    func() time.Time
+}
+
+// NewLog: create an empty event log
+func NewLog() *Log {
+	return &Log{events: make([]Event, 0), now: time.Now}
+}
+
+// Record: record an event in the mesh and write it to the daemon's log
+func (l *Log) Record(meshId string, eventType EventType, format string, args ...any) {
+	event := Event{
+		Timestamp: l.now().Unix(),
+		MeshId:    meshId,
+		Type:      eventType,
+		Message:   fmt.Sprintf(format, args...),
+	}
+
+	logging.Log.WriteWarnf("%s in %s: %s", event.Type, meshId, event.Message)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.events = append(l.events, event)
+
+	if len(l.events) > MAX_EVENTS {
+		l.events = l.events[len(l.events)-MAX_EVENTS:]
+	}
+}
+
+// List: the events recorded in the mesh oldest first. An empty mesh id
+// lists the events in every mesh
+func (l *Log) List(meshId string) []Event {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	events := make([]Event, 0)
+
+	for _, event := range l.events {
+		if meshId == "" || event.MeshId == meshId {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,33 @@
+package events
+
+import "testing"
+
+func TestListFiltersByMesh(t *testing.T) {
+	log := NewLog()
+	log.Record("mesh1", ALIAS_CONFLICT, "alias %s", "web")
+	log.Record("mesh2", ALIAS_CONFLICT, "alias %s", "db")
+
+	events := log.List("mesh1")
+
+	if len(events) != 1 || events[0].Message != "alias web" {
+		t.Fatalf(`expected the event in mesh1 got %v`, events)
+	}
+
+	if len(log.List("")) != 2 {
+		t.Fatalf(`expected the events in every mesh`)
+	}
+}
+
+func TestRecordDropsOldestEvents(t *testing.T) {
+	log := NewLog()
+
+	for i := 0; i < MAX_EVENTS+5; i++ {
+		log.Record("mesh", ALIAS_CONFLICT, "event %d", i)
+	}
+
+	events := log.List("mesh")
+
+	if len(events) != MAX_EVENTS || events[0].Message != "event 5" {
+		t.Fatalf(`expected the %d most recent events`, MAX_EVENTS)
+	}
+}
@@ -0,0 +1,158 @@
+// health runs the local health checks of the services the node offers
+// and publishes the services with their status in the node's meshes
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+)
+
+// MAX_OUTPUT: maximum number of bytes of output kept from a check
+const MAX_OUTPUT = 256
+
+// Result: the outcome of running a check
+type Result struct {
+	Status mesh.ServiceStatus
+	// Output: why the check is not passing if it is not
+	Output string
+}
+
+// truncate: limit the output of a check to MAX_OUTPUT bytes
+func truncate(output string) string {
+	output = strings.TrimSpace(output)
+
+	if len(output) > MAX_OUTPUT {
+		return output[:MAX_OUTPUT]
+	}
+
+	return output
+}
+
+// checkTcp: passing if a connection can be made to the target
+func checkTcp(ctx context.Context, target string) Result {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+
+	if err != nil {
+		return Result{Status: mesh.SERVICE_CRITICAL, Output: err.Error()}
+	}
+
+	conn.Close()
+	return Result{Status: mesh.SERVICE_PASSING}
+}
+
+// checkHttp: passing if a GET of the target returns 2xx, warning if it
+// returns 429 Too Many Requests and critical otherwise
+func checkHttp(ctx context.Context, target string) Result {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+
+	if err != nil {
+		return Result{Status: mesh.SERVICE_CRITICAL, Output: err.Error()}
+	}
+
+	response, err := http.DefaultClient.Do(request)
+
+	if err != nil {
+		return Result{Status: mesh.SERVICE_CRITICAL, Output: err.Error()}
+	}
+
+	response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return Result{Status: mesh.SERVICE_PASSING}
+	case response.StatusCode == http.StatusTooManyRequests:
+		return Result{Status: mesh.SERVICE_WARNING, Output: response.Status}
+	}
+
+	return Result{Status: mesh.SERVICE_CRITICAL, Output: response.Status}
+}
+
+// checkExec: passing if the command exits with 0, warning if it exits
+// with 1 and critical otherwise
+func checkExec(ctx context.Context, command []string) Result {
+	output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+
+	if err == nil {
+		return Result{Status: mesh.SERVICE_PASSING}
+	}
+
+	var exitErr *exec.ExitError
+
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return Result{Status: mesh.SERVICE_WARNING, Output: truncate(string(output))}
+	}
+
+	if len(output) == 0 {
+		output = []byte(err.Error())
+	}
+
+	return Result{Status: mesh.SERVICE_CRITICAL, Output: truncate(string(output))}
+}
+
+// RunCheck: run the check of the service listening on the port. The
+// check is critical if it does not complete within its timeout
+func RunCheck(check *conf.CheckConfiguration, port int) Result {
+	timeout := time.Duration(check.Timeout) * time.Second
+
+	if timeout == 0 {
+		timeout = conf.DEFAULT_CHECK_TIMEOUT * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch check.Type {
+	case conf.TCP_CHECK:
+		target := check.Target
+
+		if target == "" {
+			target = net.JoinHostPort("localhost", fmt.Sprint(port))
+		}
+
+		return checkTcp(ctx, target)
+	case conf.HTTP_CHECK:
+		target := check.Target
+
+		if target == "" {
+			target = fmt.Sprintf("http://%s/", net.JoinHostPort("localhost", fmt.Sprint(port)))
+		}
+
+		return checkHttp(ctx, target)
+	case conf.EXEC_CHECK:
+		if len(check.Command) == 0 {
+			return Result{Status: mesh.SERVICE_CRITICAL, Output: "no command to run"}
+		}
+
+		return checkExec(ctx, check.Command)
+	}
+
+	return Result{Status: mesh.SERVICE_CRITICAL, Output: fmt.Sprintf("unknown check type %s", check.Type)}
+}
+
+// Aggregate: the status of a service given the results of its checks.
+// Critical if any check is critical, warning if any check is warning
+// and passing otherwise
+func Aggregate(results []Result) mesh.ServiceStatus {
+	status := mesh.SERVICE_PASSING
+
+	for _, result := range results {
+		switch result.Status {
+		case mesh.SERVICE_CRITICAL:
+			return mesh.SERVICE_CRITICAL
+		case mesh.SERVICE_WARNING:
+			status = mesh.SERVICE_WARNING
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,94 @@
+package health
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+)
+
+func TestTcpCheckPassing(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	defer listener.Close()
+
+	result := RunCheck(&conf.CheckConfiguration{Type: conf.TCP_CHECK, Target: listener.Addr().String(), Timeout: 1}, 0)
+
+	if result.Status != mesh.SERVICE_PASSING {
+		t.Fatalf(`expected check to pass got %s: %s`, result.Status, result.Output)
+	}
+}
+
+func TestTcpCheckCritical(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	target := listener.Addr().String()
+	listener.Close()
+
+	result := RunCheck(&conf.CheckConfiguration{Type: conf.TCP_CHECK, Target: target, Timeout: 1}, 0)
+
+	if result.Status != mesh.SERVICE_CRITICAL {
+		t.Fatalf(`expected check of a closed port to be critical got %s`, result.Status)
+	}
+}
+
+func TestHttpCheckStatus(t *testing.T) {
+	statuses := map[int]mesh.ServiceStatus{
+		http.StatusOK:                 mesh.SERVICE_PASSING,
+		http.StatusTooManyRequests:    mesh.SERVICE_WARNING,
+		http.StatusServiceUnavailable: mesh.SERVICE_CRITICAL,
+	}
+
+	for code, expected := range statuses {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}))
+
+		result := RunCheck(&conf.CheckConfiguration{Type: conf.HTTP_CHECK, Target: server.URL, Timeout: 1}, 0)
+		server.Close()
+
+		if result.Status != expected {
+			t.Fatalf(`expected %d to be %s got %s`, code, expected, result.Status)
+		}
+	}
+}
+
+func TestExecCheckExitCodes(t *testing.T) {
+	commands := map[string]mesh.ServiceStatus{
+		"exit 0": mesh.SERVICE_PASSING,
+		"exit 1": mesh.SERVICE_WARNING,
+		"exit 2": mesh.SERVICE_CRITICAL,
+	}
+
+	for command, expected := range commands {
+		check := &conf.CheckConfiguration{Type: conf.EXEC_CHECK, Command: []string{"sh", "-c", command}, Timeout: 1}
+
+		if result := RunCheck(check, 0); result.Status != expected {
+			t.Fatalf(`expected %q to be %s got %s`, command, expected, result.Status)
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	passing := Result{Status: mesh.SERVICE_PASSING}
+	warning := Result{Status: mesh.SERVICE_WARNING}
+	critical := Result{Status: mesh.SERVICE_CRITICAL}
+
+	if Aggregate(nil) != mesh.SERVICE_PASSING {
+		t.Fatalf(`expected a service without checks to pass`)
+	}
+
+	if Aggregate([]Result{passing, warning}) != mesh.SERVICE_WARNING {
+		t.Fatalf(`expected a warning check to make the service warning`)
+	}
+
+	if Aggregate([]Result{critical, warning, passing}) != mesh.SERVICE_CRITICAL {
+		t.Fatalf(`expected a critical check to make the service critical`)
+	}
+}
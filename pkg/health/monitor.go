@@ -0,0 +1,152 @@
+package health
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+)
+
+// Monitor: runs the health checks of the node's services and publishes
+// the services in the node's meshes. A record is republished when its
+// status changes or half of its TTL has passed so that it does not
+// expire while the service is healthy
+type Monitor struct {
+	meshManager mesh.MeshManager
+	conf        *conf.DaemonConfiguration
+	// lock: guards published and status
+	lock sync.Mutex
+	// published: the record last published for each service in each mesh
+	published map[string]map[string]mesh.Service
+	// status: the status of each service when it was last checked
+	status   map[string]mesh.ServiceStatus
+	runCheck func(check *conf.CheckConfiguration, port int) Result
+}
+
+// NewMonitorParams: params to create a new monitor
+type NewMonitorParams struct {
+	MeshManager mesh.MeshManager
+	Conf        *conf.DaemonConfiguration
+}
+
+// NewMonitor: create a new monitor
+func NewMonitor(params *NewMonitorParams) *Monitor {
+	return &Monitor{
+		meshManager: params.MeshManager,
+		conf:        params.Conf,
+		published:   make(map[string]map[string]mesh.Service),
+		status:      make(map[string]mesh.ServiceStatus),
+		runCheck:    RunCheck,
+	}
+}
+
+// check: run the checks of the service and log changes in its status
+func (m *Monitor) check(service *conf.ServiceConfiguration) mesh.ServiceStatus {
+	results := make([]Result, len(service.Checks))
+
+	for i := range service.Checks {
+		results[i] = m.runCheck(&service.Checks[i], service.Port)
+	}
+
+	status := Aggregate(results)
+
+	m.lock.Lock()
+	previous, checked := m.status[service.Name]
+	m.status[service.Name] = status
+	m.lock.Unlock()
+
+	if checked && previous == status {
+		return status
+	}
+
+	for _, result := range results {
+		if result.Status != mesh.SERVICE_PASSING {
+			logging.Log.WriteWarnf("service %s is %s: %s", service.Name, result.Status, result.Output)
+		}
+	}
+
+	if status == mesh.SERVICE_PASSING {
+		logging.Log.WriteInfof("service %s is %s", service.Name, status)
+	}
+
+	return status
+}
+
+// due: returns true if the record needs to be published in the mesh
+func (m *Monitor) due(meshId string, record *mesh.Service) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	published, ok := m.published[meshId][record.Name]
+
+	if !ok || published.Status != record.Status {
+		return true
+	}
+
+	return record.TTL != 0 && record.Updated >= published.Updated+record.TTL/2
+}
+
+// setPublished: record that the record was published in the mesh
+func (m *Monitor) setPublished(meshId string, record mesh.Service) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.published[meshId]; !ok {
+		m.published[meshId] = make(map[string]mesh.Service)
+	}
+
+	m.published[meshId][record.Name] = record
+}
+
+// Check: run the checks of the named service and publish the service
+// in every mesh it is offered in if it is due
+func (m *Monitor) Check(name string) error {
+	index := slices.IndexFunc(m.conf.Services, func(s conf.ServiceConfiguration) bool {
+		return s.Name == name
+	})
+
+	if index == -1 {
+		return nil
+	}
+
+	service := &m.conf.Services[index]
+	status := m.check(service)
+	self := m.meshManager.GetPublicKey().String()
+
+	for meshId, theMesh := range m.meshManager.GetMeshes() {
+		if len(service.Meshes) != 0 && !slices.Contains(service.Meshes, meshId) {
+			continue
+		}
+
+		// We are yet to be added to the mesh
+		if !theMesh.NodeExists(self) {
+			continue
+		}
+
+		record := mesh.Service{
+			Name:     service.Name,
+			Port:     service.Port,
+			Protocol: service.Protocol,
+			Tags:     service.Tags,
+			Metadata: service.Metadata,
+			TTL:      int64(service.Ttl),
+			Status:   status,
+			Updated:  time.Now().Unix(),
+		}
+
+		if !m.due(meshId, &record) {
+			continue
+		}
+
+		if err := m.meshManager.PutServiceRecord(meshId, record); err != nil {
+			return err
+		}
+
+		m.setPublished(meshId, record)
+	}
+
+	return nil
+}
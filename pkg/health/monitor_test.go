@@ -0,0 +1,94 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// providerStub: a mesh the node is part of
+type providerStub struct {
+	mesh.MeshProvider
+}
+
+func (p *providerStub) NodeExists(nodeId string) bool {
+	return true
+}
+
+// managerStub: records the services the monitor publishes
+type managerStub struct {
+	mesh.MeshManager
+	key       wgtypes.Key
+	published []mesh.Service
+}
+
+func (m *managerStub) GetPublicKey() *wgtypes.Key {
+	return &m.key
+}
+
+func (m *managerStub) GetMeshes() map[string]mesh.MeshProvider {
+	return map[string]mesh.MeshProvider{"mesh": &providerStub{}}
+}
+
+func (m *managerStub) PutServiceRecord(meshId string, service mesh.Service) error {
+	m.published = append(m.published, service)
+	return nil
+}
+
+func newMonitor(status *mesh.ServiceStatus) (*Monitor, *managerStub) {
+	key, _ := wgtypes.GeneratePrivateKey()
+	manager := &managerStub{key: key}
+
+	monitor := NewMonitor(&NewMonitorParams{
+		MeshManager: manager,
+		Conf: &conf.DaemonConfiguration{
+			Services: []conf.ServiceConfiguration{{
+				Name:     "db",
+				Port:     5432,
+				Protocol: "tcp",
+				Ttl:      3600,
+				Checks:   []conf.CheckConfiguration{{Type: conf.TCP_CHECK}},
+			}},
+		},
+	})
+
+	monitor.runCheck = func(check *conf.CheckConfiguration, port int) Result {
+		return Result{Status: *status}
+	}
+
+	return monitor, manager
+}
+
+func TestMonitorPublishesService(t *testing.T) {
+	status := mesh.SERVICE_PASSING
+	monitor, manager := newMonitor(&status)
+
+	if err := monitor.Check("db"); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if len(manager.published) != 1 || manager.published[0].Port != 5432 || manager.published[0].Status != status {
+		t.Fatalf(`expected the service to be published`)
+	}
+}
+
+func TestMonitorRepublishesOnlyOnChange(t *testing.T) {
+	status := mesh.SERVICE_PASSING
+	monitor, manager := newMonitor(&status)
+
+	monitor.Check("db")
+	monitor.Check("db")
+
+	if len(manager.published) != 1 {
+		t.Fatalf(`expected an unchanged service not to be republished before its TTL`)
+	}
+
+	status = mesh.SERVICE_CRITICAL
+	monitor.Check("db")
+
+	if len(manager.published) != 2 || manager.published[1].Status != mesh.SERVICE_CRITICAL {
+		t.Fatalf(`expected the service to be republished when its status changes`)
+	}
+}
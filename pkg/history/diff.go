@@ -0,0 +1,169 @@
+package history
+
+import (
+	"slices"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+)
+
+// RouteChange: routes a node started and stopped advertising
+type RouteChange struct {
+	PublicKey string
+	Added     []string
+	Removed   []string
+}
+
+// ServiceChange: services a node added, removed or changed
+type ServiceChange struct {
+	PublicKey string
+	Added     map[string]string
+	Removed   []string
+	Changed   map[string]string
+}
+
+// ServiceRecordChange: service records a node added, removed or changed
+type ServiceRecordChange struct {
+	PublicKey string
+	Added     []Service
+	Removed   []string
+	Changed   []Service
+}
+
+// Diff: the changes to a mesh between two points in time
+type Diff struct {
+	From           int64
+	To             int64
+	Joined         []Node
+	Left           []Node
+	RouteChanges   []RouteChange
+	ServiceChanges []ServiceChange
+	RecordChanges  []ServiceRecordChange
+}
+
+// difference: elements in s1 that are not in s2
+func difference(s1, s2 []string) []string {
+	return lib.Filter(s1, func(s string) bool {
+		return !slices.Contains(s2, s)
+	})
+}
+
+// DiffSnapshots: compute the joins, leaves, route and service changes
+// between the from and to snapshot
+func DiffSnapshots(from, to *Snapshot) *Diff {
+	diff := &Diff{
+		From:           from.Timestamp,
+		To:             to.Timestamp,
+		Joined:         make([]Node, 0),
+		Left:           make([]Node, 0),
+		RouteChanges:   make([]RouteChange, 0),
+		ServiceChanges: make([]ServiceChange, 0),
+		RecordChanges:  make([]ServiceRecordChange, 0),
+	}
+
+	keys := lib.MapKeys(to.Nodes)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		toNode := to.Nodes[key]
+		fromNode, ok := from.Nodes[key]
+
+		if !ok {
+			diff.Joined = append(diff.Joined, toNode)
+			continue
+		}
+
+		added := difference(toNode.Routes, fromNode.Routes)
+		removed := difference(fromNode.Routes, toNode.Routes)
+
+		if len(added) != 0 || len(removed) != 0 {
+			diff.RouteChanges = append(diff.RouteChanges, RouteChange{
+				PublicKey: key,
+				Added:     added,
+				Removed:   removed,
+			})
+		}
+
+		serviceChange := ServiceChange{
+			PublicKey: key,
+			Added:     make(map[string]string),
+			Removed:   make([]string, 0),
+			Changed:   make(map[string]string),
+		}
+
+		for service, value := range toNode.Services {
+			fromValue, ok := fromNode.Services[service]
+
+			if !ok {
+				serviceChange.Added[service] = value
+			} else if fromValue != value {
+				serviceChange.Changed[service] = value
+			}
+		}
+
+		for service := range fromNode.Services {
+			if _, ok := toNode.Services[service]; !ok {
+				serviceChange.Removed = append(serviceChange.Removed, service)
+			}
+		}
+
+		slices.Sort(serviceChange.Removed)
+
+		if len(serviceChange.Added) != 0 || len(serviceChange.Removed) != 0 || len(serviceChange.Changed) != 0 {
+			diff.ServiceChanges = append(diff.ServiceChanges, serviceChange)
+		}
+
+		if recordChange := diffRecords(key, &fromNode, &toNode); recordChange != nil {
+			diff.RecordChanges = append(diff.RecordChanges, *recordChange)
+		}
+	}
+
+	keys = lib.MapKeys(from.Nodes)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if _, ok := to.Nodes[key]; !ok {
+			diff.Left = append(diff.Left, from.Nodes[key])
+		}
+	}
+
+	return diff
+}
+
+// diffRecords: compute the service records added, removed or changed
+// by the node. Returns nil if the records are the same
+func diffRecords(key string, fromNode, toNode *Node) *ServiceRecordChange {
+	change := &ServiceRecordChange{
+		PublicKey: key,
+		Added:     make([]Service, 0),
+		Removed:   make([]string, 0),
+		Changed:   make([]Service, 0),
+	}
+
+	names := lib.MapKeys(toNode.ServiceRecords)
+	slices.Sort(names)
+
+	for _, name := range names {
+		toRecord := toNode.ServiceRecords[name]
+		fromRecord, ok := fromNode.ServiceRecords[name]
+
+		if !ok {
+			change.Added = append(change.Added, toRecord)
+		} else if !serviceEqual(&fromRecord, &toRecord) {
+			change.Changed = append(change.Changed, toRecord)
+		}
+	}
+
+	for name := range fromNode.ServiceRecords {
+		if _, ok := toNode.ServiceRecords[name]; !ok {
+			change.Removed = append(change.Removed, name)
+		}
+	}
+
+	slices.Sort(change.Removed)
+
+	if len(change.Added) == 0 && len(change.Removed) == 0 && len(change.Changed) == 0 {
+		return nil
+	}
+
+	return change
+}
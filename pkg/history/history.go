@@ -0,0 +1,353 @@
+// history provides a bounded on-disk record of how each mesh
+// has changed over time
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+)
+
+// Node: the state of a node at a point in time. Excludes the
+// timestamp so that heartbeats do not produce new history entries
+type Node struct {
+	PublicKey    string            `json:"publicKey"`
+	HostEndpoint string            `json:"hostEndpoint"`
+	WgEndpoint   string            `json:"wgEndpoint"`
+	WgHost       string            `json:"wgHost"`
+	Alias        string            `json:"alias"`
+	Description  string            `json:"description"`
+	Type         string            `json:"type"`
+	Routes       []string          `json:"routes"`
+	Services     map[string]string `json:"services"`
+	// ServiceRecords: the structured services offered by the node
+	ServiceRecords map[string]Service `json:"serviceRecords"`
+}
+
+// Service: the state of a service record at a point in time. Excludes
+// the time the record was updated so that refreshes do not produce
+// new history entries
+type Service struct {
+	Name     string             `json:"name"`
+	Port     int                `json:"port"`
+	Protocol string             `json:"protocol"`
+	Tags     []string           `json:"tags"`
+	Metadata map[string]string  `json:"metadata"`
+	TTL      int64              `json:"ttl"`
+	Status   mesh.ServiceStatus `json:"status"`
+}
+
+func serviceEqual(s1, s2 *Service) bool {
+	return s1.Name == s2.Name && s1.Port == s2.Port && s1.Protocol == s2.Protocol &&
+		slices.Equal(s1.Tags, s2.Tags) && maps.Equal(s1.Metadata, s2.Metadata) &&
+		s1.TTL == s2.TTL && s1.Status == s2.Status
+}
+
+// Snapshot: the state of the mesh at a point in time
+type Snapshot struct {
+	// Timestamp: UNIX time in seconds the snapshot was taken
+	Timestamp int64 `json:"timestamp"`
+	// Nodes: nodes in the mesh keyed by their public key
+	Nodes map[string]Node `json:"nodes"`
+}
+
+// equal: returns true if the two snapshots describe the same mesh
+func (s *Snapshot) equal(other *Snapshot) bool {
+	if len(s.Nodes) != len(other.Nodes) {
+		return false
+	}
+
+	for key, node := range s.Nodes {
+		otherNode, ok := other.Nodes[key]
+
+		if !ok || !nodeEqual(&node, &otherNode) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func nodeEqual(n1, n2 *Node) bool {
+	if n1.PublicKey != n2.PublicKey || n1.HostEndpoint != n2.HostEndpoint ||
+		n1.WgEndpoint != n2.WgEndpoint || n1.WgHost != n2.WgHost ||
+		n1.Alias != n2.Alias || n1.Description != n2.Description || n1.Type != n2.Type {
+		return false
+	}
+
+	if !slices.Equal(n1.Routes, n2.Routes) || len(n1.Services) != len(n2.Services) {
+		return false
+	}
+
+	for key, value := range n1.Services {
+		if otherValue, ok := n2.Services[key]; !ok || otherValue != value {
+			return false
+		}
+	}
+
+	if len(n1.ServiceRecords) != len(n2.ServiceRecords) {
+		return false
+	}
+
+	for key, value := range n1.ServiceRecords {
+		if otherValue, ok := n2.ServiceRecords[key]; !ok || !serviceEqual(&value, &otherValue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewSnapshot: converts the mesh snapshot into a history snapshot
+func NewSnapshot(snapshot mesh.MeshSnapshot, timestamp time.Time) *Snapshot {
+	nodes := make(map[string]Node)
+
+	for key, node := range snapshot.GetNodes() {
+		routes := lib.Map(node.GetRoutes(), func(r mesh.Route) string {
+			return r.GetDestination().String()
+		})
+		slices.Sort(routes)
+
+		services := make(map[string]string)
+
+		for service, value := range node.GetServices() {
+			services[service] = value
+		}
+
+		records := make(map[string]Service)
+
+		for name, service := range node.GetServiceRecords() {
+			tags := slices.Clone(service.Tags)
+			slices.Sort(tags)
+
+			records[name] = Service{
+				Name:     service.Name,
+				Port:     service.Port,
+				Protocol: service.Protocol,
+				Tags:     tags,
+				Metadata: maps.Clone(service.Metadata),
+				TTL:      service.TTL,
+				Status:   service.Status,
+			}
+		}
+
+		wgHost := ""
+
+		if node.GetWgHost() != nil {
+			wgHost = node.GetWgHost().String()
+		}
+
+		nodes[key] = Node{
+			PublicKey:      key,
+			HostEndpoint:   node.GetHostEndpoint(),
+			WgEndpoint:     node.GetWgEndpoint(),
+			WgHost:         wgHost,
+			Alias:          node.GetAlias(),
+			Description:    node.GetDescription(),
+			Type:           string(node.GetType()),
+			Routes:         routes,
+			Services:       services,
+			ServiceRecords: records,
+		}
+	}
+
+	return &Snapshot{
+		Timestamp: timestamp.Unix(),
+		Nodes:     nodes,
+	}
+}
+
+// Entry: summary of a snapshot in the history
+type Entry struct {
+	Timestamp int64
+	NodeCount int
+}
+
+// Store: stores the history of each mesh
+type Store interface {
+	// Record: records the snapshot of the mesh. Snapshots identical
+	// to the previous one are ignored
+	Record(meshId string, snapshot *Snapshot) error
+	// List: list every snapshot recorded for the mesh, oldest first
+	List(meshId string) ([]Entry, error)
+	// At: get the state of the mesh at the given time
+	At(meshId string, at time.Time) (*Snapshot, error)
+}
+
+// FileStoreParams: parameters required to construct a file store
+type FileStoreParams struct {
+	// Path: directory to store the history in
+	Path string
+	// MaxEntries: maximum number of snapshots to keep per mesh
+	MaxEntries int
+	// Retention: how long to keep snapshots for
+	Retention time.Duration
+	// CompactAfter: age after which snapshots are thinned out
+	CompactAfter time.Duration
+	// CompactInterval: keep at most one snapshot per interval once compacted
+	CompactInterval time.Duration
+}
+
+// FileStore: stores the history of each mesh as a JSON file
+// per mesh
+type FileStore struct {
+	params    FileStoreParams
+	lock      sync.Mutex
+	snapshots map[string][]*Snapshot
+	now       func() time.Time
+}
+
+func (f *FileStore) meshPath(meshId string) string {
+	return filepath.Join(f.params.Path, meshId+".json")
+}
+
+// load: get the snapshots of the mesh loading them from disk
+// if required. Must hold the lock
+func (f *FileStore) load(meshId string) ([]*Snapshot, error) {
+	if snapshots, ok := f.snapshots[meshId]; ok {
+		return snapshots, nil
+	}
+
+	snapshots := make([]*Snapshot, 0)
+	contents, err := os.ReadFile(f.meshPath(meshId))
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(contents, &snapshots); err != nil {
+			return nil, fmt.Errorf("history: %s is corrupt: %s", f.meshPath(meshId), err.Error())
+		}
+	}
+
+	f.snapshots[meshId] = snapshots
+	return snapshots, nil
+}
+
+// save: write the history of the mesh to disk. Must hold the lock
+func (f *FileStore) save(meshId string, snapshots []*Snapshot) error {
+	contents, err := json.Marshal(snapshots)
+
+	if err != nil {
+		return err
+	}
+
+	tmpFile := f.meshPath(meshId) + ".tmp"
+
+	if err := os.WriteFile(tmpFile, contents, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile, f.meshPath(meshId))
+}
+
+// compact: drop snapshots outside of the retention period, thin out
+// old snapshots and bound the number of snapshots
+func (f *FileStore) compact(snapshots []*Snapshot) []*Snapshot {
+	now := f.now().Unix()
+	compacted := make([]*Snapshot, 0, len(snapshots))
+
+	var lastKept int64 = 0
+
+	for index, snapshot := range snapshots {
+		age := now - snapshot.Timestamp
+		isLatest := index == len(snapshots)-1
+
+		if f.params.Retention > 0 && age > int64(f.params.Retention.Seconds()) && !isLatest {
+			continue
+		}
+
+		if f.params.CompactAfter > 0 && age > int64(f.params.CompactAfter.Seconds()) && !isLatest &&
+			len(compacted) != 0 && snapshot.Timestamp-lastKept < int64(f.params.CompactInterval.Seconds()) {
+			continue
+		}
+
+		compacted = append(compacted, snapshot)
+		lastKept = snapshot.Timestamp
+	}
+
+	if f.params.MaxEntries > 0 && len(compacted) > f.params.MaxEntries {
+		compacted = compacted[len(compacted)-f.params.MaxEntries:]
+	}
+
+	return compacted
+}
+
+// Record: records the snapshot of the mesh compacting the history
+func (f *FileStore) Record(meshId string, snapshot *Snapshot) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	snapshots, err := f.load(meshId)
+
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) != 0 && snapshots[len(snapshots)-1].equal(snapshot) {
+		return nil
+	}
+
+	snapshots = f.compact(append(snapshots, snapshot))
+	f.snapshots[meshId] = snapshots
+	return f.save(meshId, snapshots)
+}
+
+// List: list the snapshots recorded for the mesh oldest first
+func (f *FileStore) List(meshId string) ([]Entry, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	snapshots, err := f.load(meshId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lib.Map(snapshots, func(s *Snapshot) Entry {
+		return Entry{Timestamp: s.Timestamp, NodeCount: len(s.Nodes)}
+	}), nil
+}
+
+// At: get the most recent snapshot taken at or before the given time
+func (f *FileStore) At(meshId string, at time.Time) (*Snapshot, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	snapshots, err := f.load(meshId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for index := len(snapshots) - 1; index >= 0; index-- {
+		if snapshots[index].Timestamp <= at.Unix() {
+			return snapshots[index], nil
+		}
+	}
+
+	return nil, fmt.Errorf("history: no snapshot of %s at or before %s", meshId, at.Format(time.RFC3339))
+}
+
+// NewFileStore: create a new file store, creates the directory
+// if it does not exist
+func NewFileStore(params *FileStoreParams) (*FileStore, error) {
+	if err := os.MkdirAll(params.Path, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		params:    *params,
+		snapshots: make(map[string][]*Snapshot),
+		now:       time.Now,
+	}, nil
+}
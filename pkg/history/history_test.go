@@ -0,0 +1,195 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+)
+
+func getTestStore(t *testing.T, params *FileStoreParams) *FileStore {
+	params.Path = t.TempDir()
+	store, err := NewFileStore(params)
+
+	if err != nil {
+		t.Fatalf(`could not create store %s`, err.Error())
+	}
+
+	return store
+}
+
+func getSnapshot(timestamp int64, nodes ...Node) *Snapshot {
+	snapshot := &Snapshot{
+		Timestamp: timestamp,
+		Nodes:     make(map[string]Node),
+	}
+
+	for _, node := range nodes {
+		snapshot.Nodes[node.PublicKey] = node
+	}
+
+	return snapshot
+}
+
+func TestRecordIgnoresIdenticalSnapshots(t *testing.T) {
+	store := getTestStore(t, &FileStoreParams{})
+
+	store.Record("mesh", getSnapshot(1, Node{PublicKey: "a"}))
+	store.Record("mesh", getSnapshot(2, Node{PublicKey: "a"}))
+
+	entries, _ := store.List("mesh")
+
+	if len(entries) != 1 {
+		t.Fatalf(`expected 1 entry got %d`, len(entries))
+	}
+}
+
+func TestRecordBoundsNumberOfEntries(t *testing.T) {
+	store := getTestStore(t, &FileStoreParams{MaxEntries: 2})
+
+	store.Record("mesh", getSnapshot(1, Node{PublicKey: "a"}))
+	store.Record("mesh", getSnapshot(2, Node{PublicKey: "b"}))
+	store.Record("mesh", getSnapshot(3, Node{PublicKey: "c"}))
+
+	entries, _ := store.List("mesh")
+
+	if len(entries) != 2 || entries[0].Timestamp != 2 {
+		t.Fatalf(`expected the oldest entry to be dropped`)
+	}
+}
+
+func TestRecordCompactsOldEntries(t *testing.T) {
+	store := getTestStore(t, &FileStoreParams{
+		CompactAfter:    time.Hour,
+		CompactInterval: 10 * time.Minute,
+	})
+	store.now = func() time.Time { return time.Unix(10000, 0) }
+
+	store.Record("mesh", getSnapshot(100, Node{PublicKey: "a"}))
+	store.Record("mesh", getSnapshot(200, Node{PublicKey: "b"}))
+	store.Record("mesh", getSnapshot(9990, Node{PublicKey: "c"}))
+
+	entries, _ := store.List("mesh")
+
+	if len(entries) != 2 {
+		t.Fatalf(`expected 2 entries got %d`, len(entries))
+	}
+}
+
+func TestAtReturnsMostRecentSnapshotBeforeTime(t *testing.T) {
+	store := getTestStore(t, &FileStoreParams{})
+
+	store.Record("mesh", getSnapshot(100, Node{PublicKey: "a"}))
+	store.Record("mesh", getSnapshot(200, Node{PublicKey: "b"}))
+
+	snapshot, err := store.At("mesh", time.Unix(150, 0))
+
+	if err != nil {
+		t.Fatalf(`error should not have returned`)
+	}
+
+	if snapshot.Timestamp != 100 {
+		t.Fatalf(`expected snapshot at 100 got %d`, snapshot.Timestamp)
+	}
+
+	if _, err := store.At("mesh", time.Unix(50, 0)); err == nil {
+		t.Fatalf(`error should have returned`)
+	}
+}
+
+func TestHistoryPersistsToDisk(t *testing.T) {
+	store := getTestStore(t, &FileStoreParams{})
+	store.Record("mesh", getSnapshot(100, Node{PublicKey: "a"}))
+
+	store2, _ := NewFileStore(&store.params)
+	entries, err := store2.List("mesh")
+
+	if err != nil || len(entries) != 1 {
+		t.Fatalf(`expected history to be loaded from disk`)
+	}
+}
+
+func TestDiffSnapshotsJoinsAndLeaves(t *testing.T) {
+	from := getSnapshot(1, Node{PublicKey: "a"}, Node{PublicKey: "b"})
+	to := getSnapshot(2, Node{PublicKey: "b"}, Node{PublicKey: "c"})
+
+	diff := DiffSnapshots(from, to)
+
+	if len(diff.Joined) != 1 || diff.Joined[0].PublicKey != "c" {
+		t.Fatalf(`expected c to have joined`)
+	}
+
+	if len(diff.Left) != 1 || diff.Left[0].PublicKey != "a" {
+		t.Fatalf(`expected a to have left`)
+	}
+}
+
+func TestDiffSnapshotsRouteAndServiceChanges(t *testing.T) {
+	from := getSnapshot(1, Node{
+		PublicKey: "a",
+		Routes:    []string{"fd00::/64"},
+		Services:  map[string]string{"web": "80", "db": "5432"},
+	})
+	to := getSnapshot(2, Node{
+		PublicKey: "a",
+		Routes:    []string{"fd01::/64"},
+		Services:  map[string]string{"web": "8080", "dns": "53"},
+	})
+
+	diff := DiffSnapshots(from, to)
+
+	if len(diff.RouteChanges) != 1 || diff.RouteChanges[0].Added[0] != "fd01::/64" || diff.RouteChanges[0].Removed[0] != "fd00::/64" {
+		t.Fatalf(`expected route fd00::/64 to be replaced by fd01::/64`)
+	}
+
+	if len(diff.ServiceChanges) != 1 {
+		t.Fatalf(`expected 1 service change got %d`, len(diff.ServiceChanges))
+	}
+
+	change := diff.ServiceChanges[0]
+
+	if change.Added["dns"] != "53" || change.Changed["web"] != "8080" || change.Removed[0] != "db" {
+		t.Fatalf(`service changes were not computed correctly`)
+	}
+}
+
+func TestDiffSnapshotsServiceRecordChanges(t *testing.T) {
+	from := getSnapshot(1, Node{
+		PublicKey: "a",
+		ServiceRecords: map[string]Service{
+			"web": {Name: "web", Port: 80, Protocol: "tcp"},
+			"db":  {Name: "db", Port: 5432, Protocol: "tcp"},
+		},
+	})
+	to := getSnapshot(2, Node{
+		PublicKey: "a",
+		ServiceRecords: map[string]Service{
+			"web": {Name: "web", Port: 80, Protocol: "tcp", Status: mesh.SERVICE_CRITICAL},
+			"dns": {Name: "dns", Port: 53, Protocol: "udp"},
+		},
+	})
+
+	if from.equal(to) {
+		t.Fatalf(`snapshots with different service records should differ`)
+	}
+
+	diff := DiffSnapshots(from, to)
+
+	if len(diff.RecordChanges) != 1 {
+		t.Fatalf(`expected 1 service record change got %d`, len(diff.RecordChanges))
+	}
+
+	change := diff.RecordChanges[0]
+
+	if len(change.Added) != 1 || change.Added[0].Name != "dns" {
+		t.Fatalf(`expected dns to have been added`)
+	}
+
+	if len(change.Changed) != 1 || change.Changed[0].Status != mesh.SERVICE_CRITICAL {
+		t.Fatalf(`expected web to have become critical`)
+	}
+
+	if len(change.Removed) != 1 || change.Removed[0] != "db" {
+		t.Fatalf(`expected db to have been removed`)
+	}
+}
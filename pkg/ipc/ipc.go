@@ -8,7 +8,12 @@ import (
 	ipcRPC "net/rpc"
 	"os"
 
+	"github.com/tim-beatham/smegmesh/pkg/acl"
 	"github.com/tim-beatham/smegmesh/pkg/ctrlserver"
+	"github.com/tim-beatham/smegmesh/pkg/events"
+	"github.com/tim-beatham/smegmesh/pkg/history"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/query"
 )
 
 const SockAddr = "/tmp/smeg.sock"
@@ -24,6 +29,24 @@ type MeshIpc interface {
 	PutAlias(args PutAliasArgs, reply *string) error
 	PutService(args PutServiceArgs, reply *string) error
 	DeleteService(args DeleteServiceArgs, reply *string) error
+	PutLabel(args PutLabelArgs, reply *string) error
+	DeleteLabel(args DeleteLabelArgs, reply *string) error
+	DiffState(args DiffStateArgs, reply *DiffStateReply) error
+	History(meshId string, reply *HistoryReply) error
+	ShowAt(args ShowAtArgs, reply *history.Snapshot) error
+	DiffHistory(args DiffHistoryArgs, reply *history.Diff) error
+	GrantJoin(args GrantJoinArgs, reply *string) error
+	CreateInvite(args CreateInviteArgs, reply *string) error
+	PendingJoins(meshId string, reply *PendingJoinsReply) error
+	DecideJoin(args DecideJoinArgs, reply *string) error
+	Evict(args EvictArgs, reply *string) error
+	GrantAdmin(args GrantAdminArgs, reply *string) error
+	ListAdmins(meshId string, reply *ListAdminsReply) error
+	RotateKey(_ string, reply *RotateKeyReply) error
+	SetAcl(args SetAclArgs, reply *string) error
+	GetAcl(meshId string, reply *GetAclReply) error
+	Watch(args WatchArgs, reply *WatchReply) error
+	Status(meshId string, reply *StatusReply) error
 }
 
 // WireGuardArgs are provided args specific to WireGuard
@@ -49,6 +72,15 @@ type WireGuardArgs struct {
 type NewMeshArgs struct {
 	// WgArgs are specific WireGuard args to use
 	WgArgs WireGuardArgs
+	// RequireApproval: new nodes must be approved by an admin
+	RequireApproval bool
+	// PresharedKeys: every pair of nodes agrees a WireGuard pre-shared key
+	PresharedKeys bool
+	// Name: human readable name of the mesh that can be used in place of
+	// its id
+	Name string
+	// Description: description of the mesh
+	Description string
 }
 
 type JoinMeshArgs struct {
@@ -58,6 +90,16 @@ type JoinMeshArgs struct {
 	IpAddress string
 	// WgArgs is the WireGuard parameters to use.
 	WgArgs WireGuardArgs
+	// Credential is a join credential issued by a member of the mesh
+	Credential string
+	// Invite is an invite issued by a member of the mesh. Provides the
+	// mesh id and bootstrap endpoints if they are not given
+	Invite string
+	// Alias is the alias to request if the mesh requires approval
+	Alias string
+	// Description is the description to give the mesh's admins if the
+	// mesh requires approval
+	Description string
 }
 
 // PutServiceArgs: args to place a service into the data store
@@ -73,6 +115,19 @@ type DeleteServiceArgs struct {
 	MeshId  string
 }
 
+// PutLabelArgs: args to place a label on a node
+type PutLabelArgs struct {
+	Key    string
+	Value  string
+	MeshId string
+}
+
+// DeleteLabelArgs: args to remove a label from a node
+type DeleteLabelArgs struct {
+	Key    string
+	MeshId string
+}
+
 // PutAliasArgs: args to assign an alias to a node
 type PutAliasArgs struct {
 	// Alias: represents the alias of the node
@@ -91,12 +146,18 @@ type PutDescriptionArgs struct {
 
 // GetMeshReply: ipc reply to get the mesh network
 type GetMeshReply struct {
+	// MeshId: id of the mesh even if the mesh was asked for by name
+	MeshId string
+	// Info: metadata of the mesh. Nil if the mesh has none
+	Info  *mesh.MeshInfo
 	Nodes []ctrlserver.MeshNode
 }
 
 // ListMeshReply: ipc reply of the networks the node is part of
 type ListMeshReply struct {
 	Meshes []string
+	// Infos: metadata of the meshes keyed by mesh id
+	Infos map[string]mesh.MeshInfo
 }
 
 // Querymesh: ipc args to query a mesh network
@@ -105,6 +166,180 @@ type QueryMesh struct {
 	MeshId string
 	// JMESPath: query string to query
 	Query string
+	// Selector: label selector such as env=prod,role!=db restricting
+	// the nodes queried
+	Selector string
+}
+
+// DiffStateArgs: ipc args to compare our store with a peer's
+type DiffStateArgs struct {
+	// MeshId: id of the mesh to compare
+	MeshId string
+	// Peer: gRPC endpoint of the peer to compare against
+	Peer string
+}
+
+// DiffStateReply: ipc reply containing the divergence between our
+// store and the peer's
+type DiffStateReply struct {
+	LocalHighestStale  uint64
+	RemoteHighestStale uint64
+	Differences        []mesh.StateDifference
+}
+
+// HistoryReply: ipc reply listing the snapshots recorded for a mesh
+type HistoryReply struct {
+	Entries []history.Entry
+}
+
+// ShowAtArgs: ipc args to get the state of the mesh at a point in time
+type ShowAtArgs struct {
+	// MeshId: id of the mesh to show
+	MeshId string
+	// At: UNIX time to show the mesh at
+	At int64
+}
+
+// DiffHistoryArgs: ipc args to compare the mesh at two points in time
+type DiffHistoryArgs struct {
+	// MeshId: id of the mesh to compare
+	MeshId string
+	// From: UNIX time of the earlier point in time
+	From int64
+	// To: UNIX time of the later point in time
+	To int64
+}
+
+// GrantJoinArgs: ipc args to issue a join credential
+type GrantJoinArgs struct {
+	// MeshId: id of the mesh to grant access to
+	MeshId string
+	// PublicKey: WireGuard public key of the node joining the mesh
+	PublicKey string
+	// Ttl: number of seconds the credential is valid for
+	Ttl int
+}
+
+// CreateInviteArgs: ipc args to issue an invite to a mesh
+type CreateInviteArgs struct {
+	// MeshId: id of the mesh to invite nodes to
+	MeshId string
+	// Ttl: number of seconds the invite is valid for
+	Ttl int
+	// Uses: number of nodes that may redeem the invite
+	Uses int
+	// Role: role nodes must join with, any role if empty
+	Role string
+}
+
+// PendingJoinsReply: ipc reply listing requests to join a mesh that
+// an admin has not decided on
+type PendingJoinsReply struct {
+	Requests []mesh.JoinRequest
+}
+
+// DecideJoinArgs: ipc args to approve or reject a node
+type DecideJoinArgs struct {
+	// MeshId: id of the mesh the node requested to join
+	MeshId string
+	// PublicKey: WireGuard public key of the node
+	PublicKey string
+	// Approve: true to admit the node false to reject it
+	Approve bool
+}
+
+// EvictArgs: ipc args to evict a node from a mesh
+type EvictArgs struct {
+	// MeshId: id of the mesh to evict the node from
+	MeshId string
+	// PublicKey: WireGuard public key of the node to evict
+	PublicKey string
+}
+
+// GrantAdminArgs: ipc args to grant or revoke a node's admin rights
+type GrantAdminArgs struct {
+	// MeshId: id of the mesh to grant admin rights in
+	MeshId string
+	// PublicKey: WireGuard public key of the node
+	PublicKey string
+	// Admin: true to grant admin rights false to revoke them
+	Admin bool
+}
+
+// ListAdminsReply: the owner and admins of a mesh
+type ListAdminsReply struct {
+	// Owner: public key of the node that created the mesh
+	Owner string
+	// Admins: public keys of the nodes with admin rights
+	Admins []string
+}
+
+// RotateKeyReply: the key the node is rotating to
+type RotateKeyReply struct {
+	// PublicKey: the key being replaced
+	PublicKey string
+	// Successor: the key that replaces it
+	Successor string
+	// Activates: UNIX time at which the successor replaces the key
+	Activates int64
+}
+
+// SetAclArgs: ipc args to set the access control policy of a mesh
+type SetAclArgs struct {
+	// MeshId: id of the mesh to set the policy of
+	MeshId string
+	// Policy: the policy to set. Nil clears the policy
+	Policy *acl.Policy
+}
+
+// GetAclReply: the access control policy of a mesh
+type GetAclReply struct {
+	// Policy: the policy. Nil if no policy is set
+	Policy *acl.Policy
+	// Author: public key of the node that set the policy
+	Author string
+	// Timestamp: UNIX time the policy was set
+	Timestamp int64
+}
+
+// WatchArgs: ipc args to wait for the nodes in the meshes to change
+type WatchArgs struct {
+	// Version: version of the nodes the caller has. Empty if the caller
+	// has none
+	Version string
+}
+
+// WatchReply: the nodes in every mesh the node has joined
+type WatchReply struct {
+	// Version: identifies the state of the nodes. Changes whenever a
+	// node changes but not when a node only sends a heartbeat
+	Version string
+	// Meshes: the nodes in each mesh keyed by mesh id
+	Meshes map[string][]query.QueryNode
+	// Names: the id of each named mesh keyed by its lowercased name
+	Names map[string]string
+}
+
+// MeshStatus: the state of the node in a mesh
+type MeshStatus struct {
+	MeshId string
+	// Nodes: number of nodes in the mesh
+	Nodes int
+	// Alias: the alias the node claimed
+	Alias string
+	// ResolvedAlias: the alias the node is known by. Differs from Alias
+	// if another node keeps the alias
+	ResolvedAlias string
+	// AliasConflicts: aliases claimed by more than one node
+	AliasConflicts []mesh.AliasConflict
+}
+
+// StatusReply: the state of the node in each mesh
+type StatusReply struct {
+	PublicKey string
+	Meshes    []MeshStatus
+	// Events: events the daemon observed in the meshes oldest first
+	Events []events.Event
 }
 
 // ClientIpc: Framework to invoke ipc calls to the daemon
@@ -129,6 +364,44 @@ type ClientIpc interface {
 	PutService(args PutServiceArgs, reply *string) error
 	// DeleteService: retract a service
 	DeleteService(args DeleteServiceArgs, reply *string) error
+	// PutLabel: place a label on yourself
+	PutLabel(args PutLabelArgs, reply *string) error
+	// DeleteLabel: remove a label from yourself
+	DeleteLabel(args DeleteLabelArgs, reply *string) error
+	// DiffState: compare our store with the store of a peer
+	DiffState(args DiffStateArgs, reply *DiffStateReply) error
+	// History: list the snapshots recorded for the mesh
+	History(meshId string, reply *HistoryReply) error
+	// ShowAt: get the state of the mesh at the given time
+	ShowAt(args ShowAtArgs, reply *history.Snapshot) error
+	// DiffHistory: get the changes to the mesh between two points in time
+	DiffHistory(args DiffHistoryArgs, reply *history.Diff) error
+	// GrantJoin: issue a credential granting a node access to the mesh
+	GrantJoin(args GrantJoinArgs, reply *string) error
+	// CreateInvite: issue an invite to the mesh
+	CreateInvite(args CreateInviteArgs, reply *string) error
+	// PendingJoins: list requests to join the mesh awaiting a decision
+	PendingJoins(meshId string, reply *PendingJoinsReply) error
+	// DecideJoin: approve or reject a node's request to join the mesh
+	DecideJoin(args DecideJoinArgs, reply *string) error
+	// Evict: evict a node from the mesh
+	Evict(args EvictArgs, reply *string) error
+	// GrantAdmin: grant or revoke a node's admin rights
+	GrantAdmin(args GrantAdminArgs, reply *string) error
+	// ListAdmins: list the owner and admins of the mesh
+	ListAdmins(meshId string, reply *ListAdminsReply) error
+	// RotateKey: replace the node's WireGuard key in every mesh
+	RotateKey(reply *RotateKeyReply) error
+	// SetAcl: set or clear the access control policy of the mesh
+	SetAcl(args SetAclArgs, reply *string) error
+	// GetAcl: get the access control policy of the mesh
+	GetAcl(meshId string, reply *GetAclReply) error
+	// Watch: wait until the nodes in the meshes differ from the given
+	// version or a timeout passes and return the nodes
+	Watch(args WatchArgs, reply *WatchReply) error
+	// Status: get the state of the node in the mesh or in every mesh
+	// if the mesh id is empty
+	Status(meshId string, reply *StatusReply) error
 }
 
 type SmegmeshIpc struct {
@@ -187,6 +460,78 @@ func (c *SmegmeshIpc) DeleteService(args DeleteServiceArgs, reply *string) error
 	return c.client.Call("IpcHandler.DeleteService", &args, reply)
 }
 
+func (c *SmegmeshIpc) PutLabel(args PutLabelArgs, reply *string) error {
+	return c.client.Call("IpcHandler.PutLabel", &args, reply)
+}
+
+func (c *SmegmeshIpc) DeleteLabel(args DeleteLabelArgs, reply *string) error {
+	return c.client.Call("IpcHandler.DeleteLabel", &args, reply)
+}
+
+func (c *SmegmeshIpc) DiffState(args DiffStateArgs, reply *DiffStateReply) error {
+	return c.client.Call("IpcHandler.DiffState", &args, reply)
+}
+
+func (c *SmegmeshIpc) History(meshId string, reply *HistoryReply) error {
+	return c.client.Call("IpcHandler.History", &meshId, reply)
+}
+
+func (c *SmegmeshIpc) ShowAt(args ShowAtArgs, reply *history.Snapshot) error {
+	return c.client.Call("IpcHandler.ShowAt", &args, reply)
+}
+
+func (c *SmegmeshIpc) DiffHistory(args DiffHistoryArgs, reply *history.Diff) error {
+	return c.client.Call("IpcHandler.DiffHistory", &args, reply)
+}
+
+func (c *SmegmeshIpc) GrantJoin(args GrantJoinArgs, reply *string) error {
+	return c.client.Call("IpcHandler.GrantJoin", &args, reply)
+}
+
+func (c *SmegmeshIpc) CreateInvite(args CreateInviteArgs, reply *string) error {
+	return c.client.Call("IpcHandler.CreateInvite", &args, reply)
+}
+
+func (c *SmegmeshIpc) PendingJoins(meshId string, reply *PendingJoinsReply) error {
+	return c.client.Call("IpcHandler.PendingJoins", &meshId, reply)
+}
+
+func (c *SmegmeshIpc) DecideJoin(args DecideJoinArgs, reply *string) error {
+	return c.client.Call("IpcHandler.DecideJoin", &args, reply)
+}
+
+func (c *SmegmeshIpc) Evict(args EvictArgs, reply *string) error {
+	return c.client.Call("IpcHandler.Evict", &args, reply)
+}
+
+func (c *SmegmeshIpc) GrantAdmin(args GrantAdminArgs, reply *string) error {
+	return c.client.Call("IpcHandler.GrantAdmin", &args, reply)
+}
+
+func (c *SmegmeshIpc) ListAdmins(meshId string, reply *ListAdminsReply) error {
+	return c.client.Call("IpcHandler.ListAdmins", &meshId, reply)
+}
+
+func (c *SmegmeshIpc) RotateKey(reply *RotateKeyReply) error {
+	return c.client.Call("IpcHandler.RotateKey", "", reply)
+}
+
+func (c *SmegmeshIpc) SetAcl(args SetAclArgs, reply *string) error {
+	return c.client.Call("IpcHandler.SetAcl", &args, reply)
+}
+
+func (c *SmegmeshIpc) GetAcl(meshId string, reply *GetAclReply) error {
+	return c.client.Call("IpcHandler.GetAcl", &meshId, reply)
+}
+
+func (c *SmegmeshIpc) Watch(args WatchArgs, reply *WatchReply) error {
+	return c.client.Call("IpcHandler.Watch", &args, reply)
+}
+
+func (c *SmegmeshIpc) Status(meshId string, reply *StatusReply) error {
+	return c.client.Call("IpcHandler.Status", &meshId, reply)
+}
+
 func (c *SmegmeshIpc) Close() error {
 	return c.client.Close()
 }
@@ -0,0 +1,47 @@
+// labels validates the labels nodes place on themselves and selects
+// nodes by their labels
+package labels
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MAX_LENGTH: maximum length of a label key or value
+const MAX_LENGTH = 63
+
+// labelPattern: keys and values are alphanumeric and may contain -, _
+// and . between the first and last characters
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+// ValidateKey: check the key can be used as a label key
+func ValidateKey(key string) error {
+	if len(key) > MAX_LENGTH || !labelPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+
+	return nil
+}
+
+// ValidateValue: check the value can be used as a label value. Values
+// may be empty
+func ValidateValue(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if len(value) > MAX_LENGTH || !labelPattern.MatchString(value) {
+		return fmt.Errorf("invalid label value %q", value)
+	}
+
+	return nil
+}
+
+// Validate: check the label can be placed on a node
+func Validate(key, value string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	return ValidateValue(value)
+}
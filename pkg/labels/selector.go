@@ -0,0 +1,179 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Operator: how a requirement compares a label
+type Operator string
+
+const (
+	EQUALS         Operator = "="
+	NOT_EQUALS     Operator = "!="
+	IN             Operator = "in"
+	NOT_IN         Operator = "notin"
+	EXISTS         Operator = "exists"
+	DOES_NOT_EXIST Operator = "!"
+)
+
+// Requirement: a condition on a single label
+type Requirement struct {
+	Key      string
+	Operator Operator
+	// Values: the values compared against. Empty for EXISTS and
+	// DOES_NOT_EXIST
+	Values []string
+}
+
+// Selector: selects the nodes whose labels meet every requirement. The
+// empty selector selects every node
+type Selector []Requirement
+
+// setPattern: matches requirements such as env in (prod, staging)
+var setPattern = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// splitRequirements: split the selector on the commas that separate
+// requirements ignoring commas within sets
+func splitRequirements(selector string) ([]string, error) {
+	requirements := make([]string, 0)
+	depth := 0
+	start := 0
+
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				requirements = append(requirements, selector[start:i])
+				start = i + 1
+			}
+		}
+
+		if depth < 0 || depth > 1 {
+			return nil, errors.New("unbalanced parentheses")
+		}
+	}
+
+	if depth != 0 {
+		return nil, errors.New("unbalanced parentheses")
+	}
+
+	return append(requirements, selector[start:]), nil
+}
+
+// parseRequirement: parse a single requirement of the selector
+func parseRequirement(requirement string) (Requirement, error) {
+	if matches := setPattern.FindStringSubmatch(requirement); matches != nil {
+		values := make([]string, 0)
+
+		for _, value := range strings.Split(matches[3], ",") {
+			value = strings.TrimSpace(value)
+
+			if err := ValidateValue(value); err != nil {
+				return Requirement{}, err
+			}
+
+			values = append(values, value)
+		}
+
+		return Requirement{Key: matches[1], Operator: Operator(matches[2]), Values: values}, nil
+	}
+
+	if key, ok := strings.CutPrefix(requirement, "!"); ok {
+		return Requirement{Key: strings.TrimSpace(key), Operator: DOES_NOT_EXIST}, nil
+	}
+
+	for _, operator := range []string{"!=", "==", "="} {
+		key, value, ok := strings.Cut(requirement, operator)
+
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		if err := ValidateValue(value); err != nil {
+			return Requirement{}, err
+		}
+
+		parsed := Requirement{Key: strings.TrimSpace(key), Operator: EQUALS, Values: []string{value}}
+
+		if operator == "!=" {
+			parsed.Operator = NOT_EQUALS
+		}
+
+		return parsed, nil
+	}
+
+	return Requirement{Key: requirement, Operator: EXISTS}, nil
+}
+
+// Parse: parse a selector such as env=prod,role!=db. Requirements are
+// separated by commas and take the forms key=value, key==value,
+// key!=value, key in (a, b), key notin (a, b), key and !key
+func Parse(selector string) (Selector, error) {
+	parsed := make(Selector, 0)
+
+	if strings.TrimSpace(selector) == "" {
+		return parsed, nil
+	}
+
+	requirements, err := splitRequirements(selector)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+
+	for _, requirement := range requirements {
+		requirement, err := parseRequirement(strings.TrimSpace(requirement))
+
+		if err == nil {
+			err = ValidateKey(requirement.Key)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+		}
+
+		parsed = append(parsed, requirement)
+	}
+
+	return parsed, nil
+}
+
+// Matches: returns true if the labels meet the requirement. Labels
+// that are absent never equal a value
+func (r *Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+
+	switch r.Operator {
+	case EQUALS, IN:
+		return ok && slices.Contains(r.Values, value)
+	case NOT_EQUALS, NOT_IN:
+		return !ok || !slices.Contains(r.Values, value)
+	case EXISTS:
+		return ok
+	case DOES_NOT_EXIST:
+		return !ok
+	}
+
+	return false
+}
+
+// Matches: returns true if the labels meet every requirement
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, requirement := range s {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,64 @@
+package labels
+
+import "testing"
+
+func TestParseInvalidSelector(t *testing.T) {
+	for _, selector := range []string{"=prod", "env in (prod", "env=pr od", "!"} {
+		if _, err := Parse(selector); err == nil {
+			t.Fatalf(`expected selector %q to be rejected`, selector)
+		}
+	}
+}
+
+func TestEmptySelectorMatchesEverything(t *testing.T) {
+	selector, err := Parse("")
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if !selector.Matches(nil) {
+		t.Fatalf(`expected the empty selector to match every node`)
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"env": "prod", "role": "web", "canary": ""}
+
+	matches := map[string]bool{
+		"env=prod":                      true,
+		"env==prod":                     true,
+		"env=staging":                   false,
+		"env=prod,role!=db":             true,
+		"env=prod,role!=web":            false,
+		"zone!=eu":                      true,
+		"canary":                        true,
+		"!canary":                       false,
+		"!zone":                         true,
+		"env in (staging, prod)":        true,
+		"role notin (web, db),env":      false,
+		"zone notin (eu),env in (prod)": true,
+	}
+
+	for expression, expected := range matches {
+		selector, err := Parse(expression)
+
+		if err != nil {
+			t.Fatalf(`%s`, err.Error())
+		}
+
+		if selector.Matches(labels) != expected {
+			t.Fatalf(`expected %q matching to be %t`, expression, expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("env", ""); err != nil {
+		t.Fatalf(`expected an empty value to be valid got %s`, err.Error())
+	}
+
+	if err := Validate("-env", "prod"); err == nil {
+		t.Fatalf(`expected a key starting with - to be rejected`)
+	}
+}
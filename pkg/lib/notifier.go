@@ -0,0 +1,30 @@
+package lib
+
+import "sync"
+
+// Notifier: wakes the goroutines waiting for the next change
+type Notifier struct {
+	lock    sync.Mutex
+	changed chan struct{}
+}
+
+// NewNotifier: create a notifier nothing is waiting on
+func NewNotifier() *Notifier {
+	return &Notifier{changed: make(chan struct{})}
+}
+
+// Notify: wake every goroutine waiting for a change
+func (n *Notifier) Notify() {
+	n.lock.Lock()
+	close(n.changed)
+	n.changed = make(chan struct{})
+	n.lock.Unlock()
+}
+
+// Wait: returns a channel that is closed by the next call to Notify.
+// Get the channel before reading the state so no change is missed
+func (n *Notifier) Wait() <-chan struct{} {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.changed
+}
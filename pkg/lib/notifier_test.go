@@ -0,0 +1,28 @@
+package lib
+
+import "testing"
+
+func TestNotifyWakesWaiters(t *testing.T) {
+	notifier := NewNotifier()
+	changed := notifier.Wait()
+
+	select {
+	case <-changed:
+		t.Fatalf(`expected the waiter to wait until notified`)
+	default:
+	}
+
+	notifier.Notify()
+
+	select {
+	case <-changed:
+	default:
+		t.Fatalf(`expected the waiter to be woken`)
+	}
+
+	select {
+	case <-notifier.Wait():
+		t.Fatalf(`expected later waiters to wait for the next change`)
+	default:
+	}
+}
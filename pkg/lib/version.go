@@ -0,0 +1,5 @@
+package lib
+
+// Version: the software version of smegmesh. Set at build time with
+// -ldflags "-X github.com/tim-beatham/smegmesh/pkg/lib.Version=<version>"
+var Version = "dev"
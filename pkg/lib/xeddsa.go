@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+
+	"filippo.io/edwards25519"
+	"filippo.io/edwards25519/field"
+)
+
+// XEdDSA signs messages with an X25519 key such as a WireGuard key
+// so that the signature is bound to the node's WireGuard identity.
+// See https://signal.org/docs/specifications/xeddsa/
+// Operations on the private key use constant time scalar and point
+// arithmetic so that signing does not leak the key through timing
+
+const (
+	XEDDSA_SIGNATURE_SIZE = 64
+)
+
+// hashScalar: SHA-512 of the inputs reduced modulo the group order
+func hashScalar(inputs ...[]byte) *edwards25519.Scalar {
+	hash := sha512.New()
+
+	for _, input := range inputs {
+		hash.Write(input)
+	}
+
+	scalar, _ := edwards25519.NewScalar().SetUniformBytes(hash.Sum(nil))
+	return scalar
+}
+
+// XEdDSASign: sign the message with the X25519 private key
+func XEdDSASign(privateKey [32]byte, message []byte) ([]byte, error) {
+	a, err := edwards25519.NewScalar().SetBytesWithClamping(privateKey[:])
+
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := new(edwards25519.Point).ScalarBaseMult(a).Bytes()
+
+	// The public key is the Edwards point with a sign bit of 0. Negate
+	// the private scalar in constant time if the sign bit is set
+	scalar := a.Bytes()
+	negated := edwards25519.NewScalar().Negate(a).Bytes()
+	subtle.ConstantTimeCopy(int(publicKey[31]>>7), scalar, negated)
+	publicKey[31] &= 0x7f
+
+	if _, err := a.SetCanonicalBytes(scalar); err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, 64)
+
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, 32)
+	prefix[0] = 0xfe
+
+	for i := 1; i < len(prefix); i++ {
+		prefix[i] = 0xff
+	}
+
+	r := hashScalar(prefix, a.Bytes(), message, random)
+	R := new(edwards25519.Point).ScalarBaseMult(r).Bytes()
+	h := hashScalar(R, publicKey, message)
+
+	s := edwards25519.NewScalar().MultiplyAdd(h, a, r)
+
+	return append(R, s.Bytes()...), nil
+}
+
+// XEdDSAVerify: verify the signature of the message against the
+// X25519 public key
+func XEdDSAVerify(publicKey [32]byte, message, signature []byte) error {
+	if len(signature) != XEDDSA_SIGNATURE_SIZE {
+		return errors.New("xeddsa: invalid signature length")
+	}
+
+	u, err := new(field.Element).SetBytes(publicKey[:])
+
+	// Reject u coordinates that are not canonically encoded
+	if err != nil || publicKey[31]&0x80 != 0 || [32]byte(u.Bytes()) != publicKey {
+		return errors.New("xeddsa: invalid public key")
+	}
+
+	// Convert the Montgomery u coordinate to the Edwards y coordinate
+	// y = (u - 1) / (u + 1)
+	one := new(field.Element).One()
+	denominator := new(field.Element).Add(u, one)
+
+	if denominator.Equal(new(field.Element).Zero()) == 1 {
+		return errors.New("xeddsa: invalid public key")
+	}
+
+	y := new(field.Element).Subtract(u, one)
+	y.Multiply(y, new(field.Element).Invert(denominator))
+
+	if !ed25519.Verify(ed25519.PublicKey(y.Bytes()), message, signature) {
+		return errors.New("xeddsa: invalid signature")
+	}
+
+	return nil
+}
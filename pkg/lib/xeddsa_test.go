@@ -0,0 +1,44 @@
+package lib
+
+import (
+	"testing"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func TestXEdDSASignThenVerify(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		key, _ := wgtypes.GeneratePrivateKey()
+		message := []byte("a message to sign")
+
+		signature, err := XEdDSASign(key, message)
+
+		if err != nil {
+			t.Fatalf(`%s`, err.Error())
+		}
+
+		if err := XEdDSAVerify(key.PublicKey(), message, signature); err != nil {
+			t.Fatalf(`signature should be valid: %s`, err.Error())
+		}
+	}
+}
+
+func TestXEdDSAVerifyModifiedMessageFails(t *testing.T) {
+	key, _ := wgtypes.GeneratePrivateKey()
+	signature, _ := XEdDSASign(key, []byte("a message to sign"))
+
+	if err := XEdDSAVerify(key.PublicKey(), []byte("a different message"), signature); err == nil {
+		t.Fatalf(`signature should not be valid for a different message`)
+	}
+}
+
+func TestXEdDSAVerifyDifferentKeyFails(t *testing.T) {
+	key, _ := wgtypes.GeneratePrivateKey()
+	other, _ := wgtypes.GeneratePrivateKey()
+	message := []byte("a message to sign")
+	signature, _ := XEdDSASign(key, message)
+
+	if err := XEdDSAVerify(other.PublicKey(), message, signature); err == nil {
+		t.Fatalf(`signature should not be valid for a different key`)
+	}
+}
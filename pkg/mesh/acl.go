@@ -0,0 +1,97 @@
+package mesh
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/tim-beatham/smegmesh/pkg/acl"
+)
+
+// AclPolicy: the access control policy of the mesh signed by the node
+// that set it. The latest policy set by an admin applies. In meshes
+// without admins any node may set the policy
+type AclPolicy struct {
+	// Policy: the policy. Nil if the policy has been cleared
+	Policy *acl.Policy
+	// Author: public key of the node that set the policy
+	Author string
+	// Timestamp: UNIX time the policy was set
+	Timestamp int64
+	// Signature: XEdDSA signature of the author over the policy
+	Signature []byte
+}
+
+// signedBytes: the bytes of the policy covered by the signature
+func (p *AclPolicy) signedBytes() ([]byte, error) {
+	unsigned := *p
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the policy using the author's signer
+func (p *AclPolicy) Sign(sign func([]byte) ([]byte, error)) error {
+	message, err := p.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	p.Signature, err = sign(message)
+	return err
+}
+
+// Verify: verify the author set the policy and the policy is valid
+func (p *AclPolicy) Verify() error {
+	message, err := p.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	if err := verifySignature(p.Author, message, p.Signature); err != nil {
+		return err
+	}
+
+	if p.Policy == nil {
+		return nil
+	}
+
+	return p.Policy.Validate()
+}
+
+// supersedes: returns true if the policy was set after the other. Ties
+// are broken by signature so that every node picks the same policy
+func (p *AclPolicy) supersedes(other *AclPolicy) bool {
+	if other == nil || p.Timestamp != other.Timestamp {
+		return other == nil || p.Timestamp > other.Timestamp
+	}
+
+	return bytes.Compare(p.Signature, other.Signature) > 0
+}
+
+// addAclPolicies: find the latest policy set by a node allowed to set it
+func (a *Admission) addAclPolicies(nodes []MeshNode) {
+	for _, node := range nodes {
+		policy := node.GetAclPolicy()
+
+		if policy == nil || !policy.supersedes(a.aclPolicy) {
+			continue
+		}
+
+		if a.genesis != nil && !a.IsAdmin(policy.Author) {
+			continue
+		}
+
+		if policy.Verify() != nil {
+			continue
+		}
+
+		a.aclPolicy = policy
+	}
+}
+
+// GetAclPolicy: the access control policy of the mesh. Nil if no policy
+// has been set
+func (a *Admission) GetAclPolicy() *AclPolicy {
+	return a.aclPolicy
+}
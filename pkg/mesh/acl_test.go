@@ -0,0 +1,112 @@
+package mesh
+
+import (
+	"testing"
+
+	"github.com/tim-beatham/smegmesh/pkg/acl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func setPolicy(t *testing.T, author wgtypes.Key, policy *acl.Policy, timestamp int64) *AclPolicy {
+	aclPolicy := &AclPolicy{
+		Policy:    policy,
+		Author:    author.PublicKey().String(),
+		Timestamp: timestamp,
+	}
+
+	if err := aclPolicy.Sign(signer(author)); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return aclPolicy
+}
+
+func allowAll() *acl.Policy {
+	return &acl.Policy{Rules: []acl.Rule{{From: []string{acl.ANY}, To: []string{acl.ANY}}}}
+}
+
+func TestAclPolicyVerifyModified(t *testing.T) {
+	policy := setPolicy(t, newKey(), allowAll(), 1)
+	policy.Policy.Rules[0].Protocol = acl.TCP
+
+	if err := policy.Verify(); err == nil {
+		t.Fatalf(`expected a modified policy to be rejected`)
+	}
+}
+
+func TestAdmissionAclPolicySetByAdmin(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	policy := setPolicy(t, creator, allowAll(), 1)
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis, aclPolicy: policy},
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if admission.GetAclPolicy() != policy {
+		t.Fatalf(`expected the policy set by the owner to apply`)
+	}
+}
+
+func TestAdmissionIgnoresAclPolicyByNonAdmin(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	other := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis},
+		&MeshNodeStub{publicKey: other.PublicKey(), aclPolicy: setPolicy(t, other, allowAll(), 1)},
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if admission.GetAclPolicy() != nil {
+		t.Fatalf(`expected a policy set by a node that is not an admin to be ignored`)
+	}
+}
+
+func TestAdmissionAclPolicyWithoutGenesis(t *testing.T) {
+	node := newKey()
+	policy := setPolicy(t, node, allowAll(), 1)
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: node.PublicKey(), aclPolicy: policy},
+	}
+
+	admission := NewAdmission("mesh", nodes)
+
+	if admission.GetAclPolicy() != policy {
+		t.Fatalf(`expected any node to set the policy of a mesh without a genesis`)
+	}
+}
+
+func TestAdmissionLatestAclPolicyWins(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	latest := setPolicy(t, creator, nil, 2)
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis, aclPolicy: latest},
+		&MeshNodeStub{publicKey: newKey().PublicKey(), aclPolicy: setPolicy(t, creator, allowAll(), 1)},
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if admission.GetAclPolicy() != latest || admission.GetAclPolicy().Policy != nil {
+		t.Fatalf(`expected the latest policy to clear the earlier policy`)
+	}
+}
+
+func TestAdmissionIgnoresInvalidAclPolicy(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	invalid := &acl.Policy{Rules: []acl.Rule{{From: []string{"tag:app"}, To: []string{acl.ANY}}}}
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis, aclPolicy: setPolicy(t, creator, invalid, 1)},
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if admission.GetAclPolicy() != nil {
+		t.Fatalf(`expected a policy that does not validate to be ignored`)
+	}
+}
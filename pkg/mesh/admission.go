@@ -0,0 +1,688 @@
+package mesh
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// MeshRole: the administrative role of a node in the mesh
+type MeshRole string
+
+const (
+	// OWNER_ROLE: the creator of the mesh. May grant and revoke admin
+	// rights and can never be evicted
+	OWNER_ROLE MeshRole = "owner"
+	// ADMIN_ROLE: may approve, reject and evict nodes and issue invites
+	ADMIN_ROLE MeshRole = "admin"
+	// MEMBER_ROLE: any other node in the mesh
+	MEMBER_ROLE MeshRole = "member"
+)
+
+// Genesis: settings of the mesh fixed when the mesh is created. Signed
+// by the creator and bound to the mesh id so that no other node can
+// claim to have created the mesh
+type Genesis struct {
+	// Creator: public key of the node that created the mesh
+	Creator string
+	// Nonce: random bytes making the mesh id unique
+	Nonce []byte
+	// RequireApproval: new nodes must be approved by an admin
+	RequireApproval bool
+	// Admins: public keys of the nodes that may approve new nodes
+	Admins []string
+	// PresharedKeys: every pair of nodes installs a pre-shared key
+	PresharedKeys bool `json:",omitempty"`
+	// Signature: XEdDSA signature of the creator over the genesis
+	Signature []byte
+}
+
+const (
+	// JOIN_REQUEST_TTL: how long a join request is pending for before
+	// it expires
+	JOIN_REQUEST_TTL = 7 * 24 * time.Hour
+	// JOIN_REQUEST_SKEW: how far the time a join request was signed may
+	// differ from the time it is received
+	JOIN_REQUEST_SKEW = 5 * time.Minute
+	// MAX_JOIN_REQUESTS: maximum number of pending join requests a node
+	// carries
+	MAX_JOIN_REQUESTS = 64
+)
+
+// JoinRequest: a request by a node to be admitted to the mesh
+type JoinRequest struct {
+	// PublicKey: public key of the node requesting to join
+	PublicKey string
+	// Role: role the node requested
+	Role string
+	// Alias: alias the node requested
+	Alias string
+	// Description: description of the node
+	Description string
+	// Timestamp: UNIX time the request was made
+	Timestamp int64
+}
+
+// signedBytes: the bytes the requester signs to prove it holds its key.
+// Names the mesh so the signature cannot be replayed to another mesh
+func (r *JoinRequest) signedBytes(meshId string) ([]byte, error) {
+	return json.Marshal(struct {
+		MeshId  string
+		Request JoinRequest
+	}{meshId, *r})
+}
+
+// Sign: sign the request to join the mesh using the requester's signer
+func (r *JoinRequest) Sign(meshId string, sign func([]byte) ([]byte, error)) ([]byte, error) {
+	message, err := r.signedBytes(meshId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sign(message)
+}
+
+// Verify: verify the requester signed the request to join the mesh
+func (r *JoinRequest) Verify(meshId string, signature []byte) error {
+	message, err := r.signedBytes(meshId)
+
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(r.PublicKey, message, signature)
+}
+
+// Expired: returns true if the request has been pending for longer
+// than JOIN_REQUEST_TTL
+func (r *JoinRequest) Expired(now time.Time) bool {
+	return now.Sub(time.Unix(r.Timestamp, 0)) > JOIN_REQUEST_TTL
+}
+
+// JoinDecision: an admin's decision on whether to admit a node.
+// Signed by the admin so any node may carry it
+type JoinDecision struct {
+	// PublicKey: public key of the node the decision is about
+	PublicKey string
+	// Approved: whether the node was admitted
+	Approved bool
+	// Role: role the node is admitted with, any role if empty
+	Role string
+	// Approver: public key of the admin that made the decision
+	Approver string
+	// Timestamp: UNIX time the decision was made
+	Timestamp int64
+	// Signature: XEdDSA signature of the approver over the decision
+	Signature []byte
+}
+
+// Revocation: an admin's decision to evict a node from the mesh.
+// Revocations are permanent and take precedence over any approval
+type Revocation struct {
+	// PublicKey: public key of the evicted node
+	PublicKey string
+	// Revoker: public key of the admin that evicted the node
+	Revoker string
+	// Timestamp: UNIX time the node was evicted
+	Timestamp int64
+	// Signature: XEdDSA signature of the revoker over the revocation
+	Signature []byte
+}
+
+// Grant: the owner's decision to grant or revoke a node's admin rights.
+// The latest grant about a node takes precedence
+type Grant struct {
+	// PublicKey: public key of the node the grant is about
+	PublicKey string
+	// Admin: true if the node is an admin false if its rights are revoked
+	Admin bool
+	// Granter: public key of the owner that made the grant
+	Granter string
+	// Timestamp: UNIX time the grant was made
+	Timestamp int64
+	// Signature: XEdDSA signature of the granter over the grant
+	Signature []byte
+}
+
+// signedBytes: the bytes of the genesis covered by the signature
+func (g *Genesis) signedBytes() ([]byte, error) {
+	unsigned := *g
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the genesis using the creator's signer
+func (g *Genesis) Sign(sign func([]byte) ([]byte, error)) error {
+	message, err := g.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	g.Signature, err = sign(message)
+	return err
+}
+
+// MeshId: the id of the mesh the genesis creates
+func (g *Genesis) MeshId() string {
+	digest := sha256.New()
+	digest.Write([]byte(g.Creator))
+	digest.Write(g.Nonce)
+	return base64.RawURLEncoding.EncodeToString(digest.Sum(nil)[:16])
+}
+
+// Verify: verify the genesis created the mesh with the given id
+func (g *Genesis) Verify(meshId string) error {
+	if g.MeshId() != meshId {
+		return errors.New("genesis is for a different mesh")
+	}
+
+	message, err := g.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(g.Creator, message, g.Signature)
+}
+
+// signedBytes: the bytes of the decision covered by the signature
+func (d *JoinDecision) signedBytes() ([]byte, error) {
+	unsigned := *d
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the decision using the approver's signer
+func (d *JoinDecision) Sign(sign func([]byte) ([]byte, error)) error {
+	message, err := d.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	d.Signature, err = sign(message)
+	return err
+}
+
+// Verify: verify the decision was made by the approver
+func (d *JoinDecision) Verify() error {
+	message, err := d.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(d.Approver, message, d.Signature)
+}
+
+// signedBytes: the bytes of the revocation covered by the signature
+func (r *Revocation) signedBytes() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the revocation using the revoker's signer
+func (r *Revocation) Sign(sign func([]byte) ([]byte, error)) error {
+	message, err := r.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	r.Signature, err = sign(message)
+	return err
+}
+
+// Verify: verify the revocation was made by the revoker
+func (r *Revocation) Verify() error {
+	message, err := r.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(r.Revoker, message, r.Signature)
+}
+
+// signedBytes: the bytes of the grant covered by the signature
+func (g *Grant) signedBytes() ([]byte, error) {
+	unsigned := *g
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the grant using the granter's signer
+func (g *Grant) Sign(sign func([]byte) ([]byte, error)) error {
+	message, err := g.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	g.Signature, err = sign(message)
+	return err
+}
+
+// Verify: verify the grant was made by the granter
+func (g *Grant) Verify() error {
+	message, err := g.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(g.Granter, message, g.Signature)
+}
+
+func verifySignature(publicKey string, message, signature []byte) error {
+	key, err := wgtypes.ParseKey(publicKey)
+
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	return lib.XEdDSAVerify(key, message, signature)
+}
+
+// Admission: decides which nodes have been admitted to the mesh.
+// Records about a node apply to every key the node rotates through
+type Admission struct {
+	genesis     *Genesis
+	decisions   map[string]JoinDecision
+	requests    map[string]JoinRequest
+	revocations map[string]Revocation
+	grants      map[string]Grant
+	// identities: maps every key a node has used to its first key
+	identities map[string]string
+	// current: maps the first key of a node to its latest key
+	current map[string]string
+	// successions: successions yet to be completed by their node
+	successions map[string]Succession
+	// aclPolicy: the latest access control policy set
+	aclPolicy *AclPolicy
+	// roles: the role each node's invite restricts it to
+	roles map[string]Redemption
+}
+
+// NewAdmission: determine which nodes have been admitted to the mesh
+// from the genesis, grants, decisions, requests and revocations carried
+// by the given nodes. Everything but requests is signed so may be
+// carried by any node. Signatures are checked against the owner and the
+// admins as of the grants
+func NewAdmission(meshId string, nodes []MeshNode) *Admission {
+	admission := &Admission{
+		decisions:   make(map[string]JoinDecision),
+		requests:    make(map[string]JoinRequest),
+		revocations: make(map[string]Revocation),
+		grants:      make(map[string]Grant),
+		identities:  make(map[string]string),
+		current:     make(map[string]string),
+		successions: make(map[string]Succession),
+		roles:       make(map[string]Redemption),
+	}
+
+	admission.addSuccessions(nodes)
+
+	for _, node := range nodes {
+		genesis := node.GetGenesis()
+
+		if genesis != nil && genesis.Verify(meshId) == nil {
+			admission.genesis = genesis
+			break
+		}
+	}
+
+	// Only the owner may grant admin rights
+	for _, node := range nodes {
+		for _, grant := range node.GetGrants() {
+			identity := admission.Identity(grant.PublicKey)
+			current, ok := admission.grants[identity]
+
+			if ok && current.Timestamp >= grant.Timestamp {
+				continue
+			}
+
+			if !admission.IsOwner(grant.Granter) || grant.Verify() != nil {
+				continue
+			}
+
+			admission.grants[identity] = grant
+		}
+	}
+
+	for _, node := range nodes {
+		for _, revocation := range node.GetRevocations() {
+			identity := admission.Identity(revocation.PublicKey)
+
+			if _, ok := admission.revocations[identity]; ok {
+				continue
+			}
+
+			// The creator of the mesh cannot be evicted
+			if !admission.isAdmin(revocation.Revoker) || admission.IsOwner(revocation.PublicKey) {
+				continue
+			}
+
+			if revocation.Verify() != nil {
+				continue
+			}
+
+			admission.revocations[identity] = revocation
+		}
+	}
+
+	admission.addAclPolicies(nodes)
+	admission.addRedemptions(nodes)
+
+	if !admission.RequiresApproval() {
+		return admission
+	}
+
+	for _, node := range nodes {
+		for _, decision := range node.GetJoinDecisions() {
+			identity := admission.Identity(decision.PublicKey)
+			current, ok := admission.decisions[identity]
+
+			if ok && current.Timestamp >= decision.Timestamp {
+				continue
+			}
+
+			if !admission.IsAdmin(decision.Approver) || decision.Verify() != nil {
+				continue
+			}
+
+			admission.decisions[identity] = decision
+		}
+	}
+
+	// Only accept requests received by nodes that have been admitted
+	for _, node := range nodes {
+		key, err := node.GetPublicKey()
+
+		if err != nil || !admission.IsApproved(key.String()) {
+			continue
+		}
+
+		for _, request := range node.GetJoinRequests() {
+			identity := admission.Identity(request.PublicKey)
+			current, ok := admission.requests[identity]
+
+			if !ok || current.Timestamp < request.Timestamp {
+				admission.requests[identity] = request
+			}
+		}
+	}
+
+	return admission
+}
+
+// addRedemptions: record the role each node's invite restricts it to.
+// A node that redeemed several invites restricting its role is held to
+// the earliest
+func (a *Admission) addRedemptions(nodes []MeshNode) {
+	for _, node := range nodes {
+		for _, redemption := range node.GetRedemptions() {
+			if redemption.Role == "" {
+				continue
+			}
+
+			identity := a.Identity(redemption.Grantee)
+			current, ok := a.roles[identity]
+
+			if !ok || redemption.Timestamp < current.Timestamp {
+				a.roles[identity] = redemption
+			}
+		}
+	}
+}
+
+// addSuccessions: trace the keys each node has rotated through back to
+// the node's first key. Where more than one node claims the same first
+// key the node that has rotated the furthest is the node's latest key
+func (a *Admission) addSuccessions(nodes []MeshNode) {
+	progress := make(map[string]int)
+
+	for _, node := range nodes {
+		successions := node.GetSuccessions()
+
+		if len(successions) == 0 {
+			continue
+		}
+
+		key := NodeID(node)
+
+		if verifySuccessions(key, successions) != nil {
+			continue
+		}
+
+		identity := successions[0].PublicKey
+		last := successions[len(successions)-1]
+
+		// Rotating to the last successor counts as half a rotation
+		rotated := 2 * len(successions)
+
+		if key == last.PublicKey {
+			a.successions[key] = last
+			rotated--
+		}
+
+		if rotated <= progress[identity] {
+			continue
+		}
+
+		for _, succession := range successions {
+			a.identities[succession.PublicKey] = identity
+			a.identities[succession.Successor] = identity
+		}
+
+		progress[identity] = rotated
+		a.current[identity] = key
+	}
+}
+
+// Identity: the first key of the node with the given key
+func (a *Admission) Identity(nodeId string) string {
+	if identity, ok := a.identities[nodeId]; ok {
+		return identity
+	}
+
+	return nodeId
+}
+
+// CurrentKey: the latest key of the node with the given key
+func (a *Admission) CurrentKey(nodeId string) string {
+	if current, ok := a.current[a.Identity(nodeId)]; ok {
+		return current
+	}
+
+	return nodeId
+}
+
+// SameNode: returns true if both keys belong to the same node
+func (a *Admission) SameNode(nodeId1, nodeId2 string) bool {
+	return a.Identity(nodeId1) == a.Identity(nodeId2)
+}
+
+// IsSuperseded: returns true if the node has since rotated its key
+func (a *Admission) IsSuperseded(nodeId string) bool {
+	return a.CurrentKey(nodeId) != nodeId
+}
+
+// GetSuccession: the succession the node is rotating to if any
+func (a *Admission) GetSuccession(nodeId string) (Succession, bool) {
+	succession, ok := a.successions[nodeId]
+	return succession, ok
+}
+
+// GetGenesis: the genesis of the mesh. Nil if the mesh has no genesis
+func (a *Admission) GetGenesis() *Genesis {
+	return a.genesis
+}
+
+// RequiresApproval: returns true if nodes must be approved by an admin
+func (a *Admission) RequiresApproval() bool {
+	return a.genesis != nil && a.genesis.RequireApproval
+}
+
+// RequiresPresharedKeys: returns true if nodes must agree a pre-shared
+// key with every node they configure
+func (a *Admission) RequiresPresharedKeys() bool {
+	return a.genesis != nil && a.genesis.PresharedKeys
+}
+
+// IsOwner: returns true if the node created the mesh
+func (a *Admission) IsOwner(nodeId string) bool {
+	return a.genesis != nil && a.SameNode(a.genesis.Creator, nodeId)
+}
+
+// IsAdmin: returns true if the node may approve and evict other nodes
+func (a *Admission) IsAdmin(nodeId string) bool {
+	return a.isAdmin(nodeId) && !a.IsRevoked(nodeId)
+}
+
+// isAdmin: returns true if the node is the owner or the genesis names
+// the node as an admin unless the owner has since revoked its rights
+func (a *Admission) isAdmin(nodeId string) bool {
+	if a.IsOwner(nodeId) {
+		return true
+	}
+
+	if grant, ok := a.grants[a.Identity(nodeId)]; ok {
+		return grant.Admin
+	}
+
+	return a.genesis != nil && slices.ContainsFunc(a.genesis.Admins, func(admin string) bool {
+		return a.SameNode(admin, nodeId)
+	})
+}
+
+// GetRole: the administrative role of the node in the mesh
+func (a *Admission) GetRole(nodeId string) MeshRole {
+	switch {
+	case a.IsOwner(nodeId):
+		return OWNER_ROLE
+	case a.IsAdmin(nodeId):
+		return ADMIN_ROLE
+	default:
+		return MEMBER_ROLE
+	}
+}
+
+// GetAdmins: the latest public keys of the owner and admins of the mesh
+func (a *Admission) GetAdmins() []string {
+	admins := make([]string, 0)
+
+	if a.genesis == nil {
+		return admins
+	}
+
+	candidates := append([]string{a.genesis.Creator}, a.genesis.Admins...)
+	candidates = append(candidates, lib.MapKeys(a.grants)...)
+
+	for _, candidate := range candidates {
+		admin := a.CurrentKey(candidate)
+
+		if a.IsAdmin(admin) && !slices.Contains(admins, admin) {
+			admins = append(admins, admin)
+		}
+	}
+
+	slices.Sort(admins)
+	return admins
+}
+
+// GetGrants: all grants in the mesh
+func (a *Admission) GetGrants() []Grant {
+	return lib.MapValues(a.grants)
+}
+
+// GetDecision: get the latest decision about the node if any
+func (a *Admission) GetDecision(nodeId string) (JoinDecision, bool) {
+	decision, ok := a.decisions[a.Identity(nodeId)]
+	return decision, ok
+}
+
+// IsRevoked: returns true if the node with the given id was evicted
+func (a *Admission) IsRevoked(nodeId string) bool {
+	_, ok := a.revocations[a.Identity(nodeId)]
+	return ok
+}
+
+// GetRevocations: all revocations in the mesh
+func (a *Admission) GetRevocations() []Revocation {
+	return lib.MapValues(a.revocations)
+}
+
+// IsApproved: returns true if the node with the given id was admitted
+// and has not since been evicted
+func (a *Admission) IsApproved(nodeId string) bool {
+	if a.IsRevoked(nodeId) {
+		return false
+	}
+
+	if !a.RequiresApproval() || a.IsAdmin(nodeId) {
+		return true
+	}
+
+	decision, ok := a.decisions[a.Identity(nodeId)]
+	return ok && decision.Approved
+}
+
+// Admits: returns true if the node was admitted with the role it is
+// advertising and the role is the one its invite permits
+func (a *Admission) Admits(node MeshNode) bool {
+	key, err := node.GetPublicKey()
+
+	if err != nil {
+		return false
+	}
+
+	if !a.IsApproved(key.String()) {
+		return false
+	}
+
+	if redemption, ok := a.roles[a.Identity(key.String())]; ok && redemption.Role != string(node.GetType()) {
+		return false
+	}
+
+	decision, ok := a.decisions[a.Identity(key.String())]
+	return !ok || decision.Role == "" || decision.Role == string(node.GetType())
+}
+
+// Pending: requests to join the mesh an admin has not decided on
+// since the request was made and that have not expired
+func (a *Admission) Pending() []JoinRequest {
+	pending := make([]JoinRequest, 0)
+	now := time.Now()
+
+	for key, request := range a.requests {
+		decision, decided := a.decisions[key]
+
+		if (decided && decision.Timestamp >= request.Timestamp) || a.IsAdmin(key) || a.IsRevoked(key) {
+			continue
+		}
+
+		if request.Expired(now) {
+			continue
+		}
+
+		pending = append(pending, request)
+	}
+
+	slices.SortFunc(pending, func(r1, r2 JoinRequest) int {
+		return cmp.Compare(r1.Timestamp, r2.Timestamp)
+	})
+
+	return pending
+}
@@ -0,0 +1,481 @@
+package mesh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func signer(key wgtypes.Key) func([]byte) ([]byte, error) {
+	return func(message []byte) ([]byte, error) {
+		return lib.XEdDSASign(key, message)
+	}
+}
+
+func newKey() wgtypes.Key {
+	key, _ := wgtypes.GeneratePrivateKey()
+	return key
+}
+
+func setUpGenesis(t *testing.T) (*Genesis, wgtypes.Key) {
+	creator := newKey()
+
+	genesis := &Genesis{
+		Creator:         creator.PublicKey().String(),
+		Nonce:           []byte{1, 2, 3, 4},
+		RequireApproval: true,
+		Admins:          []string{creator.PublicKey().String()},
+	}
+
+	if err := genesis.Sign(signer(creator)); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return genesis, creator
+}
+
+func decide(t *testing.T, approver wgtypes.Key, node wgtypes.Key, approved bool, timestamp int64) JoinDecision {
+	decision := JoinDecision{
+		PublicKey: node.PublicKey().String(),
+		Approved:  approved,
+		Approver:  approver.PublicKey().String(),
+		Timestamp: timestamp,
+	}
+
+	if err := decision.Sign(signer(approver)); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return decision
+}
+
+func TestGenesisVerify(t *testing.T) {
+	genesis, _ := setUpGenesis(t)
+
+	if err := genesis.Verify(genesis.MeshId()); err != nil {
+		t.Fatalf(`expected genesis to verify got %s`, err.Error())
+	}
+}
+
+func TestGenesisVerifyWrongMesh(t *testing.T) {
+	genesis, _ := setUpGenesis(t)
+
+	if err := genesis.Verify("other"); err == nil {
+		t.Fatalf(`expected genesis for another mesh to be rejected`)
+	}
+}
+
+func TestGenesisVerifyModified(t *testing.T) {
+	genesis, _ := setUpGenesis(t)
+	meshId := genesis.MeshId()
+	genesis.Admins = append(genesis.Admins, newKey().PublicKey().String())
+
+	if err := genesis.Verify(meshId); err == nil {
+		t.Fatalf(`expected modified genesis to be rejected`)
+	}
+}
+
+func TestAdmissionNoGenesisAdmitsAll(t *testing.T) {
+	node := &MeshNodeStub{publicKey: newKey().PublicKey()}
+
+	admission := NewAdmission("mesh", []MeshNode{node})
+
+	if admission.RequiresApproval() || !admission.Admits(node) {
+		t.Fatalf(`expected mesh without a genesis to admit all nodes`)
+	}
+}
+
+func TestAdmissionApprovedByAdmin(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	joiner := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			decisions: []JoinDecision{decide(t, creator, joiner, true, 1)},
+		},
+		&MeshNodeStub{publicKey: joiner.PublicKey()},
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if !admission.Admits(nodes[0]) {
+		t.Fatalf(`expected the admin to be admitted`)
+	}
+
+	if !admission.Admits(nodes[1]) {
+		t.Fatalf(`expected the approved node to be admitted`)
+	}
+}
+
+func TestAdmissionUnapprovedNode(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	joiner := &MeshNodeStub{publicKey: newKey().PublicKey()}
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis},
+		joiner,
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).Admits(joiner) {
+		t.Fatalf(`expected the unapproved node not to be admitted`)
+	}
+}
+
+func TestAdmissionIgnoresNonAdminDecisions(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	other := newKey()
+	joiner := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis},
+		&MeshNodeStub{
+			publicKey: other.PublicKey(),
+			decisions: []JoinDecision{decide(t, other, joiner, true, 1)},
+		},
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).IsApproved(joiner.PublicKey().String()) {
+		t.Fatalf(`expected a decision by a non admin to be ignored`)
+	}
+}
+
+func TestAdmissionLatestDecisionWins(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	joiner := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			decisions: []JoinDecision{decide(t, creator, joiner, false, 2)},
+		},
+		&MeshNodeStub{
+			publicKey: newKey().PublicKey(),
+			decisions: []JoinDecision{decide(t, creator, joiner, true, 1)},
+		},
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).IsApproved(joiner.PublicKey().String()) {
+		t.Fatalf(`expected the later rejection to take precedence`)
+	}
+}
+
+func TestAdmissionRoleMismatch(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	joiner := newKey()
+	decision := decide(t, creator, joiner, true, 1)
+	decision.Role = "client"
+	decision.Sign(signer(creator))
+
+	node := &MeshNodeStub{publicKey: joiner.PublicKey()}
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			decisions: []JoinDecision{decision},
+		},
+		node,
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).Admits(node) {
+		t.Fatalf(`expected a node advertising a different role not to be admitted`)
+	}
+}
+
+func TestAdmissionRedeemedRoleMismatch(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	joiner := newKey()
+
+	node := &MeshNodeStub{publicKey: joiner.PublicKey()}
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			decisions: []JoinDecision{decide(t, creator, joiner, true, 1)},
+			redemptions: []Redemption{
+				{Invite: "invite", Grantee: joiner.PublicKey().String(), Timestamp: 1, Role: string(conf.CLIENT_ROLE)},
+			},
+		},
+		node,
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).Admits(node) {
+		t.Fatalf(`expected a node advertising a role its invite does not permit not to be admitted`)
+	}
+
+	nodes[0].(*MeshNodeStub).redemptions[0].Role = string(conf.PEER_ROLE)
+
+	if !NewAdmission(genesis.MeshId(), nodes).Admits(node) {
+		t.Fatalf(`expected a node advertising the role its invite permits to be admitted`)
+	}
+}
+
+func TestAdmissionPending(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	decided := newKey()
+	waiting := newKey()
+	expired := newKey()
+	now := time.Now().Unix()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			requests: []JoinRequest{
+				{PublicKey: decided.PublicKey().String(), Timestamp: now},
+				{PublicKey: waiting.PublicKey().String(), Timestamp: now},
+				{PublicKey: expired.PublicKey().String(), Timestamp: 1},
+			},
+			decisions: []JoinDecision{decide(t, creator, decided, false, now+1)},
+		},
+		&MeshNodeStub{
+			publicKey: waiting.PublicKey(),
+			requests:  []JoinRequest{{PublicKey: newKey().PublicKey().String(), Timestamp: 1}},
+		},
+	}
+
+	pending := NewAdmission(genesis.MeshId(), nodes).Pending()
+
+	if len(pending) != 1 || pending[0].PublicKey != waiting.PublicKey().String() {
+		t.Fatalf(`expected only the undecided request to be pending got %v`, pending)
+	}
+}
+
+func TestJoinRequestVerify(t *testing.T) {
+	genesis, _ := setUpGenesis(t)
+	key := newKey()
+
+	request := JoinRequest{PublicKey: key.PublicKey().String(), Alias: "web", Timestamp: 1}
+	signature, err := request.Sign(genesis.MeshId(), signer(key))
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if err := request.Verify(genesis.MeshId(), signature); err != nil {
+		t.Fatalf(`expected the request to verify got %s`, err.Error())
+	}
+
+	if request.Verify("othermesh", signature) == nil {
+		t.Fatalf(`expected the request not to verify for another mesh`)
+	}
+
+	request.Alias = "api"
+
+	if request.Verify(genesis.MeshId(), signature) == nil {
+		t.Fatalf(`expected a modified request not to verify`)
+	}
+
+	forged := JoinRequest{PublicKey: newKey().PublicKey().String(), Timestamp: 1}
+	signature, _ = forged.Sign(genesis.MeshId(), signer(key))
+
+	if forged.Verify(genesis.MeshId(), signature) == nil {
+		t.Fatalf(`expected a request signed by another key not to verify`)
+	}
+}
+
+func revoke(t *testing.T, revoker wgtypes.Key, node string) Revocation {
+	revocation := Revocation{
+		PublicKey: node,
+		Revoker:   revoker.PublicKey().String(),
+		Timestamp: 1,
+	}
+
+	if err := revocation.Sign(signer(revoker)); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return revocation
+}
+
+func TestAdmissionRevokedNode(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	genesis.RequireApproval = false
+	genesis.Sign(signer(creator))
+
+	evicted := &MeshNodeStub{publicKey: newKey().PublicKey()}
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey:   creator.PublicKey(),
+			genesis:     genesis,
+			revocations: []Revocation{revoke(t, creator, evicted.publicKey.String())},
+		},
+		evicted,
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if !admission.IsRevoked(evicted.publicKey.String()) || admission.Admits(evicted) {
+		t.Fatalf(`expected the evicted node not to be admitted`)
+	}
+}
+
+func TestAdmissionRevocationOverridesApproval(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	evicted := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey:   creator.PublicKey(),
+			genesis:     genesis,
+			decisions:   []JoinDecision{decide(t, creator, evicted, true, 2)},
+			revocations: []Revocation{revoke(t, creator, evicted.PublicKey().String())},
+		},
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).IsApproved(evicted.PublicKey().String()) {
+		t.Fatalf(`expected the revocation to take precedence over the approval`)
+	}
+}
+
+func TestAdmissionIgnoresNonAdminRevocations(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	other := newKey()
+	target := newKey().PublicKey().String()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis},
+		&MeshNodeStub{
+			publicKey:   other.PublicKey(),
+			revocations: []Revocation{revoke(t, other, target)},
+		},
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).IsRevoked(target) {
+		t.Fatalf(`expected a revocation by a non admin to be ignored`)
+	}
+}
+
+func TestAdmissionCreatorCannotBeRevoked(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	admin := newKey()
+	genesis.Admins = append(genesis.Admins, admin.PublicKey().String())
+	genesis.Sign(signer(creator))
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey:   creator.PublicKey(),
+			genesis:     genesis,
+			revocations: []Revocation{revoke(t, admin, creator.PublicKey().String())},
+		},
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).IsRevoked(creator.PublicKey().String()) {
+		t.Fatalf(`expected the creator of the mesh not to be revoked`)
+	}
+}
+
+func grant(t *testing.T, granter wgtypes.Key, node wgtypes.Key, admin bool, timestamp int64) Grant {
+	grant := Grant{
+		PublicKey: node.PublicKey().String(),
+		Admin:     admin,
+		Granter:   granter.PublicKey().String(),
+		Timestamp: timestamp,
+	}
+
+	if err := grant.Sign(signer(granter)); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return grant
+}
+
+func TestAdmissionOwnerGrantsAdmin(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	admin := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			grants:    []Grant{grant(t, creator, admin, true, 1)},
+		},
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if admission.GetRole(admin.PublicKey().String()) != ADMIN_ROLE {
+		t.Fatalf(`expected the granted node to be an admin`)
+	}
+
+	if admission.GetRole(creator.PublicKey().String()) != OWNER_ROLE {
+		t.Fatalf(`expected the creator to be the owner`)
+	}
+
+	if len(admission.GetAdmins()) != 2 {
+		t.Fatalf(`expected two admins got %v`, admission.GetAdmins())
+	}
+}
+
+func TestAdmissionOwnerRevokesAdmin(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	admin := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			grants:    []Grant{grant(t, creator, admin, false, 2)},
+		},
+		&MeshNodeStub{
+			publicKey: admin.PublicKey(),
+			grants:    []Grant{grant(t, creator, admin, true, 1)},
+		},
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).IsAdmin(admin.PublicKey().String()) {
+		t.Fatalf(`expected the later revocation of admin rights to take precedence`)
+	}
+}
+
+func TestAdmissionIgnoresGrantsByAdmins(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	admin := newKey()
+	other := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			grants:    []Grant{grant(t, creator, admin, true, 1)},
+		},
+		&MeshNodeStub{
+			publicKey: admin.PublicKey(),
+			grants:    []Grant{grant(t, admin, other, true, 1)},
+		},
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).IsAdmin(other.PublicKey().String()) {
+		t.Fatalf(`expected only the owner to be able to grant admin rights`)
+	}
+}
+
+func TestAdmissionDecisionByRevokedAdminIgnored(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	admin := newKey()
+	joiner := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			grants:    []Grant{grant(t, creator, admin, false, 2)},
+		},
+		&MeshNodeStub{
+			publicKey: admin.PublicKey(),
+			decisions: []JoinDecision{decide(t, admin, joiner, true, 1)},
+		},
+	}
+
+	if NewAdmission(genesis.MeshId(), nodes).IsApproved(joiner.PublicKey().String()) {
+		t.Fatalf(`expected a decision by a node that is not an admin to be ignored`)
+	}
+}
@@ -0,0 +1,274 @@
+package mesh
+
+import (
+	"cmp"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/tim-beatham/smegmesh/pkg/events"
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// MAX_ALIAS_LENGTH: maximum length of an alias. Aliases name nodes in
+// DNS so must fit in a single label
+const MAX_ALIAS_LENGTH = 63
+
+// ALIAS_SUFFIX_LENGTH: number of hex characters of a node's public key
+// appended to an alias that another node keeps
+const ALIAS_SUFFIX_LENGTH = 6
+
+// labelPattern: letters, digits and hyphens that do not start or end
+// with a hyphen
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9]*[A-Za-z0-9])?$`)
+
+// IsDnsLabel: returns true if the name can be used as a DNS label
+func IsDnsLabel(name string) bool {
+	return len(name) <= MAX_ALIAS_LENGTH && labelPattern.MatchString(name)
+}
+
+// ValidateAlias: check the alias can be used as a DNS label. Aliases are
+// compared ignoring case as in DNS. An empty alias clears the alias
+func ValidateAlias(alias string) error {
+	if alias != "" && !IsDnsLabel(alias) {
+		return fmt.Errorf("invalid alias %q: must be at most %d letters, digits or hyphens and cannot start or end with a hyphen",
+			alias, MAX_ALIAS_LENGTH)
+	}
+
+	return nil
+}
+
+// AliasConflict: nodes in a mesh that claim the same alias
+type AliasConflict struct {
+	Alias string
+	// Owner: public key of the node that keeps the alias
+	Owner string
+	// Renamed: the alias each of the other claimants is known by keyed
+	// by public key
+	Renamed map[string]string
+}
+
+// aliasClaim: a node's claim to an alias
+type aliasClaim struct {
+	publicKey string
+	// alias: the alias as the node claimed it
+	alias string
+	// rank: precedence of the node's role, lower ranks keep the alias
+	rank int
+	// identity: the first public key of the node
+	identity string
+}
+
+// claimRank: the precedence of the node's claims. The owner's claims
+// come first then the admins' as the roles are signed
+func claimRank(admission *Admission, publicKey string) int {
+	switch {
+	case admission == nil:
+		return 2
+	case admission.IsOwner(publicKey):
+		return 0
+	case admission.IsAdmin(publicKey):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// suffixedAlias: the alias a node is known by when another node keeps
+// its alias. Lengthens the suffix until the alias is not taken
+func suffixedAlias(claim aliasClaim, taken map[string]bool) string {
+	key, err := wgtypes.ParseKey(claim.publicKey)
+	suffix := hex.EncodeToString([]byte(claim.publicKey))
+
+	if err == nil {
+		suffix = hex.EncodeToString(key[:])
+	}
+
+	alias := strings.ToLower(claim.alias)
+	var renamed string
+
+	for length := ALIAS_SUFFIX_LENGTH; length <= min(len(suffix), MAX_ALIAS_LENGTH-2); length += 2 {
+		prefix := alias[:min(len(alias), MAX_ALIAS_LENGTH-length-1)]
+		renamed = strings.TrimSuffix(prefix, "-") + "-" + suffix[:length]
+
+		if !taken[renamed] {
+			break
+		}
+	}
+
+	return renamed
+}
+
+// ResolveAliases: the alias each node in the mesh is known by keyed by
+// public key and the conflicts between nodes claiming the same alias.
+// Aliases are compared ignoring case. The owner keeps an alias over the
+// admins and the admins over the other nodes. Otherwise the node with
+// the smallest first public key keeps it, so that the order neither
+// changes when a node rotates its key nor depends on anything a node
+// sets itself. The other claimants are suffixed with part of their
+// public key. Every node resolves the same aliases from the same nodes.
+// admission may be nil in which case every node has the same role
+func ResolveAliases(admission *Admission, nodes map[string]MeshNode) (map[string]string, []AliasConflict) {
+	claims := make(map[string][]aliasClaim)
+	taken := make(map[string]bool)
+
+	for _, node := range nodes {
+		if node.GetAlias() == "" {
+			continue
+		}
+
+		publicKey, _ := node.GetPublicKey()
+		alias := strings.ToLower(node.GetAlias())
+
+		identity := publicKey.String()
+
+		if admission != nil {
+			identity = admission.Identity(identity)
+		}
+
+		claims[alias] = append(claims[alias], aliasClaim{
+			publicKey: publicKey.String(),
+			alias:     node.GetAlias(),
+			rank:      claimRank(admission, publicKey.String()),
+			identity:  identity,
+		})
+		taken[alias] = true
+	}
+
+	resolved := make(map[string]string)
+	conflicts := make([]AliasConflict, 0)
+	aliases := lib.MapKeys(claims)
+	slices.Sort(aliases)
+
+	for _, alias := range aliases {
+		claimants := claims[alias]
+		slices.SortFunc(claimants, func(c1, c2 aliasClaim) int {
+			if c1.rank != c2.rank {
+				return cmp.Compare(c1.rank, c2.rank)
+			}
+
+			if c1.identity != c2.identity {
+				return strings.Compare(c1.identity, c2.identity)
+			}
+
+			return strings.Compare(c1.publicKey, c2.publicKey)
+		})
+
+		owner := claimants[0]
+		resolved[owner.publicKey] = owner.alias
+
+		if len(claimants) == 1 {
+			continue
+		}
+
+		conflict := AliasConflict{
+			Alias:   alias,
+			Owner:   owner.publicKey,
+			Renamed: make(map[string]string),
+		}
+
+		for _, claim := range claimants[1:] {
+			renamed := suffixedAlias(claim, taken)
+			taken[renamed] = true
+			resolved[claim.publicKey] = renamed
+			conflict.Renamed[claim.publicKey] = renamed
+		}
+
+		conflicts = append(conflicts, conflict)
+	}
+
+	return resolved, conflicts
+}
+
+// AliasMonitor: records an event whenever nodes in a mesh start or stop
+// claiming the same alias
+type AliasMonitor struct {
+	manager MeshManager
+	events  *events.Log
+	// conflicts: the claimants of each conflicting alias in each mesh
+	conflicts map[string]map[string]string
+}
+
+// NewAliasMonitorParams: params to create a new alias monitor
+type NewAliasMonitorParams struct {
+	MeshManager MeshManager
+	Events      *events.Log
+}
+
+// NewAliasMonitor: create a new alias monitor
+func NewAliasMonitor(params *NewAliasMonitorParams) *AliasMonitor {
+	return &AliasMonitor{
+		manager:   params.MeshManager,
+		events:    params.Events,
+		conflicts: make(map[string]map[string]string),
+	}
+}
+
+// claimants: the public keys claiming the alias in a conflict ordered
+// by precedence
+func claimants(conflict AliasConflict) string {
+	renamed := lib.MapKeys(conflict.Renamed)
+	slices.Sort(renamed)
+	return strings.Join(append([]string{conflict.Owner}, renamed...), ", ")
+}
+
+// Check: compare the aliases in each mesh with those last checked and
+// record the conflicts that started or ended
+func (a *AliasMonitor) Check() error {
+	meshes := a.manager.GetMeshes()
+
+	for meshId := range a.conflicts {
+		if _, ok := meshes[meshId]; !ok {
+			delete(a.conflicts, meshId)
+		}
+	}
+
+	for meshId, theMesh := range meshes {
+		snapshot, err := theMesh.GetMesh()
+
+		if err != nil {
+			return err
+		}
+
+		admission, err := theMesh.GetAdmission()
+
+		if err != nil {
+			return err
+		}
+
+		_, conflicts := ResolveAliases(admission, snapshot.GetNodes())
+		previous := a.conflicts[meshId]
+		current := make(map[string]string)
+
+		for _, conflict := range conflicts {
+			current[conflict.Alias] = claimants(conflict)
+
+			if previous[conflict.Alias] == current[conflict.Alias] {
+				continue
+			}
+
+			renamed := make([]string, 0)
+
+			for publicKey, alias := range conflict.Renamed {
+				renamed = append(renamed, fmt.Sprintf("%s is known as %s", publicKey, alias))
+			}
+
+			slices.Sort(renamed)
+			a.events.Record(meshId, events.ALIAS_CONFLICT, "alias %s is claimed by %s; %s keeps it and %s",
+				conflict.Alias, current[conflict.Alias], conflict.Owner, strings.Join(renamed, ", "))
+		}
+
+		for alias := range previous {
+			if _, ok := current[alias]; !ok {
+				a.events.Record(meshId, events.ALIAS_CONFLICT_RESOLVED, "alias %s is claimed by a single node", alias)
+			}
+		}
+
+		a.conflicts[meshId] = current
+	}
+
+	return nil
+}
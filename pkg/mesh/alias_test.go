@@ -0,0 +1,196 @@
+package mesh
+
+import (
+	"encoding/hex"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tim-beatham/smegmesh/pkg/events"
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+)
+
+func aliasNode(alias string) *MeshNodeStub {
+	return &MeshNodeStub{publicKey: newKey().PublicKey(), alias: alias}
+}
+
+func aliasNodes(nodes ...*MeshNodeStub) map[string]MeshNode {
+	snapshot := make(map[string]MeshNode)
+
+	for _, node := range nodes {
+		snapshot[node.publicKey.String()] = node
+	}
+
+	return snapshot
+}
+
+func TestValidateAlias(t *testing.T) {
+	for _, alias := range []string{"", "web", "Web-1", "a", strings.Repeat("a", MAX_ALIAS_LENGTH)} {
+		if err := ValidateAlias(alias); err != nil {
+			t.Fatalf(`expected %q to be valid: %s`, alias, err.Error())
+		}
+	}
+
+	for _, alias := range []string{"-web", "web-", "web.prod", "web_1", "wéb", strings.Repeat("a", MAX_ALIAS_LENGTH+1)} {
+		if err := ValidateAlias(alias); err == nil {
+			t.Fatalf(`expected %q to be invalid`, alias)
+		}
+	}
+}
+
+func TestSetAliasRejectsInvalidAlias(t *testing.T) {
+	manager := getMeshManager()
+
+	meshId, _ := manager.CreateMesh(&CreateMeshParams{Port: 5000})
+	manager.AddSelf(&AddSelfParams{MeshId: meshId, WgPort: 5000, Endpoint: "abc.com:8080"})
+
+	if err := manager.SetAlias(meshId, "not a label"); err == nil {
+		t.Fatalf(`expected the alias to be rejected`)
+	}
+}
+
+// orderByKey: the nodes ordered by public key
+func orderByKey(nodes ...*MeshNodeStub) []*MeshNodeStub {
+	slices.SortFunc(nodes, func(n1, n2 *MeshNodeStub) int {
+		return strings.Compare(n1.publicKey.String(), n2.publicKey.String())
+	})
+
+	return nodes
+}
+
+func TestSetAliasClearsAlias(t *testing.T) {
+	manager := getMeshManager()
+
+	meshId, _ := manager.CreateMesh(&CreateMeshParams{Port: 5000})
+	manager.AddSelf(&AddSelfParams{MeshId: meshId, WgPort: 5000, Endpoint: "abc.com:8080"})
+
+	if err := manager.SetAlias(meshId, "web"); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if err := manager.SetAlias(meshId, ""); err != nil {
+		t.Fatalf(`expected the alias to be cleared: %s`, err.Error())
+	}
+
+	self, _ := manager.GetSelf(meshId)
+
+	if self.GetAlias() != "" {
+		t.Fatalf(`expected no alias got %s`, self.GetAlias())
+	}
+}
+
+func TestResolveAliasesSmallestKeyKeepsAlias(t *testing.T) {
+	ordered := orderByKey(aliasNode("web"), aliasNode("WEB"))
+	first, second := ordered[0], ordered[1]
+	first.alias = "web"
+	second.alias = "WEB"
+	other := aliasNode("db")
+
+	aliases, conflicts := ResolveAliases(nil, aliasNodes(second, first, other))
+
+	if aliases[first.publicKey.String()] != "web" {
+		t.Fatalf(`expected the smallest public key to keep web got %s`, aliases[first.publicKey.String()])
+	}
+
+	if aliases[other.publicKey.String()] != "db" {
+		t.Fatalf(`expected db to be unchanged got %s`, aliases[other.publicKey.String()])
+	}
+
+	renamed := aliases[second.publicKey.String()]
+
+	if renamed != "web-"+hex.EncodeToString(second.publicKey[:ALIAS_SUFFIX_LENGTH/2]) {
+		t.Fatalf(`expected the second claimant to be suffixed got %s`, renamed)
+	}
+
+	if len(conflicts) != 1 || conflicts[0].Owner != first.publicKey.String() ||
+		conflicts[0].Renamed[second.publicKey.String()] != renamed {
+		t.Fatalf(`expected a single conflict over web got %v`, conflicts)
+	}
+}
+
+func TestResolveAliasesIsDeterministic(t *testing.T) {
+	ordered := orderByKey(aliasNode("web"), aliasNode("web"))
+
+	for i := 0; i < 10; i++ {
+		aliases, _ := ResolveAliases(nil, aliasNodes(ordered[1], ordered[0]))
+
+		if aliases[ordered[0].publicKey.String()] != "web" {
+			t.Fatalf(`expected the smallest public key to keep the alias`)
+		}
+	}
+}
+
+func TestResolveAliasesAdminsKeepAliases(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	owner := &MeshNodeStub{publicKey: creator.PublicKey(), alias: "web", genesis: genesis}
+	member := aliasNode("web")
+
+	// The member's key would otherwise keep the alias
+	for member.publicKey.String() > owner.publicKey.String() {
+		member = aliasNode("web")
+	}
+
+	claimants := []*MeshNodeStub{owner, member}
+
+	admission := NewAdmission(genesis.MeshId(), lib.Map(claimants, func(n *MeshNodeStub) MeshNode {
+		return n
+	}))
+
+	aliases, _ := ResolveAliases(admission, aliasNodes(claimants...))
+
+	if aliases[owner.publicKey.String()] != "web" {
+		t.Fatalf(`expected the owner to keep the alias got %s`, aliases[owner.publicKey.String()])
+	}
+}
+
+func TestResolveAliasesSuffixAvoidsClaimedAliases(t *testing.T) {
+	ordered := orderByKey(aliasNode("web"), aliasNode("web"))
+	first, second := ordered[0], ordered[1]
+
+	aliases, _ := ResolveAliases(nil, aliasNodes(first, second))
+	squatter := aliasNode(aliases[second.publicKey.String()])
+
+	aliases, _ = ResolveAliases(nil, aliasNodes(first, second, squatter))
+
+	if aliases[squatter.publicKey.String()] == aliases[second.publicKey.String()] {
+		t.Fatalf(`expected every node to have a different alias got %v`, aliases)
+	}
+}
+
+func TestAliasMonitorRecordsConflicts(t *testing.T) {
+	first := aliasNode("web")
+	second := aliasNode("web")
+	snapshot := &MeshSnapshotStub{nodes: aliasNodes(first)}
+
+	manager := &MeshManagerStub{meshes: map[string]MeshProvider{
+		"mesh": &MeshProviderStub{meshId: "mesh", snapshot: snapshot},
+	}}
+
+	log := events.NewLog()
+	monitor := NewAliasMonitor(&NewAliasMonitorParams{MeshManager: manager, Events: log})
+
+	monitor.Check()
+
+	if len(log.List("")) != 0 {
+		t.Fatalf(`expected no events without a conflict`)
+	}
+
+	snapshot.nodes[second.publicKey.String()] = second
+	monitor.Check()
+	monitor.Check()
+
+	recorded := log.List("mesh")
+
+	if len(recorded) != 1 || recorded[0].Type != events.ALIAS_CONFLICT {
+		t.Fatalf(`expected a single conflict event got %v`, recorded)
+	}
+
+	second.alias = "api"
+	monitor.Check()
+
+	recorded = log.List("mesh")
+
+	if len(recorded) != 2 || recorded[1].Type != events.ALIAS_CONFLICT_RESOLVED {
+		t.Fatalf(`expected the conflict to be resolved got %v`, recorded)
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/tim-beatham/smegmesh/pkg/acl"
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	"github.com/tim-beatham/smegmesh/pkg/ip"
 	"github.com/tim-beatham/smegmesh/pkg/lib"
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
 	"github.com/tim-beatham/smegmesh/pkg/route"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
@@ -27,6 +29,9 @@ type WgMeshConfigApplier struct {
 	meshManager    MeshManager
 	routeInstaller route.RouteInstaller
 	hashFunc       func(MeshNode) int
+	firewall       acl.Firewall
+	// firewalled: interfaces with an access control policy applied
+	firewalled map[string]bool
 }
 
 type routeNode struct {
@@ -448,6 +453,18 @@ func (m *WgMeshConfigApplier) updateWgConf(mesh MeshProvider, routes map[string]
 		return err
 	}
 
+	cfg.Peers, err = m.addSuccessors(mesh, cfg.Peers)
+
+	if err != nil {
+		return err
+	}
+
+	cfg.Peers, err = m.addPresharedKeys(mesh, cfg.Peers)
+
+	if err != nil {
+		return err
+	}
+
 	toRemove := m.getPeerCfgsToRemove(dev, cfg.Peers)
 	cfg.Peers = append(cfg.Peers, toRemove...)
 
@@ -457,7 +474,110 @@ func (m *WgMeshConfigApplier) updateWgConf(mesh MeshProvider, routes map[string]
 		return err
 	}
 
-	return nil
+	return m.applyAclPolicy(mesh, dev.Name, self, nodes)
+}
+
+// applyAclPolicy: enforce the mesh's access control policy on traffic
+// arriving on the interface. Traffic is unrestricted if no policy is set
+func (m *WgMeshConfigApplier) applyAclPolicy(mesh MeshProvider, ifName string, self MeshNode, nodes []MeshNode) error {
+	admission, err := mesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	policy := admission.GetAclPolicy()
+
+	if policy == nil || policy.Policy == nil {
+		delete(m.firewalled, ifName)
+		return m.firewall.Remove(ifName)
+	}
+
+	aclNode := func(node MeshNode) acl.Node {
+		return acl.Node{PublicKey: NodeIdentity(node), Address: *node.GetWgHost()}
+	}
+
+	aclNodes := make([]acl.Node, 0, len(nodes))
+
+	for _, node := range nodes {
+		aclNodes = append(aclNodes, aclNode(node))
+	}
+
+	ruleset := acl.Compile(policy.Policy, ifName, aclNode(self), aclNodes, admission.Identity)
+	m.firewalled[ifName] = true
+	return m.firewall.Apply(ruleset)
+}
+
+// addSuccessors: configure the successor of every peer rotating its key
+// alongside the peer's current key so that the peer can handshake as
+// soon as it switches. Once the succession activates the successor
+// takes over the peer's allowed IPs without waiting for the peer's
+// rotated node to arrive
+func (m *WgMeshConfigApplier) addSuccessors(mesh MeshProvider, peers []wgtypes.PeerConfig) ([]wgtypes.PeerConfig, error) {
+	admission, err := mesh.GetAdmission()
+
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]wgtypes.PeerConfig, 0, len(peers))
+
+	for _, peer := range peers {
+		succession, ok := admission.GetSuccession(peer.PublicKey.String())
+
+		if !ok {
+			configs = append(configs, peer)
+			continue
+		}
+
+		successorKey, err := wgtypes.ParseKey(succession.Successor)
+
+		if err != nil {
+			return nil, err
+		}
+
+		successor := peer
+		successor.PublicKey = successorKey
+		successor.AllowedIPs = []net.IPNet{}
+
+		if succession.IsActive(time.Now()) {
+			successor.AllowedIPs = peer.AllowedIPs
+			peer.AllowedIPs = []net.IPNet{}
+		}
+
+		configs = append(configs, peer, successor)
+	}
+
+	return configs, nil
+}
+
+// addPresharedKeys: install the pre-shared key agreed with each peer if
+// the mesh requires pre-shared keys. Peers a key is yet to be agreed
+// with are left out of the configuration until a key is exchanged
+func (m *WgMeshConfigApplier) addPresharedKeys(mesh MeshProvider, peers []wgtypes.PeerConfig) ([]wgtypes.PeerConfig, error) {
+	admission, err := mesh.GetAdmission()
+
+	if err != nil || !admission.RequiresPresharedKeys() {
+		return peers, err
+	}
+
+	presharedKeys := m.meshManager.GetPresharedKeys()
+	configs := make([]wgtypes.PeerConfig, 0, len(peers))
+
+	for _, peer := range peers {
+		identity := admission.Identity(peer.PublicKey.String())
+		key, _ := presharedKeys.Get(mesh.GetMeshId(), identity)
+
+		if key == nil {
+			logging.Log.WriteInfof("no pre-shared key agreed with %s", peer.PublicKey.String())
+			continue
+		}
+
+		peer.PresharedKey = key
+		configs = append(configs, peer)
+	}
+
+	return configs, nil
 }
 
 // getAllRoutes: works out all the routes to install out of all the routes in the
@@ -499,12 +619,31 @@ func (m *WgMeshConfigApplier) ApplyConfig() error {
 		return err
 	}
 
+	interfaces := make(map[string]bool)
+
 	for _, mesh := range m.meshManager.GetMeshes() {
 		err := m.updateWgConf(mesh, allRoutes)
 
 		if err != nil {
 			return err
 		}
+
+		if dev, err := mesh.GetDevice(); err == nil {
+			interfaces[dev.Name] = true
+		}
+	}
+
+	// Stop enforcing policies on the interfaces of meshes that were left
+	for ifName := range m.firewalled {
+		if interfaces[ifName] {
+			continue
+		}
+
+		if err := m.firewall.Remove(ifName); err != nil {
+			return err
+		}
+
+		delete(m.firewalled, ifName)
 	}
 
 	return nil
@@ -517,9 +656,12 @@ func (m *WgMeshConfigApplier) SetMeshManager(manager MeshManager) {
 func NewWgMeshConfigApplier() MeshConfigApplier {
 	return &WgMeshConfigApplier{
 		routeInstaller: route.NewRouteInstaller(),
+		// Hash the node's first key so that rotating keys does not
+		// move the node
 		hashFunc: func(mn MeshNode) int {
-			pubKey, _ := mn.GetPublicKey()
-			return lib.HashString(pubKey.String())
+			return lib.HashString(NodeIdentity(mn))
 		},
+		firewall:   acl.NewNftFirewall(),
+		firewalled: make(map[string]bool),
 	}
 }
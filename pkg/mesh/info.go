@@ -0,0 +1,172 @@
+package mesh
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+)
+
+// MeshInfo: metadata describing the mesh set when the mesh is created.
+// Signed by the owner or an admin and carried by every node so that it
+// outlives the signer's node
+type MeshInfo struct {
+	// MeshId: id of the mesh the metadata describes
+	MeshId string
+	// Name: human readable name of the mesh. Empty if the mesh has no
+	// name
+	Name string
+	// Creator: public key of the node that signed the metadata
+	Creator string
+	// Created: UNIX time the mesh was created
+	Created     int64
+	Description string
+	// Signature: XEdDSA signature of the creator over the metadata
+	Signature []byte
+}
+
+// signedBytes: the bytes of the metadata covered by the signature
+func (i *MeshInfo) signedBytes() ([]byte, error) {
+	unsigned := *i
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the metadata using the creator's signer
+func (i *MeshInfo) Sign(sign func([]byte) ([]byte, error)) error {
+	message, err := i.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	i.Signature, err = sign(message)
+	return err
+}
+
+// Verify: verify the creator signed the metadata of the mesh with the
+// given id
+func (i *MeshInfo) Verify(meshId string) error {
+	if i.MeshId != meshId {
+		return errors.New("metadata is for a different mesh")
+	}
+
+	message, err := i.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(i.Creator, message, i.Signature)
+}
+
+// ValidateMeshName: check the name can be used as a DNS label
+func ValidateMeshName(name string) error {
+	if !IsDnsLabel(name) {
+		return fmt.Errorf("invalid mesh name %q: must be at most %d letters, digits or hyphens and cannot start or end with a hyphen",
+			name, MAX_ALIAS_LENGTH)
+	}
+
+	return nil
+}
+
+// ResolveMeshInfo: the metadata of the mesh carried by the nodes. Nil
+// if no node carries valid metadata. Only metadata signed by the owner
+// or an admin of the mesh is accepted so meshes without a genesis have
+// none. The owner's metadata wins, otherwise the earliest with ties
+// broken by signer
+func ResolveMeshInfo(meshId string, nodes []MeshNode) *MeshInfo {
+	admission := NewAdmission(meshId, nodes)
+
+	if admission.GetGenesis() == nil {
+		return nil
+	}
+
+	var resolved *MeshInfo
+
+	for _, node := range nodes {
+		info := node.GetMeshInfo()
+
+		if info == nil || info.Verify(meshId) != nil || !admission.IsAdmin(info.Creator) {
+			continue
+		}
+
+		if resolved == nil || compareMeshInfo(admission, info, resolved) < 0 {
+			resolved = info
+		}
+	}
+
+	return resolved
+}
+
+// compareMeshInfo: orders metadata by precedence. The owner's first then
+// the earliest then by signer
+func compareMeshInfo(admission *Admission, i1, i2 *MeshInfo) int {
+	if owner1, owner2 := admission.IsOwner(i1.Creator), admission.IsOwner(i2.Creator); owner1 != owner2 {
+		if owner1 {
+			return -1
+		}
+
+		return 1
+	}
+
+	if i1.Created != i2.Created {
+		return cmp.Compare(i1.Created, i2.Created)
+	}
+
+	return strings.Compare(i1.Creator, i2.Creator)
+}
+
+// GetMeshInfo: the metadata of the mesh. Nil if the mesh has none
+func GetMeshInfo(mesh MeshProvider) (*MeshInfo, error) {
+	snapshot, err := mesh.GetMesh()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ResolveMeshInfo(mesh.GetMeshId(), lib.MapValues(snapshot.GetNodes())), nil
+}
+
+// GetMeshName: the name of the mesh. Empty if the mesh has no name
+func GetMeshName(mesh MeshProvider) string {
+	info, err := GetMeshInfo(mesh)
+
+	if err != nil || info == nil {
+		return ""
+	}
+
+	return info.Name
+}
+
+// ResolveMeshId: the id of the local mesh with the given id or name.
+// Names are compared ignoring case. Returns the argument unchanged if
+// no mesh has the id or name and an error if several meshes have the
+// name
+func ResolveMeshId(manager MeshManager, nameOrId string) (string, error) {
+	meshes := manager.GetMeshes()
+
+	if _, ok := meshes[nameOrId]; ok || nameOrId == "" {
+		return nameOrId, nil
+	}
+
+	matches := make([]string, 0)
+
+	for meshId, mesh := range meshes {
+		if strings.EqualFold(GetMeshName(mesh), nameOrId) {
+			matches = append(matches, meshId)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nameOrId, nil
+	case 1:
+		return matches[0], nil
+	}
+
+	return "", fmt.Errorf("several meshes are named %s, use the mesh id instead", nameOrId)
+}
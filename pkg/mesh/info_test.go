@@ -0,0 +1,221 @@
+package mesh
+
+import (
+	"strings"
+	"testing"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func signedInfo(t *testing.T, creator wgtypes.Key, meshId, name string, created int64) *MeshInfo {
+	info := &MeshInfo{
+		MeshId:  meshId,
+		Name:    name,
+		Creator: creator.PublicKey().String(),
+		Created: created,
+	}
+
+	if err := info.Sign(signer(creator)); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return info
+}
+
+func TestMeshInfoVerify(t *testing.T) {
+	info := signedInfo(t, newKey(), "mesh", "prod-eu", 100)
+
+	if err := info.Verify("mesh"); err != nil {
+		t.Fatalf(`expected the metadata to verify got %s`, err.Error())
+	}
+
+	if err := info.Verify("other"); err == nil {
+		t.Fatalf(`expected metadata for another mesh to be rejected`)
+	}
+
+	info.Name = "prod-us"
+
+	if err := info.Verify("mesh"); err == nil {
+		t.Fatalf(`expected modified metadata to be rejected`)
+	}
+}
+
+func TestValidateMeshName(t *testing.T) {
+	for _, name := range []string{"prod-eu", "Prod1", strings.Repeat("a", MAX_ALIAS_LENGTH)} {
+		if err := ValidateMeshName(name); err != nil {
+			t.Fatalf(`expected %q to be valid: %s`, name, err.Error())
+		}
+	}
+
+	for _, name := range []string{"", "-prod", "prod.eu", "prod eu", strings.Repeat("a", MAX_ALIAS_LENGTH+1)} {
+		if err := ValidateMeshName(name); err == nil {
+			t.Fatalf(`expected %q to be invalid`, name)
+		}
+	}
+}
+
+func TestResolveMeshInfoRequiresGenesis(t *testing.T) {
+	creator := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), info: signedInfo(t, creator, "mesh", "prod-eu", 100)},
+	}
+
+	if info := ResolveMeshInfo("mesh", nodes); info != nil {
+		t.Fatalf(`expected metadata of a mesh without a genesis to be ignored got %v`, info)
+	}
+}
+
+func TestResolveMeshInfoAcceptsAdmins(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	admin := newKey()
+	genesis.Admins = append(genesis.Admins, admin.PublicKey().String())
+	genesis.Sign(signer(creator))
+	meshId := genesis.MeshId()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis},
+		&MeshNodeStub{publicKey: admin.PublicKey(), info: signedInfo(t, admin, meshId, "prod-eu", 100)},
+	}
+
+	info := ResolveMeshInfo(meshId, nodes)
+
+	if info == nil || info.Name != "prod-eu" {
+		t.Fatalf(`expected the metadata of an admin got %v`, info)
+	}
+
+	nodes[0].(*MeshNodeStub).info = signedInfo(t, creator, meshId, "prod-us", 200)
+	info = ResolveMeshInfo(meshId, nodes)
+
+	if info == nil || info.Name != "prod-us" {
+		t.Fatalf(`expected the owner's metadata to win got %v`, info)
+	}
+}
+
+func TestResolveMeshInfoIgnoresUnverifiedMetadata(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	forger := newKey()
+	info := signedInfo(t, forger, genesis.MeshId(), "prod-eu", 100)
+	info.Creator = creator.PublicKey().String()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis},
+		&MeshNodeStub{publicKey: forger.PublicKey(), info: info},
+	}
+
+	if resolved := ResolveMeshInfo(genesis.MeshId(), nodes); resolved != nil {
+		t.Fatalf(`expected forged metadata to be ignored got %v`, resolved)
+	}
+}
+
+func TestResolveMeshInfoIgnoresMembers(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	meshId := genesis.MeshId()
+	other := newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			info:      signedInfo(t, creator, meshId, "prod-eu", 200),
+		},
+		&MeshNodeStub{publicKey: other.PublicKey(), info: signedInfo(t, other, meshId, "hijack", 100)},
+	}
+
+	info := ResolveMeshInfo(meshId, nodes)
+
+	if info == nil || info.Name != "prod-eu" {
+		t.Fatalf(`expected the metadata of a member to be ignored got %v`, info)
+	}
+}
+
+func namedMesh(t *testing.T, name string) (string, MeshProvider) {
+	genesis, creator := setUpGenesis(t)
+	meshId := genesis.MeshId()
+
+	node := &MeshNodeStub{
+		publicKey: creator.PublicKey(),
+		genesis:   genesis,
+		info:      signedInfo(t, creator, meshId, name, 100),
+	}
+
+	return meshId, &MeshProviderStub{
+		meshId:   meshId,
+		snapshot: &MeshSnapshotStub{nodes: map[string]MeshNode{creator.PublicKey().String(): node}},
+	}
+}
+
+func namedMeshes(t *testing.T, names ...string) (*MeshManagerStub, []string) {
+	manager := &MeshManagerStub{meshes: make(map[string]MeshProvider)}
+	meshIds := make([]string, 0)
+
+	for _, name := range names {
+		meshId, theMesh := namedMesh(t, name)
+		manager.meshes[meshId] = theMesh
+		meshIds = append(meshIds, meshId)
+	}
+
+	return manager, meshIds
+}
+
+func TestResolveMeshId(t *testing.T) {
+	manager, meshIds := namedMeshes(t, "prod-eu", "prod-us")
+
+	for nameOrId, expected := range map[string]string{
+		meshIds[0]: meshIds[0],
+		"prod-us":  meshIds[1],
+		"PROD-EU":  meshIds[0],
+		"unknown":  "unknown",
+	} {
+		meshId, err := ResolveMeshId(manager, nameOrId)
+
+		if err != nil || meshId != expected {
+			t.Fatalf(`expected %s to resolve to %s got %s`, nameOrId, expected, meshId)
+		}
+	}
+}
+
+func TestResolveMeshIdAmbiguousName(t *testing.T) {
+	manager, _ := namedMeshes(t, "prod", "Prod")
+
+	if _, err := ResolveMeshId(manager, "prod"); err == nil {
+		t.Fatalf(`expected a name shared by several meshes to be rejected`)
+	}
+}
+
+func TestCreateMeshStoresMeshInfo(t *testing.T) {
+	manager := getMeshManager()
+
+	meshId, err := manager.CreateMesh(&CreateMeshParams{Port: 5000, Name: "prod-eu", Description: "production"})
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	manager.AddSelf(&AddSelfParams{MeshId: meshId, WgPort: 5000, Endpoint: "abc.com:8080"})
+
+	info, err := GetMeshInfo(manager.GetMesh(meshId))
+
+	if err != nil || info == nil {
+		t.Fatalf(`expected the mesh to have metadata`)
+	}
+
+	if info.Name != "prod-eu" || info.Description != "production" {
+		t.Fatalf(`expected the metadata given on creation got %v`, info)
+	}
+}
+
+func TestCreateMeshRejectsDuplicateName(t *testing.T) {
+	manager := getMeshManager()
+
+	meshId, _ := manager.CreateMesh(&CreateMeshParams{Port: 5000, Name: "prod-eu"})
+	manager.AddSelf(&AddSelfParams{MeshId: meshId, WgPort: 5000, Endpoint: "abc.com:8080"})
+
+	if _, err := manager.CreateMesh(&CreateMeshParams{Port: 5001, Name: "Prod-EU"}); err == nil {
+		t.Fatalf(`expected a duplicate mesh name to be rejected`)
+	}
+
+	if _, err := manager.CreateMesh(&CreateMeshParams{Port: 5001, Name: meshId}); err == nil {
+		t.Fatalf(`expected a name equal to a mesh id to be rejected`)
+	}
+}
@@ -1,14 +1,18 @@
 package mesh
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"net"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/tim-beatham/smegmesh/pkg/cmd"
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	"github.com/tim-beatham/smegmesh/pkg/ip"
+	"github.com/tim-beatham/smegmesh/pkg/labels"
 	"github.com/tim-beatham/smegmesh/pkg/lib"
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
 	"github.com/tim-beatham/smegmesh/pkg/wg"
@@ -32,17 +36,41 @@ type MeshManager interface {
 	SetAlias(meshId, alias string) error
 	SetService(meshId, service, value string) error
 	RemoveService(meshId, service string) error
+	SetLabel(meshId, key, value string) error
+	RemoveLabel(meshId, key string) error
+	PutServiceRecord(meshId string, service Service) error
+	RemoveServiceRecord(meshId, name string) error
 	UpdateTimeStamp() error
 	GetClient() *wgctrl.Client
 	GetMeshes() map[string]MeshProvider
 	Close() error
 	GetNode(string, string) MeshNode
 	GetRouteManager() RouteManager
+	// Sign: sign the message with the node's WireGuard key
+	Sign(message []byte) ([]byte, error)
+	// GetCredential: get the credential used to join the mesh. Presented
+	// to peers until they learn we are a member
+	GetCredential(meshId string) string
+	// RotateKey: announce a successor to the node's WireGuard key that
+	// replaces the key after the overlap
+	RotateKey(overlap time.Duration) (*Succession, error)
+	// GetSuccession: the succession the node is rotating to if any
+	GetSuccession() *Succession
+	// GetPresharedKeys: the pre-shared keys agreed with other nodes
+	GetPresharedKeys() *PresharedKeys
+	// Updated: returns a channel closed the next time the nodes in a
+	// mesh change or the node joins or leaves a mesh
+	Updated() <-chan struct{}
 }
 
 type MeshManagerImpl struct {
-	meshLock             sync.RWMutex
-	meshes               map[string]MeshProvider
+	meshLock    sync.RWMutex
+	meshes      map[string]MeshProvider
+	credentials map[string]string
+	// geneses: genesis of meshes we created until we add ourselves
+	geneses map[string]*Genesis
+	// infos: metadata of meshes we created until we add ourselves
+	infos                map[string]*MeshInfo
 	RouteManager         RouteManager
 	Client               *wgctrl.Client
 	HostParameters       *HostParameters
@@ -55,6 +83,13 @@ type MeshManagerImpl struct {
 	interfaceManipulator wg.WgInterfaceManipulator
 	cmdRunner            cmd.CmdRunner
 	OnDelete             func(MeshProvider)
+	// rotationLock: guards the key the node is rotating to
+	rotationLock  sync.Mutex
+	successor     *wgtypes.Key
+	succession    *Succession
+	presharedKeys *PresharedKeys
+	// updated: notified whenever the nodes in a mesh change
+	updated *lib.Notifier
 }
 
 func (m *MeshManagerImpl) GetRouteManager() RouteManager {
@@ -91,6 +126,70 @@ func (m *MeshManagerImpl) SetService(meshId, service, value string) error {
 	return mesh.AddService(m.HostParameters.GetPublicKey(), service, value)
 }
 
+// PutServiceRecord: publish the service record in the given mesh
+func (m *MeshManagerImpl) PutServiceRecord(meshId string, service Service) error {
+	mesh := m.GetMesh(meshId)
+
+	if mesh == nil {
+		return fmt.Errorf("mesh %s does not exist", meshId)
+	}
+
+	if !mesh.NodeExists(m.HostParameters.GetPublicKey()) {
+		return fmt.Errorf("node %s does not exist in the mesh", meshId)
+	}
+
+	return mesh.PutServiceRecord(m.HostParameters.GetPublicKey(), service)
+}
+
+// RemoveServiceRecord: withdraw the service record from the given mesh
+func (m *MeshManagerImpl) RemoveServiceRecord(meshId, name string) error {
+	mesh := m.GetMesh(meshId)
+
+	if mesh == nil {
+		return fmt.Errorf("mesh %s does not exist", meshId)
+	}
+
+	if !mesh.NodeExists(m.HostParameters.GetPublicKey()) {
+		return fmt.Errorf("node %s does not exist in the mesh", meshId)
+	}
+
+	return mesh.RemoveServiceRecord(m.HostParameters.GetPublicKey(), name)
+}
+
+// SetLabel: place a label on ourselves in the given mesh
+func (m *MeshManagerImpl) SetLabel(meshId, key, value string) error {
+	if err := labels.Validate(key, value); err != nil {
+		return err
+	}
+
+	mesh := m.GetMesh(meshId)
+
+	if mesh == nil {
+		return fmt.Errorf("mesh %s does not exist", meshId)
+	}
+
+	if !mesh.NodeExists(m.HostParameters.GetPublicKey()) {
+		return fmt.Errorf("node %s does not exist in the mesh", meshId)
+	}
+
+	return mesh.SetLabel(m.HostParameters.GetPublicKey(), key, value)
+}
+
+// RemoveLabel: remove a label from ourselves in the given mesh
+func (m *MeshManagerImpl) RemoveLabel(meshId, key string) error {
+	mesh := m.GetMesh(meshId)
+
+	if mesh == nil {
+		return fmt.Errorf("mesh %s does not exist", meshId)
+	}
+
+	if !mesh.NodeExists(m.HostParameters.GetPublicKey()) {
+		return fmt.Errorf("node %s does not exist in the mesh", meshId)
+	}
+
+	return mesh.RemoveLabel(m.HostParameters.GetPublicKey(), key)
+}
+
 // GetNode: gets the node with given id in the mesh network
 func (m *MeshManagerImpl) GetNode(meshid, nodeId string) MeshNode {
 	mesh, ok := m.meshes[meshid]
@@ -112,6 +211,15 @@ func (m *MeshManagerImpl) GetNode(meshid, nodeId string) MeshNode {
 type CreateMeshParams struct {
 	Port int
 	Conf *conf.WgConfiguration
+	// RequireApproval: new nodes must be approved by an admin
+	RequireApproval bool
+	// PresharedKeys: every pair of nodes agrees a pre-shared key
+	PresharedKeys bool
+	// Name: human readable name of the mesh. Must be unique among the
+	// meshes the node has joined
+	Name string
+	// Description: description of the mesh
+	Description string
 }
 
 // getConf: gets the new configuration with the base configuration overriden
@@ -144,7 +252,30 @@ func (m *MeshManagerImpl) CreateMesh(args *CreateMeshParams) (string, error) {
 		return "", fmt.Errorf("cannot create mesh as a client")
 	}
 
-	meshId, err := m.idGenerator.GetId()
+	if args.Name != "" {
+		if err := ValidateMeshName(args.Name); err != nil {
+			return "", err
+		}
+
+		if err := m.checkMeshName(args.Name); err != nil {
+			return "", err
+		}
+	}
+
+	var meshId string
+	var genesis *Genesis
+
+	// The metadata of the mesh is only accepted from its owner and admins
+	// which requires a genesis
+	if args.RequireApproval || args.PresharedKeys || args.Name != "" || args.Description != "" {
+		genesis, err = m.createGenesis(args)
+
+		if err == nil {
+			meshId = genesis.MeshId()
+		}
+	} else {
+		meshId, err = m.idGenerator.GetId()
+	}
 
 	var ifName string = ""
 
@@ -152,10 +283,23 @@ func (m *MeshManagerImpl) CreateMesh(args *CreateMeshParams) (string, error) {
 		return "", err
 	}
 
+	info := &MeshInfo{
+		MeshId:      meshId,
+		Name:        args.Name,
+		Creator:     m.HostParameters.GetPublicKey(),
+		Created:     time.Now().Unix(),
+		Description: args.Description,
+	}
+
+	if err := info.Sign(m.Sign); err != nil {
+		return "", err
+	}
+
 	m.cmdRunner.RunCommands(m.conf.BaseConfiguration.PreUp...)
 
 	if !m.conf.StubWg {
-		ifName, err = m.interfaceManipulator.CreateInterface(args.Port, m.HostParameters.PrivateKey)
+		privateKey := m.HostParameters.GetPrivateKey()
+		ifName, err = m.interfaceManipulator.CreateInterface(args.Port, &privateKey)
 
 		if err != nil {
 			return "", fmt.Errorf("error creating mesh: %w", err)
@@ -170,6 +314,8 @@ func (m *MeshManagerImpl) CreateMesh(args *CreateMeshParams) (string, error) {
 		MeshId:     meshId,
 		DaemonConf: m.conf,
 		NodeID:     m.HostParameters.GetPublicKey(),
+		Host:       m.HostParameters,
+		OnUpdate:   m.updated.Notify,
 	})
 
 	if err != nil {
@@ -178,18 +324,53 @@ func (m *MeshManagerImpl) CreateMesh(args *CreateMeshParams) (string, error) {
 
 	m.meshLock.Lock()
 	m.meshes[meshId] = nodeManager
+
+	if genesis != nil {
+		m.geneses[meshId] = genesis
+		m.infos[meshId] = info
+	}
 	m.meshLock.Unlock()
+	m.updated.Notify()
 
 	m.cmdRunner.RunCommands(m.conf.BaseConfiguration.PostUp...)
 
 	return meshId, nil
 }
 
+// createGenesis: create the genesis of a mesh that requires new nodes
+// to be approved, pre-shared keys or that has metadata. We are the only
+// admin
+func (m *MeshManagerImpl) createGenesis(args *CreateMeshParams) (*Genesis, error) {
+	nonce := make([]byte, 16)
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	self := m.HostParameters.GetPublicKey()
+
+	genesis := &Genesis{
+		Creator:         self,
+		Nonce:           nonce,
+		RequireApproval: args.RequireApproval,
+		Admins:          []string{self},
+		PresharedKeys:   args.PresharedKeys,
+	}
+
+	if err := genesis.Sign(m.Sign); err != nil {
+		return nil, err
+	}
+
+	return genesis, nil
+}
+
 type AddMeshParams struct {
 	MeshId    string
 	WgPort    int
 	MeshBytes []byte
 	Conf      *conf.WgConfiguration
+	// Credential: credential used to join the mesh if any
+	Credential string
 }
 
 // AddMesh: Add a new mesh network to the list of addresses
@@ -206,7 +387,8 @@ func (m *MeshManagerImpl) AddMesh(params *AddMeshParams) error {
 	m.cmdRunner.RunCommands(meshConfiguration.PreUp...)
 
 	if !m.conf.StubWg {
-		ifName, err = m.interfaceManipulator.CreateInterface(params.WgPort, m.HostParameters.PrivateKey)
+		privateKey := m.HostParameters.GetPrivateKey()
+		ifName, err = m.interfaceManipulator.CreateInterface(params.WgPort, &privateKey)
 
 		if err != nil {
 			return err
@@ -221,6 +403,8 @@ func (m *MeshManagerImpl) AddMesh(params *AddMeshParams) error {
 		MeshId:     params.MeshId,
 		DaemonConf: m.conf,
 		NodeID:     m.HostParameters.GetPublicKey(),
+		Host:       m.HostParameters,
+		OnUpdate:   m.updated.Notify,
 	})
 
 	m.cmdRunner.RunCommands(meshConfiguration.PostUp...)
@@ -235,9 +419,20 @@ func (m *MeshManagerImpl) AddMesh(params *AddMeshParams) error {
 		return err
 	}
 
+	if name := GetMeshName(meshProvider); name != "" {
+		if err := m.checkMeshName(name); err != nil {
+			return err
+		}
+	}
+
 	m.meshLock.Lock()
 	m.meshes[params.MeshId] = meshProvider
+
+	if params.Credential != "" {
+		m.credentials[params.MeshId] = params.Credential
+	}
 	m.meshLock.Unlock()
+	m.updated.Notify()
 	return nil
 }
 
@@ -254,7 +449,7 @@ func (m *MeshManagerImpl) GetMesh(meshId string) MeshProvider {
 
 // GetPublicKey: Gets the public key of the WireGuard mesh
 func (s *MeshManagerImpl) GetPublicKey() *wgtypes.Key {
-	key := s.HostParameters.PrivateKey.PublicKey()
+	key := s.HostParameters.GetPrivateKey().PublicKey()
 	return &key
 }
 
@@ -287,7 +482,7 @@ func (s *MeshManagerImpl) AddSelf(params *AddSelfParams) error {
 		params.WgPort = device.ListenPort
 	}
 
-	pubKey := s.HostParameters.PrivateKey.PublicKey()
+	pubKey := s.HostParameters.GetPrivateKey().PublicKey()
 
 	collisionCount := uint8(0)
 
@@ -344,9 +539,235 @@ func (s *MeshManagerImpl) AddSelf(params *AddSelfParams) error {
 	}
 
 	s.meshes[params.MeshId].AddNode(node)
+
+	if succession := s.GetSuccession(); succession != nil {
+		if err := mesh.AddSuccession(pubKey.String(), *succession); err != nil {
+			return err
+		}
+	}
+
+	if err := s.carryGenesis(mesh); err != nil {
+		return err
+	}
+
+	return s.carryMeshInfo(mesh)
+}
+
+// checkMeshName: check no mesh we have joined has the name either as
+// its name or its id
+func (m *MeshManagerImpl) checkMeshName(name string) error {
+	for meshId, mesh := range m.GetMeshes() {
+		if meshId == name || strings.EqualFold(GetMeshName(mesh), name) {
+			return fmt.Errorf("a mesh named %s has already been joined", name)
+		}
+	}
+
+	return nil
+}
+
+// carryMeshInfo: carry the metadata of the mesh in our node so that the
+// metadata outlives the creator's node
+func (s *MeshManagerImpl) carryMeshInfo(mesh MeshProvider) error {
+	s.meshLock.Lock()
+	info := s.infos[mesh.GetMeshId()]
+	delete(s.infos, mesh.GetMeshId())
+	s.meshLock.Unlock()
+
+	if info == nil {
+		var err error
+		info, err = GetMeshInfo(mesh)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if info == nil {
+		return nil
+	}
+
+	return mesh.SetMeshInfo(s.HostParameters.GetPublicKey(), *info)
+}
+
+// carryGenesis: carry the genesis of the mesh in our node so that the
+// genesis outlives the creator's node
+func (s *MeshManagerImpl) carryGenesis(mesh MeshProvider) error {
+	s.meshLock.Lock()
+	genesis := s.geneses[mesh.GetMeshId()]
+	delete(s.geneses, mesh.GetMeshId())
+	s.meshLock.Unlock()
+
+	if genesis == nil {
+		admission, err := mesh.GetAdmission()
+
+		if err != nil {
+			return err
+		}
+
+		genesis = admission.GetGenesis()
+	}
+
+	if genesis == nil {
+		return nil
+	}
+
+	return mesh.SetGenesis(s.HostParameters.GetPublicKey(), *genesis)
+}
+
+// carryApproval: carry the decision approving us in our node so that
+// the decision outlives the admin's node
+func (s *MeshManagerImpl) carryApproval(mesh MeshProvider) error {
+	admission, err := mesh.GetAdmission()
+
+	if err != nil || !admission.RequiresApproval() {
+		return err
+	}
+
+	decision, ok := admission.GetDecision(s.HostParameters.GetPublicKey())
+
+	if !ok {
+		return nil
+	}
+
+	self, err := mesh.GetNode(s.HostParameters.GetPublicKey())
+
+	if err != nil {
+		return err
+	}
+
+	for _, carried := range self.GetJoinDecisions() {
+		if carried.PublicKey == decision.PublicKey && carried.Timestamp >= decision.Timestamp {
+			return nil
+		}
+	}
+
+	return mesh.AddJoinDecision(s.HostParameters.GetPublicKey(), decision)
+}
+
+// carryRevocations: carry every revocation in the mesh in our node so
+// that evicted nodes remain evicted after the admin's node is gone
+func (s *MeshManagerImpl) carryRevocations(mesh MeshProvider) error {
+	admission, err := mesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	if admission.IsRevoked(s.HostParameters.GetPublicKey()) {
+		logging.Log.WriteWarnf("we have been evicted from mesh %s", mesh.GetMeshId())
+		return nil
+	}
+
+	self, err := mesh.GetNode(s.HostParameters.GetPublicKey())
+
+	if err != nil {
+		return err
+	}
+
+	carried := make(map[string]bool)
+
+	for _, revocation := range self.GetRevocations() {
+		carried[revocation.PublicKey] = true
+	}
+
+	for _, revocation := range admission.GetRevocations() {
+		if carried[revocation.PublicKey] {
+			continue
+		}
+
+		if err := mesh.AddRevocation(s.HostParameters.GetPublicKey(), revocation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// carryGrants: carry the latest grant about every node in our node so
+// that admin rights outlive the owner's node
+func (s *MeshManagerImpl) carryGrants(mesh MeshProvider) error {
+	admission, err := mesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	self, err := mesh.GetNode(s.HostParameters.GetPublicKey())
+
+	if err != nil {
+		return err
+	}
+
+	carried := make(map[string]int64)
+
+	for _, grant := range self.GetGrants() {
+		carried[grant.PublicKey] = grant.Timestamp
+	}
+
+	for _, grant := range admission.GetGrants() {
+		if timestamp, ok := carried[grant.PublicKey]; ok && timestamp >= grant.Timestamp {
+			continue
+		}
+
+		if err := mesh.AddGrant(s.HostParameters.GetPublicKey(), grant); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// carryAclPolicy: carry the mesh's access control policy in our node so
+// that the policy outlives the node that set it
+func (s *MeshManagerImpl) carryAclPolicy(mesh MeshProvider) error {
+	admission, err := mesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	policy := admission.GetAclPolicy()
+
+	if policy == nil {
+		return nil
+	}
+
+	self, err := mesh.GetNode(s.HostParameters.GetPublicKey())
+
+	if err != nil {
+		return err
+	}
+
+	if !policy.supersedes(self.GetAclPolicy()) {
+		return nil
+	}
+
+	return mesh.SetAclPolicy(s.HostParameters.GetPublicKey(), *policy)
+}
+
+// Sign: sign the message with the node's WireGuard key
+func (s *MeshManagerImpl) Sign(message []byte) ([]byte, error) {
+	return lib.XEdDSASign(s.HostParameters.GetPrivateKey(), message)
+}
+
+// GetCredential: get the credential used to join the mesh
+func (s *MeshManagerImpl) GetCredential(meshId string) string {
+	s.meshLock.RLock()
+	defer s.meshLock.RUnlock()
+	return s.credentials[meshId]
+}
+
+// Updated: returns a channel closed the next time the nodes in a mesh
+// change or the node joins or leaves a mesh
+func (s *MeshManagerImpl) Updated() <-chan struct{} {
+	return s.updated.Wait()
+}
+
+// GetPresharedKeys: the pre-shared keys agreed with other nodes
+func (s *MeshManagerImpl) GetPresharedKeys() *PresharedKeys {
+	return s.presharedKeys
+}
+
 // LeaveMesh: leaves the mesh network and force a synchronsiation
 func (s *MeshManagerImpl) LeaveMesh(meshId string) error {
 	mesh := s.GetMesh(meshId)
@@ -367,7 +788,11 @@ func (s *MeshManagerImpl) LeaveMesh(meshId string) error {
 
 	s.meshLock.Lock()
 	delete(s.meshes, meshId)
+	delete(s.credentials, meshId)
 	s.meshLock.Unlock()
+	s.updated.Notify()
+
+	s.presharedKeys.RemoveMesh(meshId)
 
 	s.cmdRunner.RunCommands(s.conf.BaseConfiguration.PreDown...)
 
@@ -440,12 +865,20 @@ func (s *MeshManagerImpl) SetAlias(meshId, alias string) error {
 		return fmt.Errorf("node %s does not exist in the mesh", meshId)
 	}
 
+	if err := ValidateAlias(alias); err != nil {
+		return err
+	}
+
 	return mesh.SetAlias(s.HostParameters.GetPublicKey(), alias)
 }
 
 // UpdateTimeStamp: updates the timestamp of this node in all meshes
 // essentially performs heartbeat if the node is the leader
 func (s *MeshManagerImpl) UpdateTimeStamp() error {
+	if err := s.completeRotation(); err != nil {
+		return err
+	}
+
 	meshes := s.GetMeshes()
 	for _, mesh := range meshes {
 		if mesh.NodeExists(s.HostParameters.GetPublicKey()) {
@@ -454,6 +887,22 @@ func (s *MeshManagerImpl) UpdateTimeStamp() error {
 			if err != nil {
 				return err
 			}
+
+			if err := s.carryApproval(mesh); err != nil {
+				return err
+			}
+
+			if err := s.carryRevocations(mesh); err != nil {
+				return err
+			}
+
+			if err := s.carryGrants(mesh); err != nil {
+				return err
+			}
+
+			if err := s.carryAclPolicy(mesh); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -517,18 +966,30 @@ type NewMeshManagerParams struct {
 	RouteManager         RouteManager
 	CommandRunner        cmd.CmdRunner
 	OnDelete             func(MeshProvider)
+	// PrivateKey: WireGuard private key of the node. A new key is
+	// generated if not provided
+	PrivateKey *wgtypes.Key
 }
 
 // NewMeshManager: Creates a new instance of a mesh manager with the given parameters
 func NewMeshManager(params *NewMeshManagerParams) MeshManager {
-	privateKey, _ := wgtypes.GeneratePrivateKey()
-	hostParams := HostParameters{
-		PrivateKey: &privateKey,
+	privateKey := params.PrivateKey
+
+	if privateKey == nil {
+		key, _ := wgtypes.GeneratePrivateKey()
+		privateKey = &key
 	}
 
+	hostParams := NewHostParameters(*privateKey)
+
 	m := &MeshManagerImpl{
 		meshes:              make(map[string]MeshProvider),
-		HostParameters:      &hostParams,
+		credentials:         make(map[string]string),
+		geneses:             make(map[string]*Genesis),
+		infos:               make(map[string]*MeshInfo),
+		presharedKeys:       NewPresharedKeys(),
+		updated:             lib.NewNotifier(),
+		HostParameters:      hostParams,
 		meshProviderFactory: params.MeshProvider,
 		nodeFactory:         params.NodeFactory,
 		Client:              params.Client,
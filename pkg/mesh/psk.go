@@ -0,0 +1,141 @@
+package mesh
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// PresharedKeyExchange: a WireGuard pre-shared key one node hands
+// another over the mTLS control channel. Signed by the initiator so
+// the responder only installs keys chosen by the node at the other end
+// of the tunnel
+type PresharedKeyExchange struct {
+	// MeshId: id of the mesh the key is used in
+	MeshId string
+	// Initiator: public key of the node that chose the key
+	Initiator string
+	// Responder: public key of the node the key is for
+	Responder string
+	// Key: the pre-shared key
+	Key []byte
+	// Timestamp: UNIX time the key was chosen
+	Timestamp int64
+	// Signature: XEdDSA signature of the initiator over the exchange
+	Signature []byte
+}
+
+// signedBytes: the bytes of the exchange covered by the signature
+func (e *PresharedKeyExchange) signedBytes() ([]byte, error) {
+	unsigned := *e
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the exchange using the initiator's signer
+func (e *PresharedKeyExchange) Sign(sign func([]byte) ([]byte, error)) error {
+	message, err := e.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	e.Signature, err = sign(message)
+	return err
+}
+
+// Verify: verify the initiator signed the exchange
+func (e *PresharedKeyExchange) Verify() error {
+	message, err := e.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(e.Initiator, message, e.Signature)
+}
+
+// InitiatesExchange: returns true if self rotates the pre-shared key it
+// uses with other. Clients cannot be reached so always choose the key,
+// otherwise the node with the lower first key chooses it. Either node
+// chooses a key if it holds none
+func InitiatesExchange(self, other MeshNode) bool {
+	if self.GetType() != other.GetType() {
+		return self.GetType() == conf.CLIENT_ROLE
+	}
+
+	return strings.Compare(NodeIdentity(self), NodeIdentity(other)) < 0
+}
+
+type presharedKey struct {
+	key       wgtypes.Key
+	timestamp int64
+}
+
+// PresharedKeys: the pre-shared key agreed with each node in every
+// mesh. Keys are stored against the node's first key so that they
+// survive the node rotating its key
+type PresharedKeys struct {
+	lock sync.RWMutex
+	keys map[string]map[string]presharedKey
+}
+
+// NewPresharedKeys: create an empty set of pre-shared keys
+func NewPresharedKeys() *PresharedKeys {
+	return &PresharedKeys{keys: make(map[string]map[string]presharedKey)}
+}
+
+// Get: the key agreed with the node and the UNIX time it was chosen.
+// Nil if no key has been agreed
+func (p *PresharedKeys) Get(meshId, identity string) (*wgtypes.Key, int64) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	current, ok := p.keys[meshId][identity]
+
+	if !ok {
+		return nil, 0
+	}
+
+	return &current.key, current.timestamp
+}
+
+// Set: use the key with the node if it was chosen after the current
+// key. Where both nodes chose a key at the same time the greater key
+// is used so both nodes settle on the same key. Returns false if the
+// key is not used
+func (p *PresharedKeys) Set(meshId, identity string, key wgtypes.Key, timestamp int64) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	meshKeys, ok := p.keys[meshId]
+
+	if !ok {
+		meshKeys = make(map[string]presharedKey)
+		p.keys[meshId] = meshKeys
+	}
+
+	if current, ok := meshKeys[identity]; ok {
+		if timestamp < current.timestamp {
+			return false
+		}
+
+		if timestamp == current.timestamp && bytes.Compare(key[:], current.key[:]) <= 0 {
+			return false
+		}
+	}
+
+	meshKeys[identity] = presharedKey{key: key, timestamp: timestamp}
+	return true
+}
+
+// RemoveMesh: forget the keys agreed in the mesh
+func (p *PresharedKeys) RemoveMesh(meshId string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.keys, meshId)
+}
@@ -0,0 +1,132 @@
+package mesh
+
+import (
+	"testing"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func newExchange(t *testing.T, initiator wgtypes.Key, responder string) PresharedKeyExchange {
+	key, _ := wgtypes.GenerateKey()
+
+	exchange := PresharedKeyExchange{
+		MeshId:    "mesh",
+		Initiator: initiator.PublicKey().String(),
+		Responder: responder,
+		Key:       key[:],
+		Timestamp: 1,
+	}
+
+	if err := exchange.Sign(signer(initiator)); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return exchange
+}
+
+func TestPresharedKeyExchangeVerify(t *testing.T) {
+	exchange := newExchange(t, newKey(), newKey().PublicKey().String())
+
+	if err := exchange.Verify(); err != nil {
+		t.Fatalf(`expected exchange to verify got %s`, err.Error())
+	}
+}
+
+func TestPresharedKeyExchangeVerifyModified(t *testing.T) {
+	exchange := newExchange(t, newKey(), newKey().PublicKey().String())
+	exchange.Responder = newKey().PublicKey().String()
+
+	if err := exchange.Verify(); err == nil {
+		t.Fatalf(`expected exchange handed to another node to be rejected`)
+	}
+}
+
+func TestInitiatesExchangeExactlyOneNode(t *testing.T) {
+	node1 := &MeshNodeStub{publicKey: newKey().PublicKey()}
+	node2 := &MeshNodeStub{publicKey: newKey().PublicKey()}
+
+	if InitiatesExchange(node1, node2) == InitiatesExchange(node2, node1) {
+		t.Fatalf(`expected exactly one node to choose the key`)
+	}
+}
+
+func TestPresharedKeysNewerKeyReplaces(t *testing.T) {
+	presharedKeys := NewPresharedKeys()
+	key1, _ := wgtypes.GenerateKey()
+	key2, _ := wgtypes.GenerateKey()
+
+	presharedKeys.Set("mesh", "node", key1, 2)
+
+	if presharedKeys.Set("mesh", "node", key2, 1) {
+		t.Fatalf(`expected an older key not to replace the current key`)
+	}
+
+	if !presharedKeys.Set("mesh", "node", key2, 3) {
+		t.Fatalf(`expected a newer key to replace the current key`)
+	}
+
+	key, timestamp := presharedKeys.Get("mesh", "node")
+
+	if *key != key2 || timestamp != 3 {
+		t.Fatalf(`expected key %s got %s`, key2.String(), key.String())
+	}
+}
+
+func TestPresharedKeysSimultaneousKeysSettle(t *testing.T) {
+	key1, _ := wgtypes.GenerateKey()
+	key2, _ := wgtypes.GenerateKey()
+
+	node1 := NewPresharedKeys()
+	node1.Set("mesh", "node", key1, 1)
+	node1.Set("mesh", "node", key2, 1)
+
+	node2 := NewPresharedKeys()
+	node2.Set("mesh", "node", key2, 1)
+	node2.Set("mesh", "node", key1, 1)
+
+	chosen1, _ := node1.Get("mesh", "node")
+	chosen2, _ := node2.Get("mesh", "node")
+
+	if *chosen1 != *chosen2 {
+		t.Fatalf(`expected both nodes to settle on the same key`)
+	}
+}
+
+func TestPresharedKeysRemoveMesh(t *testing.T) {
+	presharedKeys := NewPresharedKeys()
+	key, _ := wgtypes.GenerateKey()
+
+	presharedKeys.Set("mesh", "node", key, 1)
+	presharedKeys.RemoveMesh("mesh")
+
+	if key, _ := presharedKeys.Get("mesh", "node"); key != nil {
+		t.Fatalf(`expected the keys of a mesh that was left to be forgotten`)
+	}
+}
+
+func TestCreateMeshRequiringPresharedKeys(t *testing.T) {
+	manager := getMeshManager()
+
+	meshId, err := manager.CreateMesh(&CreateMeshParams{
+		Port:          5000,
+		Conf:          &conf.WgConfiguration{},
+		PresharedKeys: true,
+	})
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	manager.AddSelf(&AddSelfParams{
+		MeshId:   meshId,
+		WgPort:   5000,
+		Endpoint: "abc.com:8080",
+	})
+
+	admission, _ := manager.GetMesh(meshId).GetAdmission()
+
+	if !admission.RequiresPresharedKeys() || admission.RequiresApproval() {
+		t.Fatalf(`expected the mesh to require pre-shared keys and not approval`)
+	}
+}
@@ -0,0 +1,215 @@
+package mesh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/lib"
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
+	"github.com/tim-beatham/smegmesh/pkg/wg"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// Succession: a node's announcement that its WireGuard key is replaced
+// by a successor key. Signed by both keys so that only the holder of
+// the current key may name a successor and no other node can claim
+// the successor
+type Succession struct {
+	// PublicKey: the key being replaced
+	PublicKey string
+	// Successor: the key that replaces it
+	Successor string
+	// Activates: UNIX time at which the successor replaces the key
+	Activates int64
+	// Signature: XEdDSA signature of PublicKey over the succession
+	Signature []byte
+	// SuccessorSignature: XEdDSA signature of Successor over the succession
+	SuccessorSignature []byte
+}
+
+// signedBytes: the bytes of the succession covered by both signatures
+func (s *Succession) signedBytes() ([]byte, error) {
+	unsigned := *s
+	unsigned.Signature = nil
+	unsigned.SuccessorSignature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign: sign the succession with the current key and the successor key
+func (s *Succession) Sign(sign, signSuccessor func([]byte) ([]byte, error)) error {
+	message, err := s.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	if s.Signature, err = sign(message); err != nil {
+		return err
+	}
+
+	s.SuccessorSignature, err = signSuccessor(message)
+	return err
+}
+
+// Verify: verify both keys signed the succession
+func (s *Succession) Verify() error {
+	message, err := s.signedBytes()
+
+	if err != nil {
+		return err
+	}
+
+	if err := verifySignature(s.PublicKey, message, s.Signature); err != nil {
+		return err
+	}
+
+	return verifySignature(s.Successor, message, s.SuccessorSignature)
+}
+
+// IsActive: returns true if the successor has replaced the key
+func (s *Succession) IsActive(now time.Time) bool {
+	return now.Unix() >= s.Activates
+}
+
+// verifySuccessions: verify the successions form a chain of keys that
+// ends with the node's key. The node is still using the key the last
+// succession replaces if it is yet to rotate
+func verifySuccessions(nodeId string, successions []Succession) error {
+	for i, succession := range successions {
+		if i != 0 && succession.PublicKey != successions[i-1].Successor {
+			return errors.New("successions do not form a chain")
+		}
+
+		if err := succession.Verify(); err != nil {
+			return err
+		}
+	}
+
+	last := successions[len(successions)-1]
+
+	if nodeId != last.PublicKey && nodeId != last.Successor {
+		return fmt.Errorf("successions do not end with %s", nodeId)
+	}
+
+	return nil
+}
+
+// NodeIdentity: the key the node was first known by. Stays the same
+// when the node rotates its key
+func NodeIdentity(node MeshNode) string {
+	if successions := node.GetSuccessions(); len(successions) != 0 {
+		return successions[0].PublicKey
+	}
+
+	return NodeID(node)
+}
+
+// RotateKey: announce a successor to the node's WireGuard key in every
+// mesh. Peers configure the successor alongside the current key until
+// the succession activates after the overlap, at which point the node
+// replaces its key
+func (s *MeshManagerImpl) RotateKey(overlap time.Duration) (*Succession, error) {
+	s.rotationLock.Lock()
+	defer s.rotationLock.Unlock()
+
+	if s.succession != nil {
+		return nil, fmt.Errorf("already rotating to %s", s.succession.Successor)
+	}
+
+	successor, err := wgtypes.GeneratePrivateKey()
+
+	if err != nil {
+		return nil, err
+	}
+
+	self := s.HostParameters.GetPublicKey()
+
+	succession := &Succession{
+		PublicKey: self,
+		Successor: successor.PublicKey().String(),
+		Activates: time.Now().Add(overlap).Unix(),
+	}
+
+	err = succession.Sign(s.Sign, func(message []byte) ([]byte, error) {
+		return lib.XEdDSASign(successor, message)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mesh := range s.GetMeshes() {
+		if !mesh.NodeExists(self) {
+			continue
+		}
+
+		if err := mesh.AddSuccession(self, *succession); err != nil {
+			return nil, err
+		}
+	}
+
+	s.successor = &successor
+	s.succession = succession
+	return succession, nil
+}
+
+// completeRotation: replace the node's key with its successor once the
+// succession activates. The node keeps its address and everything it
+// carries in every mesh
+func (s *MeshManagerImpl) completeRotation() error {
+	s.rotationLock.Lock()
+	defer s.rotationLock.Unlock()
+
+	if s.succession == nil || !s.succession.IsActive(time.Now()) {
+		return nil
+	}
+
+	previous := s.HostParameters.GetPublicKey()
+	successor := s.successor
+
+	// The mesh providers sign our node with the key the host holds
+	s.HostParameters.SetPrivateKey(*successor)
+
+	for _, mesh := range s.GetMeshes() {
+		if mesh.NodeExists(previous) {
+			if err := mesh.RotateNode(previous, s.succession.Successor); err != nil {
+				return err
+			}
+		}
+
+		if s.conf.StubWg {
+			continue
+		}
+
+		device, err := mesh.GetDevice()
+
+		if err != nil {
+			return err
+		}
+
+		if err := s.interfaceManipulator.SetPrivateKey(device.Name, successor); err != nil {
+			return err
+		}
+	}
+
+	if s.conf.WgPrivateKeyPath != "" {
+		if err := wg.SavePrivateKey(s.conf.WgPrivateKeyPath, successor); err != nil {
+			logging.Log.WriteErrorf("could not save WireGuard key: %s", err.Error())
+		}
+	}
+
+	logging.Log.WriteInfof("replaced WireGuard key %s with %s", previous, s.succession.Successor)
+	s.successor = nil
+	s.succession = nil
+	return nil
+}
+
+// GetSuccession: the succession the node is rotating to. Nil if the
+// node is not rotating its key
+func (s *MeshManagerImpl) GetSuccession() *Succession {
+	s.rotationLock.Lock()
+	defer s.rotationLock.Unlock()
+	return s.succession
+}
@@ -0,0 +1,260 @@
+package mesh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func succeed(t *testing.T, key wgtypes.Key, successor wgtypes.Key, activates int64) Succession {
+	succession := Succession{
+		PublicKey: key.PublicKey().String(),
+		Successor: successor.PublicKey().String(),
+		Activates: activates,
+	}
+
+	if err := succession.Sign(signer(key), signer(successor)); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	return succession
+}
+
+func TestSuccessionVerify(t *testing.T) {
+	succession := succeed(t, newKey(), newKey(), 1)
+
+	if err := succession.Verify(); err != nil {
+		t.Fatalf(`expected succession to verify got %s`, err.Error())
+	}
+}
+
+func TestSuccessionVerifyNotSignedBySuccessor(t *testing.T) {
+	key := newKey()
+	succession := succeed(t, key, newKey(), 1)
+	succession.SuccessorSignature, _ = signer(key)([]byte("succession"))
+
+	if err := succession.Verify(); err == nil {
+		t.Fatalf(`expected succession not signed by the successor to be rejected`)
+	}
+}
+
+func TestVerifySuccessionsChain(t *testing.T) {
+	first, second, third := newKey(), newKey(), newKey()
+
+	successions := []Succession{
+		succeed(t, first, second, 1),
+		succeed(t, second, third, 2),
+	}
+
+	if err := verifySuccessions(third.PublicKey().String(), successions); err != nil {
+		t.Fatalf(`expected chain to verify got %s`, err.Error())
+	}
+
+	if err := verifySuccessions(first.PublicKey().String(), successions); err == nil {
+		t.Fatalf(`expected chain not ending with the node's key to be rejected`)
+	}
+
+	broken := []Succession{successions[0], succeed(t, newKey(), third, 2)}
+
+	if err := verifySuccessions(third.PublicKey().String(), broken); err == nil {
+		t.Fatalf(`expected successions that do not form a chain to be rejected`)
+	}
+}
+
+func TestAdmissionRotatedNodeKeepsApproval(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	node, successor := newKey(), newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey: creator.PublicKey(),
+			genesis:   genesis,
+			decisions: []JoinDecision{decide(t, creator, node, true, 1)},
+		},
+		&MeshNodeStub{
+			publicKey:   successor.PublicKey(),
+			successions: []Succession{succeed(t, node, successor, 1)},
+		},
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if !admission.IsApproved(successor.PublicKey().String()) {
+		t.Fatalf(`expected the approval of the old key to carry over to the successor`)
+	}
+
+	if admission.CurrentKey(node.PublicKey().String()) != successor.PublicKey().String() {
+		t.Fatalf(`expected the successor to be the node's current key`)
+	}
+
+	if !admission.IsSuperseded(node.PublicKey().String()) {
+		t.Fatalf(`expected the old key to be superseded`)
+	}
+}
+
+func TestAdmissionRotatedNodeStaysRevoked(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	genesis.RequireApproval = false
+	genesis.Sign(signer(creator))
+	node, successor := newKey(), newKey()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{
+			publicKey:   creator.PublicKey(),
+			genesis:     genesis,
+			revocations: []Revocation{revoke(t, creator, node.PublicKey().String())},
+		},
+		&MeshNodeStub{
+			publicKey:   successor.PublicKey(),
+			successions: []Succession{succeed(t, node, successor, 1)},
+		},
+	}
+
+	if !NewAdmission(genesis.MeshId(), nodes).IsRevoked(successor.PublicKey().String()) {
+		t.Fatalf(`expected an evicted node not to escape the revocation by rotating its key`)
+	}
+}
+
+func TestAdmissionPendingSuccession(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	node, successor := newKey(), newKey()
+	activates := time.Now().Add(time.Hour).Unix()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis},
+		&MeshNodeStub{
+			publicKey:   node.PublicKey(),
+			successions: []Succession{succeed(t, node, successor, activates)},
+		},
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if admission.IsSuperseded(node.PublicKey().String()) {
+		t.Fatalf(`expected the node to keep its key until it rotates`)
+	}
+
+	succession, ok := admission.GetSuccession(node.PublicKey().String())
+
+	if !ok || succession.Successor != successor.PublicKey().String() {
+		t.Fatalf(`expected the pending succession to be found`)
+	}
+
+	if !admission.SameNode(node.PublicKey().String(), successor.PublicKey().String()) {
+		t.Fatalf(`expected both keys to belong to the same node`)
+	}
+}
+
+func TestAdmissionForgedSuccessionIgnored(t *testing.T) {
+	genesis, creator := setUpGenesis(t)
+	attacker := newKey()
+
+	// A successor cannot claim the creator's identity without its key
+	succession := succeed(t, attacker, attacker, 1)
+	succession.PublicKey = creator.PublicKey().String()
+
+	nodes := []MeshNode{
+		&MeshNodeStub{publicKey: creator.PublicKey(), genesis: genesis},
+		&MeshNodeStub{publicKey: attacker.PublicKey(), successions: []Succession{succession}},
+	}
+
+	admission := NewAdmission(genesis.MeshId(), nodes)
+
+	if admission.IsOwner(attacker.PublicKey().String()) {
+		t.Fatalf(`expected a forged succession to be ignored`)
+	}
+}
+
+func TestRotateKeyReplacesKeyOnceActive(t *testing.T) {
+	manager := getMeshManager()
+
+	meshId, _ := manager.CreateMesh(&CreateMeshParams{
+		Port: 5000,
+		Conf: &conf.WgConfiguration{},
+	})
+
+	manager.AddSelf(&AddSelfParams{
+		MeshId:   meshId,
+		WgPort:   5000,
+		Endpoint: "abc.com:8080",
+	})
+
+	previous := manager.GetPublicKey().String()
+	succession, err := manager.RotateKey(0)
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if _, err := manager.RotateKey(0); err == nil {
+		t.Fatalf(`expected a second rotation to be refused whilst rotating`)
+	}
+
+	if err := manager.UpdateTimeStamp(); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	if manager.GetPublicKey().String() != succession.Successor {
+		t.Fatalf(`expected the node to use its successor key`)
+	}
+
+	theMesh := manager.GetMesh(meshId)
+
+	if theMesh.NodeExists(previous) || !theMesh.NodeExists(succession.Successor) {
+		t.Fatalf(`expected the node to be known by its successor key`)
+	}
+
+	if manager.GetSuccession() != nil {
+		t.Fatalf(`expected the rotation to be complete`)
+	}
+}
+
+func TestRotateKeyWhileSigning(t *testing.T) {
+	manager := getMeshManager()
+
+	meshId, _ := manager.CreateMesh(&CreateMeshParams{
+		Port: 5000,
+		Conf: &conf.WgConfiguration{},
+	})
+
+	manager.AddSelf(&AddSelfParams{
+		MeshId:   meshId,
+		WgPort:   5000,
+		Endpoint: "abc.com:8080",
+	})
+
+	if _, err := manager.RotateKey(0); err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+
+	signing := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	// Sign concurrently with the key being replaced so that the race
+	// detector catches the key being modified in place
+	go func() {
+		defer close(done)
+		close(signing)
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				manager.Sign([]byte("message"))
+			}
+		}
+	}()
+
+	<-signing
+	err := manager.UpdateTimeStamp()
+	close(stop)
+	<-done
+
+	if err != nil {
+		t.Fatalf(`%s`, err.Error())
+	}
+}
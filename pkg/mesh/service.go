@@ -0,0 +1,70 @@
+package mesh
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ServiceStatus: the health of a service as reported by its checks
+type ServiceStatus string
+
+const (
+	// SERVICE_PASSING: every check of the service passes
+	SERVICE_PASSING ServiceStatus = "passing"
+	// SERVICE_WARNING: the service is up but degraded
+	SERVICE_WARNING ServiceStatus = "warning"
+	// SERVICE_CRITICAL: the service is down
+	SERVICE_CRITICAL ServiceStatus = "critical"
+)
+
+// Service: a service offered by a node
+type Service struct {
+	// Name: name of the service unique to the node
+	Name string
+	// Port: port the service listens on at the node's mesh address
+	Port int
+	// Protocol: tcp or udp
+	Protocol string
+	// Tags: tags consumers filter the service by
+	Tags []string
+	// Metadata: free-form key value pairs describing the service
+	Metadata map[string]string
+	// TTL: number of seconds the record is valid for after it was
+	// updated. The record never expires if zero
+	TTL int64
+	// Status: the health of the service when it was updated
+	Status ServiceStatus
+	// Updated: UNIX time the record was last published
+	Updated int64
+}
+
+// Expired: returns true if the node has not refreshed the record
+// within its TTL
+func (s *Service) Expired(now time.Time) bool {
+	return s.TTL != 0 && now.Unix() > s.Updated+s.TTL
+}
+
+// Discoverable: returns true if the service should be returned to
+// consumers. Services that are critical or have expired are hidden
+func (s *Service) Discoverable(now time.Time) bool {
+	return s.Status != SERVICE_CRITICAL && !s.Expired(now)
+}
+
+// DiscoverableServices: the node's services that should be returned to
+// consumers ordered by name
+func DiscoverableServices(node MeshNode, now time.Time) []Service {
+	services := make([]Service, 0)
+
+	for _, service := range node.GetServiceRecords() {
+		if service.Discoverable(now) {
+			services = append(services, service)
+		}
+	}
+
+	slices.SortFunc(services, func(a, b Service) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return services
+}
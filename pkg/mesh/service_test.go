@@ -0,0 +1,25 @@
+package mesh
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiscoverableServicesHidesUnhealthy(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	node := &MeshNodeStub{
+		records: map[string]Service{
+			"web":     {Name: "web", Status: SERVICE_PASSING, TTL: 30, Updated: 990},
+			"db":      {Name: "db", Status: SERVICE_WARNING},
+			"cache":   {Name: "cache", Status: SERVICE_CRITICAL},
+			"metrics": {Name: "metrics", Status: SERVICE_PASSING, TTL: 30, Updated: 900},
+		},
+	}
+
+	services := DiscoverableServices(node, now)
+
+	if len(services) != 2 || services[0].Name != "db" || services[1].Name != "web" {
+		t.Fatalf(`expected only the healthy services that have not expired got %v`, services)
+	}
+}
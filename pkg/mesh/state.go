@@ -0,0 +1,158 @@
+package mesh
+
+import (
+	"cmp"
+	"slices"
+)
+
+// MeshStateEntry: a single key within the replicated store of
+// a mesh provider
+type MeshStateEntry struct {
+	// Key: hashed key of the entry in the store
+	Key uint64
+	// InAdd: whether or not the key is in the add map
+	InAdd bool
+	// AddClock: the vector clock of the key in the add map
+	AddClock uint64
+	// InRemove: whether or not the key is in the remove map
+	InRemove bool
+	// RemoveClock: the vector clock of the key in the remove map
+	RemoveClock uint64
+	// Gravestone: true if the node has been marked as unreachable
+	Gravestone bool
+	// PublicKey: the decoded public key of the node. Empty if the
+	// key is only present in the remove map
+	PublicKey string
+	// Alias: the decoded alias of the node
+	Alias string
+	// WgHost: the decoded WireGuard IP of the node
+	WgHost string
+	// Timestamp: the decoded timestamp of the node
+	Timestamp int64
+}
+
+// IsRemoved: returns true if the entry is tombstoned in the
+// remove map
+func (e *MeshStateEntry) IsRemoved() bool {
+	return e.InRemove && (!e.InAdd || e.RemoveClock > e.AddClock)
+}
+
+// MeshState: the internal state of a mesh's replicated store. Used
+// to diagnose divergence between nodes
+type MeshState struct {
+	// Vectors: the vector clock of every process
+	Vectors map[uint64]uint64
+	// HighestStale: the highest clock that has been garbage collected
+	HighestStale uint64
+	// Entries: every key in either the add or remove map
+	Entries map[uint64]MeshStateEntry
+}
+
+// StateDifferenceType: the kind of divergence detected between
+// two states
+type StateDifferenceType string
+
+const (
+	// MISSING_LOCAL: the key exists remotely but not locally
+	MISSING_LOCAL StateDifferenceType = "missing-local"
+	// MISSING_REMOTE: the key exists locally but not remotely
+	MISSING_REMOTE StateDifferenceType = "missing-remote"
+	// STALE_LOCAL: the remote copy of the key is more recent
+	STALE_LOCAL StateDifferenceType = "stale-local"
+	// STALE_REMOTE: the local copy of the key is more recent
+	STALE_REMOTE StateDifferenceType = "stale-remote"
+	// TOMBSTONED_LOCAL: the key is removed locally but alive remotely
+	TOMBSTONED_LOCAL StateDifferenceType = "tombstoned-local"
+	// TOMBSTONED_REMOTE: the key is removed remotely but alive locally
+	TOMBSTONED_REMOTE StateDifferenceType = "tombstoned-remote"
+	// HIGHEST_STALE: the garbage collection watermarks differ
+	HIGHEST_STALE StateDifferenceType = "highest-stale"
+)
+
+// StateDifference: a single divergence between a local and remote state
+type StateDifference struct {
+	Type   StateDifferenceType
+	Key    uint64
+	Local  *MeshStateEntry
+	Remote *MeshStateEntry
+	// Reason: human readable explanation of the divergence
+	Reason string
+}
+
+// DiffState: compares the local and remote state entry by entry and
+// returns every divergence. Keys whose clocks fall below the other side's
+// highest stale value are flagged as they will be rejected on merge
+func DiffState(local, remote *MeshState) []StateDifference {
+	differences := make([]StateDifference, 0)
+
+	if local.HighestStale != remote.HighestStale {
+		differences = append(differences, StateDifference{
+			Type:   HIGHEST_STALE,
+			Reason: "garbage collection watermarks differ",
+		})
+	}
+
+	keys := make([]uint64, 0, len(local.Entries)+len(remote.Entries))
+
+	for key := range local.Entries {
+		keys = append(keys, key)
+	}
+
+	for key := range remote.Entries {
+		if _, ok := local.Entries[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.SortFunc(keys, cmp.Compare[uint64])
+
+	for _, key := range keys {
+		localEntry, inLocal := local.Entries[key]
+		remoteEntry, inRemote := remote.Entries[key]
+
+		difference := StateDifference{Key: key}
+
+		if inLocal {
+			difference.Local = &localEntry
+		}
+
+		if inRemote {
+			difference.Remote = &remoteEntry
+		}
+
+		switch {
+		case !inLocal:
+			difference.Type = MISSING_LOCAL
+			difference.Reason = "key is not in the local store"
+
+			if remoteEntry.AddClock <= local.HighestStale && remoteEntry.RemoveClock <= local.HighestStale {
+				difference.Reason = "key is below the local highest stale and will be ignored"
+			}
+		case !inRemote:
+			difference.Type = MISSING_REMOTE
+			difference.Reason = "key is not in the remote store"
+
+			if localEntry.AddClock <= remote.HighestStale && localEntry.RemoveClock <= remote.HighestStale {
+				difference.Reason = "key is below the remote highest stale and will be ignored"
+			}
+		case localEntry.IsRemoved() && !remoteEntry.IsRemoved():
+			difference.Type = TOMBSTONED_LOCAL
+			difference.Reason = "key is removed locally but alive remotely"
+		case !localEntry.IsRemoved() && remoteEntry.IsRemoved():
+			difference.Type = TOMBSTONED_REMOTE
+			difference.Reason = "key is removed remotely but alive locally"
+		case localEntry.AddClock < remoteEntry.AddClock || localEntry.RemoveClock < remoteEntry.RemoveClock:
+			difference.Type = STALE_LOCAL
+			difference.Reason = "remote copy has a higher clock"
+		case localEntry.AddClock > remoteEntry.AddClock || localEntry.RemoveClock > remoteEntry.RemoveClock:
+			difference.Type = STALE_REMOTE
+			difference.Reason = "local copy has a higher clock"
+		default:
+			continue
+		}
+
+		differences = append(differences, difference)
+	}
+
+	return differences
+}
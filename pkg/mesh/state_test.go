@@ -0,0 +1,81 @@
+package mesh
+
+import "testing"
+
+func getTestState() *MeshState {
+	return &MeshState{
+		Vectors:      map[uint64]uint64{1: 4, 2: 6},
+		HighestStale: 0,
+		Entries: map[uint64]MeshStateEntry{
+			1: {Key: 1, InAdd: true, AddClock: 4, PublicKey: "a"},
+			2: {Key: 2, InAdd: true, AddClock: 6, PublicKey: "b"},
+		},
+	}
+}
+
+func TestDiffStateIdenticalStatesHaveNoDifferences(t *testing.T) {
+	differences := DiffState(getTestState(), getTestState())
+
+	if len(differences) != 0 {
+		t.Fatalf(`expected no differences got %d`, len(differences))
+	}
+}
+
+func TestDiffStateMissingRemoteKey(t *testing.T) {
+	remote := getTestState()
+	delete(remote.Entries, 2)
+
+	differences := DiffState(getTestState(), remote)
+
+	if len(differences) != 1 {
+		t.Fatalf(`expected 1 difference got %d`, len(differences))
+	}
+
+	if differences[0].Type != MISSING_REMOTE || differences[0].Key != 2 {
+		t.Fatalf(`expected key 2 to be missing remotely got %s`, differences[0].Type)
+	}
+}
+
+func TestDiffStateMissingLocalKey(t *testing.T) {
+	local := getTestState()
+	delete(local.Entries, 1)
+
+	differences := DiffState(local, getTestState())
+
+	if len(differences) != 1 || differences[0].Type != MISSING_LOCAL {
+		t.Fatalf(`expected key 1 to be missing locally`)
+	}
+}
+
+func TestDiffStateStaleLocalKey(t *testing.T) {
+	remote := getTestState()
+	remote.Entries[1] = MeshStateEntry{Key: 1, InAdd: true, AddClock: 10, PublicKey: "a"}
+
+	differences := DiffState(getTestState(), remote)
+
+	if len(differences) != 1 || differences[0].Type != STALE_LOCAL {
+		t.Fatalf(`expected key 1 to be stale locally`)
+	}
+}
+
+func TestDiffStateTombstonedRemoteKey(t *testing.T) {
+	remote := getTestState()
+	remote.Entries[1] = MeshStateEntry{Key: 1, InAdd: true, AddClock: 4, InRemove: true, RemoveClock: 7, PublicKey: "a"}
+
+	differences := DiffState(getTestState(), remote)
+
+	if len(differences) != 1 || differences[0].Type != TOMBSTONED_REMOTE {
+		t.Fatalf(`expected key 1 to be tombstoned remotely`)
+	}
+}
+
+func TestDiffStateHighestStaleMismatch(t *testing.T) {
+	remote := getTestState()
+	remote.HighestStale = 5
+
+	differences := DiffState(getTestState(), remote)
+
+	if len(differences) != 1 || differences[0].Type != HIGHEST_STALE {
+		t.Fatalf(`expected highest stale mismatch`)
+	}
+}
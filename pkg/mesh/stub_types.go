@@ -22,6 +22,17 @@ type MeshNodeStub struct {
 	description  string
 	alias        string
 	services     map[string]string
+	redemptions  []Redemption
+	genesis      *Genesis
+	info         *MeshInfo
+	requests     []JoinRequest
+	decisions    []JoinDecision
+	revocations  []Revocation
+	grants       []Grant
+	successions  []Succession
+	aclPolicy    *AclPolicy
+	labels       map[string]string
+	records      map[string]Service
 }
 
 // GetType implements MeshNode.
@@ -34,6 +45,16 @@ func (m *MeshNodeStub) GetServices() map[string]string {
 	return m.services
 }
 
+// GetLabels implements MeshNode.
+func (m *MeshNodeStub) GetLabels() map[string]string {
+	return m.labels
+}
+
+// GetServiceRecords implements MeshNode.
+func (m *MeshNodeStub) GetServiceRecords() map[string]Service {
+	return m.records
+}
+
 // GetAlias implements MeshNode.
 func (s *MeshNodeStub) GetAlias() string {
 	return s.alias
@@ -71,6 +92,47 @@ func (m *MeshNodeStub) GetDescription() string {
 	return m.description
 }
 
+func (m *MeshNodeStub) GetVersion() string {
+	return lib.Version
+}
+
+func (m *MeshNodeStub) GetRedemptions() []Redemption {
+	return m.redemptions
+}
+
+// GetMeshInfo implements MeshNode.
+func (m *MeshNodeStub) GetMeshInfo() *MeshInfo {
+	return m.info
+}
+
+func (m *MeshNodeStub) GetGenesis() *Genesis {
+	return m.genesis
+}
+
+func (m *MeshNodeStub) GetJoinRequests() []JoinRequest {
+	return m.requests
+}
+
+func (m *MeshNodeStub) GetJoinDecisions() []JoinDecision {
+	return m.decisions
+}
+
+func (m *MeshNodeStub) GetRevocations() []Revocation {
+	return m.revocations
+}
+
+func (m *MeshNodeStub) GetGrants() []Grant {
+	return m.grants
+}
+
+func (m *MeshNodeStub) GetSuccessions() []Succession {
+	return m.successions
+}
+
+func (m *MeshNodeStub) GetAclPolicy() *AclPolicy {
+	return m.aclPolicy
+}
+
 type MeshSnapshotStub struct {
 	nodes map[string]MeshNode
 }
@@ -141,6 +203,128 @@ func (m *MeshProviderStub) RemoveService(nodeId string, key string) error {
 	return nil
 }
 
+// PutServiceRecord implements MeshProvider.
+func (m *MeshProviderStub) PutServiceRecord(nodeId string, service Service) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+
+	if node.records == nil {
+		node.records = make(map[string]Service)
+	}
+
+	node.records[service.Name] = service
+	return nil
+}
+
+// RemoveServiceRecord implements MeshProvider.
+func (m *MeshProviderStub) RemoveServiceRecord(nodeId string, name string) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	delete(node.records, name)
+	return nil
+}
+
+// SetLabel implements MeshProvider.
+func (m *MeshProviderStub) SetLabel(nodeId string, key string, value string) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+
+	if node.labels == nil {
+		node.labels = make(map[string]string)
+	}
+
+	node.labels[key] = value
+	return nil
+}
+
+// RemoveLabel implements MeshProvider.
+func (m *MeshProviderStub) RemoveLabel(nodeId string, key string) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	delete(node.labels, key)
+	return nil
+}
+
+// AddRedemption implements MeshProvider.
+func (m *MeshProviderStub) AddRedemption(nodeId string, redemption Redemption) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	node.redemptions = append(node.redemptions, redemption)
+	return nil
+}
+
+// SetGenesis implements MeshProvider.
+func (m *MeshProviderStub) SetGenesis(nodeId string, genesis Genesis) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	node.genesis = &genesis
+	return nil
+}
+
+// SetMeshInfo implements MeshProvider.
+func (m *MeshProviderStub) SetMeshInfo(nodeId string, info MeshInfo) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	node.info = &info
+	return nil
+}
+
+// AddJoinRequest implements MeshProvider.
+func (m *MeshProviderStub) AddJoinRequest(nodeId string, request JoinRequest) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	node.requests = append(node.requests, request)
+	return nil
+}
+
+// AddJoinDecision implements MeshProvider.
+func (m *MeshProviderStub) AddJoinDecision(nodeId string, decision JoinDecision) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	node.decisions = append(node.decisions, decision)
+	return nil
+}
+
+// AddRevocation implements MeshProvider.
+func (m *MeshProviderStub) AddRevocation(nodeId string, revocation Revocation) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	node.revocations = append(node.revocations, revocation)
+	return nil
+}
+
+// AddGrant implements MeshProvider.
+func (m *MeshProviderStub) AddGrant(nodeId string, grant Grant) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	node.grants = append(node.grants, grant)
+	return nil
+}
+
+// AddSuccession implements MeshProvider.
+func (m *MeshProviderStub) AddSuccession(nodeId string, succession Succession) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	node.successions = append(node.successions, succession)
+	return nil
+}
+
+// SetAclPolicy implements MeshProvider.
+func (m *MeshProviderStub) SetAclPolicy(nodeId string, policy AclPolicy) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	node.aclPolicy = &policy
+	return nil
+}
+
+// RotateNode implements MeshProvider.
+func (m *MeshProviderStub) RotateNode(nodeId string, successor string) error {
+	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
+	key, err := wgtypes.ParseKey(successor)
+
+	if err != nil {
+		return err
+	}
+
+	rotated := *node
+	rotated.publicKey = key
+	delete(m.snapshot.nodes, nodeId)
+	m.snapshot.nodes[successor] = &rotated
+	return nil
+}
+
+// GetAdmission implements MeshProvider.
+func (m *MeshProviderStub) GetAdmission() (*Admission, error) {
+	return NewAdmission(m.meshId, lib.MapValues(m.snapshot.nodes)), nil
+}
+
 // SetAlias implements MeshProvider.
 func (m *MeshProviderStub) SetAlias(nodeId string, alias string) error {
 	node := (m.snapshot.nodes[nodeId]).(*MeshNodeStub)
@@ -224,6 +408,13 @@ func (s *MeshProviderStub) SetDescription(nodeId string, description string) err
 	return nil
 }
 
+func (s *MeshProviderStub) GetState() (*MeshState, error) {
+	return &MeshState{
+		Vectors: make(map[uint64]uint64),
+		Entries: make(map[uint64]MeshStateEntry),
+	}, nil
+}
+
 type StubMeshProviderFactory struct{}
 
 func (s *StubMeshProviderFactory) CreateMesh(params *MeshProviderFactoryParams) (MeshProvider, error) {
@@ -290,6 +481,26 @@ func (*MeshManagerStub) SetService(meshId, service, value string) error {
 	return nil
 }
 
+// PutServiceRecord implements MeshManager.
+func (*MeshManagerStub) PutServiceRecord(meshId string, service Service) error {
+	return nil
+}
+
+// RemoveServiceRecord implements MeshManager.
+func (*MeshManagerStub) RemoveServiceRecord(meshId, name string) error {
+	return nil
+}
+
+// SetLabel implements MeshManager.
+func (*MeshManagerStub) SetLabel(meshId, key, value string) error {
+	return nil
+}
+
+// RemoveLabel implements MeshManager.
+func (*MeshManagerStub) RemoveLabel(meshId, key string) error {
+	return nil
+}
+
 // SetAlias implements MeshManager.
 func (*MeshManagerStub) SetAlias(meshId, alias string) error {
 	return nil
@@ -313,6 +524,30 @@ func (m *MeshManagerStub) CreateMesh(*CreateMeshParams) (string, error) {
 	return "tim123", nil
 }
 
+func (m *MeshManagerStub) Sign(message []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func (m *MeshManagerStub) RotateKey(overlap time.Duration) (*Succession, error) {
+	return nil, nil
+}
+
+func (m *MeshManagerStub) GetSuccession() *Succession {
+	return nil
+}
+
+func (m *MeshManagerStub) GetPresharedKeys() *PresharedKeys {
+	return NewPresharedKeys()
+}
+
+func (m *MeshManagerStub) Updated() <-chan struct{} {
+	return make(chan struct{})
+}
+
+func (m *MeshManagerStub) GetCredential(meshId string) string {
+	return ""
+}
+
 func (m *MeshManagerStub) AddMesh(params *AddMeshParams) error {
 	m.meshes[params.MeshId] = &MeshProviderStub{
 		params.MeshId,
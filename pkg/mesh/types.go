@@ -5,6 +5,7 @@ package mesh
 import (
 	"net"
 	"slices"
+	"sync/atomic"
 
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	"golang.zx2c4.com/wireguard/wgctrl"
@@ -43,6 +44,18 @@ func (r *RouteStub) GetPath() []string {
 	return r.Path
 }
 
+// Redemption: records that a node redeemed an invite to the mesh
+type Redemption struct {
+	// Invite: id of the invite that was redeemed
+	Invite string
+	// Grantee: public key of the node that redeemed the invite
+	Grantee string
+	// Timestamp: UNIX time the invite was redeemed
+	Timestamp int64
+	// Role: role the invite restricts the grantee to, any role if empty
+	Role string
+}
+
 // MeshNode represents an implementation of a node in a mesh
 type MeshNode interface {
 	// GetHostEndpoint: gets the gRPC endpoint of the node
@@ -66,7 +79,33 @@ type MeshNode interface {
 	GetAlias() string
 	// GetServices: returns a list of services offered by the node
 	GetServices() map[string]string
+	// GetLabels: returns the labels the node has placed on itself
+	GetLabels() map[string]string
+	// GetServiceRecords: returns the structured services offered by the
+	// node keyed by name
+	GetServiceRecords() map[string]Service
 	GetType() conf.NodeType
+	// GetVersion: returns the software version the node is running
+	GetVersion() string
+	// GetRedemptions: returns the invites redeemed through the node
+	GetRedemptions() []Redemption
+	// GetGenesis: returns the genesis of the mesh if the node carries it
+	GetGenesis() *Genesis
+	// GetMeshInfo: returns the metadata of the mesh if the node carries it
+	GetMeshInfo() *MeshInfo
+	// GetJoinRequests: returns the join requests received by the node
+	GetJoinRequests() []JoinRequest
+	// GetJoinDecisions: returns the join decisions carried by the node
+	GetJoinDecisions() []JoinDecision
+	// GetRevocations: returns the revocations carried by the node
+	GetRevocations() []Revocation
+	// GetGrants: returns the admin grants carried by the node
+	GetGrants() []Grant
+	// GetAclPolicy: returns the access control policy carried by the node
+	GetAclPolicy() *AclPolicy
+	// GetSuccessions: returns the keys the node has rotated through and
+	// the key it is rotating to if any
+	GetSuccessions() []Succession
 }
 
 // NodeEquals: determines if two mesh nodes are equivalent to one another
@@ -96,6 +135,13 @@ type MeshSyncer interface {
 	GenerateMessage() ([]byte, bool)
 	RecvMessage(mesg []byte) error
 	Complete()
+	// Capabilities: the capabilities the syncer supports in order
+	// of preference such as the encodings it can speak
+	Capabilities() []string
+	// Negotiate: select the encoding to use given the capabilities
+	// of the peer. Returns the selected encoding or an error if there
+	// is no common encoding
+	Negotiate(capabilities []string) (string, error)
 }
 
 // Mesh: Represents an implementation of a mesh
@@ -136,6 +182,41 @@ type MeshProvider interface {
 	AddService(nodeId, key, value string) error
 	// RemoveService: removes the service form the node. throws an error if the service does not exist
 	RemoveService(nodeId, key string) error
+	// PutServiceRecord: places the service record in the given node
+	// replacing any record with the same name
+	PutServiceRecord(nodeId string, service Service) error
+	// RemoveServiceRecord: removes the service record from the node. throws an error if the record does not exist
+	RemoveServiceRecord(nodeId, name string) error
+	// SetLabel: places the label on the given node
+	SetLabel(nodeId, key, value string) error
+	// RemoveLabel: removes the label from the node. throws an error if the node does not have the label
+	RemoveLabel(nodeId, key string) error
+	// AddRedemption: records that an invite was redeemed through the node
+	AddRedemption(nodeId string, redemption Redemption) error
+	// SetGenesis: places the genesis of the mesh in the node
+	SetGenesis(nodeId string, genesis Genesis) error
+	// SetMeshInfo: places the metadata of the mesh in the node
+	SetMeshInfo(nodeId string, info MeshInfo) error
+	// AddJoinRequest: records a join request received by the node.
+	// Replaces any earlier request by the same node. Refuses the
+	// request if the node carries MAX_JOIN_REQUESTS unexpired requests
+	AddJoinRequest(nodeId string, request JoinRequest) error
+	// AddJoinDecision: records a join decision in the node
+	AddJoinDecision(nodeId string, decision JoinDecision) error
+	// AddRevocation: records the eviction of a node in the node
+	AddRevocation(nodeId string, revocation Revocation) error
+	// AddGrant: records a grant of admin rights in the node. Replaces
+	// any earlier grant about the same node
+	AddGrant(nodeId string, grant Grant) error
+	// AddSuccession: announce the successor to the node's key
+	AddSuccession(nodeId string, succession Succession) error
+	// SetAclPolicy: places the access control policy in the node
+	SetAclPolicy(nodeId string, policy AclPolicy) error
+	// RotateNode: move the node to its successor key keeping everything
+	// else about the node
+	RotateNode(nodeId string, successor string) error
+	// GetAdmission: determine which nodes have been admitted to the mesh
+	GetAdmission() (*Admission, error)
 	// Prune: prunes all nodes that have not updated their
 	// vector clock
 	Prune() error
@@ -151,16 +232,39 @@ type MeshProvider interface {
 	// GetConfiguration: gets the configuration parameters specific for this
 	// mesh network
 	GetConfiguration() *conf.WgConfiguration
+	// GetState: get the internal state of the replicated store
+	// used to diagnose divergence between nodes
+	GetState() (*MeshState, error)
 }
 
 // HostParameters contains the IDs of a node
 type HostParameters struct {
-	PrivateKey *wgtypes.Key
+	// privateKey: WireGuard private key of the node. Replaced rather
+	// than modified when the node rotates its key as other goroutines
+	// sign with it
+	privateKey atomic.Pointer[wgtypes.Key]
+}
+
+// NewHostParameters: create the parameters of a node with the key
+func NewHostParameters(privateKey wgtypes.Key) *HostParameters {
+	hostParams := &HostParameters{}
+	hostParams.SetPrivateKey(privateKey)
+	return hostParams
+}
+
+// GetPrivateKey: gets the current private key of the node
+func (h *HostParameters) GetPrivateKey() wgtypes.Key {
+	return *h.privateKey.Load()
+}
+
+// SetPrivateKey: replace the private key of the node
+func (h *HostParameters) SetPrivateKey(privateKey wgtypes.Key) {
+	h.privateKey.Store(&privateKey)
 }
 
 // GetPublicKey: gets the public key of the node
 func (h *HostParameters) GetPublicKey() string {
-	return h.PrivateKey.PublicKey().String()
+	return h.GetPrivateKey().PublicKey().String()
 }
 
 // MeshProviderFactoryParams parameters required to build a mesh provider
@@ -172,6 +276,11 @@ type MeshProviderFactoryParams struct {
 	DaemonConf *conf.DaemonConfiguration
 	Client     *wgctrl.Client
 	NodeID     string
+	// Host: the node's parameters. Its current private key signs the
+	// node's entry in the mesh
+	Host *HostParameters
+	// OnUpdate: called whenever the nodes in the mesh change
+	OnUpdate func()
 }
 
 // MeshProviderFactory creates an instance of a mesh provider
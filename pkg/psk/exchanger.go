@@ -0,0 +1,245 @@
+package psk
+
+import (
+	"context"
+	"time"
+
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/conn"
+	logging "github.com/tim-beatham/smegmesh/pkg/log"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/rpc"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const (
+	// HANDSHAKE_TIMEOUT: time after a key is chosen within which the
+	// WireGuard handshake must succeed with the node if we have sent it
+	// traffic. Otherwise the node is assumed not to hold the key
+	HANDSHAKE_TIMEOUT = 3 * time.Minute
+)
+
+// observation: bytes transmitted to a node when first observed under
+// the key chosen at the given time
+type observation struct {
+	chosen      int64
+	transmitted int64
+}
+
+// Exchanger: agrees a WireGuard pre-shared key with every node the node
+// configures in meshes that require pre-shared keys. Keys are chosen at
+// random and handed over the mTLS control channel so they cannot be
+// derived from the WireGuard handshake
+type Exchanger struct {
+	connectionManager conn.ConnectionManager
+	meshManager       mesh.MeshManager
+	conf              *conf.DaemonConfiguration
+	// observations: traffic to each node under its current key by mesh
+	// and node identity
+	observations map[string]map[string]observation
+}
+
+// NewExchangerParams: params to create a new exchanger
+type NewExchangerParams struct {
+	ConnectionManager conn.ConnectionManager
+	MeshManager       mesh.MeshManager
+	Conf              *conf.DaemonConfiguration
+}
+
+// NewExchanger: create a new exchanger
+func NewExchanger(params *NewExchangerParams) *Exchanger {
+	return &Exchanger{
+		connectionManager: params.ConnectionManager,
+		meshManager:       params.MeshManager,
+		conf:              params.Conf,
+		observations:      make(map[string]map[string]observation),
+	}
+}
+
+// Exchange: hand a new key to every node we hold no key for, whose
+// handshake is failing under the key or whose key we choose and is due
+// to be rotated. Applies the WireGuard configuration if any key changed
+func (e *Exchanger) Exchange() error {
+	changed := false
+
+	for _, theMesh := range e.meshManager.GetMeshes() {
+		exchanged, err := e.exchangeMesh(theMesh)
+
+		if err != nil {
+			logging.Log.WriteErrorf("could not exchange pre-shared keys in %s: %s", theMesh.GetMeshId(), err.Error())
+		}
+
+		changed = changed || exchanged
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return e.meshManager.ApplyConfig()
+}
+
+// exchangeMesh: exchange keys with the nodes in the mesh. Returns true
+// if any key changed
+func (e *Exchanger) exchangeMesh(theMesh mesh.MeshProvider) (bool, error) {
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil || !admission.RequiresPresharedKeys() {
+		return false, err
+	}
+
+	self, err := theMesh.GetNode(e.meshManager.GetPublicKey().String())
+
+	// We are yet to be added to the mesh
+	if err != nil {
+		return false, nil
+	}
+
+	snapshot, err := theMesh.GetMesh()
+
+	if err != nil {
+		return false, err
+	}
+
+	peers := make(map[string]wgtypes.Peer)
+
+	if device, err := theMesh.GetDevice(); err == nil && device != nil {
+		for _, peer := range device.Peers {
+			peers[peer.PublicKey.String()] = peer
+		}
+	}
+
+	meshId := theMesh.GetMeshId()
+	presharedKeys := e.meshManager.GetPresharedKeys()
+	changed := false
+
+	for nodeId, node := range snapshot.GetNodes() {
+		if mesh.NodeEquals(self, node) {
+			continue
+		}
+
+		identity := admission.Identity(nodeId)
+		_, chosen := presharedKeys.Get(meshId, identity)
+
+		if !e.needsExchange(meshId, identity, self, node, chosen, peers[nodeId]) {
+			continue
+		}
+
+		if err := e.exchange(meshId, admission, node); err != nil {
+			logging.Log.WriteWarnf("could not exchange pre-shared key with %s: %s", nodeId, err.Error())
+			continue
+		}
+
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// needsExchange: returns true if a new key must be handed to the node.
+// Keys are only held in memory so either node exchanges a key if it
+// holds none, as after either node restarts. The key is exchanged again
+// if the handshake is failing under it. Otherwise the node that chooses
+// the key rotates it
+func (e *Exchanger) needsExchange(meshId, identity string, self, node mesh.MeshNode, chosen int64, peer wgtypes.Peer) bool {
+	// Clients cannot be reached so always choose the key
+	if node.GetType() == conf.CLIENT_ROLE && self.GetType() != conf.CLIENT_ROLE {
+		return false
+	}
+
+	if chosen == 0 {
+		return true
+	}
+
+	if e.handshakeFailing(meshId, identity, chosen, peer) {
+		logging.Log.WriteWarnf("handshake with %s failing under the pre-shared key", identity)
+		return true
+	}
+
+	rotation := time.Duration(e.conf.PresharedKeyRotation) * time.Second
+	return mesh.InitiatesExchange(self, node) && time.Since(time.Unix(chosen, 0)) >= rotation
+}
+
+// handshakeFailing: returns true if we have sent traffic to the node
+// since the key was chosen but no handshake has succeeded under the key
+// within HANDSHAKE_TIMEOUT
+func (e *Exchanger) handshakeFailing(meshId, identity string, chosen int64, peer wgtypes.Peer) bool {
+	meshObservations, ok := e.observations[meshId]
+
+	if !ok {
+		meshObservations = make(map[string]observation)
+		e.observations[meshId] = meshObservations
+	}
+
+	observed, ok := meshObservations[identity]
+
+	// The counters restart if the peer is configured again
+	if !ok || observed.chosen != chosen || peer.TransmitBytes < observed.transmitted {
+		meshObservations[identity] = observation{chosen: chosen, transmitted: peer.TransmitBytes}
+		return false
+	}
+
+	chosenAt := time.Unix(chosen, 0)
+
+	return peer.TransmitBytes > observed.transmitted && peer.LastHandshakeTime.Before(chosenAt) &&
+		time.Since(chosenAt) >= HANDSHAKE_TIMEOUT
+}
+
+// exchange: choose a new key and hand it to the node
+func (e *Exchanger) exchange(meshId string, admission *mesh.Admission, node mesh.MeshNode) error {
+	key, err := wgtypes.GenerateKey()
+
+	if err != nil {
+		return err
+	}
+
+	nodeId := mesh.NodeID(node)
+
+	exchange := mesh.PresharedKeyExchange{
+		MeshId:    meshId,
+		Initiator: e.meshManager.GetPublicKey().String(),
+		Responder: nodeId,
+		Key:       key[:],
+		Timestamp: time.Now().Unix(),
+	}
+
+	if err := exchange.Sign(e.meshManager.Sign); err != nil {
+		return err
+	}
+
+	peerConnection, err := e.connectionManager.GetConnection(node.GetHostEndpoint())
+
+	if err != nil {
+		return err
+	}
+
+	client, err := peerConnection.GetClient()
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.conf.Timeout)*time.Second)
+	defer cancel()
+
+	reply, err := rpc.NewMeshCtrlServerClient(client).ExchangePresharedKey(ctx, &rpc.ExchangePresharedKeyRequest{
+		MeshId:    exchange.MeshId,
+		Initiator: exchange.Initiator,
+		Responder: exchange.Responder,
+		Key:       exchange.Key,
+		Timestamp: exchange.Timestamp,
+		Signature: exchange.Signature,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// The node holds a key it chose itself since
+	if !reply.Installed {
+		return nil
+	}
+
+	e.meshManager.GetPresharedKeys().Set(meshId, admission.Identity(nodeId), key, exchange.Timestamp)
+	return nil
+}
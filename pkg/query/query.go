@@ -1,12 +1,17 @@
 package query
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/jmespath/go-jmespath"
 	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/labels"
 	"github.com/tim-beatham/smegmesh/pkg/lib"
 	"github.com/tim-beatham/smegmesh/pkg/mesh"
 )
@@ -14,7 +19,9 @@ import (
 // Querier queries a data store for the given data
 // and returns data in the corresponding encoding
 type Querier interface {
-	Query(meshId string, queryParams string) ([]byte, error)
+	// Query: query the nodes matching the label selector. An empty
+	// selector matches every node and an empty query returns the nodes
+	Query(meshId string, selector string, queryParams string) ([]byte, error)
 }
 
 // JmesQuerier: queries the datstore in JMESPath syntax
@@ -34,6 +41,16 @@ type QueryRoute struct {
 	Path        string `json:"path"`
 }
 
+// QueryService: represents a healthy service offered by a node
+type QueryService struct {
+	Name     string             `json:"name"`
+	Port     int                `json:"port"`
+	Protocol string             `json:"protocol"`
+	Tags     []string           `json:"tags"`
+	Metadata map[string]string  `json:"metadata"`
+	Status   mesh.ServiceStatus `json:"status"`
+}
+
 // QueryNode: represents a single node in the query
 type QueryNode struct {
 	HostEndpoint string            `json:"hostEndpoint"`
@@ -45,7 +62,12 @@ type QueryNode struct {
 	Routes       []QueryRoute      `json:"routes"`
 	Alias        string            `json:"alias"`
 	Services     map[string]string `json:"services"`
-	Type         conf.NodeType     `json:"type"`
+	Labels       map[string]string `json:"labels"`
+	// ServiceRecords: services the node offers that are not critical
+	// and have not expired
+	ServiceRecords []QueryService `json:"serviceRecords"`
+	Type           conf.NodeType  `json:"type"`
+	Version        string         `json:"version"`
 }
 
 func (m *QueryError) Error() string {
@@ -53,20 +75,39 @@ func (m *QueryError) Error() string {
 }
 
 // Query: queries the the datastore at the given meshid
-func (j *JmesQuerier) Query(meshId, queryParams string) ([]byte, error) {
+func (j *JmesQuerier) Query(meshId, selector, queryParams string) ([]byte, error) {
 	mesh, ok := j.manager.GetMeshes()[meshId]
 
 	if !ok {
 		return nil, &QueryError{msg: fmt.Sprintf("%s does not exist", meshId)}
 	}
 
+	labelSelector, err := labels.Parse(selector)
+
+	if err != nil {
+		return nil, &QueryError{msg: err.Error()}
+	}
+
 	snapshot, err := mesh.GetMesh()
 
 	if err != nil {
 		return nil, err
 	}
 
-	nodes := lib.Map(lib.MapValues(snapshot.GetNodes()), MeshNodeToQueryNode)
+	admission, err := mesh.GetAdmission()
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := SnapshotToQueryNodes(admission, snapshot)
+	nodes = lib.Filter(nodes, func(node *QueryNode) bool {
+		return labelSelector.Matches(node.Labels)
+	})
+
+	if queryParams == "" {
+		return json.Marshal(nodes)
+	}
 
 	result, err := jmespath.Search(queryParams, nodes)
 
@@ -100,11 +141,119 @@ func MeshNodeToQueryNode(node mesh.MeshNode) *QueryNode {
 	queryNode.Description = node.GetDescription()
 	queryNode.Alias = node.GetAlias()
 	queryNode.Services = node.GetServices()
+	queryNode.Labels = node.GetLabels()
+
+	if queryNode.Labels == nil {
+		queryNode.Labels = make(map[string]string)
+	}
+	queryNode.ServiceRecords = lib.Map(mesh.DiscoverableServices(node, time.Now()), func(s mesh.Service) QueryService {
+		tags := s.Tags
+
+		if tags == nil {
+			tags = make([]string, 0)
+		}
+
+		metadata := s.Metadata
+
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+
+		return QueryService{
+			Name:     s.Name,
+			Port:     s.Port,
+			Protocol: s.Protocol,
+			Tags:     tags,
+			Metadata: metadata,
+			Status:   s.Status,
+		}
+	})
 	queryNode.Type = node.GetType()
+	queryNode.Version = node.GetVersion()
 
 	return queryNode
 }
 
+// SnapshotToQueryNodes: convert the nodes in the snapshot into the query
+// abstraction. Nodes claiming the same alias are given the aliases
+// resolved by mesh.ResolveAliases
+func SnapshotToQueryNodes(admission *mesh.Admission, snapshot mesh.MeshSnapshot) []*QueryNode {
+	nodes := snapshot.GetNodes()
+	aliases, _ := mesh.ResolveAliases(admission, nodes)
+
+	return lib.Map(lib.MapValues(nodes), func(node mesh.MeshNode) *QueryNode {
+		queryNode := MeshNodeToQueryNode(node)
+
+		if alias, ok := aliases[queryNode.PublicKey]; ok {
+			queryNode.Alias = alias
+		}
+
+		return queryNode
+	})
+}
+
 func NewJmesQuerier(manager mesh.MeshManager) Querier {
 	return &JmesQuerier{manager: manager}
 }
+
+// Nodes: the nodes in every mesh keyed by mesh id, the id of every
+// named mesh keyed by its lowercased name and a version that changes whenever a node
+// or name changes. Timestamps are omitted so that heartbeats do not
+// change the version
+func Nodes(manager mesh.MeshManager) (map[string][]QueryNode, map[string]string, string, error) {
+	meshes := make(map[string][]QueryNode)
+	names := make(map[string]string)
+	ambiguous := make(map[string]bool)
+
+	for meshId, theMesh := range manager.GetMeshes() {
+		snapshot, err := theMesh.GetMesh()
+
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		if name := strings.ToLower(mesh.GetMeshName(theMesh)); name != "" {
+			if _, ok := names[name]; ok {
+				ambiguous[name] = true
+			}
+
+			names[name] = meshId
+		}
+
+		admission, err := theMesh.GetAdmission()
+
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		nodes := lib.Map(SnapshotToQueryNodes(admission, snapshot), func(queryNode *QueryNode) QueryNode {
+			queryNode.Timestamp = 0
+
+			slices.SortFunc(queryNode.Routes, func(r1, r2 QueryRoute) int {
+				return strings.Compare(r1.Destination, r2.Destination)
+			})
+
+			return *queryNode
+		})
+
+		slices.SortFunc(nodes, func(n1, n2 QueryNode) int {
+			return strings.Compare(n1.PublicKey, n2.PublicKey)
+		})
+
+		meshes[meshId] = nodes
+	}
+
+	// Names shared by several meshes cannot be resolved
+	for name := range ambiguous {
+		delete(names, name)
+	}
+
+	bytes, err := json.Marshal([]any{meshes, names})
+
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	sum := sha256.Sum256(bytes)
+	return meshes, names, hex.EncodeToString(sum[:]), nil
+}
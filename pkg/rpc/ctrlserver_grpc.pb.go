@@ -2,7 +2,7 @@
 // versions:
 // - protoc-gen-go-grpc v1.2.0
 // - protoc             v3.21.12
-// source: pkg/grpc/ctrlserver/ctrlserver.proto
+// source: pkg/grpc/ctrlserver.proto
 
 package rpc
 
@@ -23,6 +23,10 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MeshCtrlServerClient interface {
 	GetMesh(ctx context.Context, in *GetMeshRequest, opts ...grpc.CallOption) (*GetMeshReply, error)
+	GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*GetStateReply, error)
+	RequestJoin(ctx context.Context, in *RequestJoinRequest, opts ...grpc.CallOption) (*RequestJoinReply, error)
+	SignCertificate(ctx context.Context, in *SignCertificateRequest, opts ...grpc.CallOption) (*SignCertificateReply, error)
+	ExchangePresharedKey(ctx context.Context, in *ExchangePresharedKeyRequest, opts ...grpc.CallOption) (*ExchangePresharedKeyReply, error)
 }
 
 type meshCtrlServerClient struct {
@@ -42,11 +46,51 @@ func (c *meshCtrlServerClient) GetMesh(ctx context.Context, in *GetMeshRequest,
 	return out, nil
 }
 
+func (c *meshCtrlServerClient) GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*GetStateReply, error) {
+	out := new(GetStateReply)
+	err := c.cc.Invoke(ctx, "/rpctypes.MeshCtrlServer/GetState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshCtrlServerClient) RequestJoin(ctx context.Context, in *RequestJoinRequest, opts ...grpc.CallOption) (*RequestJoinReply, error) {
+	out := new(RequestJoinReply)
+	err := c.cc.Invoke(ctx, "/rpctypes.MeshCtrlServer/RequestJoin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshCtrlServerClient) SignCertificate(ctx context.Context, in *SignCertificateRequest, opts ...grpc.CallOption) (*SignCertificateReply, error) {
+	out := new(SignCertificateReply)
+	err := c.cc.Invoke(ctx, "/rpctypes.MeshCtrlServer/SignCertificate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshCtrlServerClient) ExchangePresharedKey(ctx context.Context, in *ExchangePresharedKeyRequest, opts ...grpc.CallOption) (*ExchangePresharedKeyReply, error) {
+	out := new(ExchangePresharedKeyReply)
+	err := c.cc.Invoke(ctx, "/rpctypes.MeshCtrlServer/ExchangePresharedKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MeshCtrlServerServer is the server API for MeshCtrlServer service.
 // All implementations must embed UnimplementedMeshCtrlServerServer
 // for forward compatibility
 type MeshCtrlServerServer interface {
 	GetMesh(context.Context, *GetMeshRequest) (*GetMeshReply, error)
+	GetState(context.Context, *GetStateRequest) (*GetStateReply, error)
+	RequestJoin(context.Context, *RequestJoinRequest) (*RequestJoinReply, error)
+	SignCertificate(context.Context, *SignCertificateRequest) (*SignCertificateReply, error)
+	ExchangePresharedKey(context.Context, *ExchangePresharedKeyRequest) (*ExchangePresharedKeyReply, error)
 	mustEmbedUnimplementedMeshCtrlServerServer()
 }
 
@@ -57,6 +101,18 @@ type UnimplementedMeshCtrlServerServer struct {
 func (UnimplementedMeshCtrlServerServer) GetMesh(context.Context, *GetMeshRequest) (*GetMeshReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMesh not implemented")
 }
+func (UnimplementedMeshCtrlServerServer) GetState(context.Context, *GetStateRequest) (*GetStateReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetState not implemented")
+}
+func (UnimplementedMeshCtrlServerServer) RequestJoin(context.Context, *RequestJoinRequest) (*RequestJoinReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestJoin not implemented")
+}
+func (UnimplementedMeshCtrlServerServer) SignCertificate(context.Context, *SignCertificateRequest) (*SignCertificateReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignCertificate not implemented")
+}
+func (UnimplementedMeshCtrlServerServer) ExchangePresharedKey(context.Context, *ExchangePresharedKeyRequest) (*ExchangePresharedKeyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExchangePresharedKey not implemented")
+}
 func (UnimplementedMeshCtrlServerServer) mustEmbedUnimplementedMeshCtrlServerServer() {}
 
 // UnsafeMeshCtrlServerServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +144,78 @@ func _MeshCtrlServer_GetMesh_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MeshCtrlServer_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshCtrlServerServer).GetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpctypes.MeshCtrlServer/GetState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshCtrlServerServer).GetState(ctx, req.(*GetStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshCtrlServer_RequestJoin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestJoinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshCtrlServerServer).RequestJoin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpctypes.MeshCtrlServer/RequestJoin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshCtrlServerServer).RequestJoin(ctx, req.(*RequestJoinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshCtrlServer_SignCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshCtrlServerServer).SignCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpctypes.MeshCtrlServer/SignCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshCtrlServerServer).SignCertificate(ctx, req.(*SignCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshCtrlServer_ExchangePresharedKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExchangePresharedKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshCtrlServerServer).ExchangePresharedKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpctypes.MeshCtrlServer/ExchangePresharedKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshCtrlServerServer).ExchangePresharedKey(ctx, req.(*ExchangePresharedKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MeshCtrlServer_ServiceDesc is the grpc.ServiceDesc for MeshCtrlServer service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,7 +227,23 @@ var MeshCtrlServer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMesh",
 			Handler:    _MeshCtrlServer_GetMesh_Handler,
 		},
+		{
+			MethodName: "GetState",
+			Handler:    _MeshCtrlServer_GetState_Handler,
+		},
+		{
+			MethodName: "RequestJoin",
+			Handler:    _MeshCtrlServer_RequestJoin_Handler,
+		},
+		{
+			MethodName: "SignCertificate",
+			Handler:    _MeshCtrlServer_SignCertificate_Handler,
+		},
+		{
+			MethodName: "ExchangePresharedKey",
+			Handler:    _MeshCtrlServer_ExchangePresharedKey_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
-	Metadata: "pkg/grpc/ctrlserver/ctrlserver.proto",
+	Metadata: "pkg/grpc/ctrlserver.proto",
 }
@@ -1,8 +1,8 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.1
+// 	protoc-gen-go v1.31.0
 // 	protoc        v3.21.12
-// source: pkg/grpc/ctrlserver/syncservice.proto
+// source: pkg/grpc/syncservice.proto
 
 package rpc
 
@@ -31,7 +31,7 @@ type GetConfRequest struct {
 func (x *GetConfRequest) Reset() {
 	*x = GetConfRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[0]
+		mi := &file_pkg_grpc_syncservice_proto_msgTypes[0]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -44,7 +44,7 @@ func (x *GetConfRequest) String() string {
 func (*GetConfRequest) ProtoMessage() {}
 
 func (x *GetConfRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[0]
+	mi := &file_pkg_grpc_syncservice_proto_msgTypes[0]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -57,7 +57,7 @@ func (x *GetConfRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetConfRequest.ProtoReflect.Descriptor instead.
 func (*GetConfRequest) Descriptor() ([]byte, []int) {
-	return file_pkg_grpc_ctrlserver_syncservice_proto_rawDescGZIP(), []int{0}
+	return file_pkg_grpc_syncservice_proto_rawDescGZIP(), []int{0}
 }
 
 func (x *GetConfRequest) GetMeshId() string {
@@ -78,7 +78,7 @@ type GetConfReply struct {
 func (x *GetConfReply) Reset() {
 	*x = GetConfReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[1]
+		mi := &file_pkg_grpc_syncservice_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -91,7 +91,7 @@ func (x *GetConfReply) String() string {
 func (*GetConfReply) ProtoMessage() {}
 
 func (x *GetConfReply) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[1]
+	mi := &file_pkg_grpc_syncservice_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -104,7 +104,7 @@ func (x *GetConfReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetConfReply.ProtoReflect.Descriptor instead.
 func (*GetConfReply) Descriptor() ([]byte, []int) {
-	return file_pkg_grpc_ctrlserver_syncservice_proto_rawDescGZIP(), []int{1}
+	return file_pkg_grpc_syncservice_proto_rawDescGZIP(), []int{1}
 }
 
 func (x *GetConfReply) GetMesh() []byte {
@@ -114,19 +114,31 @@ func (x *GetConfReply) GetMesh() []byte {
 	return nil
 }
 
+// SyncMeshRequest: the first request in a stream is a handshake
+// containing the protocol version and capabilities without changes.
+// A version of 0 denotes a legacy node that does not handshake
 type SyncMeshRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	MeshId  string `protobuf:"bytes,1,opt,name=meshId,proto3" json:"meshId,omitempty"`
-	Changes []byte `protobuf:"bytes,2,opt,name=changes,proto3" json:"changes,omitempty"`
+	MeshId       string   `protobuf:"bytes,1,opt,name=meshId,proto3" json:"meshId,omitempty"`
+	Changes      []byte   `protobuf:"bytes,2,opt,name=changes,proto3" json:"changes,omitempty"`
+	Version      uint32   `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	MinVersion   uint32   `protobuf:"varint,4,opt,name=minVersion,proto3" json:"minVersion,omitempty"`
+	Capabilities []string `protobuf:"bytes,5,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	// nodeId: public key of the node syncing. Must be named by the
+	// node's certificate if certificates are bound
+	NodeId string `protobuf:"bytes,6,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	// credential: join credential of a node that peers may not yet
+	// know is a member
+	Credential string `protobuf:"bytes,7,opt,name=credential,proto3" json:"credential,omitempty"`
 }
 
 func (x *SyncMeshRequest) Reset() {
 	*x = SyncMeshRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[2]
+		mi := &file_pkg_grpc_syncservice_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -139,7 +151,7 @@ func (x *SyncMeshRequest) String() string {
 func (*SyncMeshRequest) ProtoMessage() {}
 
 func (x *SyncMeshRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[2]
+	mi := &file_pkg_grpc_syncservice_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -152,7 +164,7 @@ func (x *SyncMeshRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SyncMeshRequest.ProtoReflect.Descriptor instead.
 func (*SyncMeshRequest) Descriptor() ([]byte, []int) {
-	return file_pkg_grpc_ctrlserver_syncservice_proto_rawDescGZIP(), []int{2}
+	return file_pkg_grpc_syncservice_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *SyncMeshRequest) GetMeshId() string {
@@ -169,19 +181,59 @@ func (x *SyncMeshRequest) GetChanges() []byte {
 	return nil
 }
 
+func (x *SyncMeshRequest) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *SyncMeshRequest) GetMinVersion() uint32 {
+	if x != nil {
+		return x.MinVersion
+	}
+	return 0
+}
+
+func (x *SyncMeshRequest) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *SyncMeshRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *SyncMeshRequest) GetCredential() string {
+	if x != nil {
+		return x.Credential
+	}
+	return ""
+}
+
+// SyncMeshReply: the first reply in a stream responds to the handshake
+// with the negotiated version and capabilities
 type SyncMeshReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Changes []byte `protobuf:"bytes,2,opt,name=changes,proto3" json:"changes,omitempty"`
+	Success      bool     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Changes      []byte   `protobuf:"bytes,2,opt,name=changes,proto3" json:"changes,omitempty"`
+	Version      uint32   `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	MinVersion   uint32   `protobuf:"varint,4,opt,name=minVersion,proto3" json:"minVersion,omitempty"`
+	Capabilities []string `protobuf:"bytes,5,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
 }
 
 func (x *SyncMeshReply) Reset() {
 	*x = SyncMeshReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[3]
+		mi := &file_pkg_grpc_syncservice_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -194,7 +246,7 @@ func (x *SyncMeshReply) String() string {
 func (*SyncMeshReply) ProtoMessage() {}
 
 func (x *SyncMeshReply) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[3]
+	mi := &file_pkg_grpc_syncservice_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -207,7 +259,7 @@ func (x *SyncMeshReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SyncMeshReply.ProtoReflect.Descriptor instead.
 func (*SyncMeshReply) Descriptor() ([]byte, []int) {
-	return file_pkg_grpc_ctrlserver_syncservice_proto_rawDescGZIP(), []int{3}
+	return file_pkg_grpc_syncservice_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *SyncMeshReply) GetSuccess() bool {
@@ -224,59 +276,95 @@ func (x *SyncMeshReply) GetChanges() []byte {
 	return nil
 }
 
-var File_pkg_grpc_ctrlserver_syncservice_proto protoreflect.FileDescriptor
-
-var file_pkg_grpc_ctrlserver_syncservice_proto_rawDesc = []byte{
-	0x0a, 0x25, 0x70, 0x6b, 0x67, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x63, 0x74, 0x72, 0x6c, 0x73,
-	0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x73, 0x79, 0x6e, 0x63, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
-	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0b, 0x73, 0x79, 0x6e, 0x63, 0x73, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x22, 0x28, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x73, 0x68, 0x49, 0x64,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x65, 0x73, 0x68, 0x49, 0x64, 0x22, 0x22,
-	0x0a, 0x0c, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12,
-	0x0a, 0x04, 0x6d, 0x65, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x6d, 0x65,
-	0x73, 0x68, 0x22, 0x43, 0x0a, 0x0f, 0x53, 0x79, 0x6e, 0x63, 0x4d, 0x65, 0x73, 0x68, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x73, 0x68, 0x49, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x65, 0x73, 0x68, 0x49, 0x64, 0x12, 0x18, 0x0a,
-	0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07,
-	0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x22, 0x43, 0x0a, 0x0d, 0x53, 0x79, 0x6e, 0x63, 0x4d,
-	0x65, 0x73, 0x68, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63,
-	0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65,
-	0x73, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x32, 0x9e, 0x01, 0x0a,
-	0x0b, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x43, 0x0a, 0x07,
-	0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x1b, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x73, 0x65,
-	0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x73, 0x65, 0x72, 0x76, 0x69,
-	0x63, 0x65, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22,
-	0x00, 0x12, 0x4a, 0x0a, 0x08, 0x53, 0x79, 0x6e, 0x63, 0x4d, 0x65, 0x73, 0x68, 0x12, 0x1c, 0x2e,
-	0x73, 0x79, 0x6e, 0x63, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x79, 0x6e, 0x63,
-	0x4d, 0x65, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x73, 0x79,
-	0x6e, 0x63, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x4d, 0x65,
-	0x73, 0x68, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x42, 0x09, 0x5a,
-	0x07, 0x70, 0x6b, 0x67, 0x2f, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *SyncMeshReply) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *SyncMeshReply) GetMinVersion() uint32 {
+	if x != nil {
+		return x.MinVersion
+	}
+	return 0
+}
+
+func (x *SyncMeshReply) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+var File_pkg_grpc_syncservice_proto protoreflect.FileDescriptor
+
+var file_pkg_grpc_syncservice_proto_rawDesc = []byte{
+	0x0a, 0x1a, 0x70, 0x6b, 0x67, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x73, 0x79, 0x6e, 0x63, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0b, 0x73, 0x79,
+	0x6e, 0x63, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x22, 0x28, 0x0a, 0x0e, 0x47, 0x65, 0x74,
+	0x43, 0x6f, 0x6e, 0x66, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6d,
+	0x65, 0x73, 0x68, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x65, 0x73,
+	0x68, 0x49, 0x64, 0x22, 0x22, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x65, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x04, 0x6d, 0x65, 0x73, 0x68, 0x22, 0xd9, 0x01, 0x0a, 0x0f, 0x53, 0x79, 0x6e, 0x63,
+	0x4d, 0x65, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6d,
+	0x65, 0x73, 0x68, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x65, 0x73,
+	0x68, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x12, 0x18, 0x0a,
+	0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x69, 0x6e, 0x56, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x6d, 0x69, 0x6e,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62,
+	0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x63,
+	0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x6e,
+	0x6f, 0x64, 0x65, 0x49, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6e, 0x6f, 0x64,
+	0x65, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61,
+	0x6c, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74,
+	0x69, 0x61, 0x6c, 0x22, 0xa1, 0x01, 0x0a, 0x0d, 0x53, 0x79, 0x6e, 0x63, 0x4d, 0x65, 0x73, 0x68,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12,
+	0x18, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x69, 0x6e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x6d, 0x69, 0x6e, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74,
+	0x69, 0x65, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62,
+	0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x32, 0x9e, 0x01, 0x0a, 0x0b, 0x53, 0x79, 0x6e, 0x63,
+	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x43, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x43, 0x6f,
+	0x6e, 0x66, 0x12, 0x1b, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x2e, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x19, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x47, 0x65,
+	0x74, 0x43, 0x6f, 0x6e, 0x66, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x4a, 0x0a, 0x08,
+	0x53, 0x79, 0x6e, 0x63, 0x4d, 0x65, 0x73, 0x68, 0x12, 0x1c, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x4d, 0x65, 0x73, 0x68, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x73, 0x79, 0x6e, 0x63, 0x73, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x79, 0x6e, 0x63, 0x4d, 0x65, 0x73, 0x68, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x42, 0x09, 0x5a, 0x07, 0x70, 0x6b, 0x67, 0x2f,
+	0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
-	file_pkg_grpc_ctrlserver_syncservice_proto_rawDescOnce sync.Once
-	file_pkg_grpc_ctrlserver_syncservice_proto_rawDescData = file_pkg_grpc_ctrlserver_syncservice_proto_rawDesc
+	file_pkg_grpc_syncservice_proto_rawDescOnce sync.Once
+	file_pkg_grpc_syncservice_proto_rawDescData = file_pkg_grpc_syncservice_proto_rawDesc
 )
 
-func file_pkg_grpc_ctrlserver_syncservice_proto_rawDescGZIP() []byte {
-	file_pkg_grpc_ctrlserver_syncservice_proto_rawDescOnce.Do(func() {
-		file_pkg_grpc_ctrlserver_syncservice_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_grpc_ctrlserver_syncservice_proto_rawDescData)
+func file_pkg_grpc_syncservice_proto_rawDescGZIP() []byte {
+	file_pkg_grpc_syncservice_proto_rawDescOnce.Do(func() {
+		file_pkg_grpc_syncservice_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_grpc_syncservice_proto_rawDescData)
 	})
-	return file_pkg_grpc_ctrlserver_syncservice_proto_rawDescData
+	return file_pkg_grpc_syncservice_proto_rawDescData
 }
 
-var file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
-var file_pkg_grpc_ctrlserver_syncservice_proto_goTypes = []interface{}{
+var file_pkg_grpc_syncservice_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_pkg_grpc_syncservice_proto_goTypes = []interface{}{
 	(*GetConfRequest)(nil),  // 0: syncservice.GetConfRequest
 	(*GetConfReply)(nil),    // 1: syncservice.GetConfReply
 	(*SyncMeshRequest)(nil), // 2: syncservice.SyncMeshRequest
 	(*SyncMeshReply)(nil),   // 3: syncservice.SyncMeshReply
 }
-var file_pkg_grpc_ctrlserver_syncservice_proto_depIdxs = []int32{
+var file_pkg_grpc_syncservice_proto_depIdxs = []int32{
 	0, // 0: syncservice.SyncService.GetConf:input_type -> syncservice.GetConfRequest
 	2, // 1: syncservice.SyncService.SyncMesh:input_type -> syncservice.SyncMeshRequest
 	1, // 2: syncservice.SyncService.GetConf:output_type -> syncservice.GetConfReply
@@ -288,13 +376,13 @@ var file_pkg_grpc_ctrlserver_syncservice_proto_depIdxs = []int32{
 	0, // [0:0] is the sub-list for field type_name
 }
 
-func init() { file_pkg_grpc_ctrlserver_syncservice_proto_init() }
-func file_pkg_grpc_ctrlserver_syncservice_proto_init() {
-	if File_pkg_grpc_ctrlserver_syncservice_proto != nil {
+func init() { file_pkg_grpc_syncservice_proto_init() }
+func file_pkg_grpc_syncservice_proto_init() {
+	if File_pkg_grpc_syncservice_proto != nil {
 		return
 	}
 	if !protoimpl.UnsafeEnabled {
-		file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+		file_pkg_grpc_syncservice_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*GetConfRequest); i {
 			case 0:
 				return &v.state
@@ -306,7 +394,7 @@ func file_pkg_grpc_ctrlserver_syncservice_proto_init() {
 				return nil
 			}
 		}
-		file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+		file_pkg_grpc_syncservice_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*GetConfReply); i {
 			case 0:
 				return &v.state
@@ -318,7 +406,7 @@ func file_pkg_grpc_ctrlserver_syncservice_proto_init() {
 				return nil
 			}
 		}
-		file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+		file_pkg_grpc_syncservice_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*SyncMeshRequest); i {
 			case 0:
 				return &v.state
@@ -330,7 +418,7 @@ func file_pkg_grpc_ctrlserver_syncservice_proto_init() {
 				return nil
 			}
 		}
-		file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+		file_pkg_grpc_syncservice_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*SyncMeshReply); i {
 			case 0:
 				return &v.state
@@ -347,18 +435,18 @@ func file_pkg_grpc_ctrlserver_syncservice_proto_init() {
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_pkg_grpc_ctrlserver_syncservice_proto_rawDesc,
+			RawDescriptor: file_pkg_grpc_syncservice_proto_rawDesc,
 			NumEnums:      0,
 			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
-		GoTypes:           file_pkg_grpc_ctrlserver_syncservice_proto_goTypes,
-		DependencyIndexes: file_pkg_grpc_ctrlserver_syncservice_proto_depIdxs,
-		MessageInfos:      file_pkg_grpc_ctrlserver_syncservice_proto_msgTypes,
+		GoTypes:           file_pkg_grpc_syncservice_proto_goTypes,
+		DependencyIndexes: file_pkg_grpc_syncservice_proto_depIdxs,
+		MessageInfos:      file_pkg_grpc_syncservice_proto_msgTypes,
 	}.Build()
-	File_pkg_grpc_ctrlserver_syncservice_proto = out.File
-	file_pkg_grpc_ctrlserver_syncservice_proto_rawDesc = nil
-	file_pkg_grpc_ctrlserver_syncservice_proto_goTypes = nil
-	file_pkg_grpc_ctrlserver_syncservice_proto_depIdxs = nil
+	File_pkg_grpc_syncservice_proto = out.File
+	file_pkg_grpc_syncservice_proto_rawDesc = nil
+	file_pkg_grpc_syncservice_proto_goTypes = nil
+	file_pkg_grpc_syncservice_proto_depIdxs = nil
 }
@@ -2,7 +2,7 @@
 // versions:
 // - protoc-gen-go-grpc v1.2.0
 // - protoc             v3.21.12
-// source: pkg/grpc/ctrlserver/syncservice.proto
+// source: pkg/grpc/syncservice.proto
 
 package rpc
 
@@ -170,5 +170,5 @@ var SyncService_ServiceDesc = grpc.ServiceDesc{
 			ClientStreams: true,
 		},
 	},
-	Metadata: "pkg/grpc/ctrlserver/syncservice.proto",
+	Metadata: "pkg/grpc/syncservice.proto",
 }
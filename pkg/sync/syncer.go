@@ -9,6 +9,7 @@ import (
 
 	"github.com/tim-beatham/smegmesh/pkg/conf"
 	"github.com/tim-beatham/smegmesh/pkg/conn"
+	"github.com/tim-beatham/smegmesh/pkg/history"
 	"github.com/tim-beatham/smegmesh/pkg/lib"
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
 	"github.com/tim-beatham/smegmesh/pkg/mesh"
@@ -32,6 +33,7 @@ type SyncerImpl struct {
 	lastPoll       map[string]int64
 	lastSyncLock   sync.RWMutex
 	lastPollLock   sync.RWMutex
+	history        history.Store
 }
 
 // Sync: Sync with random nodes. Returns true if there was changes false otherwise
@@ -48,6 +50,7 @@ func (s *SyncerImpl) Sync(correspondingMesh mesh.MeshProvider) (bool, error) {
 
 	if correspondingMesh.HasChanges() {
 		logging.Log.WriteInfof("meshes %s has changes", correspondingMesh.GetMeshId())
+		s.recordHistory(correspondingMesh)
 	}
 
 	// If removed sync with other nodes to gossip the node is removed
@@ -134,6 +137,26 @@ func (s *SyncerImpl) Sync(correspondingMesh mesh.MeshProvider) (bool, error) {
 	return true, nil
 }
 
+// recordHistory: record the current state of the mesh in the history
+func (s *SyncerImpl) recordHistory(correspondingMesh mesh.MeshProvider) {
+	if s.history == nil {
+		return
+	}
+
+	snapshot, err := correspondingMesh.GetMesh()
+
+	if err != nil {
+		logging.Log.WriteErrorf("could not record history %s", err.Error())
+		return
+	}
+
+	err = s.history.Record(correspondingMesh.GetMeshId(), history.NewSnapshot(snapshot, time.Now()))
+
+	if err != nil {
+		logging.Log.WriteErrorf("could not record history %s", err.Error())
+	}
+}
+
 // Pull one node in the cluster, if there has not been message dissemination
 // in a certain period of time pull a random node within the cluster
 func (s *SyncerImpl) Pull(self mesh.MeshNode, mesh mesh.MeshProvider) (bool, error) {
@@ -243,6 +266,8 @@ type NewSyncerParams struct {
 	ConnectionManager conn.ConnectionManager
 	Configuration     *conf.DaemonConfiguration
 	Requester         SyncRequester
+	// History: records the history of each mesh. Not recorded if nil
+	History history.Store
 }
 
 func NewSyncer(params *NewSyncerParams) Syncer {
@@ -260,6 +285,7 @@ func NewSyncer(params *NewSyncerParams) Syncer {
 		infectionCount: 0,
 		syncCount:      0,
 		cluster:        cluster,
+		history:        params.History,
 		lastSync:       make(map[string]int64),
 		lastPoll:       make(map[string]int64)}
 }
@@ -40,6 +40,13 @@ func (s *SyncErrorHandlerImpl) handleDeadlineExceeded(mesh mesh.MeshProvider, no
 	return true
 }
 
+// handleIncompatible: the peer speaks an incompatible version of
+// the protocol. It is still alive so do not mark it as failed
+func (s *SyncErrorHandlerImpl) handleIncompatible(mesh mesh.MeshProvider, nodeId string, message string) bool {
+	logging.Log.WriteWarnf("cannot sync with %s in mesh %s: %s", nodeId, mesh.GetMeshId(), message)
+	return true
+}
+
 func (s *SyncErrorHandlerImpl) Handle(mesh mesh.MeshProvider, nodeId string, err error) bool {
 	errStatus, _ := status.FromError(err)
 
@@ -50,6 +57,8 @@ func (s *SyncErrorHandlerImpl) Handle(mesh mesh.MeshProvider, nodeId string, err
 		return s.handleFailed(mesh, nodeId)
 	case codes.DeadlineExceeded:
 		return s.handleDeadlineExceeded(mesh, nodeId)
+	case codes.FailedPrecondition:
+		return s.handleIncompatible(mesh, nodeId, errStatus.Message())
 	}
 
 	return false
@@ -10,6 +10,8 @@ import (
 	logging "github.com/tim-beatham/smegmesh/pkg/log"
 	"github.com/tim-beatham/smegmesh/pkg/mesh"
 	"github.com/tim-beatham/smegmesh/pkg/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // SyncRequester: coordinates the syncing of meshes
@@ -58,7 +60,7 @@ func (s *SyncRequesterImpl) SyncMesh(mesh mesh.MeshProvider, meshNode mesh.MeshN
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(syncTimeOut))
 	defer cancel()
 
-	err = s.syncMesh(mesh, ctx, c)
+	err = s.syncMesh(mesh, pubKey.String(), ctx, c)
 
 	if err != nil {
 		s.handleErr(mesh, pubKey.String(), err)
@@ -68,7 +70,59 @@ func (s *SyncRequesterImpl) SyncMesh(mesh mesh.MeshProvider, meshNode mesh.MeshN
 	return err
 }
 
-func (s *SyncRequesterImpl) syncMesh(mesh mesh.MeshProvider, ctx context.Context, client rpc.SyncServiceClient) error {
+// handshake: negotiate the protocol version and encoding with the
+// server. Returns false if the server is a legacy node that does not
+// understand the handshake
+func (s *SyncRequesterImpl) handshake(meshId string, stream rpc.SyncService_SyncMeshClient, syncer mesh.MeshSyncer) (bool, error) {
+	err := stream.Send(&rpc.SyncMeshRequest{
+		MeshId:       meshId,
+		Version:      PROTOCOL_VERSION,
+		MinVersion:   MIN_PROTOCOL_VERSION,
+		Capabilities: syncer.Capabilities(),
+		NodeId:       s.manager.GetPublicKey().String(),
+		Credential:   s.manager.GetCredential(meshId),
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	in, err := stream.Recv()
+
+	if err != nil {
+		return false, err
+	}
+
+	if in.Version == 0 {
+		return false, nil
+	}
+
+	if _, err := negotiateVersion(in.Version, in.MinVersion); err != nil {
+		return false, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	if _, err := syncer.Negotiate(in.Capabilities); err != nil {
+		return false, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return true, nil
+}
+
+// verifyPeer: if certificates are bound verify the endpoint we dialled
+// belongs to the node we expect
+func (s *SyncRequesterImpl) verifyPeer(stream rpc.SyncService_SyncMeshClient, theMesh mesh.MeshProvider, pubKey string) error {
+	if !s.configuration.BindCertificates {
+		return nil
+	}
+
+	if err := verifyPeerKey(stream.Context(), theMesh, pubKey); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return nil
+}
+
+func (s *SyncRequesterImpl) syncMesh(mesh mesh.MeshProvider, pubKey string, ctx context.Context, client rpc.SyncServiceClient) error {
 	stream, err := client.SyncMesh(ctx)
 
 	syncer := mesh.GetSyncer()
@@ -77,6 +131,30 @@ func (s *SyncRequesterImpl) syncMesh(mesh mesh.MeshProvider, ctx context.Context
 		return err
 	}
 
+	if err := s.verifyPeer(stream, mesh, pubKey); err != nil {
+		stream.CloseSend()
+		return err
+	}
+
+	negotiated, err := s.handshake(mesh.GetMeshId(), stream, syncer)
+
+	if err != nil {
+		return err
+	}
+
+	// A legacy server treats the handshake as the start of a sync
+	// so restart the stream and sync without it
+	if !negotiated {
+		logging.Log.WriteWarnf("node does not support sync negotiation, falling back to legacy sync")
+		stream.CloseSend()
+
+		stream, err = client.SyncMesh(ctx)
+
+		if err != nil {
+			return err
+		}
+	}
+
 	for {
 		msg, moreMessages := syncer.GenerateMessage()
 
@@ -4,23 +4,156 @@ package sync
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 
+	"github.com/tim-beatham/smegmesh/pkg/auth"
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/conn"
 	"github.com/tim-beatham/smegmesh/pkg/mesh"
 	"github.com/tim-beatham/smegmesh/pkg/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type SyncServiceImpl struct {
 	rpc.UnimplementedSyncServiceServer
 	MeshManager mesh.MeshManager
+	Conf        *conf.DaemonConfiguration
+	// Authoriser: if set authorises clients per mesh
+	Authoriser auth.Authoriser
 }
 
-// GetMesh: Gets a nodes local mesh configuration as a CRDT
-func (s *SyncServiceImpl) GetConf(context context.Context, request *rpc.GetConfRequest) (*rpc.GetConfReply, error) {
+// authenticate: if per mesh authorisation is on the client must be a
+// member of the mesh or hold a valid credential. Otherwise if
+// certificates are bound the client must not have been evicted and if
+// member is true the client's certificate must name the key of a node
+// in the mesh
+func (s *SyncServiceImpl) authenticate(ctx context.Context, theMesh mesh.MeshProvider, credential string, member bool) error {
+	if s.Authoriser != nil {
+		return s.Authoriser.Authorise(ctx, theMesh, credential)
+	}
+
+	if theMesh == nil {
+		return errors.New("mesh does not exist")
+	}
+
+	// Clients can only be identified if certificates are bound
+	if s.Conf == nil || !s.Conf.BindCertificates {
+		return nil
+	}
+
+	if err := auth.RefuseEvicted(ctx, theMesh); err != nil {
+		return err
+	}
+
+	if !member {
+		return nil
+	}
+
+	key, err := conn.PeerWgKey(ctx)
+
+	if err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	if !theMesh.NodeExists(admission.CurrentKey(key.String())) {
+		return status.Errorf(codes.PermissionDenied, "%s is not a member of the mesh", key.String())
+	}
+
+	return nil
+}
+
+// authenticateSync: authenticate the client syncing the mesh. If
+// certificates are bound check the client's certificate names the node
+// the client claims to be
+func (s *SyncServiceImpl) authenticateSync(stream rpc.SyncService_SyncMeshServer, theMesh mesh.MeshProvider, in *rpc.SyncMeshRequest) error {
+	if err := s.authenticate(stream.Context(), theMesh, in.Credential, false); err != nil {
+		return err
+	}
+
+	if err := s.admitted(stream, theMesh, in); err != nil {
+		return err
+	}
+
+	if s.Conf == nil || !s.Conf.BindCertificates {
+		return nil
+	}
+
+	if in.Version == 0 {
+		return status.Error(codes.PermissionDenied, "legacy nodes cannot be authenticated")
+	}
+
+	if err := verifyPeerKey(stream.Context(), theMesh, in.NodeId); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return nil
+}
+
+// verifyPeerKey: verify the peer's certificate names the node's key. A
+// certificate naming a key the node has rotated from or is rotating to
+// is accepted until the node is issued a new certificate
+func verifyPeerKey(ctx context.Context, theMesh mesh.MeshProvider, nodeId string) error {
+	key, err := conn.PeerWgKey(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	if !admission.SameNode(key.String(), nodeId) {
+		return fmt.Errorf("peer certificate names %s expected %s", key.String(), nodeId)
+	}
+
+	return nil
+}
+
+// admitted: refuse to sync with nodes that have been evicted or that an
+// admin has not approved if the mesh requires approval. The node is
+// identified by its certificate if it names a WireGuard key
+func (s *SyncServiceImpl) admitted(stream rpc.SyncService_SyncMeshServer, theMesh mesh.MeshProvider, in *rpc.SyncMeshRequest) error {
+	admission, err := theMesh.GetAdmission()
+
+	if err != nil {
+		return err
+	}
+
+	nodeId := in.NodeId
+
+	if key, err := conn.PeerWgKey(stream.Context()); err == nil {
+		nodeId = key.String()
+	}
+
+	if admission.IsRevoked(nodeId) {
+		return status.Errorf(codes.PermissionDenied, "%s has been evicted from the mesh", nodeId)
+	}
+
+	if !admission.IsApproved(nodeId) {
+		return status.Errorf(codes.PermissionDenied, "%s has not been approved to join the mesh", nodeId)
+	}
+
+	return nil
+}
+
+// GetConf: Gets a nodes local mesh configuration as a CRDT. Only
+// members of the mesh may read it
+func (s *SyncServiceImpl) GetConf(ctx context.Context, request *rpc.GetConfRequest) (*rpc.GetConfReply, error) {
 	mesh := s.MeshManager.GetMesh(request.MeshId)
 
-	if mesh == nil {
-		return nil, errors.New("mesh does not exist")
+	if err := s.authenticate(ctx, mesh, "", true); err != nil {
+		return nil, err
 	}
 
 	meshBytes := mesh.Save()
@@ -32,6 +165,29 @@ func (s *SyncServiceImpl) GetConf(context context.Context, request *rpc.GetConfR
 	return &reply, nil
 }
 
+// handshake: negotiate the protocol version and encoding with the
+// client. Refuses with FailedPrecondition if the two are incompatible
+func (s *SyncServiceImpl) handshake(stream rpc.SyncService_SyncMeshServer, in *rpc.SyncMeshRequest, syncer mesh.MeshSyncer) error {
+	version, err := negotiateVersion(in.Version, in.MinVersion)
+
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	encoding, err := syncer.Negotiate(in.Capabilities)
+
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return stream.Send(&rpc.SyncMeshReply{
+		Success:      true,
+		Version:      version,
+		MinVersion:   MIN_PROTOCOL_VERSION,
+		Capabilities: []string{encoding},
+	})
+}
+
 // Sync: Pings a node and syncs the mesh configuration with the other node
 // SyncMesh: syncs the two streams changes
 func (s *SyncServiceImpl) SyncMesh(stream rpc.SyncService_SyncMeshServer) error {
@@ -57,11 +213,21 @@ func (s *SyncServiceImpl) SyncMesh(stream rpc.SyncService_SyncMeshServer) error
 
 			mesh := s.MeshManager.GetMesh(meshId)
 
-			if mesh == nil {
-				return errors.New("mesh does not exist")
+			if err := s.authenticateSync(stream, mesh, in); err != nil {
+				return err
 			}
 
 			syncer = mesh.GetSyncer()
+
+			// Legacy nodes do not send a version and start syncing
+			// straight away
+			if in.Version != 0 {
+				if err := s.handshake(stream, in, syncer); err != nil {
+					return err
+				}
+
+				continue
+			}
 		} else if meshId != in.MeshId {
 			return errors.New("differing meshids")
 		}
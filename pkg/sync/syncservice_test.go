@@ -0,0 +1,28 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tim-beatham/smegmesh/pkg/auth"
+	"github.com/tim-beatham/smegmesh/pkg/conf"
+	"github.com/tim-beatham/smegmesh/pkg/mesh"
+	"github.com/tim-beatham/smegmesh/pkg/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetConfUnauthenticatedCallerDenied(t *testing.T) {
+	services := []*SyncServiceImpl{
+		{MeshManager: mesh.NewMeshManagerStub(), Authoriser: auth.NewMeshAuthoriser()},
+		{MeshManager: mesh.NewMeshManagerStub(), Conf: &conf.DaemonConfiguration{BindCertificates: true}},
+	}
+
+	for _, service := range services {
+		_, err := service.GetConf(context.Background(), &rpc.GetConfRequest{MeshId: "mesh"})
+
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf(`expected PermissionDenied got %v`, err)
+		}
+	}
+}
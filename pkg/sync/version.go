@@ -0,0 +1,27 @@
+package sync
+
+import (
+	"fmt"
+)
+
+const (
+	// PROTOCOL_VERSION: version of the sync protocol spoken by this node.
+	// Version 0 is a legacy node that does not perform the handshake
+	PROTOCOL_VERSION uint32 = 1
+	// MIN_PROTOCOL_VERSION: the oldest version of the sync protocol
+	// this node can still speak
+	MIN_PROTOCOL_VERSION uint32 = 1
+)
+
+// negotiateVersion: negotiates the protocol version to use with a peer.
+// Returns an error if the two version ranges do not overlap
+func negotiateVersion(version, minVersion uint32) (uint32, error) {
+	negotiated := min(version, PROTOCOL_VERSION)
+
+	if negotiated < minVersion || negotiated < MIN_PROTOCOL_VERSION {
+		return 0, fmt.Errorf("incompatible sync protocol: local %d-%d, peer %d-%d",
+			MIN_PROTOCOL_VERSION, PROTOCOL_VERSION, minVersion, version)
+	}
+
+	return negotiated, nil
+}
@@ -0,0 +1,59 @@
+package wg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// LoadOrCreatePrivateKey: loads the base64 encoded WireGuard private key
+// at the given path. Generates and saves a new key if the file does
+// not exist
+func LoadOrCreatePrivateKey(path string) (*wgtypes.Key, error) {
+	contents, err := os.ReadFile(path)
+
+	if err == nil {
+		key, err := wgtypes.ParseKey(strings.TrimSpace(string(contents)))
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid WireGuard private key %s: %w", path, err)
+		}
+
+		return &key, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := wgtypes.GeneratePrivateKey()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, []byte(key.String()+"\n"), 0600); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// SavePrivateKey: saves the base64 encoded WireGuard private key to the
+// given path replacing any key that is already there
+func SavePrivateKey(path string, key *wgtypes.Key) error {
+	temporary := path + ".tmp"
+
+	if err := os.WriteFile(temporary, []byte(key.String()+"\n"), 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary, path)
+}
@@ -18,3 +18,8 @@ func (w *WgInterfaceManipulatorStub) AddAddress(ifName string, addr string) erro
 func (w *WgInterfaceManipulatorStub) RemoveInterface(ifName string) error {
 	return nil
 }
+
+// SetPrivateKey replaces the private key of the given interface
+func (w *WgInterfaceManipulatorStub) SetPrivateKey(ifName string, privateKey *wgtypes.Key) error {
+	return nil
+}
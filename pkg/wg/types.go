@@ -9,6 +9,8 @@ type WgInterfaceManipulator interface {
 	AddAddress(ifName string, addr string) error
 	// RemoveInterface removes the specified interface
 	RemoveInterface(ifName string) error
+	// SetPrivateKey replaces the private key of the given interface
+	SetPrivateKey(ifName string, privateKey *wgtypes.Key) error
 }
 
 type WgError struct {
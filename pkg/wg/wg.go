@@ -88,6 +88,18 @@ func (*WgInterfaceManipulatorImpl) RemoveInterface(ifName string) error {
 	return rtnl.DeleteLink(ifName)
 }
 
+// SetPrivateKey: replaces the private key of the given interface. Peers
+// and the listen port are left untouched
+func (m *WgInterfaceManipulatorImpl) SetPrivateKey(ifName string, privateKey *wgtypes.Key) error {
+	err := m.client.ConfigureDevice(ifName, wgtypes.Config{PrivateKey: privateKey})
+
+	if err != nil {
+		return fmt.Errorf("failed to set private key: %w", err)
+	}
+
+	return nil
+}
+
 func NewWgInterfaceManipulator(client *wgctrl.Client) WgInterfaceManipulator {
 	return &WgInterfaceManipulatorImpl{client: client}
 }